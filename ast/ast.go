@@ -0,0 +1,98 @@
+// Package ast declares the types of the syntax tree of a HOCON
+// document, as package parser builds it and package format prints it.
+//
+// The tree keeps what formatting keeps: comments, blank lines, and the
+// text of keys, values and include targets as written, with their
+// quotes. It does not merge objects, follow includes or resolve
+// substitutions.
+package ast
+
+// A Node is one of *File, *Object, *Array, *Field, *Include, *Value and
+// *Comment.
+type Node interface {
+	astNode()
+}
+
+// A File is a whole document. Root is an *Object, which is written
+// without braces if its Braces is false, or an *Array. Before and After
+// hold the comments before and after the braces or brackets of a root
+// written with them; Blank reports whether a blank line comes between
+// Before and Root.
+type File struct {
+	Before []Item
+	Blank  bool
+	Root   Node
+	After  []Item
+}
+
+// An Object is an object. Its items are *Field, *Include and *Comment
+// nodes. Braces is false only for the root of a File written without
+// braces. An object whose Multiline is false is written on one line
+// if it holds no comments.
+type Object struct {
+	Braces    bool
+	Multiline bool
+	Items     []Item
+}
+
+// An Array is an array. Its items are *Value and *Comment nodes. An
+// array whose Multiline is false is written on one line if it holds no
+// comments.
+type Array struct {
+	Multiline bool
+	Items     []Item
+}
+
+// A Field is a key and its value. Key is the key as written, such as
+// a.b or "a b"; Sep is "=", ":", "+=", or "" before an object written
+// without a separator.
+type Field struct {
+	Key   string
+	Sep   string
+	Value *Value
+}
+
+// An Include is an include statement. Target is what follows the
+// include keyword, such as "a.conf" or required(file("a.conf")).
+type Include struct {
+	Target string
+}
+
+// A Value is a value: a single part, or the parts of a concatenation,
+// as in ${base} "/logs" or {a = 1} {b = 2}.
+type Value struct {
+	Parts []Part
+}
+
+// A Part is one part of a Value. It is either text, such as 10,
+// "a string" or ${a.b}, or an *Object or *Array in Node. Space is the
+// white space, spaces and tabs, between the part and the one before,
+// as written; between strings it is part of the value of the
+// concatenation. It is empty for the first part.
+type Part struct {
+	Space string
+	Text  string
+	Node  Node
+}
+
+// A Comment is a comment, with its marker, such as # note or // note.
+type Comment struct {
+	Text string
+}
+
+// An Item is one item of an object, an array or the comments around a
+// root. Comment is the comment after Node on its line, if any, and
+// Blank reports whether a blank line comes before the item.
+type Item struct {
+	Node    Node
+	Comment *Comment
+	Blank   bool
+}
+
+func (*File) astNode()    {}
+func (*Object) astNode()  {}
+func (*Array) astNode()   {}
+func (*Field) astNode()   {}
+func (*Include) astNode() {}
+func (*Value) astNode()   {}
+func (*Comment) astNode() {}
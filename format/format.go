@@ -0,0 +1,107 @@
+// Package format formats HOCON documents as the hoconfmt command does,
+// for programs that format HOCON without running the command.
+package format
+
+import (
+	"io"
+
+	"github.com/chankh/hoconfmt/ast"
+	"github.com/chankh/hoconfmt/internal/hoconfmt"
+)
+
+// Options select how File formats a document, as the flags of the
+// hoconfmt command do. The zero value, and DefaultOptions, format as
+// hoconfmt does without flags.
+type Options = hoconfmt.Options
+
+// DefaultOptions returns the options of hoconfmt run without flags.
+func DefaultOptions() Options {
+	return hoconfmt.DefaultOptions()
+}
+
+// A UnitClassifier recognizes units besides those of HOCON durations,
+// periods and sizes, such as req/s in 10 req/s, for Options. Values
+// with such a unit are typed values: formatting neither quotes them
+// nor changes their spacing.
+type UnitClassifier = hoconfmt.UnitClassifier
+
+// Units is a UnitClassifier of the units it maps to their kind, such
+// as Units{"req/s": "rate"}.
+type Units = hoconfmt.Units
+
+// A ValueTransformer rewrites the values of fields, such as to write
+// host names in lower case, for Options. With opts.Verify, File fails
+// if formatting its result again would change it, as it would with a
+// ValueTransformer that rewrites what it wrote itself.
+type ValueTransformer = hoconfmt.ValueTransformer
+
+// Source formats src, the contents of a HOCON file, as hoconfmt
+// formats it without flags. Warnings, such as those about block
+// comments kept as text, are dropped.
+func Source(src []byte) ([]byte, error) {
+	return File("", src, options())
+}
+
+// File formats src, the contents of the file filename, with opts. The
+// filename is used in errors and warnings and, with opts.EditorConfig,
+// to find the .editorconfig; it may be empty. Warnings go to
+// opts.Warnings, or to standard error if it is nil.
+func File(filename string, src []byte, opts Options) ([]byte, error) {
+	return hoconfmt.Format(filename, src, opts)
+}
+
+// Node writes node to w formatted as Source formats a document. A
+// *ast.File is written as a document; an *ast.Object, *ast.Array,
+// *ast.Field, *ast.Include or *ast.Comment as a document holding only
+// it; and an *ast.Value as it is formatted as the value of a field,
+// without the key.
+func Node(w io.Writer, node ast.Node) error {
+	return hoconfmt.FormatNode(w, node, options())
+}
+
+// Equal reports whether the HOCON documents a and b have the same
+// value, whatever their layout, comments and the order of their keys,
+// as -verify compares a document with its formatting. Fields are merged
+// as HOCON merges them, and substitutions resolved where the document
+// sets their paths; include statements are not followed. If the
+// documents differ, the error is a *Difference describing the first
+// difference.
+func Equal(a, b []byte) (bool, error) {
+	return hoconfmt.Equal(a, b)
+}
+
+// A Difference is the first difference Equal finds between two
+// documents.
+type Difference = hoconfmt.Difference
+
+// A Value is a HOCON value built in Go, to be written by Marshal: nil,
+// a bool, a string, an int, int64, float64 or json.Number, a Subst, a
+// []Value, an Object, or a map[string]Value, written with its keys
+// sorted.
+type Value = hoconfmt.Value
+
+// Subst is a substitution of the value at Path, such as ${db.host}, or
+// ${?db.host} if Optional.
+type Subst = hoconfmt.Subst
+
+// Object is an object whose fields keep their order.
+type Object = hoconfmt.Object
+
+// A Field is one key and value of an Object.
+type Field = hoconfmt.Field
+
+// Marshal writes v as the text of a HOCON value, laid out as File lays
+// out a document: an object is written in braces, one field per line,
+// with opts.ObjectEq deciding whether keys with object values get a
+// separator. The text does not end in a newline.
+func Marshal(v Value, opts Options) ([]byte, error) {
+	return hoconfmt.FormatValue(v, opts)
+}
+
+// options returns the options of a hoconfmt run without flags, with
+// warnings dropped rather than written to standard error.
+func options() Options {
+	opts := DefaultOptions()
+	opts.Warnings = io.Discard
+	return opts
+}
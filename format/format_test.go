@@ -0,0 +1,126 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/chankh/hoconfmt/ast"
+	"github.com/chankh/hoconfmt/parser"
+)
+
+func TestSource(t *testing.T) {
+	for _, test := range []struct{ src, want string }{
+		{"a {\nb = 1\n}\n", "a {\n    b = 1\n}\n"},
+		{"a = [1,2]", "a = [1, 2]\n"},
+		{"/* kept */\na = 1\n", "/* kept */\na = 1\n"},
+	} {
+		got, err := Source([]byte(test.src))
+		if err != nil {
+			t.Errorf("Source(%q): %v", test.src, err)
+		} else if string(got) != test.want {
+			t.Errorf("Source(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+	if _, err := Source([]byte("a = {\n")); err == nil {
+		t.Error("Source of an unclosed object succeeded")
+	}
+}
+
+func TestNode(t *testing.T) {
+	const src = "# top\n\na{b:1 ,c=[1,2] # x\n}\nd { e = \"x\" ${y} }\ninclude \"f.conf\"\n"
+	f, err := parser.ParseFile([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := f.Root.(*ast.Object).Items[1].Node.(*ast.Field)
+	for _, test := range []struct {
+		node ast.Node
+		want string
+	}{
+		{f, "# top\n\na {\n    b: 1\n    c = [1, 2] # x\n}\nd { e = \"x\" ${y} }\ninclude \"f.conf\"\n"},
+		{a, "a {\n    b: 1\n    c = [1, 2] # x\n}\n"},
+		{a.Value, "{\n    b: 1\n    c = [1, 2] # x\n}"},
+		{&ast.Value{Parts: []ast.Part{{Text: "${base}"}, {Space: " ", Text: `"/logs"`}}}, `${base} "/logs"`},
+		{&ast.Value{Parts: []ast.Part{{Text: "hello"}, {Space: "  ", Text: "big"}, {Space: "\t", Text: "world"}}}, "hello  big\tworld"},
+		{&ast.Object{Braces: true, Items: []ast.Item{
+			{Node: &ast.Field{Key: "port", Sep: "=", Value: &ast.Value{Parts: []ast.Part{{Text: "8080"}}}}},
+			{Node: &ast.Field{Key: "hosts", Sep: ":", Value: &ast.Value{Parts: []ast.Part{{Node: &ast.Array{Items: []ast.Item{
+				{Node: &ast.Value{Parts: []ast.Part{{Text: `"a"`}}}},
+				{Node: &ast.Value{Parts: []ast.Part{{Text: `"b"`}}}, Comment: &ast.Comment{Text: "# second"}},
+			}}}}}}, Blank: true},
+		}}, "{\n    port = 8080\n\n    hosts: [\n        \"a\"\n        \"b\" # second\n    ]\n}\n"},
+	} {
+		var b bytes.Buffer
+		if err := Node(&b, test.node); err != nil {
+			t.Errorf("Node(%#v): %v", test.node, err)
+		} else if b.String() != test.want {
+			t.Errorf("Node(%#v) = %q, want %q", test.node, b.String(), test.want)
+		}
+	}
+}
+
+func TestNodeKeepsSpace(t *testing.T) {
+	const src = "greeting = hello  big\tworld\n"
+	f, err := parser.ParseFile([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := Node(&b, f); err != nil {
+		t.Fatal(err)
+	} else if b.String() != src {
+		t.Errorf("Node(ParseFile(%q)) = %q", src, b.String())
+	}
+}
+
+func TestNodeErrors(t *testing.T) {
+	for _, node := range []ast.Node{
+		&ast.Field{Key: "a", Sep: "="},
+		&ast.Value{},
+		&ast.Value{Parts: []ast.Part{{Node: &ast.Comment{Text: "# c"}}}},
+		&ast.Field{Key: "a", Sep: "=", Value: &ast.Value{Parts: []ast.Part{{Text: "{"}}}},
+		&ast.Value{Parts: []ast.Part{{Text: "a"}, {Space: "\n", Text: "b"}}},
+	} {
+		var b bytes.Buffer
+		if err := Node(&b, node); err == nil {
+			t.Errorf("Node(%#v) = %q, want an error", node, b.String())
+		}
+	}
+}
+
+func TestFileUnits(t *testing.T) {
+	const src = "rate = 10 req/s\narea = 10m^2\n"
+	opts := DefaultOptions()
+	opts.FixQuotes, opts.UnitClassifier = true, Units{"req/s": "rate", "m^2": "area"}
+	got, err := File("a.conf", []byte(src), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != src {
+		t.Errorf("got %q, want %q", got, src)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	if ok, err := Equal([]byte("a.b = 1\nc = ${a.b}"), []byte("a { b: 1 }\nc = 1\n")); !ok || err != nil {
+		t.Errorf("Equal of the same value = %v, %v", ok, err)
+	}
+	ok, err := Equal([]byte("a = 1"), []byte("a = 2"))
+	if d, isDiff := err.(*Difference); ok || !isDiff || d.Path != "a" {
+		t.Errorf("Equal of different values = %v, %v, want a difference at a", ok, err)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	v := Object{
+		{Key: "db", Value: Object{{Key: "host", Value: "localhost"}, {Key: "port", Value: 5432}}},
+		{Key: "url", Value: Subst{Path: "db.host"}},
+	}
+	got, err := Marshal(v, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n    db {\n        host = \"localhost\"\n        port = 5432\n    }\n    url = ${db.host}\n}"; string(got) != want {
+		t.Errorf("Marshal = %q, want %q", got, want)
+	}
+}
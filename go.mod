@@ -0,0 +1,5 @@
+module github.com/chankh/hoconfmt
+
+go 1.26.0
+
+require golang.org/x/text v0.42.0
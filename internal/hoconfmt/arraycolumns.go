@@ -0,0 +1,194 @@
+package hoconfmt
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// columnArrays lays out the arrays of src whose elements are single
+// numbers, strings or words, as in lookup tables and weights, in rows
+// of up to columns elements, separated by commas and right-aligned in
+// columns:
+//
+//	weights = [
+//	      1,  20, 300
+//	    400,   5,  60
+//	]
+//
+// The rows are indented one indent deeper than the line of the opening
+// bracket, and the closing bracket goes on a line of its own. An array
+// of such arrays that each have up to columns elements is a matrix: it
+// gets one row per line, and the columns line up across its rows. An
+// array with up to columns elements that is not a row of a matrix, and
+// an array holding a comment, a substitution, a concatenation or a
+// multi-line string, is left alone. Only the white space and commas
+// between elements change, so the arrays read back the same.
+func columnArrays(src []byte, columns int, indent string) ([]byte, error) {
+	doc, err := parseDocument(src)
+	if err != nil {
+		return nil, err
+	}
+	c := &columnizer{src: src, columns: columns, indent: indent}
+	switch root := doc.root.(type) {
+	case *objectNode:
+		c.object(root)
+	case *arrayNode:
+		c.array(root)
+	}
+	return spliceEdits(src, c.edits), nil
+}
+
+// A columnizer collects the edits of columnArrays, in the order of the
+// source.
+type columnizer struct {
+	src     []byte
+	columns int
+	indent  string
+	edits   []textEdit
+}
+
+func (c *columnizer) object(o *objectNode) {
+	for _, it := range o.items {
+		if f, ok := it.node.(*fieldNode); ok {
+			c.value(f.value)
+		}
+	}
+}
+
+func (c *columnizer) value(v *valueNode) {
+	for _, part := range v.parts {
+		switch n := part.node.(type) {
+		case *objectNode:
+			c.object(n)
+		case *arrayNode:
+			if len(v.parts) == 1 {
+				c.array(n)
+			} else {
+				c.elements(n)
+			}
+		}
+	}
+}
+
+// elements visits the elements of a, without laying out a itself.
+func (c *columnizer) elements(a *arrayNode) {
+	for _, it := range a.items {
+		if v, ok := it.node.(*valueNode); ok {
+			c.value(v)
+		}
+	}
+}
+
+func (c *columnizer) array(a *arrayNode) {
+	lead := lineIndent(c.src, a.off)
+	if rows := c.matrix(a); rows != nil {
+		c.layout(a, lead, rows, true)
+		return
+	}
+	elems := scalarElements(a)
+	if len(elems) <= c.columns {
+		c.elements(a)
+		return
+	}
+	var rows [][]string
+	for len(elems) > 0 {
+		n := min(c.columns, len(elems))
+		rows = append(rows, elems[:n])
+		elems = elems[n:]
+	}
+	c.layout(a, lead, rows, false)
+}
+
+// matrix returns the elements of the rows of a if a is a matrix: an
+// array of at least two arrays, each with up to c.columns elements
+// that are single numbers, strings or words.
+func (c *columnizer) matrix(a *arrayNode) [][]string {
+	if len(a.items) < 2 {
+		return nil
+	}
+	var rows [][]string
+	for _, it := range a.items {
+		v, ok := it.node.(*valueNode)
+		if !ok || it.comment != nil || len(v.parts) != 1 {
+			return nil
+		}
+		row, ok := v.parts[0].node.(*arrayNode)
+		if !ok {
+			return nil
+		}
+		elems := scalarElements(row)
+		if elems == nil || len(elems) > c.columns {
+			return nil
+		}
+		rows = append(rows, elems)
+	}
+	return rows
+}
+
+// layout replaces a with rows, one to a line, right-aligned in columns.
+// Each row of a matrix is written in brackets.
+func (c *columnizer) layout(a *arrayNode, lead string, rows [][]string, matrix bool) {
+	var widths []int
+	for _, row := range rows {
+		for i, e := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], utf8.RuneCountInString(e))
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("[\n")
+	for _, row := range rows {
+		b.WriteString(lead + c.indent)
+		if matrix {
+			b.WriteByte('[')
+		}
+		for i, e := range row {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(e)))
+			b.WriteString(e)
+		}
+		if matrix {
+			b.WriteByte(']')
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString(lead + "]")
+	c.edits = append(c.edits, textEdit{a.off, a.end - a.off, b.String()})
+}
+
+// lineIndent returns the white space at the start of the line of src
+// holding the offset off.
+func lineIndent(src []byte, off int) string {
+	start := off
+	for start > 0 && src[start-1] != '\n' {
+		start--
+	}
+	end := start
+	for end < off && (src[end] == ' ' || src[end] == '\t') {
+		end++
+	}
+	return string(src[start:end])
+}
+
+// scalarElements returns the text of the elements of a if each is a
+// single number, string or word, without a comment, and nil otherwise.
+func scalarElements(a *arrayNode) []string {
+	var elems []string
+	for _, it := range a.items {
+		v, ok := it.node.(*valueNode)
+		if !ok || it.comment != nil || len(v.parts) != 1 {
+			return nil
+		}
+		t := v.parts[0].tok
+		if v.parts[0].node != nil || t.kind != tokUnquoted && t.kind != tokString {
+			return nil
+		}
+		elems = append(elems, t.lit)
+	}
+	return elems
+}
@@ -0,0 +1,43 @@
+package hoconfmt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestColumnArraysMatrix(t *testing.T) {
+	const src = "m = [[1, 20, 3], [-4, 5, 600], [7, 8.5, 9]]\n"
+	const want = `m = [
+    [ 1,  20,   3]
+    [-4,   5, 600]
+    [ 7, 8.5,   9]
+]
+`
+	res, err := columnArrays([]byte(src), 3, "    ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != want {
+		t.Errorf("got\n%s\nwant\n%s", res, want)
+	}
+
+	before, err := mergeDocument([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := mergeDocument(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("result reads back as %v, want %v", after, before)
+	}
+
+	again, err := columnArrays(res, 3, "    ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != want {
+		t.Errorf("laying out the result again gave\n%s", again)
+	}
+}
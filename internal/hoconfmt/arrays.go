@@ -0,0 +1,345 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Values of the -continuation-indent flag.
+const (
+	continuationAlign  = "align"  // under the first element
+	continuationIndent = "indent" // one level deeper than the line
+)
+
+func checkContinuationIndent(style string) error {
+	switch style {
+	case "", continuationAlign, continuationIndent:
+		return nil
+	}
+	return fmt.Errorf("invalid -continuation-indent %q: must be %s or %s", style, continuationAlign, continuationIndent)
+}
+
+// wrapArrays breaks arrays written on a single line that is wider than
+// width between elements, filling each line with as many elements as
+// fit. The comma at a break is dropped, as hoconizeJSON does, since a
+// newline separates array elements just as well.
+// With continuationAlign the continuation lines start in the column of
+// the first element; with continuationIndent they get the indentation
+// of the line plus one indent. Only the first array of a line that
+// opens and closes on it is wrapped, and an element that is wider than
+// width on its own stays whole. Arrays already spread over several
+// lines are left alone, but a continuation line that is still wider
+// than width, such as one holding an inner array, is wrapped in turn,
+// so wrapping a wrapped array again changes nothing.
+//
+// Concatenations are never wrapped: a newline ends a HOCON value, so
+// there is no way to continue one on the next line.
+func wrapArrays(src []byte, style string, width, tabwidth int, indent string) ([]byte, error) {
+	for {
+		res, err := wrapLines(src, style, width, tabwidth, indent)
+		if err != nil || bytes.Equal(res, src) {
+			return res, err
+		}
+		src = res
+	}
+}
+
+// wrapLines wraps the arrays of the lines of src as wrapArrays does,
+// measuring each line as it is in src.
+func wrapLines(src []byte, style string, width, tabwidth int, indent string) ([]byte, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []byte
+	for _, l := range splitLines(toks) {
+		code := l.toks
+		if n := len(code); n > 0 && code[n-1].kind == tokNewline {
+			code = code[:n-1]
+		}
+		open, elems, end := lineArray(code)
+		if textWidth(code, tabwidth) <= width || len(elems) < 2 {
+			res = l.appendTo(res)
+			continue
+		}
+
+		lead := leadingSpace(l)
+		prefix := string(lineText(code[:open+1]))
+		cont := lead + indent
+		if style == continuationAlign {
+			cont = lead + strings.Repeat(" ", textWidth([]tok{{lit: prefix}}, tabwidth)-textWidth([]tok{{lit: lead}}, tabwidth))
+		}
+		suffix := string(lineText(code[end:]))
+
+		line := prefix + elems[0]
+		for i, e := range elems[1:] {
+			next := line + ", " + e
+			if i == len(elems)-2 {
+				next += suffix
+			}
+			if textWidth([]tok{{lit: next}}, tabwidth) > width {
+				res = append(res, line...)
+				res = append(res, '\n')
+				line = cont + e
+				continue
+			}
+			line += ", " + e
+		}
+		res = append(res, line...)
+		res = append(res, suffix...)
+		if len(code) < len(l.toks) {
+			res = append(res, '\n')
+		}
+	}
+	return res, nil
+}
+
+// lineArray finds the first array of the line code that is closed on the
+// same line. It returns the index of its opening bracket, the text of
+// its elements without surrounding white space and the index of its
+// closing bracket. elems is nil if there is no such array, or if the
+// array has an empty element or contains a comment or a multi-line
+// string.
+func lineArray(code []tok) (open int, elems []string, end int) {
+	for open = range code {
+		if code[open].kind != tokLBracket {
+			continue
+		}
+		depth, start := 0, open+1
+		for i := open + 1; i < len(code); i++ {
+			switch code[i].kind {
+			case tokComment, tokMultiline:
+				return 0, nil, 0
+			case tokLBrace, tokLBracket:
+				depth++
+			case tokRBrace:
+				depth--
+			case tokRBracket:
+				if depth > 0 {
+					depth--
+					continue
+				}
+				e := strings.TrimSpace(string(lineText(code[start:i])))
+				if e == "" {
+					return 0, nil, 0
+				}
+				return open, append(elems, e), i
+			case tokComma:
+				if depth > 0 {
+					continue
+				}
+				e := strings.TrimSpace(string(lineText(code[start:i])))
+				if e == "" {
+					return 0, nil, 0
+				}
+				elems = append(elems, e)
+				start = i + 1
+			}
+		}
+		return 0, nil, 0
+	}
+	return 0, nil, 0
+}
+
+// lineText returns the source text of toks.
+func lineText(toks []tok) []byte {
+	return srcLine{toks: toks}.bytes()
+}
+
+// regroupArrays puts the elements of arrays spread over several lines,
+// as in
+//
+//	ports = [
+//	    8080
+//	    8081
+//	    8082
+//	]
+//
+// perLine to a line, separated by commas, or as many as fit in width if
+// perLine is negative. Only arrays whose brackets end and start their
+// lines and whose elements are single numbers, strings or words are
+// regrouped; an array holding a comment, a blank line, a concatenation
+// or a nested value, or whose element lines are indented differently,
+// is left alone. Every line keeps the indentation of the first element,
+// so the elements stay aligned, and a newline separates them just as a
+// comma does.
+func regroupArrays(src []byte, perLine, width, tabwidth int) ([]byte, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []byte
+	for i := 0; i < len(toks); i++ {
+		res = append(res, toks[i].lit...)
+		if toks[i].kind != tokLBracket {
+			continue
+		}
+		indent, elems, end := columnArray(toks, i+1)
+		if len(elems) < 2 {
+			continue
+		}
+		res = append(res, '\n')
+		line := indent + elems[0]
+		n := 1
+		for _, e := range elems[1:] {
+			if perLine > 0 && n == perLine || perLine < 0 && textWidth([]tok{{lit: line + ", " + e}}, tabwidth) > width {
+				res = append(res, line+"\n"...)
+				line, n = indent+e, 1
+				continue
+			}
+			line += ", " + e
+			n++
+		}
+		res = append(res, line+"\n"...)
+		i = end - 1
+	}
+	return res, nil
+}
+
+// columnArray reads the elements of the array whose opening bracket
+// comes just before toks[i], if it is laid out the way regroupArrays
+// handles. It returns the indentation of the element lines, the
+// elements, and the index of the first token of the line holding the
+// closing bracket. elems is nil if the array is laid out differently.
+func columnArray(toks []tok, i int) (indent string, elems []string, end int) {
+	for i < len(toks) && toks[i].kind == tokSpace {
+		i++
+	}
+	if i == len(toks) || toks[i].kind != tokNewline {
+		return "", nil, 0
+	}
+	for line := i + 1; ; {
+		k := line
+		lead := ""
+		if k < len(toks) && toks[k].kind == tokSpace {
+			lead = toks[k].lit
+			k++
+		}
+		if k < len(toks) && toks[k].kind == tokRBracket {
+			return indent, elems, line
+		}
+		if elems == nil {
+			indent = lead
+		} else if lead != indent {
+			return "", nil, 0
+		}
+		for {
+			if k == len(toks) || toks[k].kind != tokUnquoted && toks[k].kind != tokString {
+				return "", nil, 0
+			}
+			elems = append(elems, toks[k].lit)
+			k++
+			for k < len(toks) && toks[k].kind == tokSpace {
+				k++
+			}
+			comma := k < len(toks) && toks[k].kind == tokComma
+			if comma {
+				k++
+				for k < len(toks) && toks[k].kind == tokSpace {
+					k++
+				}
+			}
+			if k < len(toks) && toks[k].kind == tokNewline {
+				break
+			}
+			if !comma {
+				return "", nil, 0
+			}
+		}
+		line = k + 1
+	}
+}
+
+// reindentArrays gives the lines inside arrays spread over several
+// lines a consistent indentation: one indent deeper than the line that
+// opens the array, one more inside each object or array that is itself
+// spread over several lines, and the indentation of the opening line
+// for the line that closes it. Only brackets that end their line count:
+// brackets opened on the same line, as in [{, add a single level, and
+// the continuation lines of an array or object whose first element
+// follows its bracket, as wrapArrays writes them, are left as they are.
+// Comment lines between elements are indented like the elements, and
+// blank lines lose their white space. Only the white space at the start
+// of lines changes, and a newline separates array elements the same
+// however it is indented.
+func reindentArrays(lx *lexer, src []byte, indent string) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	type open struct {
+		kind  tokenKind
+		level int // indentation level of the lines inside, or -1 to keep theirs
+	}
+	var stack []open
+	region := -1 // index in stack of the outermost array being reindented
+	base := ""   // indentation of the line opening it
+	res := make([]byte, 0, len(src))
+	for _, l := range splitLines(toks) {
+		level := -1 // of this line, relative to base, or -1 if it is kept
+		code := l.toks
+		if len(code) > 0 && code[0].kind == tokSpace {
+			code = code[1:]
+		}
+		if region >= 0 && len(stack) > region {
+			level = stack[len(stack)-1].level
+			if level > 0 && len(code) > 0 && (code[0].kind == tokRBrace || code[0].kind == tokRBracket) {
+				level--
+			}
+		}
+		switch {
+		case level < 0:
+			res = l.appendTo(res)
+		case len(code) == 0 || code[0].kind == tokNewline:
+			res = srcLine{toks: code}.appendTo(res)
+		default:
+			res = append(res, base+strings.Repeat(indent, level)...)
+			res = srcLine{toks: code}.appendTo(res)
+		}
+
+		for i, t := range l.toks {
+			switch t.kind {
+			case tokLBrace, tokLBracket:
+				inner := -1
+				if endsLine(l.toks[i+1:]) {
+					inner = max(level, 0) + 1
+					if region >= 0 && level < 0 {
+						inner = -1 // inside lines that are kept
+					}
+				}
+				stack = append(stack, open{t.kind, inner})
+				if region < 0 && t.kind == tokLBracket && inner > 0 {
+					region = len(stack) - 1
+					base = leadingSpace(l)
+				}
+			case tokRBrace, tokRBracket:
+				if len(stack) > 0 {
+					stack = stack[:len(stack)-1]
+				}
+				if region == len(stack) {
+					region = -1
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// endsLine reports whether toks holds nothing but white space and a
+// comment up to the end of its line.
+func endsLine(toks []tok) bool {
+	for _, t := range toks {
+		switch t.kind {
+		case tokSpace, tokComment:
+		case tokNewline:
+			return true
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package hoconfmt
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestWrapArrays(t *testing.T) {
+	const src = "\tlist = [one, two, three, four] # note\n\tobj = [{ a = 1, b = 2 }, [3, 4], five]\n"
+	for _, test := range []struct {
+		style, want string
+	}{
+		{continuationAlign, "\tlist = [one, two\n\t        three\n\t        four] # note\n\tobj = [{ a = 1, b = 2 }\n\t       [3, 4], five]\n"},
+		{continuationIndent, "\tlist = [one, two\n\t\tthree\n\t\tfour] # note\n\tobj = [{ a = 1, b = 2 }\n\t\t[3, 4], five]\n"},
+	} {
+		got, err := wrapArrays([]byte(src), test.style, 24, 4, "\t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("%s:\n%s\nwant:\n%s", test.style, got, test.want)
+		}
+		again, err := wrapArrays(got, test.style, 24, 4, "\t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(again) != string(got) {
+			t.Errorf("%s: wrapping again changed\n%s\ninto\n%s", test.style, got, again)
+		}
+	}
+}
+
+// TestContinuationIndentTwice checks that formatting the result of
+// -continuation-indent again at a narrow width changes nothing, also
+// where a continuation line holds an array that is too wide itself.
+func TestContinuationIndentTwice(t *testing.T) {
+	for _, src := range []string{
+		"a {\n  b = [1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12]\n}\n",
+		"a = [[1, 2], [3, 4], [5, 6, 7, 8]]\n",
+		"a = [{x = [1, 2, 3]}, [[4, 5], [6, 7, 8]], 9] # note\n",
+	} {
+		for _, style := range []string{continuationAlign, continuationIndent} {
+			for width := 8; width <= 30; width++ {
+				opts := DefaultOptions()
+				opts.ContinuationIndent, opts.Width = style, width
+				once, err := formatFile("a.conf", []byte(src), opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				twice, err := formatFile("a.conf", once, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(twice) != string(once) {
+					t.Errorf("%s, width %d: formatting %q again changed\n%s\ninto\n%s", style, width, src, once, twice)
+				}
+			}
+		}
+	}
+}
+
+func TestRegroupArraysSameConfig(t *testing.T) {
+	const src = "a = [\n    1,\n    \"two\"\n    3, 4\n    five\n]\nb {\n    c = [\n        x\n        y\n    ]\n}\n"
+	want, err := flattenConfig([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, perLine := range []int{1, 2, 4, -1} {
+		res, err := regroupArrays([]byte(src), perLine, 20, 8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := flattenConfig(res)
+		if err != nil {
+			t.Fatalf("%d per line: %v\n%s", perLine, err, res)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%d per line: regrouped config\n%s\nreads as %+v, want %+v", perLine, res, got, want)
+		}
+	}
+}
+
+func TestReindentArraysSameConfig(t *testing.T) {
+	src, err := ioutil.ReadFile("testdata/arrayindent.input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := flattenConfig(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := reindentArrays(nil, src, "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := flattenConfig(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reindented config\n%s\nreads as %+v, want %+v", res, got, want)
+	}
+}
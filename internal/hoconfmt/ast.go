@@ -0,0 +1,125 @@
+package hoconfmt
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// printAST writes the syntax tree of src to out for -print-ast: one
+// line per object, array, field, include, comment and value token,
+// indented by nesting, with its position. A comment after a field or
+// include on its line is printed among the field's values.
+func printAST(out io.Writer, src []byte) error {
+	doc, err := parseDocument(src)
+	if err != nil {
+		return err
+	}
+	a := &astPrinter{src: src}
+	a.line(0, 0, "Object")
+	a.items(doc.before, 1)
+	switch root := doc.root.(type) {
+	case *objectNode:
+		if root.braces {
+			a.object(root, 1)
+		} else {
+			a.items(root.items, 1)
+		}
+	case *arrayNode:
+		a.array(root, 1)
+	}
+	a.items(doc.after, 1)
+	_, err = io.WriteString(out, a.b.String())
+	return err
+}
+
+// An astPrinter writes the lines of printAST.
+type astPrinter struct {
+	src []byte
+	b   strings.Builder
+}
+
+func (a *astPrinter) line(depth, off int, format string, args ...interface{}) {
+	a.b.WriteString(strings.Repeat("  ", depth))
+	fmt.Fprintf(&a.b, format, args...)
+	pos := position(a.src, off)
+	fmt.Fprintf(&a.b, " %d:%d\n", pos.Line, pos.Column)
+}
+
+func (a *astPrinter) items(items []item, depth int) {
+	for _, it := range items {
+		switch n := it.node.(type) {
+		case *commentNode:
+			a.comment(n, depth)
+		case *includeNode:
+			a.line(depth, n.off, "Include")
+			for _, t := range n.target {
+				a.token(t, depth+1)
+			}
+		case *fieldNode:
+			sep := ""
+			if n.sep != "" {
+				sep = " " + n.sep
+			}
+			a.line(depth, n.offset(), "Field %q%s", n.keyText(), sep)
+			a.value(n.value, depth+1)
+		case *valueNode:
+			a.value(n, depth)
+		}
+		if it.comment != nil {
+			if _, ok := it.node.(*valueNode); ok {
+				a.comment(it.comment, depth)
+			} else {
+				a.comment(it.comment, depth+1)
+			}
+		}
+	}
+}
+
+func (a *astPrinter) comment(c *commentNode, depth int) {
+	a.line(depth, c.off, "Comment %q", c.text)
+}
+
+func (a *astPrinter) token(t tok, depth int) {
+	if t.kind != tokSpace {
+		a.line(depth, t.off, "%s %q", tokenName(t.kind), t.lit)
+	}
+}
+
+func (a *astPrinter) value(v *valueNode, depth int) {
+	for _, part := range v.parts {
+		switch n := part.node.(type) {
+		case *objectNode:
+			a.object(n, depth)
+		case *arrayNode:
+			a.array(n, depth)
+		default:
+			a.token(part.tok, depth)
+		}
+	}
+}
+
+func (a *astPrinter) object(o *objectNode, depth int) {
+	a.line(depth, o.off, "Object")
+	a.items(o.items, depth+1)
+}
+
+func (a *astPrinter) array(arr *arrayNode, depth int) {
+	a.line(depth, arr.off, "Array")
+	a.items(arr.items, depth+1)
+}
+
+// tokenName returns the name printAST uses for tokens of kind k.
+func tokenName(k tokenKind) string {
+	switch k {
+	case tokString:
+		return "String"
+	case tokMultiline:
+		return "Multiline"
+	case tokSubst:
+		return "Subst"
+	case tokSep:
+		return "Sep"
+	}
+	return "Unquoted"
+}
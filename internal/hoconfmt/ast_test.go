@@ -0,0 +1,43 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintAST(t *testing.T) {
+	const src = `# top
+include "base.conf"
+a.b = ${x} "/y" # note
+c {
+    d: [1, { e = true }]
+}
+${f}.g = 1
+`
+	const want = `Object 1:1
+  Comment "# top" 1:1
+  Include 2:1
+    String "\"base.conf\"" 2:9
+  Field "a.b" = 3:1
+    Subst "${x}" 3:7
+    String "\"/y\"" 3:12
+    Comment "# note" 3:17
+  Field "c" 4:1
+    Object 4:3
+      Field "d" : 5:5
+        Array 5:8
+          Unquoted "1" 5:9
+          Object 5:12
+            Field "e" = 5:14
+              Unquoted "true" 5:18
+  Field "${f}.g" = 7:1
+    Unquoted "1" 7:10
+`
+	var out bytes.Buffer
+	if err := printAST(&out, []byte(src)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.Bytes(), want)
+	}
+}
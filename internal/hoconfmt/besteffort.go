@@ -0,0 +1,55 @@
+package hoconfmt
+
+// formatParseable formats the parts of src that can be formatted on
+// their own and copies the rest as it is, for -best-effort. The file is
+// cut into blocks at the blank lines between top-level fields, and the
+// blocks containing a syntax error are left untouched; an unterminated
+// multi-line string runs to the end of the file, so its block does too.
+// The result must have exactly the syntax errors of src. If src has no
+// syntax error, or the result would not satisfy this, formatParseable
+// returns false.
+func formatParseable(filename string, src []byte, opts Options) ([]byte, bool) {
+	if opts.FromProperties {
+		return nil, false
+	}
+	toks, err := tokenize(src)
+	list, ok := err.(errorList)
+	if !ok {
+		return nil, false
+	}
+
+	// Formatting a block at a time would wrap each one in braces.
+	opts.RootBraces = ""
+
+	var res []byte
+	block := func(start, end int) {
+		for _, e := range list {
+			if e.Pos.Offset >= start && e.Pos.Offset < end {
+				res = append(res, src[start:end]...)
+				return
+			}
+		}
+		f, err := formatFile(filename, src[start:end], opts)
+		if err != nil {
+			f = src[start:end]
+		}
+		res = append(res, f...)
+	}
+	start := 0
+	for _, l := range splitLines(toks) {
+		if !l.blank() || l.depth != 0 {
+			continue
+		}
+		off := l.toks[0].off
+		block(start, off)
+		res = l.appendTo(res)
+		start = off + len(l.bytes())
+	}
+	block(start, len(src))
+
+	_, err = tokenize(res)
+	if after, ok := err.(errorList); !ok || len(after) != len(list) {
+		return nil, false
+	}
+	return res, true
+}
@@ -0,0 +1,70 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const brokenBlock = `a = {"x": 1}
+
+b {
+    s = "unterminated
+    t = {"y": 2}
+}
+
+c = {"z": 3}
+`
+
+func TestBestEffort(t *testing.T) {
+	defer func() { exitCode = 0 }()
+
+	// By default a file with a syntax error is reported and not
+	// formatted at all.
+	var out bytes.Buffer
+	if err := processFile("broken.conf", strings.NewReader(brokenBlock), &out); err == nil {
+		t.Error("no error for broken file")
+	}
+	if out.Len() != 0 {
+		t.Errorf("broken file was formatted:\n%s", out.Bytes())
+	}
+
+	*bestEff = true
+	defer func() { *bestEff = false }()
+	out.Reset()
+	if err := processFile("broken.conf", strings.NewReader(brokenBlock), &out); err != nil {
+		t.Fatal(err)
+	}
+	const want = `a { x = 1 }
+
+b {
+    s = "unterminated
+    t = {"y": 2}
+}
+
+c { z = 3 }
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.Bytes(), want)
+	}
+	if exitCode != 2 {
+		t.Errorf("syntax error not reported with -best-effort: exit code %d", exitCode)
+	}
+}
+
+func TestFormatParseable(t *testing.T) {
+	// Without syntax errors there is nothing to do.
+	if _, ok := formatParseable("a.conf", []byte("a = 1\n"), DefaultOptions()); ok {
+		t.Error("formatParseable succeeded on a valid file")
+	}
+
+	// Nothing after an unterminated multi-line string is formatted.
+	const src = "a = {\"x\": 1}\n\nb = \"\"\"open\n\nc = {\"z\": 3}\n"
+	res, ok := formatParseable("a.conf", []byte(src), DefaultOptions())
+	if !ok {
+		t.Fatal("formatParseable failed")
+	}
+	if want := "a { x = 1 }\n\nb = \"\"\"open\n\nc = {\"z\": 3}\n"; string(res) != want {
+		t.Errorf("got\n%s\nwant\n%s", res, want)
+	}
+}
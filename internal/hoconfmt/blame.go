@@ -0,0 +1,250 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A provenance is where a value is set.
+type provenance struct {
+	File  string `json:"file"`
+	Line  int    `json:"line"`
+	Value string `json:"value"`
+	seq   int    // order in which the value is applied
+}
+
+// A blameEntry is the effective value of a key path, for -blame: where
+// it is set, and the earlier values it replaces, oldest first.
+type blameEntry struct {
+	Path string `json:"path"`
+	provenance
+	Shadowed []provenance `json:"shadowed"`
+}
+
+// A definition is a field of a file or of one of the files it includes.
+// via is the offset in the file of the include statement that brings it
+// in, or -1 for the file's own fields, and off that of its key in the
+// file it is in.
+type definition struct {
+	path   []string
+	at     provenance
+	object bool
+	via    int
+	off    int
+}
+
+// maxIncludeDepth bounds the nesting of includes, so that a file that
+// includes itself is reported rather than followed forever.
+const maxIncludeDepth = 50
+
+// blameConfig returns the effective values of the document src, read
+// from filename, with where each is set, sorted by path. Include
+// statements at the top level are followed to files relative to the
+// directory of the including file; a file named without an extension
+// is looked for with .conf added. Missing files are skipped unless the
+// include is required(...), and url(...) and classpath(...) includes
+// are not followed. Includes inside objects are not followed either.
+func blameConfig(filename string, src []byte) ([]blameEntry, error) {
+	defs, err := collectDefinitions(filename, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	list := effectiveValues(defs)
+	sort.Slice(list, func(i, j int) bool { return lessPath(list[i].Path, list[j].Path) })
+	return list, nil
+}
+
+// effectiveValues returns the values that defs, applied in order, leave,
+// in no particular order. The seq of each provenance is the index of
+// its definition in defs.
+func effectiveValues(defs []definition) []blameEntry {
+	entries := map[string]*blameEntry{}
+	// replace removes the entries at and below key, returning their
+	// provenances.
+	replace := func(key string) []provenance {
+		var gone []provenance
+		if e, ok := entries[key]; ok {
+			gone = append(append(gone, e.Shadowed...), e.provenance)
+			delete(entries, key)
+		}
+		for p, e := range entries {
+			if strings.HasPrefix(p, key+".") {
+				gone = append(append(gone, e.Shadowed...), e.provenance)
+				delete(entries, p)
+			}
+		}
+		return gone
+	}
+	for i, d := range defs {
+		d.at.seq = i
+		// A dotted key makes objects of all its prefixes, replacing
+		// their values.
+		var gone []provenance
+		for n := 1; n < len(d.path); n++ {
+			if e, ok := entries[renderPath(d.path[:n])]; ok {
+				gone = append(append(gone, e.Shadowed...), e.provenance)
+				delete(entries, e.Path)
+			}
+		}
+		key := renderPath(d.path)
+		if d.object {
+			delete(entries, key) // an object replaces a value and merges with an object
+			continue
+		}
+		entries[key] = &blameEntry{Path: key, provenance: d.at, Shadowed: append(gone, replace(key)...)}
+	}
+
+	list := make([]blameEntry, 0, len(entries))
+	for _, e := range entries {
+		if e.Shadowed == nil {
+			e.Shadowed = []provenance{}
+		}
+		sort.Slice(e.Shadowed, func(i, j int) bool { return e.Shadowed[i].seq < e.Shadowed[j].seq })
+		list = append(list, *e)
+	}
+	return list
+}
+
+// collectDefinitions returns the fields of src in the order they apply,
+// with the fields of included files in place of their include
+// statements.
+func collectDefinitions(filename string, src []byte, depth int) ([]definition, error) {
+	if depth > maxIncludeDepth {
+		return nil, fmt.Errorf("%s: includes nested more than %d deep", filename, maxIncludeDepth)
+	}
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, withFilename(err, filename)
+	}
+
+	type event struct {
+		off  int
+		defs []definition
+	}
+	var events []event
+	walkFields(toks, func(f field) {
+		at := provenance{File: filename, Line: position(src, toks[f.key].off).Line}
+		if !f.object {
+			at.Value = flatScalar(toks[f.value:f.end]).text
+			if f.appending {
+				at.Value = "+= " + at.Value
+			}
+		}
+		events = append(events, event{toks[f.key].off, []definition{{f.path, at, f.object, -1, toks[f.key].off}}})
+	})
+	for _, l := range splitLines(toks) {
+		if !isInclude(l) {
+			continue
+		}
+		name, required, ok := includeTarget(l)
+		if !ok {
+			continue
+		}
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(filename), path)
+		}
+		if filepath.Ext(path) == "" {
+			path += ".conf"
+		}
+		inc, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) && !required {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		defs, err := collectDefinitions(path, inc, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		for i := range defs {
+			defs[i].via = l.toks[0].off
+		}
+		events = append(events, event{l.toks[0].off, defs})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].off < events[j].off })
+
+	var defs []definition
+	for _, e := range events {
+		defs = append(defs, e.defs...)
+	}
+	return defs, nil
+}
+
+// includeTarget returns the file name of the include statement on the
+// line l, and whether it is required(...). ok is false for url(...)
+// and classpath(...) includes and for targets that are not quoted.
+func includeTarget(l srcLine) (name string, required, ok bool) {
+	kind, name, required, ok := includeResource(l)
+	return name, required, ok && kind == "file"
+}
+
+// includeResource returns the kind of resource the include statement on
+// the line l names, "file", "classpath" or "url", with its name and
+// whether it is required(...). A bare quoted string counts as a file.
+// ok is false for targets that are not quoted.
+func includeResource(l srcLine) (kind, name string, required, ok bool) {
+	var b strings.Builder
+	seen := false
+	for _, t := range l.toks {
+		switch {
+		case !seen && t.kind == tokUnquoted && t.lit == "include":
+			seen = true
+		case !seen, t.kind == tokComment, t.kind == tokNewline, t.kind == tokComma:
+		default:
+			b.WriteString(t.lit)
+		}
+	}
+	s := strings.TrimSpace(b.String())
+	if strings.HasPrefix(s, "required(") && strings.HasSuffix(s, ")") {
+		required = true
+		s = strings.TrimSpace(s[len("required(") : len(s)-1])
+	}
+	kind = "file"
+	for _, k := range []string{"file", "classpath", "url"} {
+		if strings.HasPrefix(s, k+"(") && strings.HasSuffix(s, ")") {
+			kind = k
+			s = strings.TrimSpace(s[len(k)+1 : len(s)-1])
+			break
+		}
+	}
+	if err := json.Unmarshal([]byte(s), &name); err != nil || !strings.HasPrefix(s, `"`) {
+		return "", "", false, false
+	}
+	return kind, name, required, true
+}
+
+// printBlame writes the -blame report of filename to out: a line for
+// each effective value with where it is set, followed by an indented
+// line for each earlier value it replaces.
+func printBlame(out io.Writer, filename string, entries []blameEntry) error {
+	if *jsonReport {
+		data, err := json.Marshal(struct {
+			File string       `json:"file"`
+			Keys []blameEntry `json:"keys"`
+		}{filename, entries})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", data)
+		return err
+	}
+	for _, e := range entries {
+		if _, err := fmt.Fprintf(out, "%s = %s\t%s:%d\n", e.Path, e.Value, e.File, e.Line); err != nil {
+			return err
+		}
+		for _, s := range e.Shadowed {
+			if _, err := fmt.Fprintf(out, "    shadows %s:%d: %s\n", s.File, s.Line, s.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package hoconfmt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBlameConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"base.conf": `db {
+    host = localhost
+    port = 5432
+}
+timeout = 10
+`,
+		"conf/prod.conf": `db.host = prod.example.com
+`,
+		"app.conf": `name = demo
+include "base.conf"
+include required(file("conf/prod"))
+include "missing.conf"
+timeout = 30
+`,
+	}
+	for name, text := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	app := filepath.Join(dir, "app.conf")
+	base := filepath.Join(dir, "base.conf")
+	prod := filepath.Join(dir, "conf", "prod.conf")
+	got, err := blameConfig(app, []byte(files["app.conf"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []blameEntry{
+		{"db.host", provenance{File: prod, Line: 1, Value: `"prod.example.com"`}, []provenance{{File: base, Line: 2, Value: `"localhost"`}}},
+		{"db.port", provenance{File: base, Line: 3, Value: "5432"}, []provenance{}},
+		{"name", provenance{File: app, Line: 1, Value: `"demo"`}, []provenance{}},
+		{"timeout", provenance{File: app, Line: 5, Value: "30"}, []provenance{{File: base, Line: 5, Value: "10"}}},
+	}
+	for i := range got {
+		got[i].seq = 0
+		for j := range got[i].Shadowed {
+			got[i].Shadowed[j].seq = 0
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("blameConfig =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// A missing required include is an error.
+	const src = "include required(\"missing.conf\")\n"
+	if _, err := blameConfig(app, []byte(src)); err == nil {
+		t.Error("no error for a missing required include")
+	}
+}
+
+func TestBlameConfigReplaced(t *testing.T) {
+	const src = `a { b = 1, c = 2 }
+a = 3
+x = 1
+x.y = 2
+`
+	got, err := blameConfig("a.conf", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	var shadowed []int
+	for _, e := range got {
+		paths = append(paths, e.Path)
+		shadowed = append(shadowed, len(e.Shadowed))
+	}
+	if want := []string{"a", "x.y"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %q, want %q", paths, want)
+	}
+	if want := []int{2, 1}; !reflect.DeepEqual(shadowed, want) {
+		t.Errorf("shadowed counts = %v, want %v", shadowed, want)
+	}
+}
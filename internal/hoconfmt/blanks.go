@@ -0,0 +1,71 @@
+package hoconfmt
+
+// defaultMaxBlank is the default number of consecutive blank lines
+// kept.
+const defaultMaxBlank = 1
+
+// limitBlankLines shortens every run of blank lines in src to at most
+// max lines, and removes the blank lines directly after an opening
+// brace or bracket that ends its line and directly before a line that
+// starts with a closing one, whatever max is. Blank lines at the start and end of
+// the file are left to the other passes.
+func limitBlankLines(lx *lexer, src []byte, max int) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	lines := splitLines(toks)
+
+	res := make([]byte, 0, len(src))
+	started := false // past the leading blank lines
+	for i := 0; i < len(lines); i++ {
+		if !lines[i].blank() || !started {
+			res = lines[i].appendTo(res)
+			started = started || !lines[i].blank()
+			continue
+		}
+		j := i
+		for j < len(lines) && lines[j].blank() {
+			j++
+		}
+		if j == len(lines) {
+			for _, l := range lines[i:] {
+				res = l.appendTo(res)
+			}
+			break
+		}
+		n := j - i
+		if opensBlock(lines[i-1]) || closesBlock(lines[j]) {
+			n = 0
+		} else if n > max {
+			n = max
+		}
+		for _, l := range lines[i : i+n] {
+			res = l.appendTo(res)
+		}
+		i = j - 1
+	}
+	return res, nil
+}
+
+// opensBlock reports whether the last token of l before its newline is
+// an opening brace or bracket.
+func opensBlock(l srcLine) bool {
+	for i := len(l.toks) - 1; i >= 0; i-- {
+		switch l.toks[i].kind {
+		case tokSpace, tokNewline:
+			continue
+		case tokLBrace, tokLBracket:
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// closesBlock reports whether the first token of l is a closing brace
+// or bracket.
+func closesBlock(l srcLine) bool {
+	t := l.first()
+	return t != nil && (t.kind == tokRBrace || t.kind == tokRBracket)
+}
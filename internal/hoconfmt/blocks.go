@@ -0,0 +1,87 @@
+package hoconfmt
+
+// topBlocks returns the spans of the top-level fields of src, each from
+// the start of the line the field starts on to the end of the line it
+// ends on, including the newline. Comment and blank lines between
+// fields belong to no block.
+func topBlocks(src []byte) ([]span, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	lines := splitLines(toks)
+
+	var blocks []span
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		if l.depth != 0 || l.blank() || l.commentOnly() {
+			continue
+		}
+		j := i + 1
+		for j < len(lines) && lines[j].depth > 0 {
+			j++
+		}
+		end := lines[j-1].toks[len(lines[j-1].toks)-1]
+		blocks = append(blocks, span{l.toks[0].off, end.off + len(end.lit)})
+		i = j - 1
+	}
+	return blocks, nil
+}
+
+// sameCode reports whether a and b hold the same tokens apart from
+// white space and newlines.
+func sameCode(a, b []byte) bool {
+	ta, err := tokenize(a)
+	if err != nil {
+		return false
+	}
+	tb, err := tokenize(b)
+	if err != nil {
+		return false
+	}
+	i, j := 0, 0
+	for {
+		for i < len(ta) && (ta[i].kind == tokSpace || ta[i].kind == tokNewline) {
+			i++
+		}
+		for j < len(tb) && (tb[j].kind == tokSpace || tb[j].kind == tokNewline) {
+			j++
+		}
+		if i == len(ta) || j == len(tb) {
+			return i == len(ta) && j == len(tb)
+		}
+		if ta[i].kind != tb[j].kind || ta[i].lit != tb[j].lit {
+			return false
+		}
+		i++
+		j++
+	}
+}
+
+// restoreCanonicalBlocks copies the top-level blocks of src that
+// formatting changed only in white space into the formatted res, so
+// that only the blocks that needed real changes show up in a diff. If
+// formatting split or joined blocks, there is no telling which block
+// became which, and res is returned as it is.
+func restoreCanonicalBlocks(src, res []byte) ([]byte, error) {
+	before, err := topBlocks(src)
+	if err != nil {
+		return nil, err
+	}
+	after, err := topBlocks(res)
+	if err != nil {
+		return nil, err
+	}
+	if len(before) != len(after) {
+		return res, nil
+	}
+	var keepBefore, keepAfter []span
+	for i, b := range before {
+		a := after[i]
+		if sameCode(src[b.start:b.end], res[a.start:a.end]) {
+			keepBefore = append(keepBefore, b)
+			keepAfter = append(keepAfter, a)
+		}
+	}
+	return restoreSpans(src, res, keepBefore, keepAfter), nil
+}
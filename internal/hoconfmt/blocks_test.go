@@ -0,0 +1,30 @@
+package hoconfmt
+
+import "testing"
+
+func TestRestoreCanonicalBlocks(t *testing.T) {
+	for _, test := range []struct {
+		src, res, want string
+	}{
+		// Only the block with a real change is taken from res.
+		{
+			"a  =  [ ]\nb = {\n  c = 1\n}\n# note\nd {\n  e = [ ]\n}\n",
+			"a  =  []\nb {\n  c = 1\n}\n# note\nd {\n  e = []\n}\n",
+			"a  =  [ ]\nb {\n  c = 1\n}\n# note\nd {\n  e = [ ]\n}\n",
+		},
+		// Blocks were split, so nothing is restored.
+		{
+			"a { x = [ ] } b = 2\n",
+			"a { x = [] }\nb = 2\n",
+			"a { x = [] }\nb = 2\n",
+		},
+	} {
+		got, err := restoreCanonicalBlocks([]byte(test.src), []byte(test.res))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("restoreCanonicalBlocks(%q, %q) = %q, want %q", test.src, test.res, got, test.want)
+		}
+	}
+}
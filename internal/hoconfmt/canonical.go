@@ -0,0 +1,224 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// canonical returns the canonical form of the document src for
+// -canonical: its fields merged as mergeDocument merges them, and
+// written so that two documents with the same value are written alike.
+//
+// Fields are sorted by key and written one per line with = as their
+// separator, or as key { for objects, indented by four spaces per
+// level whatever -indent-string says. Keys and strings are quoted only
+// if they need it, and numbers, booleans and null are written as they
+// are. Arrays are written on one line, with ", " between elements,
+// unless they hold an object with fields; then each element goes on a
+// line of its own. The parts of a concatenation that cannot be joined
+// without resolving substitutions are written next to each other, text
+// always quoted, with a space before and after objects and arrays.
+// A substitution that replaces an earlier value of its field, which
+// the field keeps if the substitution is undefined, is written after
+// that value as a field of its own. Comments, blank lines and commas
+// are left out, and include statements are errors, as are values that
+// append to such a substitution.
+func canonical(src []byte) ([]byte, error) {
+	v, err := (&merger{src: src, overrides: true}).document()
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := nestedOverride(v, nil); ok {
+		return nil, listError(codeOther, "cannot write the canonical form of "+renderPath(p)+": it appends to a substitution that overrides an earlier value")
+	}
+	var b bytes.Buffer
+	if obj, ok := v.(Object); ok {
+		writeCanonicalFields(&b, obj, 0)
+	} else {
+		writeCanonical(&b, v, 0)
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+// writeCanonicalFields writes the fields of obj at the nesting depth
+// depth, sorted by key, each line ending in a newline.
+func writeCanonicalFields(b *bytes.Buffer, obj Object, depth int) {
+	fields := append(Object(nil), obj...)
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+	for _, f := range fields {
+		writeCanonicalField(b, f.Key, f.Value, depth)
+	}
+}
+
+// writeCanonicalField writes the field key = v at the nesting depth
+// depth, ending in a newline. An override is written as the field of
+// the value it overrides followed by its own.
+func writeCanonicalField(b *bytes.Buffer, key string, v Value, depth int) {
+	if o, ok := v.(override); ok {
+		writeCanonicalField(b, key, o.prev, depth)
+		v = o.value
+	}
+	b.WriteString(strings.Repeat(" ", tabWidth*depth))
+	b.WriteString(canonicalKey(key))
+	if isObject(v) {
+		b.WriteByte(' ')
+	} else {
+		b.WriteString(" = ")
+	}
+	writeCanonical(b, v, depth)
+	b.WriteByte('\n')
+}
+
+// nestedOverride returns the path of the first value in v, at path p,
+// that holds an override other than as the value of a field, such as
+// one that += appends to, and whether there is one.
+func nestedOverride(v Value, p []string) ([]string, bool) {
+	switch v := v.(type) {
+	case Object:
+		for _, f := range v {
+			if q, ok := fieldOverride(f.Value, append(p[:len(p):len(p)], f.Key)); ok {
+				return q, true
+			}
+		}
+	case []Value:
+		for _, e := range v {
+			if q, ok := nestedOverride(e, p); ok {
+				return q, true
+			}
+		}
+	case concatenation:
+		for _, part := range v.parts {
+			if _, ok := part.(override); ok {
+				return p, true
+			}
+			if q, ok := nestedOverride(part, p); ok {
+				return q, true
+			}
+		}
+	case override:
+		return p, true
+	}
+	return nil, false
+}
+
+// fieldOverride is nestedOverride for v, the value of the field at
+// path p, which may be an override.
+func fieldOverride(v Value, p []string) ([]string, bool) {
+	if o, ok := v.(override); ok {
+		if q, ok := fieldOverride(o.prev, p); ok {
+			return q, true
+		}
+		v = o.value
+	}
+	return nestedOverride(v, p)
+}
+
+func writeCanonical(b *bytes.Buffer, v Value, depth int) {
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		if v {
+			b.WriteString("true")
+		} else {
+			b.WriteString("false")
+		}
+	case json.Number:
+		b.WriteString(string(v))
+	case string:
+		if unquotedValue(v) {
+			b.WriteString(v)
+		} else {
+			b.WriteString(quoteString(v))
+		}
+	case Subst:
+		b.WriteString(substText(Subst{Path: renderPath(pathElems(v.Path)), Optional: v.Optional}))
+	case concatenation:
+		for i, p := range v.parts {
+			if i > 0 && (isComposite(p) || isComposite(v.parts[i-1])) {
+				b.WriteByte(' ')
+			}
+			if s, ok := p.(string); ok {
+				b.WriteString(quoteString(s))
+			} else {
+				writeCanonical(b, p, depth)
+			}
+		}
+	case []Value:
+		if len(v) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		if inlineCanonical(v) {
+			b.WriteByte('[')
+			for i, e := range v {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				writeCanonical(b, e, depth)
+			}
+			b.WriteByte(']')
+			return
+		}
+		b.WriteString("[\n")
+		for _, e := range v {
+			b.WriteString(strings.Repeat(" ", tabWidth*(depth+1)))
+			writeCanonical(b, e, depth+1)
+			b.WriteByte('\n')
+		}
+		b.WriteString(strings.Repeat(" ", tabWidth*depth))
+		b.WriteByte(']')
+	case Object:
+		if len(v) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{\n")
+		writeCanonicalFields(b, v, depth+1)
+		b.WriteString(strings.Repeat(" ", tabWidth*depth))
+		b.WriteByte('}')
+	}
+}
+
+// canonicalKey returns k as a key of the canonical form. An unquoted
+// include would start an include statement, so it is quoted as well.
+func canonicalKey(k string) string {
+	if k == "include" {
+		return quoteString(k)
+	}
+	return hoconKey(k)
+}
+
+// inlineCanonical reports whether the canonical form writes the array
+// elems on one line: whether it holds no object with fields, directly
+// or in an array or concatenation.
+func inlineCanonical(elems []Value) bool {
+	for _, e := range elems {
+		switch e := e.(type) {
+		case Object:
+			if len(e) > 0 {
+				return false
+			}
+		case []Value:
+			if !inlineCanonical(e) {
+				return false
+			}
+		case concatenation:
+			if !inlineCanonical(e.parts) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isComposite(v Value) bool {
+	switch v.(type) {
+	case Object, []Value:
+		return true
+	}
+	return false
+}
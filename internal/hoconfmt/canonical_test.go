@@ -0,0 +1,107 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	const a = `# database
+db {
+  port: 5432
+  "host" = localhost
+}
+db.port = 5433
+url = "http://"${db.host}/x
+list = [1, {a = true}] [null]
+list += four
+app = ${base} { z = 1, a = 2 }
+timeout = 10s
+`
+	const b = `timeout = "10s"
+url = "http://"${ "db".host }"/x"
+list = [1, {a: true}, null, four]
+app = ${base}
+app.a = 2
+app { z = 1 }
+db.host = "localhost"
+db.port = 5433
+`
+	const want = `app = ${base} {
+    a = 2
+    z = 1
+}
+db {
+    host = localhost
+    port = 5433
+}
+list = [
+    1
+    {
+        a = true
+    }
+    null
+    four
+]
+timeout = "10s"
+url = "http://"${db.host}"/x"
+`
+	for _, src := range []string{a, b, want} {
+		res, err := canonical([]byte(src))
+		if err != nil {
+			t.Fatalf("canonical(%q): %v", src, err)
+		}
+		if string(res) != want {
+			t.Errorf("canonical(%q) =\n%s\nwant\n%s", src, res, want)
+		}
+	}
+}
+
+func TestCanonicalValues(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{`"include" = 1`, "\"include\" = 1\n"},
+		{`a = "10"`, "a = \"10\"\n"},
+		{`a = true, b = "true"`, "a = true\nb = \"true\"\n"},
+		{`a = hello   world`, "a = \"hello   world\"\n"},
+		{`a = [[1, 2], []]`, "a = [[1, 2], []]\n"},
+		{`a = ${?x} [1]`, "a = ${?x} [1]\n"},
+		{`a {}`, "a {}\n"},
+		{`["b", a]`, "[b, a]\n"},
+	}
+	for _, tt := range tests {
+		res, err := canonical([]byte(tt.src))
+		if err != nil {
+			t.Errorf("canonical(%q): %v", tt.src, err)
+			continue
+		}
+		if string(res) != tt.want {
+			t.Errorf("canonical(%q) = %q, want %q", tt.src, res, tt.want)
+		}
+	}
+
+	// An override keeps the value it overrides.
+	for _, pair := range [][2]string{
+		{"g = \"x\"\ng = ${?G}", "g = ${?G}"},
+		{"a { x = 1 }\na = ${b}", "a = ${b}"},
+	} {
+		with, err1 := canonical([]byte(pair[0]))
+		without, err2 := canonical([]byte(pair[1]))
+		if err1 != nil || err2 != nil || bytes.Equal(with, without) {
+			t.Errorf("canonical(%q) = %q, %v, canonical(%q) = %q, %v, want them to differ", pair[0], with, err1, pair[1], without, err2)
+		}
+		again, err := canonical(with)
+		if err != nil || !bytes.Equal(again, with) {
+			t.Errorf("canonical(%q) = %q, %v, want it unchanged", with, again, err)
+		}
+	}
+	if res, err := canonical([]byte("a { x = 1 }\na = ${b}\na { y = 2 }")); err != nil || string(res) != "a {\n    x = 1\n}\na = ${b} {\n    y = 2\n}\n" {
+		t.Errorf("canonical of an override merged with an object = %q, %v", res, err)
+	}
+	if _, err := canonical([]byte("a = [1]\na = ${b}\na += 2")); err == nil {
+		t.Error("canonical accepted += to an override")
+	}
+
+	if _, err := canonical([]byte("include \"base.conf\"\n")); err == nil {
+		t.Error("canonical accepted an include statement")
+	}
+}
@@ -0,0 +1,111 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// canonicalJSON returns the document src, read from filename, as
+// canonical JSON for -json-canonical, so that two documents with the
+// same value, once resolved by resolveDocument against env, are written
+// byte for byte alike, to hash them.
+//
+// The JSON is written on one line, ending in a newline, without white
+// space between its tokens. Keys are sorted byte by byte, which for
+// UTF-8 is the order of their code points. Strings are written as
+// quoteString writes them, escaping only quotes, backslashes and
+// control characters, and numbers as canonicalNumber writes them.
+func canonicalJSON(filename string, src []byte, env *environment) ([]byte, error) {
+	v, err := resolveDocument(filename, src, env)
+	if _, ok := err.(errorList); ok {
+		return nil, withFilename(err, filename)
+	} else if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	var b bytes.Buffer
+	writeCanonicalJSON(&b, v)
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+func writeCanonicalJSON(b *bytes.Buffer, v Value) {
+	switch v := v.(type) {
+	case json.Number:
+		b.WriteString(canonicalNumber(v))
+	case []Value:
+		b.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeCanonicalJSON(b, e)
+		}
+		b.WriteByte(']')
+	case Object:
+		fields := append(Object(nil), v...)
+		sort.SliceStable(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+		b.WriteByte('{')
+		for i, f := range fields {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(quoteString(f.Key))
+			b.WriteByte(':')
+			writeCanonicalJSON(b, f.Value)
+		}
+		b.WriteByte('}')
+	default:
+		writeJSON(b, v, "", 0)
+	}
+}
+
+// canonicalNumber returns the canonical text of the number n. An
+// integer keeps all its digits, as in 12345678901234567890, with -0
+// written as 0. Other numbers are read as IEEE 754 doubles and written
+// as JavaScript and RFC 8785 write them: with the fewest digits that
+// read back as the same double, in plain notation if the exponent is
+// from -7 to 20, as in 0.000001 and 100, and in exponent notation
+// otherwise, as in 1e-7 and 1e+21. So 1.0, 1e0 and 1 are all 1.
+func canonicalNumber(n json.Number) string {
+	if i, ok := new(big.Int).SetString(string(n), 10); ok {
+		return i.String()
+	}
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return string(n) // out of range as a double
+	}
+	if f == 0 {
+		return "0"
+	}
+	sign := ""
+	if f < 0 {
+		sign, f = "-", -f
+	}
+	// d.ddde±x, with the fewest digits that read back as f.
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	mant, exp := s[:strings.IndexByte(s, 'e')], s[strings.IndexByte(s, 'e')+1:]
+	digits := strings.Replace(mant, ".", "", 1)
+	e, _ := strconv.Atoi(exp)
+	k, point := len(digits), e+1 // the decimal point goes after point digits
+	switch {
+	case k <= point && point <= 21:
+		return sign + digits + strings.Repeat("0", point-k)
+	case 0 < point && point <= 21:
+		return sign + digits[:point] + "." + digits[point:]
+	case -6 < point && point <= 0:
+		return sign + "0." + strings.Repeat("0", -point) + digits
+	}
+	res := sign + digits[:1]
+	if k > 1 {
+		res += "." + digits[1:]
+	}
+	if e >= 0 {
+		return res + "e+" + strconv.Itoa(e)
+	}
+	return res + "e" + strconv.Itoa(e)
+}
@@ -0,0 +1,73 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	env := &environment{lookup: func(name string) (string, bool) { return "/home/u", name == "HOME" }}
+
+	// Two files with the same value, written differently.
+	a := `# The service.
+name = api
+port: 8080
+db { host = "localhost", "port" = 5.432e3 }
+paths = [ ${HOME}/logs, "/tmp" ]
+ratio = 0.50
+`
+	b := `{
+  paths: ["/home/u/logs"] ["/tmp"]
+  db.port = 5432.0
+  db.host = localhost
+  ratio = 5e-1
+  "name" : "api",
+  port = 80
+  port = 8080
+}
+`
+	const want = `{"db":{"host":"localhost","port":5432},"name":"api","paths":["/home/u/logs","/tmp"],"port":8080,"ratio":0.5}` + "\n"
+	for _, src := range []string{a, b} {
+		res, err := canonicalJSON("a.conf", []byte(src), env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(res) != want {
+			t.Errorf("canonical JSON of\n%s\n= %s\nwant %s", src, res, want)
+		}
+	}
+
+	res, err := canonicalJSON("a.conf", []byte("\"é\" = 1\nz = 2\n\"Z\" = \"tab\\there\"\n"), env)
+	if want := "{\"Z\":\"tab\\there\",\"z\":2,\"é\":1}\n"; err != nil || string(res) != want {
+		t.Errorf("canonical JSON = %q, %v, want %q", res, err, want)
+	}
+	if _, err := canonicalJSON("a.conf", []byte("a = ${nope}"), env); err == nil || err.Error() != "a.conf: cannot resolve ${nope}: nope is not set in the file or the environment" {
+		t.Errorf("canonical JSON of an unresolved substitution: got error %v", err)
+	}
+}
+
+func TestCanonicalNumber(t *testing.T) {
+	tests := []struct{ n, want string }{
+		{"0", "0"},
+		{"-0", "0"},
+		{"-0.0", "0"},
+		{"1.0", "1"},
+		{"1e0", "1"},
+		{"12345678901234567890", "12345678901234567890"},
+		{"-42", "-42"},
+		{"0.1", "0.1"},
+		{"1.5e2", "150"},
+		{"0.000001", "0.000001"},
+		{"1e-7", "1e-7"},
+		{"1.25E-9", "1.25e-9"},
+		{"1e20", "100000000000000000000"},
+		{"1e21", "1e+21"},
+		{"-1.5e300", "-1.5e+300"},
+		{"123.456", "123.456"},
+	}
+	for _, tt := range tests {
+		if got := canonicalNumber(json.Number(tt.n)); got != tt.want {
+			t.Errorf("canonicalNumber(%s) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
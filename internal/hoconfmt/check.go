@@ -0,0 +1,123 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// A diagnostic is an error or a warning found while formatting a file.
+type diagnostic struct {
+	Line     int    `json:"line"` // 0 if the message has no position
+	Column   int    `json:"column"`
+	Severity string `json:"severity"` // "error" or "warning"
+	Message  string `json:"message"`
+	Code     string `json:"code"` // one of the code constants
+}
+
+// The codes of diagnostics, for tools that act on kinds of errors and
+// warnings rather than on their text. Each error and warning is given
+// its code where it is made, and the codes are part of the -json-report
+// output.
+const (
+	codeSyntax        = "syntax"        // text that is not HOCON, such as an unclosed {
+	codeComma         = "comma"         // a leading or doubled comma
+	codeStrict        = "strict"        // what -strict rejects
+	codeInclude       = "include"       // an include that cannot be followed, or has no effect
+	codeDuplicateKey  = "duplicate-key" // a key whose value replaces an earlier one
+	codeConcatenation = "concatenation" // values that cannot be joined
+	codeSubstitution  = "substitution"  // a substitution that cannot be resolved
+	codeType          = "type"          // a value whose type a substitution changes
+	codeRename        = "rename"        // a -r or -key-case rename that cannot be made
+	codeComment       = "comment"       // a comment that formatting would lose
+	codeVerify        = "verify"        // a formatting that -verify rejects
+	codeWhitespace    = "whitespace"    // white space that is removed or kept in a value
+	codeSort          = "sort"          // an array that -sort-array leaves unsorted
+	codeOther         = "other"         // any other error
+)
+
+// A checkReport is the result of -check for one file.
+type checkReport struct {
+	File        string       `json:"file"`
+	Formatted   bool         `json:"formatted"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+	Diff        string       `json:"diff,omitempty"` // with -check-diff
+}
+
+// checkFormat formats src, read from filename, with opts and reports
+// whether it is formatted already, along with the errors and warnings
+// of formatting it. A file with errors is not formatted. The diff from
+// src to its formatted version is included if withDiff is set.
+func checkFormat(filename string, src []byte, opts Options, withDiff bool) checkReport {
+	r := checkReport{File: filename, Diagnostics: []diagnostic{}}
+	var warnings warningList
+	opts.Warnings = &warnings
+	res, err := formatFile(filename, src, opts)
+
+	for _, w := range warnings {
+		r.Diagnostics = append(r.Diagnostics, diagnostic{w.Pos.Line, w.Pos.Column, "warning", w.Msg, w.Code})
+	}
+	if err != nil {
+		if list, ok := err.(errorList); ok {
+			list.RemoveMultiples()
+			for _, e := range list {
+				r.Diagnostics = append(r.Diagnostics, diagnostic{e.Pos.Line, e.Pos.Column, "error", e.Msg, e.Code})
+			}
+		} else {
+			r.Diagnostics = append(r.Diagnostics, diagnostic{Severity: "error", Message: err.Error(), Code: codeOther})
+		}
+		return r
+	}
+
+	r.Formatted = bytes.Equal(src, res)
+	if !r.Formatted && withDiff {
+		var d bytes.Buffer
+		if err := diff(&d, filename, src, res); err == nil {
+			r.Diff = d.String()
+		}
+	}
+	return r
+}
+
+// failed reports whether the file of r has errors.
+func (r checkReport) failed() bool {
+	for _, d := range r.Diagnostics {
+		if d.Severity == "error" {
+			return true
+		}
+	}
+	return false
+}
+
+// printCheck writes the report of -check.
+func printCheck(out io.Writer, r checkReport) error {
+	if *jsonReport {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", data)
+		return err
+	}
+	for _, d := range r.Diagnostics {
+		prefix := r.File
+		if d.Line > 0 {
+			prefix = fmt.Sprintf("%s:%d:%d", r.File, d.Line, d.Column)
+		}
+		msg := d.Message
+		if d.Severity == "warning" {
+			msg = "warning: " + msg
+		}
+		if _, err := fmt.Fprintf(out, "%s: %s\n", prefix, msg); err != nil {
+			return err
+		}
+	}
+	if !r.Formatted && !r.failed() {
+		if _, err := fmt.Fprintf(out, "%s: not formatted\n", r.File); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(out, r.Diff)
+	return err
+}
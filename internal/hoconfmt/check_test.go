@@ -0,0 +1,161 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCheckFormat(t *testing.T) {
+	for _, test := range []struct {
+		src       string
+		formatted bool
+		diags     []diagnostic
+		diff      bool
+	}{
+		{"a = 1\n", true, nil, false},
+		{"a = [ ]\n", false, nil, true},
+		{"a = }\n", false, []diagnostic{{1, 5, "error", "unexpected }", "syntax"}}, false},
+		{"a = 1\n/* x */\n", true, []diagnostic{{2, 1, "warning", "block comment: HOCON only has # and // comments; keeping it as text", "strict"}}, false},
+	} {
+		r := checkFormat("app.conf", []byte(test.src), DefaultOptions(), true)
+		if r.Formatted != test.formatted {
+			t.Errorf("%q: formatted = %v, want %v", test.src, r.Formatted, test.formatted)
+		}
+		if len(r.Diagnostics) != len(test.diags) {
+			t.Errorf("%q: diagnostics %v, want %v", test.src, r.Diagnostics, test.diags)
+			continue
+		}
+		for i := range test.diags {
+			if r.Diagnostics[i] != test.diags[i] {
+				t.Errorf("%q: diagnostic %d = %v, want %v", test.src, i, r.Diagnostics[i], test.diags[i])
+			}
+		}
+		if hasDiff := strings.Contains(r.Diff, "\n+a = []\n"); hasDiff != test.diff {
+			t.Errorf("%q: diff %q", test.src, r.Diff)
+		}
+	}
+}
+
+func TestDiagnosticCodes(t *testing.T) {
+	for _, test := range []struct {
+		src          string
+		set          func(*Options)
+		line, column int
+		code         string
+	}{
+		{"a = \"x\n", nil, 1, 5, "syntax"},
+		{"a {\n  b = 1\n", nil, 1, 3, "syntax"},
+		{"a = [1,,2]\n", nil, 1, 8, "comma"},
+		{"a = [1,,2]\n", func(o *Options) { o.FixCommas = true }, 1, 8, "comma"},
+		{"a\n= 1\n", func(o *Options) { o.Strict = true }, 2, 1, "strict"},
+		{"include required(\"nope.conf\")\n", func(o *Options) { o.InlineIncludes = 100 }, 1, 1, "include"},
+		{"a = 1\na = 2\n", func(o *Options) { o.WarnDuplicates = true }, 2, 1, "duplicate-key"},
+		{"a { x = 1 }\na = 2\n", func(o *Options) { o.WarnDuplicates = true }, 2, 1, "duplicate-key"},
+		{"a = [x y]\n", func(o *Options) { o.WarnConcat = true }, 1, 6, "concatenation"},
+		{"myKey = 1\nmykey = 2\n", func(o *Options) { o.KeyCase = keyCaseLower }, 2, 1, "rename"},
+		{"a = \"x\u00a0y\"\n", nil, 0, 0, ""},
+	} {
+		opts := DefaultOptions()
+		if test.set != nil {
+			test.set(&opts)
+		}
+		r := checkFormat("app.conf", []byte(test.src), opts, false)
+		if test.code == "" {
+			if len(r.Diagnostics) > 0 {
+				t.Errorf("%q: diagnostics %v, want none", test.src, r.Diagnostics)
+			}
+			continue
+		}
+		if len(r.Diagnostics) == 0 {
+			t.Errorf("%q: no diagnostics, want %s", test.src, test.code)
+			continue
+		}
+		if d := r.Diagnostics[0]; d.Line != test.line || d.Column != test.column || d.Code != test.code {
+			t.Errorf("%q: diagnostic %v, want code %s at %d:%d", test.src, d, test.code, test.line, test.column)
+		}
+	}
+
+}
+
+func TestErrorCodes(t *testing.T) {
+	// Errors that checkFormat does not report, each with the code it is
+	// made with.
+	code := func(err error) string {
+		if list, ok := err.(errorList); ok && len(list) > 0 {
+			return fmt.Sprintf("%s %s", list[0].Pos, list[0].Code)
+		}
+		return fmt.Sprintf("%v", err)
+	}
+	unset := func(string) (string, bool) { return "", false }
+
+	_, err := resolveDocument("", []byte("a = ${b}\n"), &environment{lookup: unset})
+	if got := code(err); got != "- substitution" {
+		t.Errorf("unresolved substitution: got %s, want - substitution", got)
+	}
+	_, err = resolveDocument("", []byte("port = 8080\nurl = ${port} x\n"), &environment{lookup: unset, strict: true})
+	if got := code(err); got != "2:7 type" {
+		t.Errorf("type change: got %s, want 2:7 type", got)
+	}
+	err = checkComments([]byte("a = 1\n# keep\n"), []byte("a = 1\n"), false)
+	if got := code(err); got != "2:1 comment" {
+		t.Errorf("lost comment: got %s, want 2:1 comment", got)
+	}
+	want, err := verifyInput("", []byte("a = 1\nb = 2\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := code(checkValue(want, []byte("a = 1\nb = 3\n"))); got != "2:1 verify" {
+		t.Errorf("changed value: got %s, want 2:1 verify", got)
+	}
+
+	var warnings warningList
+	opts := DefaultOptions()
+	opts.SortArrays, opts.Warnings = "a=name", &warnings
+	if _, err := formatFile("", []byte("a = 1\n"), opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Pos.String() != "1:5" || warnings[0].Code != codeSort {
+		t.Errorf("-sort-array of a number: warnings %v, want one at 1:5 with code sort", warnings)
+	}
+}
+
+func TestCheckMode(t *testing.T) {
+	*checkMode, *jsonReport = true, true
+	defer func(code int) { *checkMode, *jsonReport = false, false; exitCode = code }(exitCode)
+	for _, test := range []struct {
+		src  string
+		code int
+	}{
+		{"a = 1\n", 0},
+		{"a = [ ]\n", 1},
+		{"a = }\n", 2},
+	} {
+		exitCode = 0
+		var out bytes.Buffer
+		if err := processFile("app.conf", bytes.NewBufferString(test.src), &out); err != nil {
+			t.Fatal(err)
+		}
+		if exitCode != test.code {
+			t.Errorf("-check on %q: exit code %d, want %d", test.src, exitCode, test.code)
+		}
+		var r checkReport
+		if err := json.Unmarshal(out.Bytes(), &r); err != nil {
+			t.Errorf("-check on %q: %v in %s", test.src, err, out.Bytes())
+		} else if r.File != "app.conf" || r.Formatted != (test.code == 0) {
+			t.Errorf("-check on %q: got %s", test.src, out.Bytes())
+		}
+	}
+
+	*jsonReport = false
+	exitCode = 0
+	var out bytes.Buffer
+	if err := processFile("app.conf", bytes.NewBufferString("a = [ ]\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "app.conf: not formatted\n"; out.String() != want {
+		t.Errorf("text report = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,165 @@
+package hoconfmt
+
+import (
+	"fmt"
+)
+
+// fixCommas removes the commas of src that separate nothing: a comma
+// directly after an opening brace or bracket or at the start of the
+// file, and the second of two commas with only white space, newlines
+// and comments between them, as in [1,,2]. HOCON has no empty array
+// elements or fields, so such a comma is a mistake rather than a
+// placeholder. A trailing comma before a closing brace or bracket is
+// valid and kept. fixCommas returns the fixed source and the position
+// of each removed comma with a description of it.
+func fixCommas(lx *lexer, src []byte) ([]byte, errorList, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var fixes errorList
+	drop := make([]bool, len(toks))
+	prev := tokLBrace // last token other than white space and comments; the file starts an object
+	for i, t := range toks {
+		switch t.kind {
+		case tokSpace, tokNewline, tokComment:
+			continue
+		case tokComma:
+			switch prev {
+			case tokComma:
+				fixes.Add(position(src, t.off), codeComma, "doubled comma")
+				dropComma(toks, drop, i)
+				continue
+			case tokLBrace, tokLBracket:
+				fixes.Add(position(src, t.off), codeComma, "leading comma")
+				dropComma(toks, drop, i)
+				prev = tokComma // a second one is doubled
+				continue
+			}
+		}
+		prev = t.kind
+	}
+	if len(fixes) == 0 {
+		return src, nil, nil
+	}
+
+	res := make([]byte, 0, len(src))
+	for i, t := range toks {
+		if !drop[i] {
+			res = append(res, t.lit...)
+		}
+	}
+	return res, fixes, nil
+}
+
+// dropComma marks the comma toks[i] for removal, together with the
+// white space between it and the previous token on its line, as in
+// [1, , 2], or if it starts its line or follows a bracket, with the
+// white space after it, as in [, 1].
+func dropComma(toks []tok, drop []bool, i int) {
+	drop[i] = true
+	j := i - 1
+	for j >= 0 && toks[j].kind == tokSpace {
+		j--
+	}
+	if j >= 0 && toks[j].kind != tokNewline && toks[j].kind != tokLBrace && toks[j].kind != tokLBracket {
+		for j++; j < i; j++ {
+			drop[j] = true
+		}
+		return
+	}
+	for j := i + 1; j < len(toks) && toks[j].kind == tokSpace; j++ {
+		drop[j] = true
+	}
+}
+
+// Values of the -commas flag.
+const (
+	commasKeep  = "keep"  // as written
+	commasSmart = "smart" // only between elements on the same line
+)
+
+func checkCommas(style string) error {
+	switch style {
+	case "", commasKeep, commasSmart:
+		return nil
+	}
+	return fmt.Errorf("invalid -commas %q: must be %s or %s", style, commasKeep, commasSmart)
+}
+
+// smartCommas removes the commas of src that no element follows on the
+// same line: commas at the end of a line, before a comment, and before
+// a closing brace or bracket, so that
+//
+//	a = [1, 2,]
+//	b {
+//	    c = 1,
+//	    d = [3, 4],
+//	}
+//
+// becomes
+//
+//	a = [1, 2]
+//	b {
+//	    c = 1
+//	    d = [3, 4]
+//	}
+//
+// Objects and arrays on one line keep the commas between their elements,
+// which HOCON requires there; in those spread over several lines a
+// newline separates the elements just as a comma does. It runs after
+// the passes that break lines, so that it sees the final layout. A
+// comma on a line of its own is removed with its line, and one before
+// a closing brace or bracket on its line with the white space around
+// it, leaving a single space before a brace, if there was any, and none
+// before a bracket: [1, 2, ] becomes [1, 2] and { a = 1, } { a = 1 }.
+func smartCommas(lx *lexer, src []byte) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	drop := make([]bool, len(toks))
+	space := map[int]bool{} // dropped tokens written as a single space
+	for i, t := range toks {
+		if t.kind != tokComma {
+			continue
+		}
+		j := i + 1
+		for j < len(toks) && toks[j].kind == tokSpace {
+			j++
+		}
+		if j < len(toks) {
+			switch toks[j].kind {
+			case tokNewline, tokComment, tokRBrace, tokRBracket:
+			default:
+				continue
+			}
+		}
+		from, to := i, i+1 // the comma and the white space before it
+		for from > 0 && toks[from-1].kind == tokSpace {
+			from--
+		}
+		if (from == 0 || toks[from-1].kind == tokNewline) && j < len(toks) && toks[j].kind == tokNewline {
+			to = j + 1 // the whole line
+		} else if j < len(toks) && (toks[j].kind == tokRBrace || toks[j].kind == tokRBracket) {
+			to = j // and the white space after it, as in [1, 2, ]
+			space[from] = toks[j].kind == tokRBrace && j > from+1
+		}
+		for k := from; k < to; k++ {
+			drop[k] = true
+		}
+	}
+
+	res := make([]byte, 0, len(src))
+	for i, t := range toks {
+		switch {
+		case !drop[i]:
+			res = append(res, t.lit...)
+		case space[i]:
+			res = append(res, ' ')
+		}
+	}
+	return res, nil
+}
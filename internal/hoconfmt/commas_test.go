@@ -0,0 +1,96 @@
+package hoconfmt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFixCommas(t *testing.T) {
+	for _, test := range []struct {
+		src, want string
+		fixes     []string // messages, in order
+	}{
+		{"a = [1,,2]\n", "a = [1,2]\n", []string{"1:8: doubled comma"}},
+		{"a = [1, , 2]\n", "a = [1, 2]\n", []string{"1:9: doubled comma"}},
+		{"a = [1, # one\n  , 2]\n", "a = [1, # one\n  2]\n", []string{"2:3: doubled comma"}},
+		{"a = [,1]\n", "a = [1]\n", []string{"1:6: leading comma"}},
+		{"a { , b = 1 }\n", "a { b = 1 }\n", []string{"1:5: leading comma"}},
+		{", a = 1\n", "a = 1\n", []string{"1:1: leading comma"}},
+		{"a = [,,1]\n", "a = [1]\n", []string{"1:6: leading comma", "1:7: doubled comma"}},
+		// Trailing commas and commas inside strings are fine.
+		{"a = [1,]\nb { c = 1, }\n", "a = [1,]\nb { c = 1, }\n", nil},
+		{"a = \",,\"\nb = \"\"\",,\"\"\"\n", "a = \",,\"\nb = \"\"\",,\"\"\"\n", nil},
+	} {
+		got, fixes, err := fixCommas(nil, []byte(test.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("fixCommas(%q) = %q, want %q", test.src, got, test.want)
+		}
+		var msgs []string
+		for _, f := range fixes {
+			msgs = append(msgs, f.Error())
+		}
+		if strings.Join(msgs, "\n") != strings.Join(test.fixes, "\n") {
+			t.Errorf("fixCommas(%q) reports %q, want %q", test.src, msgs, test.fixes)
+		}
+	}
+}
+
+func TestBadCommasWithoutFix(t *testing.T) {
+	if _, err := formatFile("a.conf", []byte("a = [1,,2]\n"), DefaultOptions()); err == nil || !strings.Contains(err.Error(), "a.conf:1:8: doubled comma") {
+		t.Errorf("got error %v, want a doubled comma error", err)
+	}
+	opts := DefaultOptions()
+	opts.FixCommas = true
+	res, err := formatFile("a.conf", []byte("a = [1,,2]\n"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a = [1, 2]\n"; string(res) != want {
+		t.Errorf("got %q, want %q", res, want)
+	}
+}
+
+func TestSmartCommas(t *testing.T) {
+	for _, test := range []struct {
+		src, want string
+	}{
+		{"a = [1, 2, 3]\n", "a = [1, 2, 3]\n"},
+		{"a = [1, 2,]\nb { c = 1, }\n", "a = [1, 2]\nb { c = 1 }\n"},
+		{"a = [1 , ]\n", "a = [1]\n"},
+		{"a {\n    b = 1,\n    c = 2,\n}\n", "a {\n    b = 1\n    c = 2\n}\n"},
+		{"a = [\n    1, 2,\n    3\n]\n", "a = [\n    1, 2\n    3\n]\n"},
+		{"a = 1, # one\nb = 2 ,\n", "a = 1 # one\nb = 2\n"},
+		{"a = [\n    1\n    ,\n]\n", "a = [\n    1\n]\n"},
+		{"a = 1,", "a = 1"},
+		{"a = \"x,\"\n", "a = \"x,\"\n"},
+		{"a = [1, 2, ]\nb { c = 1 , }\nd {e=1,}\n", "a = [1, 2]\nb { c = 1 }\nd {e=1}\n"},
+	} {
+		got, err := smartCommas(nil, []byte(test.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("smartCommas(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestSmartCommasTwice(t *testing.T) {
+	const src = "a = [1, 2, ]\nb { c = 1, }\nd = [\n  1,\n  2,\n]\n"
+	opts := DefaultOptions()
+	opts.Commas = commasSmart
+	once, err := formatFile("a.conf", []byte(src), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	twice, err := formatFile("a.conf", once, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(twice) != string(once) {
+		t.Errorf("formatting %q gives %q, and formatting that again %q", src, once, twice)
+	}
+}
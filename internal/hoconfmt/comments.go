@@ -0,0 +1,262 @@
+package hoconfmt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// alignComments lines up the trailing comments of consecutive lines at
+// the same nesting depth into one column, one space past the longest
+// line of the group. A blank line, a line without a trailing comment or
+// a change of depth starts a new group. Tabs count up to the next
+// multiple of tabwidth when measuring.
+//
+// If threshold is positive, a line whose code is more than threshold
+// columns wider than the median line of its group is left out of the
+// column, with one space before its comment, so that a single long key
+// does not push the comments of all the others far to the right.
+func alignComments(src []byte, tabwidth, threshold int) ([]byte, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	lines := splitLines(toks)
+
+	var res []byte
+	for i := 0; i < len(lines); {
+		if trailingComment(lines[i]) < 0 {
+			res = lines[i].appendTo(res)
+			i++
+			continue
+		}
+
+		// Collect the group and measure its widest line.
+		j := i
+		var widths []int
+		for ; j < len(lines) && lines[j].depth == lines[i].depth; j++ {
+			end := trailingComment(lines[j])
+			if end < 0 {
+				break
+			}
+			widths = append(widths, textWidth(lines[j].toks[:end], tabwidth))
+		}
+		limit := -1 // widest line that is aligned, or -1 for all
+		if threshold > 0 {
+			sorted := append([]int(nil), widths...)
+			sort.Ints(sorted)
+			limit = sorted[(len(sorted)-1)/2] + threshold
+		}
+		width := 0
+		for _, w := range widths {
+			if w > width && (limit < 0 || w <= limit) {
+				width = w
+			}
+		}
+
+		for k, l := range lines[i:j] {
+			end := trailingComment(l)
+			for _, t := range l.toks[:end] {
+				res = append(res, t.lit...)
+			}
+			pad := width - widths[k] + 1
+			if pad < 1 {
+				pad = 1
+			}
+			res = append(res, strings.Repeat(" ", pad)...)
+			for _, t := range l.toks[end:] {
+				if t.kind != tokSpace {
+					res = append(res, t.lit...)
+				}
+			}
+		}
+		i = j
+	}
+	return res, nil
+}
+
+// trailingComment returns the end of the code in l, not counting the
+// white space before the comment, if l ends in a comment that follows
+// some code on the same line. Otherwise it returns -1. Lines containing
+// multi-line strings are never aligned.
+func trailingComment(l srcLine) int {
+	n := len(l.toks)
+	if n > 0 && l.toks[n-1].kind == tokNewline {
+		n--
+	}
+	if n == 0 || l.toks[n-1].kind != tokComment {
+		return -1
+	}
+	end := n - 1
+	for end > 0 && l.toks[end-1].kind == tokSpace {
+		end--
+	}
+	if end == 0 {
+		return -1
+	}
+	for _, t := range l.toks[:end] {
+		if t.kind == tokMultiline {
+			return -1
+		}
+	}
+	return end
+}
+
+// textWidth returns the width of the text of toks in columns.
+func textWidth(toks []tok, tabwidth int) int {
+	w := 0
+	for _, t := range toks {
+		for _, r := range t.lit {
+			if r == '\t' && tabwidth > 0 {
+				w += tabwidth - w%tabwidth
+			} else {
+				w++
+			}
+		}
+	}
+	return w
+}
+
+// wrapComments breaks comment lines that are wider than width at spaces
+// into several lines, each starting with the same indentation and
+// comment marker. Only lines holding nothing but a comment are
+// wrapped. Banners and comments that look like code, such as commented
+// out fields or indented examples, are left alone, and so is a word
+// that is wider than width on its own. Lines are never joined, so
+// wrapping a wrapped comment again changes nothing.
+func wrapComments(src []byte, width, tabwidth int) ([]byte, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []byte
+	for _, l := range splitLines(toks) {
+		c := l.first()
+		if c == nil || c.kind != tokComment {
+			res = l.appendTo(res)
+			continue
+		}
+		indent := leadingSpace(l)
+		if textWidth([]tok{{lit: indent + c.lit}}, tabwidth) <= width {
+			res = l.appendTo(res)
+			continue
+		}
+		marker := "#"
+		if strings.HasPrefix(c.lit, "//") {
+			marker = "//"
+		}
+		text := strings.TrimPrefix(c.lit, marker)
+		if !strings.HasPrefix(text, " ") || looksLikeCode(text[1:]) {
+			res = l.appendTo(res)
+			continue
+		}
+
+		prefix := indent + marker + " "
+		line := prefix
+		for _, word := range strings.Fields(text) {
+			if line != prefix && textWidth([]tok{{lit: line + " " + word}}, tabwidth) > width {
+				res = append(res, line...)
+				res = append(res, '\n')
+				line = prefix
+			}
+			if line != prefix {
+				line += " "
+			}
+			line += word
+		}
+		res = append(res, line...)
+		if l.toks[len(l.toks)-1].kind == tokNewline {
+			res = append(res, '\n')
+		}
+	}
+	return res, nil
+}
+
+// looksLikeCode reports whether the text of a comment, after the marker
+// and one space, seems to be something other than prose: an indented
+// example, a banner made of punctuation, or HOCON syntax.
+func looksLikeCode(text string) bool {
+	if strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") {
+		return true
+	}
+	if strings.IndexFunc(text, unicode.IsLetter) < 0 {
+		return true
+	}
+	for _, s := range []string{"${", " = ", " += ", "{", "}", "[", "]", "\"\"\""} {
+		if strings.Contains(text, s) {
+			return true
+		}
+	}
+	return strings.HasPrefix(text, "include ") || strings.HasSuffix(text, ",") || strings.HasSuffix(text, ";")
+}
+
+// checkComments reports the first comment of src whose text does not
+// appear among the comments of res, for -no-lose-comments. Comments
+// compare by their words, so comments that formatting moves, respaces
+// or wraps onto several lines count as kept. If properties is set, src
+// is a .properties file, whose comment lines start with # or !.
+func checkComments(src, res []byte, properties bool) error {
+	have := map[string]int{} // words of the comments of res
+	toks, err := tokenize(res)
+	if err != nil {
+		return err
+	}
+	for _, t := range toks {
+		if t.kind == tokComment {
+			for _, w := range commentWords(t.lit) {
+				have[w]++
+			}
+		}
+	}
+
+	type comment struct {
+		off   int
+		text  string
+		words []string
+	}
+	var comments []comment
+	if properties {
+		off := 0
+		for _, line := range strings.SplitAfter(string(src), "\n") {
+			if l := strings.TrimLeft(line, " \t\f"); strings.HasPrefix(l, "#") || strings.HasPrefix(l, "!") {
+				comments = append(comments, comment{off + len(line) - len(l), l, strings.Fields(l[1:])})
+			}
+			off += len(line)
+		}
+	} else {
+		toks, err := tokenize(src)
+		if err != nil {
+			return err
+		}
+		for _, t := range toks {
+			if t.kind == tokComment {
+				comments = append(comments, comment{t.off, t.lit, commentWords(t.lit)})
+			}
+		}
+	}
+
+	for _, c := range comments {
+		for _, w := range c.words {
+			if have[w] == 0 {
+				var errs errorList
+				errs.Add(position(src, c.off), codeComment, fmt.Sprintf("comment would be lost: %s", strings.TrimSpace(c.text)))
+				return errs
+			}
+			have[w]--
+		}
+	}
+	return nil
+}
+
+// commentWords returns the words of the comment lit after its marker.
+func commentWords(lit string) []string {
+	switch {
+	case strings.HasPrefix(lit, "//"):
+		lit = lit[2:]
+	case strings.HasPrefix(lit, "#"):
+		lit = lit[1:]
+	}
+	return strings.Fields(lit)
+}
@@ -0,0 +1,54 @@
+package hoconfmt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckComments(t *testing.T) {
+	for _, test := range []struct {
+		src, res string
+		props    bool
+		err      string
+	}{
+		{"# a\nx = 1 // b\n", "# a\nx = 1 // b\n", false, ""},
+		{"#note\nx = # moved\n    1\n", "# note\n# moved\nx = 1\n", false, ""},
+		{"# one two\n# three\n", "# one\n# two three\n", false, ""},
+		{"# a\nx = 1 # b\n", "# a\nx = 1\n", false, "2:7: comment would be lost: # b"},
+		{"# a\n# a\n", "# a\n", false, "2:1: comment would be lost: # a"},
+		{"! one\n  # two\r\nx=1\n", "# one\n# two\nx = 1\n", true, ""},
+		{"! one\n  # two\nx=1\n", "# one\nx = 1\n", true, "2:3: comment would be lost: # two"},
+	} {
+		err := checkComments([]byte(test.src), []byte(test.res), test.props)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != test.err {
+			t.Errorf("checkComments(%q, %q) = %q, want %q", test.src, test.res, got, test.err)
+		}
+	}
+}
+
+func TestNoLoseComments(t *testing.T) {
+	const src = "db {\n    # Primary database.\n    url = \"jdbc:x\"\n}\n# Scratch space.\ntmp = 1\n"
+	opts := DefaultOptions()
+	opts.ExcludeKeys = "tmp"
+	if _, err := formatFile("a.conf", []byte(src), opts); err != nil {
+		t.Fatal(err)
+	}
+	opts.NoLoseComments = true
+	_, err := formatFile("a.conf", []byte(src), opts)
+	if err == nil || !strings.Contains(err.Error(), "a.conf:5:1: comment would be lost: # Scratch space.") {
+		t.Errorf("got error %v, want a lost comment", err)
+	}
+	opts.ExcludeKeys = "db.url"
+	if _, err := formatFile("a.conf", []byte(src), opts); err == nil || !strings.Contains(err.Error(), "a.conf:2:5: comment would be lost: # Primary database.") {
+		t.Errorf("got error %v, want a lost comment", err)
+	}
+	opts.ExcludeKeys = ""
+	opts.WrapComments, opts.Width = true, 12
+	if _, err := formatFile("a.conf", []byte(src), opts); err != nil {
+		t.Errorf("wrapping comments loses them: %v", err)
+	}
+}
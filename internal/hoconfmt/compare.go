@@ -0,0 +1,448 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// A flatValue is the value a key path has after all the fields of a
+// document have been applied.
+type flatValue struct {
+	kind string // object, array, string, number, boolean, null, substitution, concatenation or append
+	text string // canonical text; empty for objects
+}
+
+// flatConfig maps the key paths of a document, written as by
+// renderPath, to their values. Objects have an entry of their own next
+// to the entries of their fields.
+type flatConfig map[string]flatValue
+
+// flattenConfig applies the fields of src in order, the way HOCON
+// merges them: a later value for a path replaces an earlier one, except
+// that an object merges into an earlier object. Substitutions are not
+// resolved, includes are not followed, and objects inside arrays are
+// part of the array's value.
+func flattenConfig(src []byte) (flatConfig, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := flatConfig{}
+	walkFields(toks, func(f field) {
+		if f.object {
+			cfg.setObject(f.path)
+			return
+		}
+		val := flatScalar(toks[f.value:f.end])
+		if f.appending {
+			val = flatValue{"append", val.text}
+		}
+		cfg.set(f.path, val)
+	})
+	return cfg, nil
+}
+
+// A field is a key and its value, as found by walkFields.
+type field struct {
+	path      []string // full key path
+	key       int      // index of the first token of the key
+	value     int      // index of the first token of the value
+	end       int      // index just past the value; for objects, value+1
+	object    bool     // the value is an object, whose fields follow
+	appending bool     // the separator is +=
+}
+
+// walkFields calls visit for every field of the objects of toks in
+// order, objects before their fields. Fields of objects inside arrays
+// are not visited, and neither are include statements.
+func walkFields(toks []tok, visit func(field)) {
+	type level struct {
+		path   []string // key path of the object, nil inside arrays
+		object bool
+	}
+	stack := []level{{path: []string{}, object: true}}
+	atField := true // at the start of a field in an object
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		top := stack[len(stack)-1]
+		if atField && top.object && top.path != nil && isText(t) && t.lit != "include" {
+			atField = false
+			j, elems := keyPath(toks, i)
+			if j < 0 {
+				continue
+			}
+			f := field{path: append(append([]string{}, top.path...), elems...), key: i}
+			v := j
+			f.appending = toks[v].kind == tokSep && toks[v].lit == "+="
+			if toks[v].kind == tokSep {
+				v++
+			}
+			for v < len(toks) && toks[v].kind == tokSpace {
+				v++
+			}
+			if v == len(toks) {
+				break
+			}
+			f.value = v
+			if toks[v].kind == tokLBrace && !f.appending && valueEnds(toks, matchingBracket(toks, v)+1) {
+				f.object, f.end = true, v+1
+				visit(f)
+				stack = append(stack, level{path: f.path, object: true})
+				atField = true
+				i = v
+				continue
+			}
+			f.end = valueEnd(toks, v)
+			visit(f)
+			i = f.end - 1
+			continue
+		}
+
+		switch t.kind {
+		case tokLBrace:
+			l := level{object: true}
+			if i == firstToken(toks) {
+				l.path = []string{} // braces around the whole document
+			}
+			stack = append(stack, l)
+			atField = true
+		case tokLBracket:
+			stack = append(stack, level{})
+		case tokRBrace, tokRBracket:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+			atField = true
+		case tokNewline, tokComma:
+			atField = true
+		}
+	}
+}
+
+// matchingBracket returns the index of the bracket that closes the one
+// at toks[i], or len(toks)-1 if it is not closed.
+func matchingBracket(toks []tok, i int) int {
+	depth := 0
+	for j := i; j < len(toks); j++ {
+		switch toks[j].kind {
+		case tokLBrace, tokLBracket:
+			depth++
+		case tokRBrace, tokRBracket:
+			if depth--; depth == 0 {
+				return j
+			}
+		}
+	}
+	return len(toks) - 1
+}
+
+// set gives the path p the value v, replacing whatever p held before,
+// including the fields of an object.
+func (cfg flatConfig) set(p []string, v flatValue) {
+	key := renderPath(p)
+	if old, ok := cfg[key]; ok && old.kind == "object" {
+		// Only an object has paths inside it.
+		for k := range cfg {
+			if strings.HasPrefix(k, key+".") {
+				delete(cfg, k)
+			}
+		}
+	}
+	cfg.setParents(p)
+	cfg[key] = v
+}
+
+// setObject makes p an object. An earlier object at p keeps its fields;
+// any other value is replaced.
+func (cfg flatConfig) setObject(p []string) {
+	key := renderPath(p)
+	if v, ok := cfg[key]; ok && v.kind == "object" {
+		return
+	}
+	cfg.setParents(p)
+	cfg[key] = flatValue{kind: "object"}
+}
+
+// setParents makes every proper prefix of p an object.
+func (cfg flatConfig) setParents(p []string) {
+	for n := 1; n < len(p); n++ {
+		key := renderPath(p[:n])
+		if v, ok := cfg[key]; !ok || v.kind != "object" {
+			cfg[key] = flatValue{kind: "object"}
+		}
+	}
+}
+
+// renderPath writes the key path p with '.' between its elements,
+// quoting the elements that need it.
+func renderPath(p []string) string {
+	elems := make([]string, len(p))
+	for i, e := range p {
+		elems[i] = hoconKey(e)
+	}
+	return strings.Join(elems, ".")
+}
+
+// lessPath reports whether the rendered path p sorts before q. Paths
+// sort element by element on the keys themselves, so that a quoted key
+// such as "Content-Type" sorts among the unquoted ones by its content,
+// and an object comes right before its fields.
+func lessPath(p, q string) bool {
+	a, b := pathElems(p), pathElems(q)
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// pathElems splits a path written by renderPath into its elements,
+// unquoting the quoted ones.
+func pathElems(p string) []string {
+	var elems []string
+	for {
+		e := p
+		if strings.HasPrefix(p, `"`) {
+			end := 1
+			for end < len(p) && p[end] != '"' {
+				if p[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			e = p[:min(end+1, len(p))]
+			var s string
+			if err := json.Unmarshal([]byte(e), &s); err == nil {
+				elems = append(elems, s)
+			} else {
+				elems = append(elems, e)
+			}
+		} else {
+			if i := strings.IndexByte(p, '.'); i >= 0 {
+				e = p[:i]
+			}
+			elems = append(elems, e)
+		}
+		p = p[len(e):]
+		if !strings.HasPrefix(p, ".") {
+			return elems
+		}
+		p = p[1:]
+	}
+}
+
+// flatScalar returns the value written as toks, which is not an object.
+// Strings compare by content, whether they are quoted or not; arrays
+// compare by their elements, whatever separates them.
+func flatScalar(toks []tok) flatValue {
+	var text []tok
+	for _, t := range toks {
+		if t.kind != tokComment {
+			text = append(text, t)
+		}
+	}
+	if len(text) == 1 {
+		switch t := text[0]; {
+		case t.kind == tokSubst:
+			return flatValue{"substitution", t.lit}
+		case t.kind == tokUnquoted && (t.lit == "true" || t.lit == "false"):
+			return flatValue{"boolean", t.lit}
+		case t.kind == tokUnquoted && t.lit == "null":
+			return flatValue{"null", t.lit}
+		case t.kind == tokUnquoted && numberLiteral.MatchString(t.lit):
+			return flatValue{"number", t.lit}
+		case t.kind == tokUnquoted || t.kind == tokString || t.kind == tokMultiline:
+			return flatValue{"string", quoteString(stringValue(t))}
+		}
+	}
+	if len(text) > 0 && text[0].kind == tokLBracket && matchingBracket(text, 0) == len(text)-1 {
+		return flatValue{"array", canonicalArray(text)}
+	}
+	var b strings.Builder
+	for _, t := range text {
+		b.WriteString(t.lit)
+	}
+	return flatValue{"concatenation", b.String()}
+}
+
+// stringValue returns the content of the string token t.
+func stringValue(t tok) string {
+	switch t.kind {
+	case tokString:
+		var s string
+		if err := json.Unmarshal([]byte(t.lit), &s); err == nil {
+			return s
+		}
+	case tokMultiline:
+		return t.lit[3 : len(t.lit)-3]
+	}
+	return t.lit
+}
+
+// canonicalArray renders the array toks with ", " between elements, " = "
+// as every separator and no other white space except single spaces
+// inside concatenations, so that arrays written with newlines or commas
+// compare the same. The keys of objects inside the array are quoted only
+// where they need it, and a key written without a separator before its
+// object gets one, so that {"a": {}} and { a {} } compare the same too.
+func canonicalArray(toks []tok) string {
+	var b strings.Builder
+	sep := false   // a separator is pending
+	space := false // a space inside a concatenation is pending
+	last := tokLBracket
+	for i, t := range toks {
+		switch t.kind {
+		case tokComment:
+			continue
+		case tokSpace:
+			space = isText(tok{kind: last})
+			continue
+		case tokNewline, tokComma:
+			sep = last != tokLBracket && last != tokLBrace && last != tokSep
+			space = false
+			continue
+		case tokRBracket, tokRBrace:
+			sep, space = false, false
+		case tokSep:
+			b.WriteString(" = ")
+			last, sep, space = t.kind, false, false
+			continue
+		}
+		if sep {
+			b.WriteString(", ")
+		} else if space && isText(t) {
+			b.WriteByte(' ')
+		}
+		sep, space = false, false
+		j := i + 1
+		for j < len(toks) && toks[j].kind == tokSpace {
+			j++
+		}
+		switch {
+		case t.kind == tokLBrace && isText(tok{kind: last}):
+			b.WriteString(" = {")
+		case t.kind == tokString && j < len(toks) && (toks[j].kind == tokSep || toks[j].kind == tokLBrace):
+			b.WriteString(hoconKey(stringValue(t)))
+		default:
+			b.WriteString(t.lit)
+		}
+		last = t.kind
+	}
+	return b.String()
+}
+
+// A configChange is one difference reported by -compare.
+type configChange struct {
+	Op      string `json:"op"` // added, removed or changed
+	Path    string `json:"path"`
+	Old     string `json:"old,omitempty"`
+	OldType string `json:"oldType,omitempty"`
+	New     string `json:"new,omitempty"`
+	NewType string `json:"newType,omitempty"`
+}
+
+// compareConfigs returns the differences between the documents a and
+// b, sorted by path. The fields of an object that is added or removed
+// as a whole, or that replaces or is replaced by another kind of value,
+// are not listed separately.
+func compareConfigs(a, b flatConfig) []configChange {
+	paths := map[string]bool{}
+	for p := range a {
+		paths[p] = true
+	}
+	for p := range b {
+		paths[p] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for p := range paths {
+		sorted = append(sorted, p)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return lessPath(sorted[i], sorted[j]) })
+
+	changes := []configChange{}
+	var covered []string // paths whose fields are already accounted for
+	for _, p := range sorted {
+		if within(p, covered) {
+			continue
+		}
+		va, inA := a[p]
+		vb, inB := b[p]
+		switch {
+		case !inA:
+			changes = append(changes, configChange{Op: "added", Path: p, New: vb.text, NewType: vb.kind})
+		case !inB:
+			changes = append(changes, configChange{Op: "removed", Path: p, Old: va.text, OldType: va.kind})
+		case va != vb:
+			changes = append(changes, configChange{Op: "changed", Path: p, Old: va.text, OldType: va.kind, New: vb.text, NewType: vb.kind})
+		default:
+			continue
+		}
+		covered = append(covered, p)
+	}
+	return changes
+}
+
+// within reports whether the path p lies inside one of the objects at
+// paths. Path elements containing a '.' are quoted, so a path starts
+// with the elements of another exactly when it starts with its text
+// and a '.'.
+func within(p string, paths []string) bool {
+	for _, q := range paths {
+		if strings.HasPrefix(p, q+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// compareReport is the JSON output of -compare.
+type compareReport struct {
+	File    string         `json:"file"`
+	Compare string         `json:"compare"`
+	Changes []configChange `json:"changes"`
+}
+
+func printCompare(out io.Writer, r compareReport) error {
+	if *jsonReport {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", data)
+		return err
+	}
+	for _, c := range r.Changes {
+		var err error
+		switch c.Op {
+		case "added":
+			_, err = fmt.Fprintf(out, "added   %s\n", describe(c.Path, c.New, c.NewType))
+		case "removed":
+			_, err = fmt.Fprintf(out, "removed %s\n", describe(c.Path, c.Old, c.OldType))
+		default:
+			_, err = fmt.Fprintf(out, "changed %s -> %s\n", describe(c.Path, c.Old, c.OldType), describeValue(c.New, c.NewType))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func describe(path, text, kind string) string {
+	return path + " = " + describeValue(text, kind)
+}
+
+func describeValue(text, kind string) string {
+	switch kind {
+	case "object":
+		return "{...}"
+	case "array", "null":
+		return text
+	}
+	return fmt.Sprintf("%s (%s)", text, kind)
+}
@@ -0,0 +1,144 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCompareConfigs(t *testing.T) {
+	const a = `# before
+server {
+    host = "localhost"
+    port = 8080
+    tags = [a, b]
+}
+db.url = ${DB_URL}
+cache { ttl = 60 }
+retries: 3
+`
+	const b = `server {
+    host = localhost // same string, unquoted
+    port = "8080"
+    tags = [
+        a
+        b
+    ]
+}
+db.url = ${DB_URL}
+db.pool = 10
+cache = off
+`
+	fa, err := flattenConfig([]byte(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fb, err := flattenConfig([]byte(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := compareConfigs(fa, fb)
+	want := []configChange{
+		{Op: "changed", Path: "cache", OldType: "object", New: `"off"`, NewType: "string"},
+		{Op: "added", Path: "db.pool", New: "10", NewType: "number"},
+		{Op: "removed", Path: "retries", Old: "3", OldType: "number"},
+		{Op: "changed", Path: "server.port", Old: "8080", OldType: "number", New: `"8080"`, NewType: "string"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compareConfigs =\n%+v\nwant\n%+v", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := printCompare(&buf, compareReport{"a.conf", "b.conf", got}); err != nil {
+		t.Fatal(err)
+	}
+	const wantText = `changed cache = {...} -> "off" (string)
+added   db.pool = 10 (number)
+removed retries = 3 (number)
+changed server.port = 8080 (number) -> "8080" (string)
+`
+	if buf.String() != wantText {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), wantText)
+	}
+}
+
+func TestCompareConfigsQuotedKeys(t *testing.T) {
+	fa, err := flattenConfig([]byte("headers {\n    accept = \"*/*\"\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fb, err := flattenConfig([]byte(`headers {
+    "X Forwarded For" = a
+    "Content-Type" = "application/json"
+    "content-type" = "text/plain"
+    Zone = b
+    "a.b" = c
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range compareConfigs(fa, fb) {
+		got = append(got, c.Op+" "+c.Path)
+	}
+	// Quoted keys sort by their content.
+	want := []string{
+		`added headers.Content-Type`,
+		`added headers."X Forwarded For"`,
+		`added headers.Zone`,
+		`added headers."a.b"`,
+		`removed headers.accept`,
+		`added headers.content-type`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compareConfigs paths =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestFlattenConfig(t *testing.T) {
+	const src = `a { x = 1, y = 2 }
+a { y = 3 }
+b = 1
+b { z = 4 }
+c.d = 5
+c = 6
+"e.f" = 7
+list += 8
+`
+	got, err := flattenConfig([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := flatConfig{
+		"a":     {kind: "object"},
+		"a.x":   {"number", "1"},
+		"a.y":   {"number", "3"},
+		"b":     {kind: "object"},
+		"b.z":   {"number", "4"},
+		"c":     {"number", "6"},
+		`"e.f"`: {"number", "7"},
+		"list":  {"append", "8"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("flattenConfig =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestCanonicalArrayObjects(t *testing.T) {
+	same := []string{
+		`[{"a": [{"a b": {}}], "c.d": 1}]`,
+		`[{ a = [{ "a b" {} }], "c.d" = 1 }]`,
+		"[\n    {\n        a = [{ \"a b\" = {} }]\n        \"c.d\" : 1\n    }\n]",
+	}
+	want := `[{a = [{"a b" = {}}], "c.d" = 1}]`
+	for _, src := range same {
+		toks, err := tokenize([]byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := canonicalArray(toks); got != want {
+			t.Errorf("canonicalArray(%q) = %q, want %q", src, got, want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package hoconfmt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// concatOK is the comment that marks the concatenations of array
+// elements on its line as deliberate, for -warn-concat.
+const concatOK = "hoconfmt:concat"
+
+// findConcats returns the array elements of src that join several values
+// with white space, as in
+//
+//	hosts = [alpha beta]          // the single string "alpha beta"
+//	sizes = ["s" "m", "l"]        // "s m" and "l"
+//	parts = [{ a = 1 } { b = 2 }] // one merged object
+//
+// HOCON separates array elements only with commas and newlines, so such
+// an element is one concatenated value, and more often than not a comma
+// is missing. Elements holding a substitution, as in [${dir} "/bin"],
+// and values joined without space, as in [foo"bar"], are taken as
+// intended, and so is every element on a line with a "hoconfmt:concat"
+// comment.
+func findConcats(src []byte) (errorList, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	ok := map[int]bool{} // lines with a concatOK comment
+	for _, t := range toks {
+		if t.kind == tokComment && strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(t.lit, "#"), "//")) == concatOK {
+			ok[position(src, t.off).Line] = true
+		}
+	}
+
+	var list errorList
+	for i, t := range toks {
+		if t.kind != tokLBracket {
+			continue
+		}
+		end := matchingBracket(toks, i)
+		for j := i + 1; j < end; {
+			start, values, subst := j, 0, false
+			space, spaced := false, false // white space after a value, and between two
+			for ; j < end; j++ {
+				switch kind := toks[j].kind; {
+				case kind == tokComma || kind == tokNewline || kind == tokComment:
+				case kind == tokSpace:
+					space = values > 0
+					continue
+				default:
+					subst = subst || kind == tokSubst
+					spaced = spaced || space
+					space = false
+					values++
+					if kind == tokLBrace || kind == tokLBracket {
+						j = matchingBracket(toks, j)
+					}
+					continue
+				}
+				break
+			}
+			if values > 1 && spaced && !subst {
+				for start < j && toks[start].kind == tokSpace {
+					start++
+				}
+				pos := position(src, toks[start].off)
+				if !ok[pos.Line] {
+					text := strings.TrimSpace(string(lineText(toks[start:j])))
+					list.Add(pos, codeConcatenation, fmt.Sprintf("array element %s is a single concatenated value; is a comma missing?", text))
+				}
+			}
+			j++
+		}
+	}
+	list.Sort()
+	return list, nil
+}
+
+// warnConcats reports a warning to warn for every concatenated array
+// element of src.
+func warnConcats(warn func(warning), filename string, src []byte) error {
+	list, err := findConcats(src)
+	if err != nil {
+		return withFilename(err, filename)
+	}
+	for _, e := range list {
+		e.Pos.Filename = filename
+		warn(warning{e.Pos, e.Msg, e.Code})
+	}
+	return nil
+}
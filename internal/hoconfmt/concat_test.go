@@ -0,0 +1,64 @@
+package hoconfmt
+
+import "testing"
+
+func TestFindConcats(t *testing.T) {
+	const src = `hosts = [alpha beta]
+sizes = ["s" "m", "l"]
+ports = [8080 8081]
+parts = [{ a = 1 } { b = 2 }]
+lists = [[1] [2]]
+spread = [
+    one
+    two three # a comma is missing
+    four
+]
+inner = [[a b]]
+
+# Intended.
+ok1 = [alpha, beta]
+ok2 = [${dir} "/bin", ${a} ${b}]
+ok3 = [foo"bar", "a""b"]
+ok4 = ["a b", a.b ]
+ok5 = ["x" "y"] # hoconfmt:concat
+ok6 = [
+    x y // hoconfmt:concat
+]
+text = alpha beta
+obj { a = [1 ,2] }
+`
+	list, err := findConcats([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`1:10: array element alpha beta is a single concatenated value; is a comma missing?`,
+		`2:10: array element "s" "m" is a single concatenated value; is a comma missing?`,
+		`3:10: array element 8080 8081 is a single concatenated value; is a comma missing?`,
+		`4:10: array element { a = 1 } { b = 2 } is a single concatenated value; is a comma missing?`,
+		`5:10: array element [1] [2] is a single concatenated value; is a comma missing?`,
+		`8:5: array element two three is a single concatenated value; is a comma missing?`,
+		`11:11: array element a b is a single concatenated value; is a comma missing?`,
+	}
+	if len(list) != len(want) {
+		t.Fatalf("got %d warnings %v, want %d", len(list), list, len(want))
+	}
+	for i, e := range list {
+		if e.Error() != want[i] {
+			t.Errorf("warning %d = %q, want %q", i, e.Error(), want[i])
+		}
+	}
+}
+
+func TestWarnConcatKeepsSource(t *testing.T) {
+	const src = "a = [x y]\n"
+	opts := DefaultOptions()
+	opts.WarnConcat = true
+	res, err := formatFile("a.conf", []byte(src), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != src {
+		t.Errorf("got %q, want %q", res, src)
+	}
+}
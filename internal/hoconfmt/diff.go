@@ -0,0 +1,281 @@
+package hoconfmt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+)
+
+// unifiedDiff writes the differences between b1 and b2 to out in the
+// unified format of diff -u, with context lines of context around each
+// change and name1 and name2 in the file headers, followed by the times
+// time1 and time2 as diff writes them unless they are zero. It writes
+// nothing if b1 and b2 are the same. Each hunk is written as soon as
+// its last change is found. A last line without a newline is marked
+// the way diff marks it, so that the diff applies with patch.
+func unifiedDiff(out io.Writer, name1, name2 string, time1, time2 time.Time, b1, b2 []byte, context int) error {
+	a, b := splitDiffLines(b1), splitDiffLines(b2)
+	del, ins := lineChanges(a, b)
+
+	w := bufio.NewWriter(out)
+	headers := false
+	err := eachHunk(del, ins, context, func(h diffHunk) error {
+		if !headers {
+			fmt.Fprintf(w, "--- %s\n+++ %s\n", diffHeader(name1, time1), diffHeader(name2, time2))
+			headers = true
+		}
+		fmt.Fprintf(w, "@@ -%s +%s @@\n", hunkHeader(h.from1, h.to1), hunkHeader(h.from2, h.to2))
+		i, j := h.from1, h.from2
+		for i < h.to1 || j < h.to2 {
+			switch {
+			case i < h.to1 && del[i]:
+				writeDiffLine(w, '-', a[i])
+				i++
+			case j < h.to2 && ins[j]:
+				writeDiffLine(w, '+', b[j])
+				j++
+			default:
+				writeDiffLine(w, ' ', a[i])
+				i++
+				j++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// diffHeader returns the file header of a unified diff for the file
+// name changed at t, with the time as diff -u writes it.
+func diffHeader(name string, t time.Time) string {
+	if t.IsZero() {
+		return name
+	}
+	return name + "\t" + t.Format("2006-01-02 15:04:05.000000000 -0700")
+}
+
+// A diffHunk is a run of changes with their context: lines [from1,
+// to1) of the old text and [from2, to2) of the new.
+type diffHunk struct {
+	from1, to1, from2, to2 int
+}
+
+// diffHunks returns the hunks eachHunk finds.
+func diffHunks(del, ins []bool, context int) []diffHunk {
+	var hunks []diffHunk
+	eachHunk(del, ins, context, func(h diffHunk) error {
+		hunks = append(hunks, h)
+		return nil
+	})
+	return hunks
+}
+
+// eachHunk groups the deleted lines del of the old text and the
+// inserted lines ins of the new into hunks with context lines of
+// context on each side, and calls f with each hunk in order as soon as
+// it is complete. Changes that are at most 2*context unchanged lines
+// apart share a hunk. It stops at the first error f returns.
+func eachHunk(del, ins []bool, context int, f func(diffHunk) error) error {
+	var h diffHunk
+	open := false
+	last1, last2 := 0, 0 // end of the last change
+	i, j := 0, 0
+	for i < len(del) || j < len(ins) {
+		if (i < len(del) && del[i]) || (j < len(ins) && ins[j]) {
+			if !open || i-last1 > 2*context {
+				if open {
+					h.to1, h.to2 = min(last1+context, len(del)), min(last2+context, len(ins))
+					if err := f(h); err != nil {
+						return err
+					}
+				}
+				n := min(context, i, j)
+				h = diffHunk{from1: i - n, from2: j - n}
+				open = true
+			}
+			for i < len(del) && del[i] {
+				i++
+			}
+			for j < len(ins) && ins[j] {
+				j++
+			}
+			last1, last2 = i, j
+			continue
+		}
+		i++
+		j++
+	}
+	if !open {
+		return nil
+	}
+	h.to1, h.to2 = min(last1+context, len(del)), min(last2+context, len(ins))
+	return f(h)
+}
+
+// hunkHeader formats the lines [from, to) as a range of a hunk header:
+// the first line and the number of lines, which is left out if it is 1.
+// An empty range names the line before it.
+func hunkHeader(from, to int) string {
+	switch to - from {
+	case 0:
+		return fmt.Sprintf("%d,0", from)
+	case 1:
+		return fmt.Sprint(from + 1)
+	}
+	return fmt.Sprintf("%d,%d", from+1, to-from)
+}
+
+func writeDiffLine(w *bufio.Writer, mark byte, line string) {
+	w.WriteByte(mark)
+	w.WriteString(line)
+	if line == "" || line[len(line)-1] != '\n' {
+		w.WriteString("\n\\ No newline at end of file\n")
+	}
+}
+
+// splitDiffLines splits b into lines, each with its newline. The last
+// line has none if b does not end in a newline.
+func splitDiffLines(b []byte) []string {
+	var lines []string
+	start := 0
+	for i, c := range b {
+		if c == '\n' {
+			lines = append(lines, string(b[start:i+1]))
+			start = i + 1
+		}
+	}
+	if start < len(b) {
+		lines = append(lines, string(b[start:]))
+	}
+	return lines
+}
+
+// lineChanges compares the lines a and b, and reports which lines of a
+// are deleted and which lines of b are inserted by a shortest edit
+// script turning a into b. It uses the linear space variant of Myers'
+// algorithm, so that large files with few changes are compared quickly
+// and without holding a table of all line pairs.
+func lineChanges(a, b []string) (del, ins []bool) {
+	d := &differ{a: a, b: b, del: make([]bool, len(a)), ins: make([]bool, len(b))}
+	d.compare(0, len(a), 0, len(b))
+	return d.del, d.ins
+}
+
+type differ struct {
+	a, b     []string
+	del, ins []bool
+}
+
+// compare marks the changes between lines [x0, x1) of a and [y0, y1)
+// of b.
+func (d *differ) compare(x0, x1, y0, y1 int) {
+	for x0 < x1 && y0 < y1 && d.a[x0] == d.b[y0] {
+		x0++
+		y0++
+	}
+	for x0 < x1 && y0 < y1 && d.a[x1-1] == d.b[y1-1] {
+		x1--
+		y1--
+	}
+	if x0 == x1 || y0 == y1 {
+		for x := x0; x < x1; x++ {
+			d.del[x] = true
+		}
+		for y := y0; y < y1; y++ {
+			d.ins[y] = true
+		}
+		return
+	}
+	x, y, ok := d.bisect(x0, x1, y0, y1)
+	if !ok || (x == x0 && y == y0) || (x == x1 && y == y1) {
+		// Nothing in common, or no split that makes progress.
+		for x := x0; x < x1; x++ {
+			d.del[x] = true
+		}
+		for y := y0; y < y1; y++ {
+			d.ins[y] = true
+		}
+		return
+	}
+	d.compare(x0, x, y0, y)
+	d.compare(x, x1, y, y1)
+}
+
+// bisect finds the middle snake of a shortest edit script between lines
+// [x0, x1) of a and [y0, y1) of b by searching forward from the start
+// and backward from the end at the same time, and returns the point
+// where the two searches meet.
+func (d *differ) bisect(x0, x1, y0, y1 int) (x, y int, ok bool) {
+	n, m := x1-x0, y1-y0
+	maxD := (n + m + 1) / 2
+	off := maxD
+	size := 2*maxD + 2
+	vf, vb := make([]int, size), make([]int, size)
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[off+1], vb[off+1] = 0, 0
+	delta := n - m
+	front := delta%2 != 0 // the forward search checks for overlap
+	// Diagonals that ran off the edges are skipped from then on.
+	kfStart, kfEnd, kbStart, kbEnd := 0, 0, 0, 0
+	for e := 0; e < maxD; e++ {
+		for k := -e + kfStart; k <= e-kfEnd; k += 2 {
+			var xf int
+			if k == -e || (k != e && vf[off+k-1] < vf[off+k+1]) {
+				xf = vf[off+k+1]
+			} else {
+				xf = vf[off+k-1] + 1
+			}
+			yf := xf - k
+			for xf < n && yf < m && d.a[x0+xf] == d.b[y0+yf] {
+				xf++
+				yf++
+			}
+			vf[off+k] = xf
+			switch {
+			case xf > n:
+				kfEnd += 2
+			case yf > m:
+				kfStart += 2
+			case front:
+				if kb := off + delta - k; kb >= 0 && kb < size && vb[kb] != -1 && xf >= n-vb[kb] {
+					return x0 + xf, y0 + yf, true
+				}
+			}
+		}
+		for k := -e + kbStart; k <= e-kbEnd; k += 2 {
+			var xb int
+			if k == -e || (k != e && vb[off+k-1] < vb[off+k+1]) {
+				xb = vb[off+k+1]
+			} else {
+				xb = vb[off+k-1] + 1
+			}
+			yb := xb - k
+			for xb < n && yb < m && d.a[x1-xb-1] == d.b[y1-yb-1] {
+				xb++
+				yb++
+			}
+			vb[off+k] = xb
+			switch {
+			case xb > n:
+				kbEnd += 2
+			case yb > m:
+				kbStart += 2
+			case !front:
+				if kf := off + delta - k; kf >= 0 && kf < size && vf[kf] != -1 {
+					xf := vf[kf]
+					yf := off + xf - kf
+					if xf >= n-xb {
+						return x0 + xf, y0 + yf, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
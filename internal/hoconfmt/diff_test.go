@@ -0,0 +1,154 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	time1 := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	time2 := time1.Add(5250 * time.Millisecond)
+	for _, test := range []struct {
+		b1, b2  string
+		context int
+		want    string
+	}{
+		{"a\nb\nc\n", "a\nb\nc\n", 3, ""},
+		{
+			"a\nb\nc\nd\n", "a\nx\nc\nd\n", 1,
+			"@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n",
+		},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n", "0\n1\n2\n3\n4\n5\n6\n7\n", 0,
+			"@@ -0,0 +1 @@\n+0\n@@ -8 +8,0 @@\n-8\n",
+		},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n", "0\n1\n2\n3\n4\n5\n6\n7\n", 1,
+			"@@ -1 +1,2 @@\n+0\n 1\n@@ -7,2 +8 @@\n 7\n-8\n",
+		},
+		{
+			"1\n2\n3\n4\n5\n", "1\n3\n4\n5\n6\n", 1,
+			"@@ -1,3 +1,2 @@\n 1\n-2\n 3\n@@ -5 +4,2 @@\n 5\n+6\n",
+		},
+		{
+			"1\n2\n3\n4\n5\n", "1\n3\n4\n5\n6\n", 2,
+			"@@ -1,5 +1,5 @@\n 1\n-2\n 3\n 4\n 5\n+6\n",
+		},
+		{
+			"a = 1", "a = 1\n", 3,
+			"@@ -1 +1 @@\n-a = 1\n\\ No newline at end of file\n+a = 1\n",
+		},
+		{"", "a\n", 3, "@@ -0,0 +1 @@\n+a\n"},
+	} {
+		var out bytes.Buffer
+		if err := unifiedDiff(&out, "old.conf", "new.conf", time1, time2, []byte(test.b1), []byte(test.b2), test.context); err != nil {
+			t.Fatal(err)
+		}
+		got := out.String()
+		if test.want == "" {
+			if got != "" {
+				t.Errorf("diff of equal texts %q: got\n%s", test.b1, got)
+			}
+			continue
+		}
+		const header = "--- old.conf\t2024-03-01 09:30:00.000000000 +0000\n+++ new.conf\t2024-03-01 09:30:05.250000000 +0000\n"
+		if !strings.HasPrefix(got, header) {
+			t.Errorf("diff of %q and %q has no file headers:\n%s", test.b1, test.b2, got)
+		} else {
+			got = got[len(header):]
+		}
+		if got != test.want {
+			t.Errorf("diff of %q and %q:\ngot:\n%s\nwant:\n%s", test.b1, test.b2, got, test.want)
+		}
+	}
+}
+
+// TestLineChanges checks on random texts that the lines lineChanges
+// keeps are the same in both texts, and that it changes no more lines
+// than the longest common subsequence leaves.
+func TestLineChanges(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, r.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+	for n := 0; n < 500; n++ {
+		a, b := random(), random()
+		del, ins := lineChanges(a, b)
+		var kept1, kept2 []string
+		changes := 0
+		for i, d := range del {
+			if d {
+				changes++
+			} else {
+				kept1 = append(kept1, a[i])
+			}
+		}
+		for j, d := range ins {
+			if d {
+				changes++
+			} else {
+				kept2 = append(kept2, b[j])
+			}
+		}
+		if strings.Join(kept1, "") != strings.Join(kept2, "") {
+			t.Fatalf("lineChanges(%q, %q) keeps %q of the first and %q of the second", a, b, kept1, kept2)
+		}
+		if want := len(a) + len(b) - 2*lcsLen(a, b); changes != want {
+			t.Fatalf("lineChanges(%q, %q) makes %d changes, want %d", a, b, changes, want)
+		}
+	}
+}
+
+// lcsLen returns the length of the longest common subsequence of a and
+// b.
+func lcsLen(a, b []string) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func TestDiffHeaders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "a.conf")
+	if err := ioutil.WriteFile(name, []byte("a=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, 3, 1, 9, 30, 0, 0, time.Local)
+	if err := os.Chtimes(name, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := diff(&out, name, []byte("a=1\n"), []byte("a = 1\n")); err != nil {
+		t.Fatal(err)
+	}
+	want := "--- " + name + "\t" + mtime.Format("2006-01-02 15:04:05.000000000 -0700") + "\n+++ " + filepath.Join("hoconfmt", name) + "\t"
+	if !strings.HasPrefix(out.String(), want) {
+		t.Errorf("diff of %s starts\n%s\nwant\n%s", name, out.String(), want)
+	}
+}
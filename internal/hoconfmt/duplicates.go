@@ -0,0 +1,343 @@
+package hoconfmt
+
+import (
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// A duplicate is a field that replaces the value of an earlier field
+// with the same key path.
+type duplicate struct {
+	pos  token.Position // of the later key
+	msg  string
+	prev int    // line of the earlier field
+	code string // codeDuplicateKey, or codeInclude for an include
+}
+
+// findDuplicates returns the fields of src that silently replace the
+// value of an earlier field: a second value for the same path, a value
+// replacing an object, or an object replacing a value. Objects merging
+// into earlier objects are not duplicates, and neither are the
+// idioms that build on the earlier value on purpose:
+//
+//	port = 8080
+//	port = ${?PORT}       // override only if PORT is set
+//	path = ${path}":/bin" // extend the earlier value
+//	list += x             // append to it
+func findDuplicates(src []byte) ([]duplicate, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	type def struct {
+		line   int
+		object bool
+	}
+	defs := map[string]def{}
+	var dups []duplicate
+	walkFields(toks, func(f field) {
+		pos := position(src, toks[f.key].off)
+		key := renderPath(f.path)
+
+		// A dotted key makes objects of all its prefixes.
+		for n := 1; n < len(f.path); n++ {
+			p := renderPath(f.path[:n])
+			if d, ok := defs[p]; ok && !d.object {
+				dups = append(dups, duplicate{pos, fmt.Sprintf("%s makes an object of %s, replacing its value from line %d", key, p, d.line), d.line, codeDuplicateKey})
+			}
+			if d, ok := defs[p]; !ok || !d.object {
+				defs[p] = def{pos.Line, true}
+			}
+		}
+
+		d, ok := defs[key]
+		value := toks[f.value:f.end]
+		switch {
+		case f.object:
+			if ok && !d.object {
+				dups = append(dups, duplicate{pos, fmt.Sprintf("object %s replaces the value from line %d", key, d.line), d.line, codeDuplicateKey})
+			}
+			if ok && d.object {
+				return
+			}
+		case !ok || f.appending || optionalOverride(value) || refersTo(value, key):
+		case d.object:
+			dups = append(dups, duplicate{pos, fmt.Sprintf("%s replaces the object from line %d", key, d.line), d.line, codeDuplicateKey})
+		default:
+			dups = append(dups, duplicate{pos, fmt.Sprintf("%s is already set on line %d", key, d.line), d.line, codeDuplicateKey})
+		}
+		if !f.object {
+			for p := range defs {
+				if strings.HasPrefix(p, key+".") {
+					delete(defs, p)
+				}
+			}
+		}
+		defs[key] = def{pos.Line, f.object}
+	})
+	return dups, nil
+}
+
+// optionalOverride reports whether value is a single optional
+// substitution, such as ${?PORT}, which only replaces an earlier value
+// if it is set.
+func optionalOverride(value []tok) bool {
+	return len(value) == 1 && value[0].kind == tokSubst && strings.HasPrefix(value[0].lit, "${?")
+}
+
+// refersTo reports whether value contains a substitution of the path
+// key, as in path = ${path}":/bin", which extends the earlier value.
+func refersTo(value []tok, key string) bool {
+	for _, t := range value {
+		if t.kind == tokSubst {
+			p := strings.TrimPrefix(strings.TrimPrefix(t.lit, "${"), "?")
+			if strings.TrimSpace(strings.TrimSuffix(p, "}")) == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// shadowedIncludes returns the include statements of src, read from
+// filename, whose files set values that are all replaced by later
+// fields or includes, so that including them has no effect. Includes
+// are followed as blameConfig follows them; if they cannot be, there is
+// nothing to report.
+func shadowedIncludes(filename string, src []byte) []duplicate {
+	defs, err := collectDefinitions(filename, src, 0)
+	if err != nil {
+		return nil
+	}
+	sets := map[int]bool{}  // the includes that set values
+	alive := map[int]bool{} // the includes with a value that stays
+	by := map[int][]int{}   // the includes or -1 replacing each include's values
+	for _, d := range defs {
+		if !d.object {
+			sets[d.via] = true
+		}
+	}
+	for _, e := range effectiveValues(defs) {
+		via := defs[e.seq].via
+		alive[via] = true
+		for _, s := range e.Shadowed {
+			by[defs[s.seq].via] = append(by[defs[s.seq].via], via)
+		}
+	}
+
+	var offs []int
+	for via := range sets {
+		if via >= 0 && !alive[via] {
+			offs = append(offs, via)
+		}
+	}
+	sort.Ints(offs)
+	toks, _ := tokenize(src)
+	var dups []duplicate
+	for _, off := range offs {
+		pos := position(src, off)
+		msg := "every value set by " + includeName(toks, off) + " is replaced later"
+		if replacers := by[off]; sameInts(replacers) && replacers[0] >= 0 {
+			line := position(src, replacers[0]).Line
+			msg = fmt.Sprintf("every value set by %s is replaced by %s on line %d", includeName(toks, off), includeName(toks, replacers[0]), line)
+		}
+		dups = append(dups, duplicate{pos, msg, 0, codeInclude})
+	}
+	return dups
+}
+
+// A typeChange is a key that the definition defs[later] of a list of
+// definitions makes an object where the definition defs[earlier] made it
+// a value, or the other way round. HOCON does not merge the two: the
+// later one replaces the earlier one whole.
+type typeChange struct {
+	key            string
+	earlier, later int
+	object         bool // whether defs[later] makes key an object
+}
+
+// typeChanges returns the type changes of defs, in order. A dotted key
+// makes objects of all its prefixes. Values that build on the earlier
+// value, as in list += x, and optional substitutions, which may leave
+// it, change nothing.
+func typeChanges(defs []definition) []typeChange {
+	type kind struct {
+		def    int
+		object bool
+	}
+	kinds := map[string]kind{}
+	var changes []typeChange
+	for i, d := range defs {
+		v := d.at.Value
+		builds := !d.object && (strings.HasPrefix(v, "+= ") || strings.HasPrefix(v, "${?") && strings.HasSuffix(v, "}") && strings.Count(v, "${") == 1)
+		for n := 1; n <= len(d.path); n++ {
+			if n == len(d.path) && builds {
+				break
+			}
+			key := renderPath(d.path[:n])
+			object := n < len(d.path) || d.object
+			if k, ok := kinds[key]; ok && k.object != object {
+				changes = append(changes, typeChange{key, k.def, i, object})
+			}
+			if !object {
+				for p := range kinds {
+					if strings.HasPrefix(p, key+".") {
+						delete(kinds, p)
+					}
+				}
+			}
+			if k, ok := kinds[key]; !ok || !k.object || !object {
+				kinds[key] = kind{i, object} // an object merges into the first
+			}
+		}
+	}
+	return changes
+}
+
+// includeTypeChanges returns the type changes between the fields of
+// src, read from filename, and the files it includes, followed as
+// shadowedIncludes follows them, or between two includes. Changes
+// within src itself are left to findDuplicates, and changes within an
+// included file to checking that file.
+func includeTypeChanges(filename string, src []byte) []duplicate {
+	defs, err := collectDefinitions(filename, src, 0)
+	if err != nil {
+		return nil
+	}
+	toks, _ := tokenize(src)
+	// line returns the line of src that sets defs[i], and from names it.
+	line := func(i int) int {
+		if via := defs[i].via; via >= 0 {
+			return position(src, via).Line
+		}
+		return defs[i].at.Line
+	}
+	from := func(i int) string {
+		if via := defs[i].via; via >= 0 {
+			return fmt.Sprintf("set by %s on line %d", includeName(toks, via), line(i))
+		}
+		return fmt.Sprintf("from line %d", line(i))
+	}
+	var dups []duplicate
+	for _, c := range typeChanges(defs) {
+		earlier, later := defs[c.earlier], defs[c.later]
+		if earlier.via == later.via {
+			continue
+		}
+		kind, was := "a value", "object"
+		if c.object {
+			kind, was = "an object", "value"
+		}
+		if later.via < 0 {
+			msg := fmt.Sprintf("%s replaces the %s %s", c.key, was, from(c.earlier))
+			if key := renderPath(later.path); key != c.key {
+				msg = fmt.Sprintf("%s makes an object of %s, replacing its value %s", key, c.key, from(c.earlier))
+			} else if c.object {
+				msg = "object " + msg
+			}
+			dups = append(dups, duplicate{position(src, later.off), msg, line(c.earlier), codeDuplicateKey})
+			continue
+		}
+		msg := fmt.Sprintf("%s makes %s %s, replacing the %s %s", includeName(toks, later.via), c.key, kind, was, from(c.earlier))
+		dups = append(dups, duplicate{position(src, later.via), msg, line(c.earlier), codeDuplicateKey})
+	}
+	return dups
+}
+
+// mergeTypeChanges returns the type changes between the files
+// filenames, including the files they include, for -merge with
+// -warn-duplicates: the keys one file makes an object where an earlier
+// file made them a value, or the other way round, which -merge replaces
+// rather than merges. A change within one file is left to checking that
+// file. Files that cannot be read or followed leave nothing to report.
+func mergeTypeChanges(filenames []string) []warning {
+	var defs []definition
+	var srcs [][]byte
+	var file []int // the index of the file of each definition
+	for i, name := range filenames {
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil
+		}
+		d, err := collectDefinitions(name, src, 0)
+		if err != nil {
+			return nil
+		}
+		defs, srcs = append(defs, d...), append(srcs, src)
+		for range d {
+			file = append(file, i)
+		}
+	}
+	// place returns where in the merged files defs[i] is set: at the key,
+	// or at the include statement that brings it in.
+	place := func(i int) token.Position {
+		off := defs[i].off
+		if defs[i].via >= 0 {
+			off = defs[i].via
+		}
+		pos := position(srcs[file[i]], off)
+		pos.Filename = filenames[file[i]]
+		return pos
+	}
+	var warnings []warning
+	for _, c := range typeChanges(defs) {
+		if file[c.earlier] == file[c.later] {
+			continue
+		}
+		was := "object"
+		if c.object {
+			was = "value"
+		}
+		msg := fmt.Sprintf("%s replaces the %s from %s", c.key, was, place(c.earlier))
+		if key := renderPath(defs[c.later].path); key != c.key {
+			msg = fmt.Sprintf("%s makes an object of %s, replacing its value from %s", key, c.key, place(c.earlier))
+		} else if c.object {
+			msg = "object " + msg
+		}
+		warnings = append(warnings, warning{place(c.later), msg, codeDuplicateKey})
+	}
+	return warnings
+}
+
+// includeName names the include statement of toks at offset off, as in
+// the include of base.conf.
+func includeName(toks []tok, off int) string {
+	for _, l := range splitLines(toks) {
+		if len(l.toks) > 0 && l.toks[0].off == off {
+			if _, name, _, ok := includeResource(l); ok {
+				return "the include of " + name
+			}
+		}
+	}
+	return "the include"
+}
+
+// sameInts reports whether s holds one or more copies of one number.
+func sameInts(s []int) bool {
+	for _, n := range s {
+		if n != s[0] {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+// warnDuplicates reports a warning to warn for every duplicate of src,
+// and for every include statement whose values are all replaced.
+func warnDuplicates(warn func(warning), filename string, src []byte) error {
+	dups, err := findDuplicates(src)
+	if err != nil {
+		return withFilename(err, filename)
+	}
+	dups = append(dups, shadowedIncludes(filename, src)...)
+	dups = append(dups, includeTypeChanges(filename, src)...)
+	for _, d := range dups {
+		d.pos.Filename = filename
+		warn(warning{d.pos, d.msg, d.code})
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package hoconfmt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	const src = `port = 8080
+port = ${?PORT}
+path = /usr/bin
+path = ${path}":/opt/bin"
+list = [a]
+list += b
+db { host = a }
+db { port = 1 }
+name = x
+name = y
+cache = 60
+cache { ttl = 60 }
+log { level = info }
+log = off
+mode = fast
+mode.speed = 2
+`
+	dups, err := findDuplicates([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		line, prev int
+		msg        string
+	}{
+		{10, 9, "name is already set on line 9"},
+		{12, 11, "object cache replaces the value from line 11"},
+		{14, 13, "log replaces the object from line 13"},
+		{16, 15, "mode.speed makes an object of mode, replacing its value from line 15"},
+	}
+	if len(dups) != len(want) {
+		t.Fatalf("got %d duplicates %+v, want %d", len(dups), dups, len(want))
+	}
+	for i, d := range dups {
+		if d.pos.Line != want[i].line || d.prev != want[i].prev || d.msg != want[i].msg || d.code != codeDuplicateKey {
+			t.Errorf("duplicate %d = %d %d %q %s, want %+v", i, d.pos.Line, d.prev, d.msg, d.code, want[i])
+		}
+	}
+}
+
+func TestOptionalOverrideIsNoDuplicate(t *testing.T) {
+	src, err := ioutil.ReadFile("testdata/optionaloverride.input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dups, err := findDuplicates(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dups) > 0 {
+		t.Errorf("optional overrides reported as duplicates: %+v", dups)
+	}
+}
+
+func TestShadowedIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"defaults.conf": "db { host = localhost, port = 5432 }\n",
+		"prod.conf":     "db { host = prod, port = 5433 }\n",
+		"timeouts.conf": "timeout = 10\n",
+		"partly.conf":   "retries = 3\nname = x\n",
+		"empty.conf":    "# nothing yet\n",
+	}
+	for name, text := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const src = `include "defaults.conf"
+include "prod"
+include "timeouts.conf"
+include "partly.conf"
+include "empty.conf"
+include "missing.conf"
+timeout = 30
+name = app
+`
+	dups := shadowedIncludes(filepath.Join(dir, "app.conf"), []byte(src))
+	want := []struct {
+		line int
+		msg  string
+	}{
+		{1, "every value set by the include of defaults.conf is replaced by the include of prod on line 2"},
+		{3, "every value set by the include of timeouts.conf is replaced later"},
+	}
+	if len(dups) != len(want) {
+		t.Fatalf("got %d shadowed includes %+v, want %d", len(dups), dups, len(want))
+	}
+	for i, d := range dups {
+		if d.pos.Line != want[i].line || d.msg != want[i].msg || d.code != codeInclude {
+			t.Errorf("shadowed include %d = %d %q %s, want %+v", i, d.pos.Line, d.msg, d.code, want[i])
+		}
+	}
+
+	if dups := shadowedIncludes(filepath.Join(dir, "app.conf"), []byte("include required(\"missing.conf\")\n")); len(dups) > 0 {
+		t.Errorf("includes that cannot be followed reported as shadowed: %+v", dups)
+	}
+}
+
+func TestTypeChanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"db.conf":    "db { host = localhost }\nlog = info\nlist += x\n",
+		"cache.conf": "cache { ttl = 60 }\n",
+		"base.conf":  "a { x = 1 }\nb = 1\ninclude \"cache.conf\"\n",
+		"over.conf":  "a = 5\nb.c = 2\ncache = off\nb = ${?B}\n",
+	}
+	for name, text := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Between a file and its includes.
+	const src = `include "db.conf"
+db = off
+log { level = debug }
+log { file = x.log }
+list = [a]
+include "cache.conf"
+cache = ${?CACHE}
+`
+	dups := includeTypeChanges(filepath.Join(dir, "app.conf"), []byte(src))
+	want := []struct {
+		line, prev int
+		msg        string
+	}{
+		{2, 1, "db replaces the object set by the include of db.conf on line 1"},
+		{3, 1, "object log replaces the value set by the include of db.conf on line 1"},
+	}
+	if len(dups) != len(want) {
+		t.Fatalf("got %d type changes %+v, want %d", len(dups), dups, len(want))
+	}
+	for i, d := range dups {
+		if d.pos.Line != want[i].line || d.prev != want[i].prev || d.msg != want[i].msg || d.code != codeDuplicateKey {
+			t.Errorf("type change %d = %d %d %q %s, want %+v", i, d.pos.Line, d.prev, d.msg, d.code, want[i])
+		}
+	}
+	dups = includeTypeChanges(filepath.Join(dir, "app.conf"), []byte("db = off\ninclude \"db.conf\"\n"))
+	if len(dups) != 1 || dups[0].pos.Line != 2 || dups[0].msg != "the include of db.conf makes db an object, replacing the value from line 1" {
+		t.Errorf("type change by an include = %+v", dups)
+	}
+
+	// Between the files of -merge.
+	base, over := filepath.Join(dir, "base.conf"), filepath.Join(dir, "over.conf")
+	var got []string
+	for _, w := range mergeTypeChanges([]string{base, over}) {
+		got = append(got, w.String())
+	}
+	wantWarnings := []string{
+		over + ":1:1: warning: a replaces the object from " + base + ":1:1",
+		over + ":2:1: warning: b.c makes an object of b, replacing its value from " + base + ":2:1",
+		over + ":3:1: warning: cache replaces the object from " + base + ":3:1",
+	}
+	if strings.Join(got, "\n") != strings.Join(wantWarnings, "\n") {
+		t.Errorf("-merge type changes:\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(wantWarnings, "\n"))
+	}
+}
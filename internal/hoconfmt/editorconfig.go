@@ -0,0 +1,199 @@
+package hoconfmt
+
+import (
+	"bufio"
+	"go/printer"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// editorConfig holds the indentation settings read from .editorconfig
+// files. Only the keys that affect indentation are recognized; an empty
+// field means the key was not set.
+type editorConfig struct {
+	indentStyle string // "tab" or "space"
+	indentSize  string // a number or "tab"
+	tabWidth    string // a number
+}
+
+// editorConfigFor returns the printer configuration described by the
+// .editorconfig files that apply to filename, starting from cfg. The
+// files are read from the closest directory upwards until one declares
+// root = true; settings from closer files take precedence.
+func editorConfigFor(filename string, cfg printer.Config) (printer.Config, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return cfg, err
+	}
+
+	var ec editorConfig
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		root, err := ec.merge(filepath.Join(dir, ".editorconfig"), abs)
+		if err != nil {
+			return cfg, err
+		}
+		if root || filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	switch ec.indentStyle {
+	case "tab":
+		cfg.Mode &^= printer.UseSpaces
+	case "space":
+		cfg.Mode |= printer.UseSpaces
+	}
+	size := ec.indentSize
+	if size == "tab" || size == "" {
+		size = ec.tabWidth
+	}
+	if n, err := strconv.Atoi(size); err == nil && n > 0 {
+		cfg.Tabwidth = n
+	}
+	return cfg, nil
+}
+
+// merge reads the .editorconfig file at path and fills in any settings
+// of ec that are still unset from sections matching target. It reports
+// whether the file declares itself the root. A missing file is not an
+// error.
+func (ec *editorConfig) merge(path, target string) (root bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	// Within a file, later sections override earlier ones, so collect
+	// this file's settings first and only then let them fill ec.
+	var local editorConfig
+	rel, err := filepath.Rel(filepath.Dir(path), target)
+	if err != nil {
+		return false, err
+	}
+	rel = filepath.ToSlash(rel)
+
+	inPreamble, matched := true, false
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			inPreamble = false
+			matched = editorConfigMatch(line[1:len(line)-1], rel)
+			continue
+		}
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:eq]))
+		value := strings.ToLower(strings.TrimSpace(line[eq+1:]))
+		if inPreamble {
+			if key == "root" && value == "true" {
+				root = true
+			}
+			continue
+		}
+		if !matched {
+			continue
+		}
+		switch key {
+		case "indent_style":
+			local.indentStyle = value
+		case "indent_size":
+			local.indentSize = value
+		case "tab_width":
+			local.tabWidth = value
+		}
+	}
+	if err := s.Err(); err != nil {
+		return false, err
+	}
+
+	if ec.indentStyle == "" {
+		ec.indentStyle = local.indentStyle
+	}
+	if ec.indentSize == "" {
+		ec.indentSize = local.indentSize
+	}
+	if ec.tabWidth == "" {
+		ec.tabWidth = local.tabWidth
+	}
+	return root, nil
+}
+
+// editorConfigMatch reports whether the section glob matches the
+// slash-separated path rel. A glob without a slash matches the file
+// name in any directory, as specified by EditorConfig.
+func editorConfigMatch(glob, rel string) bool {
+	if !strings.Contains(glob, "/") {
+		glob = "**/" + glob
+	} else {
+		glob = strings.TrimPrefix(glob, "/")
+	}
+
+	var re strings.Builder
+	re.WriteString("^")
+	braces := 0
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// "**/" also matches no directory at all.
+					i++
+					re.WriteString("(?:.*/)?")
+				} else {
+					re.WriteString(".*")
+				}
+			} else {
+				re.WriteString("[^/]*")
+			}
+		case '?':
+			re.WriteString("[^/]")
+		case '{':
+			braces++
+			re.WriteString("(?:")
+		case '}':
+			if braces > 0 {
+				braces--
+				re.WriteString(")")
+			} else {
+				re.WriteString(`\}`)
+			}
+		case ',':
+			if braces > 0 {
+				re.WriteString("|")
+			} else {
+				re.WriteString(",")
+			}
+		case '[':
+			if j := strings.IndexByte(glob[i:], ']'); j > 0 {
+				class := glob[i+1 : i+j]
+				if strings.HasPrefix(class, "!") {
+					class = "^" + class[1:]
+				}
+				re.WriteString("[" + class + "]")
+				i += j
+			} else {
+				re.WriteString(`\[`)
+			}
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+
+	ok, err := regexp.MatchString(re.String(), rel)
+	return err == nil && ok
+}
@@ -0,0 +1,84 @@
+package hoconfmt
+
+import (
+	"go/printer"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEditorConfigMatch(t *testing.T) {
+	for _, test := range []struct {
+		glob, rel string
+		want      bool
+	}{
+		{"*", "a.conf", true},
+		{"*", "sub/a.conf", true},
+		{"*.conf", "sub/dir/a.conf", true},
+		{"*.conf", "a.json", false},
+		{"{*.conf,*.hocon}", "a.hocon", true},
+		{"sub/*.conf", "sub/a.conf", true},
+		{"sub/*.conf", "other/sub/a.conf", false},
+		{"/sub/**.conf", "sub/dir/a.conf", true},
+		{"a?.conf", "ab.conf", true},
+		{"[!b].conf", "a.conf", true},
+		{"[!b].conf", "b.conf", false},
+	} {
+		if got := editorConfigMatch(test.glob, test.rel); got != test.want {
+			t.Errorf("editorConfigMatch(%q, %q) = %v, want %v", test.glob, test.rel, got, test.want)
+		}
+	}
+}
+
+func TestEditorConfigFor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(path, content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(dir, ".editorconfig"), `root = true
+
+[*]
+indent_style = space
+indent_size = 2
+
+[*.json]
+indent_size = 8
+`)
+	write(filepath.Join(sub, ".editorconfig"), `[*.conf]
+indent_style = tab
+indent_size = tab
+tab_width = 3
+`)
+
+	def := printer.Config{Mode: printerMode, Tabwidth: tabWidth}
+	for _, test := range []struct {
+		filename string
+		want     printer.Config
+	}{
+		{filepath.Join(dir, "a.conf"), printer.Config{Mode: printer.UseSpaces, Tabwidth: 2}},
+		{filepath.Join(dir, "a.json"), printer.Config{Mode: printer.UseSpaces, Tabwidth: 8}},
+		{filepath.Join(sub, "a.conf"), printer.Config{Mode: 0, Tabwidth: 3}},
+		{filepath.Join(sub, "a.hocon"), printer.Config{Mode: printer.UseSpaces, Tabwidth: 2}},
+	} {
+		got, err := editorConfigFor(test.filename, def)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("editorConfigFor(%s) = %+v, want %+v", test.filename, got, test.want)
+		}
+	}
+}
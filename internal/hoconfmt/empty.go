@@ -0,0 +1,68 @@
+package hoconfmt
+
+import "fmt"
+
+// Values of the -empty flag.
+const (
+	emptyCompact  = "compact"  // {} and []
+	emptyExpanded = "expanded" // kept across lines if written that way
+)
+
+func checkEmpty(style string) error {
+	switch style {
+	case "", emptyCompact, emptyExpanded:
+		return nil
+	}
+	return fmt.Errorf("invalid -empty %q: must be %s or %s", style, emptyCompact, emptyExpanded)
+}
+
+// normalizeEmpty rewrites the empty objects and arrays of src, those
+// with nothing but white space between their brackets, to the given
+// style. With emptyCompact, the empty style, they become {} and [].
+// With emptyExpanded, the ones written across several lines keep their
+// closing bracket on a line of its own, at the indentation of the line
+// that opens them; the others become compact.
+func normalizeEmpty(lx *lexer, src []byte, style string) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, 0, len(src))
+	indent := "" // indentation of the current line
+	atLineStart := true
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		if atLineStart {
+			indent = ""
+			if t.kind == tokSpace {
+				indent = t.lit
+			}
+		}
+		atLineStart = t.kind == tokNewline
+		res = append(res, t.lit...)
+
+		closing := tokRBrace
+		switch t.kind {
+		case tokLBrace:
+		case tokLBracket:
+			closing = tokRBracket
+		default:
+			continue
+		}
+		j, lines := i+1, false
+		for ; j < len(toks) && (toks[j].kind == tokSpace || toks[j].kind == tokNewline); j++ {
+			lines = lines || toks[j].kind == tokNewline
+		}
+		if j == len(toks) || toks[j].kind != closing {
+			continue
+		}
+		if lines && style == emptyExpanded {
+			res = append(res, '\n')
+			res = append(res, indent...)
+		}
+		res = append(res, toks[j].lit...)
+		i = j
+	}
+	return res, nil
+}
@@ -0,0 +1,115 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// utf8BOM is the UTF-8 encoding of the byte order mark U+FEFF.
+var utf8BOM = []byte("\uFEFF")
+
+// An encodingSummary is the result of -summary: the files that would
+// need cleaning up, by problem. A file can have several problems.
+type encodingSummary struct {
+	Files   int      `json:"files"`
+	BOM     []string `json:"bom"`     // start with a byte order mark
+	CRLF    []string `json:"crlf"`    // have lines ending in CRLF
+	NotUTF8 []string `json:"notUTF8"` // are not valid UTF-8
+}
+
+// add records the encoding problems of src, the contents of filename.
+func (s *encodingSummary) add(filename string, src []byte) {
+	s.Files++
+	if bytes.HasPrefix(src, utf8BOM) {
+		s.BOM = append(s.BOM, filename)
+	}
+	if bytes.Contains(src, []byte("\r\n")) {
+		s.CRLF = append(s.CRLF, filename)
+	}
+	if !utf8.Valid(src) {
+		s.NotUTF8 = append(s.NotUTF8, filename)
+	}
+}
+
+// summarizeEncodings audits the files named by paths. Directories are
+// walked recursively for files that isConfFile accepts and -exclude
+// does not skip; files named directly are always audited. Files that
+// cannot be read are reported and skipped.
+func summarizeEncodings(paths []string) encodingSummary {
+	s := encodingSummary{BOM: []string{}, CRLF: []string{}, NotUTF8: []string{}}
+	audit := func(path string) {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			report(err)
+			return
+		}
+		s.add(path, src)
+	}
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			report(err)
+			continue
+		}
+		if !fi.IsDir() {
+			audit(path)
+			continue
+		}
+		root := path
+		err = filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+			switch {
+			case err != nil:
+				report(err)
+			case excluded(root, path, f):
+				if f.IsDir() {
+					return filepath.SkipDir
+				}
+			case isConfFile(f):
+				audit(path)
+			}
+			return nil
+		})
+		if err != nil {
+			report(err)
+		}
+	}
+	return s
+}
+
+func printSummary(out io.Writer, s encodingSummary) error {
+	if *jsonReport {
+		data, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", data)
+		return err
+	}
+	if _, err := fmt.Fprintf(out, "files: %d\n", s.Files); err != nil {
+		return err
+	}
+	for _, p := range []struct {
+		what  string
+		files []string
+	}{
+		{"byte order mark", s.BOM},
+		{"CRLF line endings", s.CRLF},
+		{"not UTF-8", s.NotUTF8},
+	} {
+		if _, err := fmt.Fprintf(out, "%s: %d\n", p.what, len(p.files)); err != nil {
+			return err
+		}
+		for _, f := range p.files {
+			if _, err := fmt.Fprintf(out, "\t%s\n", f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSummarizeEncodings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"clean.conf":       "a = 1\n",
+		"bom.conf":         "\uFEFFa = 1\n",
+		"sub/crlf.conf":    "a = 1\r\nb = 2\r\n",
+		"sub/latin1.conf":  "name = \"caf\xe9\"\r\n",
+		"sub/notes.txt":    "\uFEFFnot a config\n",
+		"sub/.hidden.conf": "\uFEFFa = 1\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := summarizeEncodings([]string{dir})
+	join := func(name string) string { return filepath.Join(dir, name) }
+	want := encodingSummary{
+		Files:   4,
+		BOM:     []string{join("bom.conf")},
+		CRLF:    []string{join("sub/crlf.conf"), join("sub/latin1.conf")},
+		NotUTF8: []string{join("sub/latin1.conf")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("summarizeEncodings =\n%+v\nwant\n%+v", got, want)
+	}
+
+	*jsonReport = true
+	defer func() { *jsonReport = false }()
+	var buf bytes.Buffer
+	if err := printSummary(&buf, encodingSummary{Files: 1, BOM: []string{"a.conf"}, CRLF: []string{}, NotUTF8: []string{}}); err != nil {
+		t.Fatal(err)
+	}
+	const wantJSON = `{"files":1,"bom":["a.conf"],"crlf":[],"notUTF8":[]}` + "\n"
+	if buf.String() != wantJSON {
+		t.Errorf("got %s, want %s", buf.String(), wantJSON)
+	}
+}
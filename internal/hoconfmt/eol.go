@@ -0,0 +1,114 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Values of the -eol flag.
+const (
+	eolLF   = "lf"
+	eolCRLF = "crlf"
+	eolKeep = "keep" // the ending used by most lines of the input
+)
+
+func checkEOL(style string) error {
+	switch style {
+	case "", eolLF, eolCRLF, eolKeep:
+		return nil
+	}
+	return fmt.Errorf("invalid -eol %q: must be %s, %s or %s", style, eolLF, eolCRLF, eolKeep)
+}
+
+// useCRLF reports whether output for src should end its lines with
+// CRLF under the given -eol style. With eolKeep, src decides: CRLF wins
+// only if more of its lines end in CRLF than in a bare LF.
+func useCRLF(src []byte, style string) bool {
+	switch style {
+	case eolCRLF:
+		return true
+	case eolKeep:
+		crlf, lf := 0, 0
+		toks, _ := tokenize(src)
+		for i, t := range toks {
+			if t.kind != tokNewline {
+				continue
+			}
+			if i > 0 && endsInCR(toks[i-1]) {
+				crlf++
+			} else {
+				lf++
+			}
+		}
+		return crlf > lf
+	}
+	return false
+}
+
+// setEOL ends every line of src with CRLF if crlf is set and with LF
+// otherwise. Line endings inside multi-line strings are content and
+// stay as they are.
+func setEOL(lx *lexer, src []byte, crlf bool) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, 0, len(src))
+	for i, t := range toks {
+		lit := t.lit
+		if i+1 < len(toks) && toks[i+1].kind == tokNewline && endsInCR(t) {
+			lit = lit[:len(lit)-1]
+		}
+		if t.kind == tokNewline && crlf {
+			res = append(res, '\r')
+		}
+		res = append(res, lit...)
+	}
+	return res, nil
+}
+
+// endsInCR reports whether t is white space or a comment ending in a
+// carriage return.
+func endsInCR(t tok) bool {
+	return (t.kind == tokSpace || t.kind == tokComment) && len(t.lit) > 0 && t.lit[len(t.lit)-1] == '\r'
+}
+
+// splitLoneCRs turns every carriage return of src that is not followed
+// by a line feed into a line feed, so that files written with classic
+// Mac line endings are read line by line. A lone CR ends a comment as a
+// line feed would. CRs inside strings are content and stay as they are.
+// The result has the length of src, so that byte offsets into it, and
+// the positions of errors, are those of the input.
+func splitLoneCRs(src []byte) []byte {
+	if !bytes.Contains(src, []byte("\r")) {
+		return src
+	}
+	res := append([]byte(nil), src...)
+	lone := func(p int) bool {
+		return res[p] == '\r' && (p+1 == len(res) || res[p+1] != '\n')
+	}
+	// A comment runs to the next line feed, so the text after a lone CR
+	// in a comment is tokenized again once the CR has become one.
+	for from := 0; from < len(res); {
+		toks, _ := tokenize(res[from:])
+		next := len(res)
+	scan:
+		for _, t := range toks {
+			if t.kind != tokSpace && t.kind != tokComment {
+				continue
+			}
+			for j := range t.lit {
+				if p := from + t.off + j; lone(p) {
+					res[p] = '\n'
+					if t.kind == tokComment {
+						next = p + 1
+						break scan
+					}
+				}
+			}
+		}
+		from = next
+	}
+	return res
+}
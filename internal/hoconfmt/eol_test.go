@@ -0,0 +1,63 @@
+package hoconfmt
+
+import "testing"
+
+func TestEOL(t *testing.T) {
+	const (
+		lf    = "a = 1\nb = \"\"\"x\r\ny\"\"\"\nc = 2 # note\n"
+		crlf  = "a = 1\r\nb = \"\"\"x\r\ny\"\"\"\r\nc = 2 # note\r\n"
+		mixed = "a = 1\r\nb = \"\"\"x\r\ny\"\"\"\r\nc = 2 # note\n" // 2 CRLF, 1 LF
+	)
+	for _, test := range []struct {
+		style, src, want string
+	}{
+		{eolLF, crlf, lf},
+		{eolLF, lf, lf},
+		{"", mixed, lf},
+		{eolCRLF, lf, crlf},
+		{eolCRLF, mixed, crlf},
+		{eolKeep, lf, lf},
+		{eolKeep, crlf, crlf},
+		{eolKeep, mixed, crlf},
+		{eolKeep, "a = 1\r\nb = 2\nc = 3\n", "a = 1\nb = 2\nc = 3\n"},
+	} {
+		opts := DefaultOptions()
+		opts.EOL = test.style
+		got, err := formatFile("eol.conf", []byte(test.src), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("-eol %s on %q: got %q, want %q", test.style, test.src, got, test.want)
+		}
+	}
+
+	if err := checkEOL("cr"); err == nil {
+		t.Error("checkEOL accepted cr")
+	}
+}
+
+func TestSplitLoneCRs(t *testing.T) {
+	for _, test := range []struct {
+		src, want string
+	}{
+		{"a = 1\rb = 2\r", "a = 1\nb = 2\n"},
+		{"a = 1\r\nb = 2\r\n", "a = 1\r\nb = 2\r\n"},
+		{"# say \"hi\rb = \"\"\"x\ry\"\"\"\r", "# say \"hi\nb = \"\"\"x\ry\"\"\"\n"},
+		{"a = \"\"\"x\ry\"\"\"\r", "a = \"\"\"x\ry\"\"\"\n"},
+		{"a = \"x\ry\"\r", "a = \"x\ry\"\n"},
+		{"a = 1 // note\r# more\rb = 2", "a = 1 // note\n# more\nb = 2"},
+	} {
+		if got := string(splitLoneCRs([]byte(test.src))); got != test.want {
+			t.Errorf("splitLoneCRs(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+
+	got, err := formatFile("cr.conf", []byte("a {\r  b = 1\r}\r"), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a {\n    b = 1\n}\n"; string(got) != want {
+		t.Errorf("formatting CR line endings: got %q, want %q", got, want)
+	}
+}
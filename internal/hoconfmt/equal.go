@@ -0,0 +1,298 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// A Difference is the first difference Equal finds between two
+// documents.
+type Difference struct {
+	// Path is the key path of the value that differs, with array
+	// elements numbered from 0, or the text of an include statement.
+	Path string
+
+	// Description says how the value differs, naming the path.
+	Description string
+}
+
+func (d *Difference) Error() string {
+	return d.Description
+}
+
+// Equal reports whether the HOCON documents a and b have the same
+// value, whatever their layout, comments and the order of their keys.
+// Their fields are merged the way HOCON merges them, and their
+// substitutions resolved where the document sets their paths; the
+// others, such as environment variables and substitutions that refer
+// to the field holding them, compare by their paths, together with the
+// value of their field they replace, if any, which is left if they turn
+// out to be undefined. Include statements are not followed: the
+// documents must include the same files in the same objects.
+//
+// If the documents differ, the error is a *Difference describing the
+// first difference; otherwise it says why a document could not be read.
+func Equal(a, b []byte) (bool, error) {
+	va, err := resolvedDocument(a)
+	if err != nil {
+		return false, fmt.Errorf("first document: %v", err)
+	}
+	vb, err := resolvedDocument(b)
+	if err != nil {
+		return false, fmt.Errorf("second document: %v", err)
+	}
+	d := valueDiff(va, vb, nil)
+	if d == nil {
+		return true, nil
+	}
+	diff := &Difference{Path: pathName(d.path)}
+	switch {
+	case d.removed:
+		diff.Description = diff.Path + " is only in the first document"
+	case d.added:
+		diff.Description = diff.Path + " is only in the second document"
+	default:
+		diff.Description = fmt.Sprintf("%s is %s in the first document and %s in the second", diff.Path, valueName(d.before), valueName(d.after))
+	}
+	return false, diff
+}
+
+// resolvedDocument merges src, keeping its include statements, and
+// resolves the substitutions of the paths it sets.
+func resolvedDocument(src []byte) (Value, error) {
+	v, err := (&merger{src: src, includes: true, overrides: true}).document()
+	if err != nil {
+		return nil, err
+	}
+	r := &resolver{root: v, active: map[string]bool{}, prev: map[string]Value{}}
+	return r.value(v, []string{}), nil
+}
+
+// A resolver replaces the substitutions of a merged document with the
+// values they stand for. active holds the paths being resolved, so
+// that a substitution of one of them, which refers to itself, is left
+// as it is. If env is set, as for resolveDocument, substitutions are
+// resolved as HOCON resolves them instead: those of paths the document
+// does not set from the environment, self-references from the value
+// they override, and optional ones that stay undefined leave nothing
+// behind. err is then the error for the first substitution that
+// cannot be resolved.
+type resolver struct {
+	root   Value
+	active map[string]bool
+	env    func(string) (string, bool)
+	prev   map[string]Value // the values overridden at active paths
+	err    error
+
+	// typeWarning, if set, takes the offset and description of every
+	// value that a substitution gives a type that looks unintended.
+	typeWarning func(off int, msg string)
+}
+
+// value returns v, the value at path, with its substitutions resolved.
+// path is nil inside arrays and concatenations. It returns undefined
+// for an optional substitution that is not defined, if r.env is set.
+func (r *resolver) value(v Value, path []string) Value {
+	if path != nil {
+		if key := pathKey(path); !r.active[key] {
+			r.active[key] = true
+			defer delete(r.active, key)
+		}
+	}
+	switch v := v.(type) {
+	case Subst:
+		p := pathElems(v.Path)
+		if r.active[pathKey(p)] {
+			return r.selfReference(v, p)
+		}
+		target, ok := r.lookup(p)
+		if !ok {
+			return r.unset(v)
+		}
+		res := r.value(target, p)
+		if isUndefined(res) && !v.Optional {
+			r.fail(fmt.Sprintf("cannot resolve %s: %s is set only to optional substitutions that are not defined", substText(v), v.Path))
+			return v
+		}
+		return res
+	case override:
+		var res Value
+		if path == nil {
+			res = r.value(v.value, path)
+		} else {
+			key := pathKey(path)
+			saved, had := r.prev[key]
+			r.prev[key] = v.prev
+			res = r.value(v.value, path)
+			delete(r.prev, key)
+			if had {
+				r.prev[key] = saved
+			}
+		}
+		if isUndefined(res) {
+			return r.value(v.prev, path)
+		}
+		switch n := res.(type) {
+		case Object:
+			// An object merges into an object it overrides.
+			if prev, ok := r.value(v.prev, path).(Object); ok {
+				return mergeObjects(prev, n)
+			}
+		case Subst, concatenation:
+			// Without an environment, what the value stands for is not
+			// known, and so neither is whether prev is left.
+			if r.env == nil {
+				return override{res, r.value(v.prev, path), v.off}
+			}
+		}
+		if r.typeWarning != nil && path != nil && kindChanges(v.prev, res) {
+			r.typeWarning(v.off, fmt.Sprintf("%s was %s, and the substitution replacing it makes it %s", renderPath(path), valueName(v.prev), valueName(res)))
+		}
+		return res
+	case concatenation:
+		var parts []Value
+		var scalars []string // numbers and booleans that substitutions join with text
+		for _, part := range v.parts {
+			res := r.value(part, nil)
+			if isUndefined(res) {
+				continue
+			}
+			if s, ok := part.(Subst); ok {
+				switch res.(type) {
+				case json.Number:
+					scalars = append(scalars, valueName(res)+" of "+substText(s))
+				case bool:
+					scalars = append(scalars, "the boolean "+valueName(res)+" of "+substText(s))
+				}
+			}
+			parts = joinPart(parts, res)
+		}
+		switch len(parts) {
+		case 0:
+			return undefined{}
+		case 1:
+			if _, ok := parts[0].(string); ok && len(scalars) > 0 && r.typeWarning != nil {
+				name := "an array element"
+				if path != nil {
+					name = renderPath(path)
+				}
+				r.typeWarning(v.off, fmt.Sprintf("%s joins %s with text, making a string", name, strings.Join(scalars, " and ")))
+			}
+			return parts[0]
+		}
+		return concatenation{parts, v.off}
+	case []Value:
+		elems := make([]Value, 0, len(v))
+		for _, e := range v {
+			if e := r.value(e, nil); !isUndefined(e) {
+				elems = append(elems, e)
+			}
+		}
+		return elems
+	case Object:
+		obj := make(Object, 0, len(v))
+		for _, f := range v {
+			var p []string
+			if path != nil {
+				p = append(path[:len(path):len(path)], f.Key)
+			}
+			if fv := r.value(f.Value, p); !isUndefined(fv) {
+				obj = append(obj, Field{f.Key, fv})
+			}
+		}
+		return obj
+	}
+	return v
+}
+
+// lookup returns the value at path p in the document. If r.env is set,
+// the values it passes through that are substitutions or
+// concatenations are resolved to find the objects they stand for.
+func (r *resolver) lookup(p []string) (Value, bool) {
+	if r.env == nil {
+		return lookupPath(r.root, p)
+	}
+	v := r.root
+	for i, k := range p {
+		switch v.(type) {
+		case Subst, concatenation, override:
+			v = r.value(v, p[:i:i])
+		}
+		var ok bool
+		if v, ok = lookupPath(v, []string{k}); !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// selfReference returns the value of s, a substitution of the path p
+// being resolved: s itself, unless r.env is set, in which case it is
+// the value that the field overrides or, if there is none, the
+// environment's.
+func (r *resolver) selfReference(s Subst, p []string) Value {
+	if r.env == nil {
+		return s
+	}
+	key := pathKey(p)
+	prev, ok := r.prev[key]
+	if !ok {
+		if v, ok := r.env(strings.Join(pathElems(s.Path), ".")); ok {
+			return v
+		}
+		if s.Optional {
+			return undefined{}
+		}
+		r.fail(fmt.Sprintf("cannot resolve %s, which refers to itself", substText(s)))
+		return s
+	}
+	delete(r.prev, key)
+	defer func() { r.prev[key] = prev }()
+	return r.value(prev, p)
+}
+
+// unset returns the value of s, a substitution of a path that the
+// document does not set: s itself, unless r.env is set, in which case
+// it is the environment variable named by the path, or undefined if s
+// is optional.
+func (r *resolver) unset(s Subst) Value {
+	if r.env == nil {
+		return s
+	}
+	if v, ok := r.env(strings.Join(pathElems(s.Path), ".")); ok {
+		return v
+	}
+	if s.Optional {
+		return undefined{}
+	}
+	r.fail(fmt.Sprintf("cannot resolve %s: %s is not set in the file or the environment", substText(s), s.Path))
+	return s
+}
+
+// kindChanges reports whether res, the value of a substitution
+// overriding prev, has another kind than prev: an object, an array or a
+// scalar. A null prev, which often stands for "not set", and a prev
+// that is not known before resolving change nothing.
+func kindChanges(prev, res Value) bool {
+	kind := func(v Value) int {
+		switch v.(type) {
+		case Object:
+			return 1
+		case []Value:
+			return 2
+		}
+		return 0
+	}
+	switch prev.(type) {
+	case nil, Subst, concatenation, override:
+		return false
+	}
+	return kind(prev) != kind(res)
+}
+
+func (r *resolver) fail(msg string) {
+	if r.err == nil {
+		r.err = listError(codeSubstitution, msg)
+	}
+}
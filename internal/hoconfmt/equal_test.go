@@ -0,0 +1,50 @@
+package hoconfmt
+
+import "testing"
+
+func TestEqual(t *testing.T) {
+	tests := []struct{ a, b, diff string }{
+		// Comments and layout.
+		{"a = 1 # one\n// two\nb: [1,2]", "a=1\nb = [\n    1\n    2\n]", ""},
+		// Key order.
+		{"a = 1\nb { c = 2, d = 3 }", "b { d = 3\nc = 2 }\na = 1", ""},
+		{"a.b = 1\na.c = 2", "a { c = 2, b = 1 }", ""},
+		{"a = 1\na = 2", "a = 2", ""},
+		{"a = \"x\"", "a = x", ""},
+		{"host = h\nurl = \"http://\"${host}", "host = h\nurl = \"http://h\"", ""},
+		{"base { x = 1 }\nc = ${base} { y = 2 }", "base { x = 1 }\nc { x = 1, y = 2 }", ""},
+		{"path = ${?PATH}\nh = ${HOME}", "h = ${HOME}\npath = ${?PATH}", ""},
+		{"a = 1", "a = \"1\"", "a is the number 1 in the first document and the string \"1\" in the second"},
+		{"a { b = [1, 2] }", "a { b = [1, 3] }", "a.b.1 is the number 2 in the first document and the number 3 in the second"},
+		{"a = 1\nb = 2", "a = 1", "b is only in the first document"},
+		{"a = 1", "a = 1\ninclude \"b.conf\"", "include \"b.conf\" is only in the second document"},
+		{"a = 1\nb = ${a}", "a = 2\nb = 2", "a is the number 1 in the first document and the number 2 in the second"},
+		// Overrides keep what they override.
+		{"g = \"x\"\ng = ${?G}", "g = x\ng = ${?G}", ""},
+		{"g = \"x\"\ng = ${?G}", "g = ${?G}", "g is the substitution ${?G} over the string \"x\" in the first document and the substitution ${?G} in the second"},
+		{"a { x = 1 }\na = ${b}", "a = ${b}", "a is the substitution ${b} over an object in the first document and the substitution ${b} in the second"},
+		{"a { x = 1 }\na = ${b}\nb { y = 1 }", "a { x = 1, y = 1 }\nb { y = 1 }", ""},
+	}
+	for _, tt := range tests {
+		ok, err := Equal([]byte(tt.a), []byte(tt.b))
+		if tt.diff == "" {
+			if !ok || err != nil {
+				t.Errorf("Equal(%q, %q) = %v, %v, want true", tt.a, tt.b, ok, err)
+			}
+			continue
+		}
+		d, isDiff := err.(*Difference)
+		if ok || !isDiff || d.Description != tt.diff {
+			t.Errorf("Equal(%q, %q) = %v, %v, want false, %s", tt.a, tt.b, ok, err, tt.diff)
+		}
+	}
+}
+
+func TestEqualErrors(t *testing.T) {
+	if ok, err := Equal([]byte("a = 1"), []byte("a = {")); ok || err == nil || err.Error() != "second document: 1:5: { is not closed" {
+		t.Errorf("Equal with an invalid document = %v, %v", ok, err)
+	}
+	if _, err := Equal([]byte("a = 1"), []byte("a = 2")); err.(*Difference).Path != "a" {
+		t.Errorf("Difference.Path = %q, want a", err.(*Difference).Path)
+	}
+}
@@ -0,0 +1,107 @@
+package hoconfmt
+
+import (
+	"fmt"
+	"go/token"
+	"io"
+	"sort"
+)
+
+// A posError is an error at a position of a file, as a scanner.Error
+// is, with the code of its kind (see check.go). The position has no
+// file name until withFilename sets it, and none at all for an error
+// that belongs to the whole file.
+type posError struct {
+	Pos  token.Position
+	Msg  string
+	Code string
+}
+
+func (e *posError) Error() string {
+	if e.Pos.Filename != "" || e.Pos.IsValid() {
+		return e.Pos.String() + ": " + e.Msg
+	}
+	return e.Msg
+}
+
+// An errorList is a list of errors in the order they are found, as a
+// scanner.ErrorList is. The zero value is an empty list ready to use.
+type errorList []*posError
+
+// Add adds an error with position pos, code and message msg to l.
+func (l *errorList) Add(pos token.Position, code, msg string) {
+	*l = append(*l, &posError{pos, msg, code})
+}
+
+func (l errorList) Len() int      { return len(l) }
+func (l errorList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+
+func (l errorList) Less(i, j int) bool {
+	e, f := &l[i].Pos, &l[j].Pos
+	if e.Filename != f.Filename {
+		return e.Filename < f.Filename
+	}
+	if e.Line != f.Line {
+		return e.Line < f.Line
+	}
+	if e.Column != f.Column {
+		return e.Column < f.Column
+	}
+	return l[i].Msg < l[j].Msg
+}
+
+// Sort sorts l by position, and errors at the same position by message.
+func (l errorList) Sort() {
+	sort.Sort(l)
+}
+
+// RemoveMultiples sorts l and keeps only the first error on each line.
+func (l *errorList) RemoveMultiples() {
+	sort.Sort(l)
+	var last token.Position
+	i := 0
+	for _, e := range *l {
+		if e.Pos.Filename != last.Filename || e.Pos.Line != last.Line {
+			last = e.Pos
+			(*l)[i] = e
+			i++
+		}
+	}
+	*l = (*l)[:i]
+}
+
+func (l errorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns l as an error, or nil if l is empty.
+func (l errorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// printError writes err to w: each error of a list on a line of its
+// own, as scanner.PrintError does.
+func printError(w io.Writer, err error) {
+	if list, ok := err.(errorList); ok {
+		for _, e := range list {
+			fmt.Fprintf(w, "%s\n", e)
+		}
+	} else if err != nil {
+		fmt.Fprintf(w, "%s\n", err)
+	}
+}
+
+// listError returns an error of the whole file, with code and message
+// msg, for errors found without a position.
+func listError(code, msg string) error {
+	return errorList{{Msg: msg, Code: code}}
+}
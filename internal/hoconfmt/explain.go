@@ -0,0 +1,251 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// typeAnnotation matches an annotation that explainTypes adds, at the
+// end of a comment.
+var typeAnnotation = regexp.MustCompile(`(^|[ \t]+)# type: [a-z]+([ \t]*)$`)
+
+// explainTypes adds a comment such as # type: duration to the end of
+// the line of every field of src, for -explain, naming the type HOCON
+// infers for its value: string, number, bool, null, object or array,
+// or duration, period or size for strings that a library reads as one.
+// A substitution gets the type of the value it stands for, if the
+// document sets it. The annotation ends the line where the value starts,
+// or the last line of a multi-line string, after any comment already
+// there and before trailing white space. Lines
+// holding several fields of the same object, and fields whose type is
+// not known, are left without one.
+//
+// The annotations that an earlier run added are removed first, so
+// that stripAnnotations(explainTypes(src)) is stripAnnotations(src).
+func explainTypes(src []byte, units UnitClassifier) ([]byte, error) {
+	src, err := stripAnnotations(src)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseDocument(src)
+	if err != nil {
+		return nil, err
+	}
+	e := &explainer{src: src, units: units, lines: map[int]*annotation{}}
+	e.root, _ = mergeDocument(src)
+	toks, _ := tokenize(src)
+	for _, t := range toks {
+		if t.kind == tokMultiline {
+			e.strings = append(e.strings, [2]int{t.off, t.off + len(t.lit)})
+		}
+	}
+	switch root := doc.root.(type) {
+	case *objectNode:
+		e.object(root)
+	case *arrayNode:
+		e.array(root)
+	}
+
+	sort.Slice(e.order, func(i, j int) bool { return e.order[i].off < e.order[j].off })
+	var res []byte
+	last := 0
+	for _, a := range e.order {
+		if a.typ == "" {
+			continue
+		}
+		res = append(res, src[last:a.off]...)
+		res = append(res, " # type: "+a.typ...)
+		last = a.off
+	}
+	return append(res, src[last:]...), nil
+}
+
+// stripAnnotations removes the annotations that explainTypes adds, and
+// the trailing comments that hold nothing else.
+func stripAnnotations(src []byte) ([]byte, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	var res []byte
+	for i, t := range toks {
+		if t.kind != tokComment || !typeAnnotation.MatchString(t.lit) {
+			res = append(res, t.lit...)
+			continue
+		}
+		lit := typeAnnotation.ReplaceAllString(t.lit, "$2")
+		j := i - 1
+		if j >= 0 && toks[j].kind == tokSpace {
+			j--
+		}
+		switch {
+		case j < 0 || toks[j].kind == tokNewline:
+			// A comment line of the user's own.
+			lit = t.lit
+		case strings.Trim(lit, " \t") == "" && toks[i-1].kind == tokSpace:
+			res = res[:len(res)-len(toks[i-1].lit)]
+		}
+		res = append(res, lit...)
+	}
+	return res, nil
+}
+
+// An explainer finds the annotations of explainTypes.
+type explainer struct {
+	src     []byte
+	root    Value // the merged document, or nil if it cannot be merged
+	units   UnitClassifier
+	strings [][2]int // the ranges of multi-line strings
+	lines   map[int]*annotation
+	order   []*annotation
+}
+
+// An annotation is the type to write at the end of a line, before the
+// offset off. parent is the object of the field it is for.
+type annotation struct {
+	off    int
+	typ    string
+	parent *objectNode
+}
+
+func (e *explainer) object(o *objectNode) {
+	for _, it := range o.items {
+		f, ok := it.node.(*fieldNode)
+		if !ok {
+			continue
+		}
+		e.annotate(o, f)
+		e.value(f.value)
+	}
+}
+
+func (e *explainer) array(a *arrayNode) {
+	for _, it := range a.items {
+		if v, ok := it.node.(*valueNode); ok {
+			e.value(v)
+		}
+	}
+}
+
+func (e *explainer) value(v *valueNode) {
+	for _, part := range v.parts {
+		switch n := part.node.(type) {
+		case *objectNode:
+			e.object(n)
+		case *arrayNode:
+			e.array(n)
+		}
+	}
+}
+
+// annotate records the annotation of the field f of the object o.
+func (e *explainer) annotate(o *objectNode, f *fieldNode) {
+	off := f.value.offset()
+	if last := f.value.parts[len(f.value.parts)-1]; last.tok.kind == tokMultiline {
+		off = last.tok.off + len(last.tok.lit)
+	}
+	off = lineEnd(e.src, off)
+	for _, s := range e.strings {
+		if s[0] < off && off < s[1] {
+			return
+		}
+	}
+	if a := e.lines[off]; a != nil {
+		if a.parent == o {
+			a.typ = "" // several fields on one line
+		}
+		return
+	}
+	m := &merger{src: e.src}
+	a := &annotation{off: off, typ: e.typeOf(m.value(f.value)), parent: o}
+	e.lines[off] = a
+	e.order = append(e.order, a)
+}
+
+// lineEnd returns the offset of the end of the line holding off, before
+// its trailing white space and newline.
+func lineEnd(src []byte, off int) int {
+	if i := bytes.IndexByte(src[off:], '\n'); i >= 0 {
+		off += i
+	} else {
+		off = len(src)
+	}
+	for off > 0 && (src[off-1] == '\r' || src[off-1] == ' ' || src[off-1] == '\t') {
+		off--
+	}
+	return off
+}
+
+// typeOf returns the name of the type of v, or "" if it is not known.
+func (e *explainer) typeOf(v Value) string {
+	for i := 0; i < 10; i++ {
+		s, ok := v.(Subst)
+		if !ok {
+			break
+		}
+		if v, ok = lookupPath(e.root, pathElems(s.Path)); !ok {
+			return ""
+		}
+	}
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case json.Number:
+		return "number"
+	case string:
+		if m := unitValue.FindStringSubmatch(strings.TrimSpace(v)); m != nil && valueUnits[m[2]] != "" {
+			return valueUnits[m[2]]
+		}
+		if kind := customUnit(strings.TrimSpace(v), e.units); kind != "" {
+			return kind
+		}
+		return "string"
+	case Object:
+		return "object"
+	case []Value:
+		return "array"
+	case concatenation:
+		text := false
+		for _, p := range v.parts {
+			switch p.(type) {
+			case Object:
+				return "object"
+			case []Value:
+				return "array"
+			case string:
+				text = true
+			}
+		}
+		if !text {
+			// Substitutions alone concatenate values of one type.
+			return e.typeOf(v.parts[0])
+		}
+		return "string"
+	}
+	return ""
+}
+
+// lookupPath returns the value at path in v, if there is one.
+func lookupPath(v Value, path []string) (Value, bool) {
+	for _, k := range path {
+		obj, ok := v.(Object)
+		if !ok {
+			return nil, false
+		}
+		found := false
+		for _, f := range obj {
+			if f.Key == k {
+				v, found = f.Value, true
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return v, true
+}
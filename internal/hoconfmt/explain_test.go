@@ -0,0 +1,74 @@
+package hoconfmt
+
+import "testing"
+
+func TestExplainTypes(t *testing.T) {
+	const src = `# type: string
+server {
+    host = localhost # the host
+    timeout = 10 seconds
+    buffer = "512MiB"
+    enabled = true
+    point { x = 1, y = 2 }
+    a = 1, b = 2
+}
+motd = """hello
+world"""
+alias = ${server.timeout}
+url = "http://"${server.host}
+list = ${?base} [1]
+env = ${?HOME}
+`
+	const want = `# type: string
+server { # type: object
+    host = localhost # the host # type: string
+    timeout = 10 seconds # type: duration
+    buffer = "512MiB" # type: size
+    enabled = true # type: bool
+    point { x = 1, y = 2 } # type: object
+    a = 1, b = 2
+}
+motd = """hello
+world""" # type: string
+alias = ${server.timeout} # type: duration
+url = "http://"${server.host} # type: string
+list = ${?base} [1] # type: array
+env = ${?HOME}
+`
+	res, err := explainTypes([]byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != want {
+		t.Errorf("got\n%s\nwant\n%s", res, want)
+	}
+
+	again, err := explainTypes(res, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != want {
+		t.Errorf("explaining the result again gave\n%s", again)
+	}
+
+	plain, err := stripAnnotations(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plain) != src {
+		t.Errorf("stripping the annotations gave\n%s\nwant\n%s", plain, src)
+	}
+
+	// Trailing white space stays at the end of the line, and a value on
+	// the line after its separator gets the annotation there.
+	res, err = explainTypes([]byte("a = 1   \nb =\n    x\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a = 1 # type: number   \nb =\n    x # type: string\n"; string(res) != want {
+		t.Errorf("got %q, want %q", res, want)
+	}
+	if plain, _ := stripAnnotations(res); string(plain) != "a = 1   \nb =\n    x\n" {
+		t.Errorf("stripping the annotations gave %q", plain)
+	}
+}
@@ -0,0 +1,319 @@
+package hoconfmt
+
+import "strings"
+
+// breakFields moves a field that starts on the same line as the closing
+// brace or bracket of the previous field's value onto a line of its
+// own, so that
+//
+//	a { x = 1 } b = 2
+//
+// becomes
+//
+//	a { x = 1 }
+//	b = 2
+//
+// Only fields of objects that are already laid out over several lines,
+// and of the root object, are moved; objects written on a single line
+// stay inline, and so does what follows a bracket that is not the value
+// of a field. The new line gets the indentation of the line that
+// opened the value, which is that of the previous field.
+func breakFields(lx *lexer, src []byte) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	type bracket struct {
+		kind   tokenKind
+		line   int    // line the bracket was opened on
+		value  bool   // the bracket opens the value of a field
+		indent string // indentation of its line
+	}
+	res := make([]byte, 0, len(src))
+	var open []bracket // enclosing { and [
+	var closed bracket // the bracket closed last
+	var last tokenKind // kind of the last token that is not white space
+	hasLast := false
+	line := 0
+	indent := "" // indentation of the current line
+	atLineStart := true
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		if atLineStart {
+			indent = ""
+			if t.kind == tokSpace {
+				indent = t.lit
+			}
+		}
+		atLineStart = t.kind == tokNewline
+
+		switch t.kind {
+		case tokNewline:
+			line++
+		case tokMultiline:
+			for _, c := range t.lit {
+				if c == '\n' {
+					line++
+				}
+			}
+		case tokLBrace, tokLBracket:
+			// A bracket that follows a key or separator opens a value;
+			// one at the start of a line or right inside another does
+			// not, and what follows it is not a field either.
+			value := hasLast && last != tokLBrace && last != tokLBracket && last != tokComma && last != tokNewline
+			b := bracket{t.kind, line, value, indent}
+			if last == tokRBrace || last == tokRBracket {
+				// A further part of a concatenation, as in [1] [2],
+				// belongs to the value the first part opened.
+				b.indent = closed.indent
+			}
+			open = append(open, b)
+		case tokRBrace, tokRBracket:
+			var b bracket
+			if len(open) > 0 {
+				b = open[len(open)-1]
+				open = open[:len(open)-1]
+			}
+			closed = b
+			block := len(open) == 0 || open[len(open)-1].kind == tokLBrace && open[len(open)-1].line < line
+			if j := i + 1; block && b.value && j+1 < len(toks) && toks[j].kind == tokSpace &&
+				(toks[j+1].kind == tokUnquoted || toks[j+1].kind == tokString) {
+				res = append(res, t.lit...)
+				res = append(res, '\n')
+				res = append(res, b.indent...)
+				i = j // drop the space
+				last, hasLast = t.kind, true
+				continue
+			}
+		}
+		if t.kind != tokSpace {
+			last, hasLast = t.kind, true
+		}
+		res = append(res, t.lit...)
+	}
+	return res, nil
+}
+
+// expandMultilineObjects lays out an object written on one line over
+// several lines when it holds a multi-line string, one field per line,
+// so that
+//
+//	a { b = """x
+//	y""", c = 1 }
+//
+// becomes
+//
+//	a {
+//	    b = """x
+//	y"""
+//	    c = 1
+//	}
+//
+// The fields are indented by one indent more than the line holding the
+// object, and the strings are copied as they are. Objects inside the
+// object that hold a multi-line string are laid out the same way; the
+// others stay on one line. Objects whose text has newlines or comments
+// outside multi-line strings are already laid out by hand and left
+// alone.
+func expandMultilineObjects(lx *lexer, src []byte, indent string) ([]byte, error) {
+	for {
+		toks, err := lx.tokenize(src)
+		if err != nil {
+			return nil, err
+		}
+		o := inlineMultilineObject(toks)
+		if o < 0 {
+			return src, nil
+		}
+		c := matchingBracket(toks, o)
+
+		// The indentation of the line holding the object.
+		lineIndent := ""
+		start := o
+		for start > 0 && toks[start-1].kind != tokNewline {
+			start--
+		}
+		if toks[start].kind == tokSpace {
+			lineIndent = toks[start].lit
+		}
+
+		var res []byte
+		for _, t := range toks[:o+1] {
+			res = append(res, t.lit...)
+		}
+		res = append(res, '\n')
+		depth := 0
+		from := o + 1 // first token of the current field
+		for i := o + 1; i <= c; i++ {
+			switch toks[i].kind {
+			case tokLBrace, tokLBracket:
+				depth++
+				continue
+			case tokRBrace, tokRBracket:
+				if i < c {
+					depth--
+					continue
+				}
+			case tokComma:
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+			field := toks[from:i]
+			for len(field) > 0 && field[0].kind == tokSpace {
+				field = field[1:]
+			}
+			for len(field) > 0 && field[len(field)-1].kind == tokSpace {
+				field = field[:len(field)-1]
+			}
+			if len(field) > 0 {
+				res = append(res, lineIndent+indent...)
+				for _, t := range field {
+					res = append(res, t.lit...)
+				}
+				res = append(res, '\n')
+			}
+			from = i + 1
+		}
+		res = append(res, lineIndent...)
+		for _, t := range toks[c:] {
+			res = append(res, t.lit...)
+		}
+		src = res
+	}
+}
+
+// inlineMultilineObject returns the index of the opening brace of the
+// first object of toks that expandMultilineObjects lays out, or -1.
+func inlineMultilineObject(toks []tok) int {
+	for o, t := range toks {
+		if t.kind != tokLBrace {
+			continue
+		}
+		c := matchingBracket(toks, o)
+		if toks[c].kind != tokRBrace {
+			continue
+		}
+		multiline, inline := false, true
+		for _, t := range toks[o+1 : c] {
+			switch t.kind {
+			case tokNewline, tokComment:
+				inline = false
+			case tokMultiline:
+				multiline = multiline || strings.Contains(t.lit, "\n")
+			}
+		}
+		if multiline && inline {
+			return o
+		}
+	}
+	return -1
+}
+
+// hoistValueComments moves a comment that stands between a separator
+// and the value on the next line above the field, and joins the value
+// back to its key:
+//
+//	key = # note
+//	    value
+//
+// becomes
+//
+//	# note
+//	key = value
+//
+// Comment lines that follow the first one before the value are hoisted
+// with it.
+func hoistValueComments(lx *lexer, src []byte) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	lines := splitLines(toks)
+
+	res := make([]byte, 0, len(src))
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		sep := dangling(l)
+		if sep < 0 {
+			res = l.appendTo(res)
+			continue
+		}
+
+		// Find the value line, skipping further comment lines.
+		j := i + 1
+		for j < len(lines) && lines[j].commentOnly() {
+			j++
+		}
+		if j == len(lines) || lines[j].blank() {
+			res = l.appendTo(res)
+			continue
+		}
+		if k := lines[j].first().kind; k == tokRBrace || k == tokRBracket || k == tokSep {
+			res = l.appendTo(res)
+			continue
+		}
+
+		indent := ""
+		if l.toks[0].kind == tokSpace {
+			indent = l.toks[0].lit
+		}
+		res = append(res, indent...)
+		res = append(res, l.toks[len(l.toks)-2].lit...) // the comment
+		res = append(res, '\n')
+		for _, c := range lines[i+1 : j] {
+			res = append(res, indent...)
+			res = append(res, c.first().lit...)
+			res = append(res, '\n')
+		}
+		for _, t := range l.toks[:sep+1] {
+			res = append(res, t.lit...)
+		}
+		res = append(res, ' ')
+		value := lines[j].toks
+		for len(value) > 0 && value[0].kind == tokSpace {
+			value = value[1:]
+		}
+		for _, t := range value {
+			res = append(res, t.lit...)
+		}
+		i = j
+	}
+	return res, nil
+}
+
+// dangling returns the index of the separator token if l is a field
+// whose value has been pushed to the next line by a comment, as in
+// "key = # note". Otherwise it returns -1.
+func dangling(l srcLine) int {
+	n := len(l.toks)
+	if n < 3 || l.toks[n-1].kind != tokNewline || l.toks[n-2].kind != tokComment {
+		return -1
+	}
+	i := n - 3
+	if l.toks[i].kind == tokSpace {
+		i--
+	}
+	if i < 1 || l.toks[i].kind != tokSep {
+		return -1
+	}
+	sep := i
+	// Everything before the separator must be the key.
+	sawKey := false
+	for _, t := range l.toks[:sep] {
+		switch t.kind {
+		case tokUnquoted, tokString:
+			sawKey = true
+		case tokSpace:
+		default:
+			return -1
+		}
+	}
+	if !sawKey {
+		return -1
+	}
+	return sep
+}
@@ -0,0 +1,77 @@
+package hoconfmt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// rewrites counts the files -w has rewritten, for the count -files-from
+// reports.
+var rewrites int64
+
+// writeChanged replaces filename, whose contents were src, with res, for
+// -w, saving src first if -backup is set.
+func writeChanged(filename string, src, res []byte) error {
+	if *backup {
+		if err := writeBackup(filename, src); err != nil {
+			return err
+		}
+	}
+	if err := writeFileAtomic(filename, res); err != nil {
+		return err
+	}
+	atomic.AddInt64(&rewrites, 1)
+	return nil
+}
+
+// readFileList returns the file names listed in the file name, one per
+// line, or on standard input if name is -, for -files-from. White space
+// around the names and blank lines are ignored.
+func readFileList(name string) ([]string, error) {
+	var in io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+	var names []string
+	s := bufio.NewScanner(in)
+	for s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line != "" {
+			names = append(names, line)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("-files-from %s: %v", name, err)
+	}
+	return names, nil
+}
+
+// processFileList formats the files of jobs as processFiles does and,
+// with -w, writes to stderr how many of them were rewritten, which is
+// only those whose formatting changed.
+func processFileList(stdout, stderr io.Writer, jobs []fileJob, n int) {
+	atomic.StoreInt64(&rewrites, 0)
+	processFiles(stdout, stderr, jobs, n)
+	if !*write || *quiet {
+		return
+	}
+	files := 0
+	for _, j := range jobs {
+		if j.err == nil {
+			files++
+		}
+	}
+	s := "s"
+	if files == 1 {
+		s = ""
+	}
+	fmt.Fprintf(stderr, "rewrote %d of %d file%s\n", atomic.LoadInt64(&rewrites), files, s)
+}
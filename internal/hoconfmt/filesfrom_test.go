@@ -0,0 +1,81 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFilesFrom(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"formatted.conf":   "a = 1\n",
+		"unformatted.conf": "a = [ ]\n",
+		"bad.conf":         "a = }\n",
+	}
+	for name, text := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list := filepath.Join(dir, "files.txt")
+	names := []string{"formatted.conf", "bad.conf", "missing.conf", "unformatted.conf"}
+	var text string
+	for _, name := range names {
+		text += "  " + filepath.Join(dir, name) + "\n\n"
+	}
+	if err := ioutil.WriteFile(list, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := readFileList(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != len(names) || paths[0] != filepath.Join(dir, "formatted.conf") {
+		t.Fatalf("readFileList = %q", paths)
+	}
+	var jobs []fileJob
+	for _, p := range paths {
+		jobs = append(jobs, fileJob{path: p})
+	}
+
+	*write = true
+	defer func(code int) { *write = false; exitCode = code }(exitCode)
+	exitCode = 0
+	var stdout, stderr bytes.Buffer
+	processFileList(&stdout, &stderr, jobs, 2)
+	if exitCode != 2 {
+		t.Errorf("exit code = %d, want 2 for the bad and missing files", exitCode)
+	}
+	for _, want := range []string{"bad.conf:1:5: unexpected }", "missing.conf: no such file", "rewrote 1 of 4 files\n"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("standard error %q does not hold %q", stderr.String(), want)
+		}
+	}
+	if stdout.Len() > 0 {
+		t.Errorf("standard output %q, want nothing", stdout.String())
+	}
+	files["unformatted.conf"] = "a = []\n"
+	for name, want := range files {
+		got, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	if _, err := readFileList(filepath.Join(dir, "nope.txt")); err == nil {
+		t.Error("reading a missing list: no error")
+	}
+}
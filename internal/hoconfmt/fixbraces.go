@@ -0,0 +1,89 @@
+package hoconfmt
+
+import (
+	"go/token"
+)
+
+// fixBraces checks that the braces and brackets of src are balanced. If
+// the only problem is a single '{' that is never closed, and every line
+// after the one it was opened on is indented deeper than that line, the
+// missing '}' can only belong at the end of the file: fixBraces appends
+// it there and returns the position of the unclosed brace. Any other
+// imbalance is ambiguous and reported as an error instead.
+func fixBraces(src []byte, tabwidth int) ([]byte, token.Position, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, token.Position{}, err
+	}
+
+	var errs errorList
+	var open []int // indices of the unclosed { and [
+	for i, t := range toks {
+		switch t.kind {
+		case tokLBrace, tokLBracket:
+			open = append(open, i)
+		case tokRBrace, tokRBracket:
+			if len(open) == 0 {
+				errs.Add(position(src, t.off), codeSyntax, "unexpected "+t.lit)
+				continue
+			}
+			o := toks[open[len(open)-1]]
+			open = open[:len(open)-1]
+			if (o.kind == tokLBrace) != (t.kind == tokRBrace) {
+				errs.Add(position(src, t.off), codeSyntax, t.lit+" does not match "+o.lit+" opened at "+position(src, o.off).String())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, token.Position{}, errs.Err()
+	}
+	switch {
+	case len(open) == 0:
+		return src, token.Position{}, nil
+	case len(open) > 1:
+		errs.Add(position(src, toks[open[0]].off), codeSyntax, "several brackets are not closed; cannot tell where to close them")
+		return nil, token.Position{}, errs.Err()
+	}
+
+	brace := toks[open[0]]
+	pos := position(src, brace.off)
+	if brace.kind != tokLBrace {
+		errs.Add(pos, codeSyntax, "[ is not closed")
+		return nil, token.Position{}, errs.Err()
+	}
+
+	// Find the line of the brace and check that every following line
+	// belongs inside it.
+	lines := splitLines(toks)
+	n := 0
+	for n+1 < len(lines) && lines[n+1].toks[0].off <= brace.off {
+		n++
+	}
+	indent := leadingSpace(lines[n])
+	width := textWidth([]tok{{lit: indent}}, tabwidth)
+	for _, l := range lines[n+1:] {
+		if l.blank() {
+			continue
+		}
+		if textWidth([]tok{{lit: leadingSpace(l)}}, tabwidth) <= width {
+			errs.Add(pos, codeSyntax, "{ is not closed; cannot tell where the } belongs")
+			return nil, token.Position{}, errs.Err()
+		}
+	}
+
+	res := append([]byte(nil), src...)
+	if len(res) > 0 && res[len(res)-1] != '\n' {
+		res = append(res, '\n')
+	}
+	res = append(res, indent...)
+	res = append(res, "}\n"...)
+	return res, pos, nil
+}
+
+// leadingSpace returns the indentation of l.
+func leadingSpace(l srcLine) string {
+	if len(l.toks) > 0 && l.toks[0].kind == tokSpace {
+		return l.toks[0].lit
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+package hoconfmt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFixBraces(t *testing.T) {
+	for _, test := range []struct {
+		src, want string
+		line      int // line of the unclosed brace, if any
+	}{
+		{"a {\n    b = 1\n}\n", "a {\n    b = 1\n}\n", 0},
+		{"a {\n    b = 1\n", "a {\n    b = 1\n}\n", 1},
+		{"a {\n    b = 1", "a {\n    b = 1\n}\n", 1},
+		{"x = 1\n  a {\n    b {\n      c = 1\n    }\n\n", "x = 1\n  a {\n    b {\n      c = 1\n    }\n\n  }\n", 2},
+		{"a { b = 1\n", "a { b = 1\n}\n", 1},
+	} {
+		got, pos, err := fixBraces([]byte(test.src), 4)
+		if err != nil {
+			t.Errorf("%q: %v", test.src, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%q: got %q, want %q", test.src, got, test.want)
+		}
+		if pos.Line != test.line {
+			t.Errorf("%q: unclosed brace reported on line %d, want %d", test.src, pos.Line, test.line)
+		}
+	}
+}
+
+func TestFixBracesAmbiguous(t *testing.T) {
+	for _, test := range []struct {
+		src, err string
+	}{
+		// The } may belong after b = 1 or after c = 2.
+		{"a {\n    b = 1\nc = 2\n", "1:3: { is not closed"},
+		{"a {\n    b {\n        c = 1\n", "1:3: several brackets"},
+		{"a = [\n    1\n", "1:5: [ is not closed"},
+		{"a = 1\n}\n", "2:1: unexpected }"},
+		{"a = [ 1 }\n", "1:9: } does not match ["},
+	} {
+		_, _, err := fixBraces([]byte(test.src), 4)
+		if err == nil {
+			t.Errorf("%q: no error", test.src)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: got error %q, want %q", test.src, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package hoconfmt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// getValue returns the value at path in the document src, read from
+// filename, for -get. path is a key path such as db."host.name", whose
+// elements are object keys or, for arrays, the numbers of their
+// elements from 0, as in servers.0.host. The fields of src are merged
+// the way HOCON merges them, so the last value of a path wins, and its
+// substitutions are resolved where src sets their paths. Include
+// statements are not followed. If env is set, as for -get with
+// -resolve, src is resolved against it by resolveDocument instead. It
+// reports whether src has a value at path.
+func getValue(filename string, src []byte, path string, env *environment) (Value, bool, error) {
+	var v Value
+	var err error
+	if env != nil {
+		v, err = resolveDocument(filename, src, env)
+	} else {
+		v, err = resolvedDocument(src)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	v, ok := lookupElems(v, pathElems(path))
+	if !ok {
+		return nil, false, nil
+	}
+	return withoutIncludes(v), true, nil
+}
+
+// lookupElems returns the value at path in v, if there is one, the
+// elements of path being object keys or the numbers of array elements.
+func lookupElems(v Value, path []string) (Value, bool) {
+	for _, e := range path {
+		switch cur := v.(type) {
+		case Object:
+			found := false
+			for _, f := range cur {
+				if f.Key == e {
+					v, found = f.Value, true
+				}
+			}
+			if !found {
+				return nil, false
+			}
+		case []Value:
+			i, err := strconv.Atoi(e)
+			if err != nil || i < 0 || i >= len(cur) || strconv.Itoa(i) != e {
+				return nil, false
+			}
+			v = cur[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// withoutIncludes returns v without the fields that stand for include
+// statements.
+func withoutIncludes(v Value) Value {
+	switch v := v.(type) {
+	case Object:
+		obj := Object{}
+		for _, f := range v {
+			if strings.HasPrefix(f.Key, includeKey) {
+				continue
+			}
+			obj = append(obj, Field{f.Key, withoutIncludes(f.Value)})
+		}
+		return obj
+	case []Value:
+		elems := make([]Value, len(v))
+		for i, e := range v {
+			elems[i] = withoutIncludes(e)
+		}
+		return elems
+	case concatenation:
+		parts := make([]Value, len(v.parts))
+		for i, p := range v.parts {
+			parts[i] = withoutIncludes(p)
+		}
+		return concatenation{parts, v.off}
+	}
+	return v
+}
+
+// printValue returns the value at path in filename, whose content is
+// src, as -get prints it, resolved against env if it is set: as HOCON,
+// formatted by FormatValue with opts, or as JSON if asJSON is set,
+// followed by a newline.
+func printValue(filename string, src []byte, path string, env *environment, opts Options, asJSON bool) ([]byte, error) {
+	v, ok, err := getValue(filename, src, path, env)
+	if _, isList := err.(errorList); isList {
+		return nil, withFilename(err, filename)
+	} else if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s: no value at %s", filename, path)
+	}
+	if asJSON {
+		res, err := toJSONValue(src, v, valueIndent(opts))
+		return res, withFilename(err, filename)
+	}
+	res, err := FormatValue(v, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return append(res, '\n'), nil
+}
@@ -0,0 +1,66 @@
+package hoconfmt
+
+import "testing"
+
+func TestPrintValue(t *testing.T) {
+	const src = `host = h
+servers = [{ host = a, port = 1 }, { host = b }]
+servers += { host = c }
+db { "x.y" = 1, url = "http://"${host}":80", env = ${?HOME}/x }
+db.pool { max = 2 }
+db.pool.max = 3
+include "extra.conf"
+`
+	tests := []struct{ path, want string }{
+		{"servers.0.host", "\"a\"\n"},
+		{"servers.2", "{\n    host = \"c\"\n}\n"},
+		{`db."x.y"`, "1\n"},
+		{`"db".url`, "\"http://h:80\"\n"},
+		{"db.env", "${?HOME}\"/x\"\n"},
+		{"db.pool", "{\n    max = 3\n}\n"},
+		{"servers.1", "{\n    host = \"b\"\n}\n"},
+	}
+	for _, tt := range tests {
+		res, err := printValue("a.conf", []byte(src), tt.path, nil, DefaultOptions(), false)
+		if err != nil {
+			t.Errorf("-get %s: %v", tt.path, err)
+			continue
+		}
+		if string(res) != tt.want {
+			t.Errorf("-get %s = %q, want %q", tt.path, res, tt.want)
+		}
+	}
+
+	res, err := printValue("a.conf", []byte(src), "db.pool", nil, DefaultOptions(), true)
+	if err != nil || string(res) != "{\n    \"max\": 3\n}\n" {
+		t.Errorf("-get db.pool -tojson = %q, %v", res, err)
+	}
+
+	for _, path := range []string{"nope", "servers.3", "servers.01", "host.x", "db.x.y"} {
+		if _, err := printValue("a.conf", []byte(src), path, nil, DefaultOptions(), false); err == nil || err.Error() != "a.conf: no value at "+path {
+			t.Errorf("-get %s: got error %v, want no value", path, err)
+		}
+	}
+}
+
+func TestPrintValueNumericKeys(t *testing.T) {
+	// A number is an element of an array and a key of an object.
+	const src = "list = [a, b]\nmap { 0 = x, 1 = y }\nmixed = [{ 0 = z }]\n"
+	tests := []struct{ path, want string }{
+		{"list.0", "\"a\"\n"},
+		{"map.0", "\"x\"\n"},
+		{"map.1", "\"y\"\n"},
+		{"mixed.0.0", "\"z\"\n"},
+	}
+	for _, tt := range tests {
+		res, err := printValue("a.conf", []byte(src), tt.path, nil, DefaultOptions(), false)
+		if err != nil || string(res) != tt.want {
+			t.Errorf("-get %s = %q, %v, want %q", tt.path, res, err, tt.want)
+		}
+	}
+	for _, path := range []string{"list.2", "map.2", "mixed.1"} {
+		if _, err := printValue("a.conf", []byte(src), path, nil, DefaultOptions(), false); err == nil {
+			t.Errorf("-get %s: got a value, want none", path)
+		}
+	}
+}
@@ -0,0 +1,1705 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/printer"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// main operation modes
+	list      = commandLine.Bool("l", false, "list files whose formatting differs from hoconfmt's, and exit with status 1 if there are any")
+	write     = commandLine.Bool("w", false, "write result to (source) file instead of stdout")
+	doDiff    = commandLine.Bool("d", false, "display diffs instead of writing files, and exit with status 1 if there are any")
+	jsonDiff  = commandLine.Bool("json-diff", false, "print the changes as JSON text edits (byte offset, length, replacement) instead of the result")
+	diffLines = commandLine.Int("diff-context", 3, "number of context lines in -d diffs")
+	diffStat  = commandLine.Bool("stat", false, "print the insertion and deletion counts of each file whose formatting differs instead of the result")
+	quiet     = commandLine.Bool("quiet", false, "do not print the insertion and deletion counts of -d diffs, the count of files -files-from rewrote, or the type warnings of -resolve, on standard error")
+	allErrors = commandLine.Bool("e", false, "report all errors (not just the first 10 on different lines)")
+	maxErrors = commandLine.Int("max-errors", 10, "report at most this many errors, one per line (0 means no limit; -e reports all)")
+	bestEff   = commandLine.Bool("best-effort", false, "format the parts of a file with syntax errors that can be formatted")
+	serveMode = commandLine.Bool("serve", false, "run as a formatting server, reading requests from stdin")
+	lspMode   = commandLine.Bool("lsp", false, "run as a language server for editors, speaking LSP on stdin and stdout")
+	selfTest  = commandLine.Bool("selftest", false, "format a built-in set of snippets and report whether the results are right")
+	showVers  = commandLine.Bool("version", false, "print the version of hoconfmt and exit")
+	outFile   = commandLine.String("o", "", "write result to this file instead of stdout (single file or stdin only)")
+	mkdirs    = commandLine.Bool("mkdir", false, "create missing parent directories of the -o file")
+	indexFile = commandLine.String("index", "", "also write the sorted key paths of the result to this `file` (single file or stdin only)")
+	outDir    = commandLine.String("out-dir", "", "write formatted copies of the .conf files under the directory arguments to this `directory`, keeping their relative paths")
+	confExt   = commandLine.String("ext", "", "comma-separated file name `suffixes`, such as .hocon or .conf.tmpl, of the files to format in directories besides .conf")
+	exclude   = commandLine.String("exclude", "", "comma-separated `globs` of the files and directories to skip in directories, matched against the name, or the path below the directory if they hold a /, where ** matches any number of directories")
+	stdinName = commandLine.String("stdin-filename", "", "`name` of standard input in messages, also used to find its .hoconfmt.conf and .editorconfig")
+	banners   = commandLine.Bool("banners", false, "precede the output of each file with a # ===== file ===== comment line")
+	split     = commandLine.Bool("split", false, "write each top-level key to its own file in -split-dir, and the result as an index of includes")
+	splitDir  = commandLine.String("split-dir", ".", "`directory` of the files written by -split")
+	splitOvr  = commandLine.Bool("split-overwrite", false, "let -split replace existing files in -split-dir")
+	backup    = commandLine.Bool("backup", false, "with -w, save the original of every changed file under the name plus -backup-suffix")
+	backupExt = commandLine.String("backup-suffix", ".bak", "file name suffix of -backup copies")
+	backupOvr = commandLine.Bool("backup-overwrite", false, "let -backup replace existing backup copies")
+	filesFrom = commandLine.String("files-from", "", "also format the files named in this `file`, one per line, or on standard input if it is -; with -w, report how many were rewritten")
+	parallel  = commandLine.Int("j", runtime.GOMAXPROCS(0), "format up to `n` files at once; output is still in the order of the files")
+
+	// analysis
+	showStats  = commandLine.Bool("stats", false, "print structural metrics instead of formatting")
+	jsonReport = commandLine.Bool("json-report", false, "print analysis reports as JSON")
+	showTodos  = commandLine.Bool("format-comments-as-todo", false, "list the comments holding one of the -todo-markers instead of formatting")
+	markers    = commandLine.String("todo-markers", defaultTodoMarkers, "comma-separated markers for -format-comments-as-todo")
+	compareTo  = commandLine.String("compare", "", "report the keys and values that change from the input to this `file`, ignoring formatting and comments")
+	summary    = commandLine.Bool("summary", false, "count the files with a byte order mark, CRLF line endings or invalid UTF-8 instead of formatting")
+	blameKeys  = commandLine.Bool("blame", false, "list where the effective value of every key is set, following includes, instead of formatting")
+	lintSeps   = commandLine.Bool("lint-separators", false, "report files that mix = and : separators or # and // comments instead of formatting")
+	checkIncl  = commandLine.Bool("check-includes", false, "report include statements whose file cannot be found, following includes, instead of formatting")
+	inclPath   = commandLine.String("include-path", "", "`directories`, separated as in $PATH, searched for classpath includes and for file includes not next to the including file, by -check-includes, -inline-includes-under and -resolve")
+	checkSubst = commandLine.Bool("check-substs", false, "report substitutions of paths that the file and its includes do not define instead of formatting")
+	checkMode  = commandLine.Bool("check", false, "report whether each file is formatted, with its errors and warnings, instead of formatting; exit with status 1 if a file is not formatted and 2 if one has errors")
+	checkDiff  = commandLine.Bool("check-diff", false, "add the diff of each file that is not formatted to the -check report")
+
+	// input conversion
+	fromProperties = commandLine.Bool("from-properties", false, "convert Java .properties input to HOCON")
+	stdinFormat    = commandLine.String("format-stdin-as", stdinHOCON, "read standard input as hocon, json or properties")
+	fixBrace       = commandLine.Bool("fix-braces", false, "add a missing final } when it can only belong at the end of the file")
+	fixComma       = commandLine.Bool("fix-commas", false, "remove leading and doubled commas, as in [,1] and [1,,2], instead of reporting them as errors")
+	strict         = commandLine.Bool("strict", false, "reject unquoted include targets, separators on their own line and /* */ comments instead of accepting them, and make the type warnings of -resolve errors")
+
+	// formatting control
+	profile         = commandLine.String("profile", "", "bundle of formatting options: lightbend, compact or verbose; the flags given override it")
+	touchOnly       = commandLine.Bool("touch", false, "only fix line endings, trailing white space and the final newline")
+	reprintAll      = commandLine.Bool("reprint", false, "lay the document out again from its syntax tree: one field or element per line, spacing and indentation by nesting, comments kept")
+	normalizeAll    = commandLine.Bool("normalize-all", false, "also space separators and comment markers and trim trailing white space; never changes meaning")
+	useEditorConfig = commandLine.Bool("editorconfig", false, "take indentation settings from the nearest .editorconfig")
+	indentStr       = commandLine.String("indent-string", "", "indentation of one level: spaces, or a tab written as \\t (default: four spaces)")
+	indentWidth     = commandLine.Int("tabwidth", 0, "indent each level by this many spaces, as -indent-string with that many spaces does")
+	indentTabs      = commandLine.Bool("usetabs", false, "indent each level by a tab, as -indent-string='\\t' does")
+	includeSpacing  = commandLine.Bool("include-spacing", false, "surround top-level include statements with blank lines")
+	inlineUnder     = commandLine.Int("inline-includes-under", 0, "replace top-level include statements whose file is smaller than this many `bytes` with the text of the file, found as with -check-includes; 0 inlines none")
+	wrapComment     = commandLine.Bool("wrap-comments", false, "wrap comment lines that are wider than -width")
+	lineWidth       = widthFlag("width", defaultWidth, "line width for -wrap-comments, -continuation-indent, -array-per-line=0 and -reprint, or auto for the terminal width")
+	contIndent      = commandLine.String("continuation-indent", "", "wrap arrays wider than -width, continuing under the first element (align) or one indent deeper (indent)")
+	arrayPerLine    = commandLine.Int("array-per-line", -1, "put up to `N` elements on each line of arrays spread over several lines; 0 fits as many as -width allows, -1 leaves them")
+	arrayColumns    = commandLine.Int("array-columns", 0, "lay out arrays of numbers, strings and words in right-aligned columns, `N` elements to a row (0 leaves them)")
+	tightSubst      = commandLine.Bool("trim-substs", false, "remove white space around the paths of substitutions, as in ${ a.b }")
+	snapshotEnvs    = commandLine.Bool("snapshot-env", false, "replace optional substitutions of set environment variables, as in ${?PORT}, with their value and a # was comment")
+	maxBlank        = commandLine.Int("max-blank", defaultMaxBlank, "keep at most this many consecutive blank lines")
+	alignComment    = commandLine.Bool("align-comments", false, "align trailing comments of consecutive lines into a column")
+	alignLimit      = commandLine.Int("align-threshold", 0, "with -align-comments, leave lines more than `N` columns wider than the median of their group out of the column (0 means no limit)")
+	objectEq        = commandLine.String("object-eq", objectEqOmit, "separator for object-valued keys: omit (key {}) or require (key = {})")
+	keyCase         = commandLine.String("key-case", keyCasePreserve, "case of unquoted keys: preserve, lower, upper or camel (maxPoolSize), renaming the substitutions of renamed keys")
+	unicodeNorm     = commandLine.Bool("unicode-normalize", false, "rewrite keys, unquoted values and substitution paths to Unicode normalization form C (NFC)")
+	unicodeStrs     = commandLine.Bool("unicode-normalize-strings", false, "also rewrite quoted and multi-line strings to NFC, as -unicode-normalize does keys")
+	keyPaths        = commandLine.String("key-paths", keyPathsKeep, "paths of fields inside objects: keep, dotted (a.b = 1) or nested (a { b = 1 }), merging the objects of a key")
+	simplifyAll     = commandLine.Bool("s", false, "simplify: merge the objects of a key, write objects of one field as dotted paths (or as -key-paths says) and drop fields that set a path to the value it already has")
+	sepStyle        = commandLine.String("separators", separatorsKeep, "separator of fields whose value is not an object: keep, equals (=) or colon (:)")
+	commaStyle      = commandLine.String("commas", commasKeep, "commas between elements: keep, or smart (only between elements on the same line, no trailing commas)")
+	commentStyle    = commandLine.String("comment-style", commentsPreserve, "comment markers: preserve, hash (#) or slash (//)")
+	emptyStyle      = commandLine.String("empty", emptyCompact, "layout of empty objects and arrays: compact ({}) or expanded (kept across lines if written so)")
+	fixQuote        = commandLine.Bool("fix-quotes", false, "remove quotes that keys and values do not need, quote keys with spaces, write strings of several lines with \"\"\" and space units as in 10s and 10 seconds")
+	keepJSON        = commandLine.Bool("keep-json-values", false, "leave values written as strict JSON as they are")
+	eol             = commandLine.String("eol", eolLF, "line endings: lf, crlf or keep (whichever most lines of the input use)")
+	preserve        = commandLine.String("preserve", "", "comma-separated key path `globs` whose values are kept byte for byte")
+	sortArray       = commandLine.String("sort-array", "", "comma-separated `path=field` pairs: sort the objects of the array at path by field")
+	sortFirst       = commandLine.String("sort-first", "", "comma-separated `keys`, such as name,version, that -sort puts first in each object holding them, in this order")
+	sortFields      = commandLine.Bool("sort", false, "order the fields of every object by key, byte by byte, within the runs of fields between blank lines and include statements")
+	includeKeys     = commandLine.String("include-keys", "", "comma-separated key path `globs`: output only these keys")
+	excludeKeys     = commandLine.String("exclude-keys", "", "comma-separated key path `globs`: leave these keys out of the output")
+	keepEmpty       = commandLine.Bool("keep-empty", false, "keep the objects that -include-keys or -exclude-keys leave empty")
+	rewriteRules    = rulesFlag("r", "rewrite `rule` old.path -> new.path: move the value at old.path, and what is inside it, to new.path; may be repeated")
+	rewriteFile     = commandLine.String("r-file", "", "read -r rewrite rules from this `file`, one per line")
+	warnDups        = commandLine.Bool("warn-duplicates", false, "warn about keys whose value replaces an earlier one, includes whose values are all replaced, and keys that includes or -merge files change from an object to a value or back")
+	warnConcat      = commandLine.Bool("warn-concat", false, "warn about array elements that join values with white space, as in [a b], instead of separating them")
+	blocksOnly      = commandLine.Bool("only-format-modified-blocks", false, "keep top-level blocks that formatting changes only in white space as they are")
+	noLoseComments  = commandLine.Bool("no-lose-comments", false, "fail if a comment of the input would not appear in the result")
+	verifyValue     = commandLine.Bool("verify", false, "fail if the result would not have the same value as the input; always on with -w")
+	rootBrace       = commandLine.String("root-braces", "", "braces around the whole document: omit or require (default: keep)")
+	explain         = commandLine.Bool("explain", false, "add a # type: comment to the line of every field naming the type of its value, such as number or duration")
+
+	// debugging
+	cpuProfile = commandLine.String("cpuprofile", "", "write cpu profile to this file")
+	memProfile = commandLine.String("memprofile", "", "write memory profile to this file")
+	debug      = commandLine.Bool("debug", false, "list the debugging flags in the help output")
+	printTree  = commandLine.Bool("print-ast", false, "print the structure of the input instead of formatting it")
+	printJSON  = commandLine.Bool("tojson", false, "print the input as JSON, merging its fields and leaving substitutions unresolved, or the -get or -resolve value, instead of formatting it")
+	printProps = commandLine.Bool("properties", false, "print the input as a Java .properties file, merging its fields and resolving the substitutions it sets, instead of formatting it")
+	propsSep   = commandLine.String("properties-array-sep", "", "with -properties, join the elements of arrays of simple values with this `separator` instead of numbering them")
+	resolve    = commandLine.Bool("resolve", false, "print the input, or the -get value, with its includes followed, its fields merged and its substitutions resolved, from the input and the environment, instead of formatting it")
+	getPath    = commandLine.String("get", "", "print the value at this key `path`, such as servers.0.host, instead of formatting the input")
+	setPath    = commandLine.String("set", "", "set the value at a key path, as in `path=value`, changing nothing else, and print the result or write it with -w")
+	mergeMode  = commandLine.Bool("merge", false, "merge the input files in order, each overriding the ones before, and print the result resolved as with -resolve, instead of formatting them")
+	mergeGlob  = commandLine.String("merge-glob", "", "merge the files matching this glob `pattern`, such as 'conf.d/*.conf', in sorted order, as -merge merges its input files")
+	canonJSON  = commandLine.Bool("json-canonical", false, "print the input resolved as with -resolve, as JSON with sorted keys and canonical numbers on one line, for hashing configurations, instead of formatting it")
+	canonForm  = commandLine.Bool("canonical", false, "print the input in a canonical form, merged, sorted and without comments, for hashing and comparing configurations, instead of formatting it")
+)
+
+const (
+	tabWidth    = 4
+	printerMode = printer.UseSpaces
+)
+
+// version is the version printed by -version, as Main is given it.
+var version = "devel"
+
+// commandLine holds the flags of the command. They are not those of
+// package flag, so that programs importing the library do not get them.
+var commandLine = flag.NewFlagSet("hoconfmt", flag.ExitOnError)
+
+var (
+	fileSet  = token.NewFileSet() // per process FileSet
+	exitCode = 0
+)
+
+func report(err error) {
+	reportTo(os.Stderr, err)
+}
+
+// reportTo writes err to w and sets the exit status for errors.
+func reportTo(w io.Writer, err error) {
+	printErrors(w, err)
+	setExitCode(2)
+}
+
+// exitMu guards exitCode, which the files formatted at the same time
+// all set.
+var exitMu sync.Mutex
+
+// setExitCode raises the exit status to code. A higher status is kept,
+// so that an error (2) is not hidden by a file found unformatted (1).
+func setExitCode(code int) {
+	exitMu.Lock()
+	if code > exitCode {
+		exitCode = code
+	}
+	exitMu.Unlock()
+}
+
+// printErrors writes err to w. Unless -e is set, the errors of a list
+// are cut down to the first on each line, and to the first -max-errors
+// of those, followed by a count of the ones left out.
+func printErrors(w io.Writer, err error) {
+	list, ok := err.(errorList)
+	if !ok || *allErrors {
+		printError(w, err)
+		return
+	}
+	list.RemoveMultiples()
+	more := 0
+	if *maxErrors > 0 && len(list) > *maxErrors {
+		more = len(list) - *maxErrors
+		list = list[:*maxErrors]
+	}
+	printError(w, list)
+	if more > 0 {
+		fmt.Fprintf(w, "... and %d more errors\n", more)
+	}
+}
+
+// withFilename sets the file name of the positions in a scanner error
+// list, which are produced without one.
+func withFilename(err error, filename string) error {
+	if list, ok := err.(errorList); ok {
+		for _, e := range list {
+			e.Pos.Filename = filename
+		}
+	}
+	return err
+}
+
+// resolveEnv returns what -resolve resolves documents against, or nil
+// without -resolve.
+func resolveEnv() *environment {
+	if !*resolve {
+		return nil
+	}
+	return flagEnvironment()
+}
+
+// flagEnvironment returns what documents are resolved against: the
+// -include-path directories and the environment of the process, with
+// type warnings printed on standard error unless -quiet is set, and
+// errors with -strict.
+func flagEnvironment() *environment {
+	env := &environment{dirs: includePath(*inclPath), lookup: os.LookupEnv, strict: *strict}
+	if !*quiet {
+		env.warn = warnFunc(os.Stderr)
+	}
+	return env
+}
+
+// debugFlags are left out of the help output unless -debug is set.
+var debugFlags = map[string]bool{"print-ast": true}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: hoconfmt [flags] [path...]\n")
+	visible := flag.NewFlagSet("hoconfmt", flag.ContinueOnError)
+	visible.SetOutput(commandLine.Output())
+	commandLine.VisitAll(func(f *flag.Flag) {
+		if *debug || !debugFlags[f.Name] {
+			visible.Var(f.Value, f.Name, f.Usage)
+			visible.Lookup(f.Name).DefValue = f.DefValue
+		}
+	})
+	visible.PrintDefaults()
+	os.Exit(2)
+}
+
+// isConfFile reports whether f is a file to format in a directory: one
+// whose name ends in .conf or a suffix of -ext and does not start with
+// a dot.
+func isConfFile(f os.FileInfo) bool {
+	name := f.Name()
+	if f.IsDir() || strings.HasPrefix(name, ".") {
+		return false
+	}
+	for _, s := range confSuffixes() {
+		if strings.HasSuffix(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// processFile formats filename, or the contents of in if it isn't nil,
+// and writes the result to out. A non-nil in is standard input, which
+// cannot be rewritten in place. Warnings go to standard error.
+func processFile(filename string, in io.Reader, out io.Writer) error {
+	return processFileTo(filename, in, out, os.Stderr)
+}
+
+// processFileTo is processFile writing warnings and the errors it goes
+// on after to errOut.
+func processFileTo(filename string, in io.Reader, out, errOut io.Writer) error {
+	if in != nil && *write {
+		return errors.New("cannot use -w with standard input")
+	}
+	if in == nil {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	src, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	opts, err := fileOptions(filename)
+	if err != nil {
+		return err
+	}
+
+	if *printTree {
+		if err := printAST(out, src); err != nil {
+			return withFilename(err, filename)
+		}
+		return nil
+	}
+
+	if *getPath != "" {
+		res, err := printValue(filename, src, *getPath, resolveEnv(), opts, *printJSON)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(res)
+		return err
+	}
+
+	if *setPath != "" {
+		path, value, err := splitAssignment(*setPath)
+		if err != nil {
+			return err
+		}
+		res, err := setValue(src, path, value, valueIndent(opts))
+		if _, ok := err.(errorList); ok {
+			return withFilename(err, filename)
+		} else if err != nil {
+			return fmt.Errorf("%s: %v", filename, err)
+		}
+		if !*write {
+			_, err = out.Write(res)
+			return err
+		}
+		if bytes.Equal(src, res) {
+			return nil
+		}
+		return writeChanged(filename, src, res)
+	}
+
+	if *resolve {
+		res, err := printResolved(filename, src, resolveEnv(), opts, *printJSON)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(res)
+		return err
+	}
+
+	if *printJSON {
+		res, err := toJSON(src, valueIndent(opts))
+		if err != nil {
+			return withFilename(err, filename)
+		}
+		_, err = out.Write(res)
+		return err
+	}
+
+	if *printProps {
+		res, err := toProperties(src, *propsSep)
+		if err != nil {
+			return withFilename(err, filename)
+		}
+		_, err = out.Write(res)
+		return err
+	}
+
+	if *canonJSON {
+		res, err := canonicalJSON(filename, src, flagEnvironment())
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(res)
+		return err
+	}
+
+	if *canonForm {
+		res, err := canonical(src)
+		if err != nil {
+			return withFilename(err, filename)
+		}
+		_, err = out.Write(res)
+		return err
+	}
+
+	if *showStats {
+		st, err := collectStats(src)
+		if err != nil {
+			return withFilename(err, filename)
+		}
+		st.File = filename
+		return printStats(out, st)
+	}
+
+	if *blameKeys {
+		entries, err := blameConfig(filename, src)
+		if err != nil {
+			return err
+		}
+		return printBlame(out, filename, entries)
+	}
+
+	if *compareTo != "" {
+		other, err := ioutil.ReadFile(*compareTo)
+		if err != nil {
+			return err
+		}
+		a, err := flattenConfig(src)
+		if err != nil {
+			return withFilename(err, filename)
+		}
+		b, err := flattenConfig(other)
+		if err != nil {
+			return withFilename(err, *compareTo)
+		}
+		changes := compareConfigs(a, b)
+		if len(changes) > 0 {
+			setExitCode(1)
+		}
+		return printCompare(out, compareReport{filename, *compareTo, changes})
+	}
+
+	if *showTodos {
+		todos, err := findTodos(src, todoMarkers(*markers))
+		if err != nil {
+			return withFilename(err, filename)
+		}
+		return printTodos(out, todoReport{filename, todos})
+	}
+
+	if *lintSeps {
+		l, err := lintStyles(src)
+		if err != nil {
+			return withFilename(err, filename)
+		}
+		l.File = filename
+		if l.mixed() {
+			setExitCode(1)
+		}
+		return printLint(out, l)
+	}
+
+	if *checkIncl {
+		missing, err := missingIncludes(filename, src, includePath(*inclPath))
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			setExitCode(1)
+		}
+		return printIncludes(out, includeReport{filename, missing})
+	}
+
+	if *checkSubst {
+		substs, err := undefinedSubsts(filename, src)
+		if err != nil {
+			return err
+		}
+		for _, u := range substs {
+			if u.broken() {
+				setExitCode(1)
+			}
+		}
+		return printSubsts(out, substReport{filename, substs})
+	}
+
+	opts.Warnings = errOut
+	if in != nil {
+		if opts, err = stdinOptions(src, *stdinFormat, opts); err != nil {
+			return withFilename(err, filename)
+		}
+	}
+	if *split {
+		opts.RootBraces = rootBracesOmit
+	}
+	if *checkMode {
+		r := checkFormat(filename, src, opts, *checkDiff)
+		switch {
+		case r.failed():
+			setExitCode(2)
+		case !r.Formatted:
+			setExitCode(1)
+		}
+		return printCheck(out, r)
+	}
+	if !*list && !*write && !*doDiff && !*diffStat && !*jsonDiff && !*split && !*bestEff && !*banners && *indexFile == "" && *outFile == "" {
+		// Only standard output gets the result, so it need not be held
+		// in memory to compare or write it elsewhere.
+		return streamFile(out, filename, src, opts)
+	}
+	res, err := formatFile(filename, src, opts)
+	if err != nil && *bestEff {
+		// Report the syntax errors, but go on with what could be
+		// formatted.
+		var ok bool
+		if res, ok = formatParseable(filename, src, opts); ok {
+			reportTo(errOut, withFilename(err, filename))
+			err = nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if *indexFile != "" {
+		if err := writeKeyIndex(*indexFile, res); err != nil {
+			return withFilename(err, filename)
+		}
+	}
+
+	if *jsonDiff {
+		return printJSONDiff(out, filename, src, res)
+	}
+
+	if *split {
+		if res, err = writeSplit(filename, res); err != nil {
+			return withFilename(err, filename)
+		}
+	}
+
+	if !bytes.Equal(src, res) {
+		// formatting has changed
+		if *list || *doDiff || *diffStat {
+			// Let scripts and CI tell that a file needs formatting.
+			setExitCode(1)
+		}
+		if *list {
+			fmt.Fprintln(out, filename)
+		}
+		if *write {
+			if err := writeChanged(filename, src, res); err != nil {
+				return err
+			}
+		}
+		if *diffStat {
+			c := &diffCounter{w: ioutil.Discard}
+			if err := diff(c, filename, src, res); err != nil {
+				return fmt.Errorf("computing diff: %s", err)
+			}
+			fmt.Fprintf(out, "%s: %s\n", filename, c)
+		}
+		if *doDiff {
+			fmt.Fprintf(out, "diff %s %s\n", filename, filepath.Join("hoconfmt", filename))
+			c := &diffCounter{w: out}
+			if err := diff(c, filename, src, res); err != nil {
+				return fmt.Errorf("computing diff: %s", err)
+			}
+			if !*quiet {
+				fmt.Fprintf(errOut, "%s: %s\n", filename, c)
+			}
+		}
+	}
+
+	if !*list && !*write && !*doDiff && !*diffStat {
+		if *outFile != "" {
+			return writeOutFile(*outFile, res)
+		}
+		if *banners {
+			if _, err := fmt.Fprintf(out, "%s\n", banner(filename)); err != nil {
+				return err
+			}
+		}
+		_, err = out.Write(res)
+	}
+	return err
+}
+
+// banner returns the comment line -banners writes before the output of
+// filename. It is a single # comment even if the name holds a newline.
+func banner(filename string) string {
+	return "# ===== " + strings.NewReplacer("\n", "\\n", "\r", "\\r").Replace(filename) + " ====="
+}
+
+func writeOutFile(filename string, res []byte) error {
+	if *mkdirs {
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(filename, res)
+}
+
+// writeBackup saves src, the original contents of filename, next to it
+// under the -backup-suffix name. An existing backup is only replaced
+// with -backup-overwrite, so a second run cannot lose the original.
+// The backup has the permissions of filename.
+func writeBackup(filename string, src []byte) error {
+	name := filename + *backupExt
+	perm := os.FileMode(0644)
+	if fi, err := os.Stat(filename); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	mode := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !*backupOvr {
+		mode |= os.O_EXCL
+	}
+	f, err := os.OpenFile(name, mode, perm)
+	if os.IsExist(err) {
+		return fmt.Errorf("%s: backup %s already exists (use -backup-overwrite to replace it)", filename, name)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(src); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// startProfiles starts the CPU profile asked for by -cpuprofile. The
+// returned function stops it and writes the memory profile asked for by
+// -memprofile; it must be called once processing is done.
+func startProfiles() (stop func() error, err error) {
+	var cpu *os.File
+	if *cpuProfile != "" {
+		if cpu, err = os.Create(*cpuProfile); err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(cpu); err != nil {
+			cpu.Close()
+			return nil, err
+		}
+	}
+	return func() error {
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			if err := cpu.Close(); err != nil {
+				return err
+			}
+		}
+		if *memProfile == "" {
+			return nil
+		}
+		f, err := os.Create(*memProfile)
+		if err != nil {
+			return err
+		}
+		runtime.GC() // get up-to-date statistics
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}, nil
+}
+
+// formatFile formats the contents src of filename according to opts.
+// Lines may end in LF, CRLF or a lone CR; the output ends them as -eol
+// says.
+func formatFile(filename string, src []byte, opts Options) ([]byte, error) {
+	lx := &lexer{}
+	res, crlf, err := formatLines(lx, filename, src, opts)
+	if err != nil {
+		return nil, err
+	}
+	res, err = setEOL(lx, res, crlf)
+	if err != nil {
+		return nil, withFilename(err, filename)
+	}
+	return res, nil
+}
+
+// formatLines does the work of formatFile but for the line endings: it
+// returns the result with its lines ending as they come, and whether
+// they are to end in CRLF. The passes share the tokens they split
+// through lx.
+func formatLines(lx *lexer, filename string, src []byte, opts Options) ([]byte, bool, error) {
+	src = splitLoneCRs(src)
+	warn := warnFunc(opts.Warnings)
+	if opts.Touch {
+		res, err := touch(src)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+		res, err = restoreOffRegions(lx, src, res)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+		return res, useCRLF(src, opts.EOL), nil
+	}
+
+	var err error
+	cfg := printer.Config{Mode: printerMode, Tabwidth: tabWidth}
+	if opts.EditorConfig {
+		cfg, err = editorConfigFor(filename, cfg)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	switch {
+	case opts.Indent == "\t":
+		cfg.Mode &^= printer.UseSpaces
+	case opts.Indent != "":
+		cfg.Mode |= printer.UseSpaces
+		cfg.Tabwidth = len(opts.Indent)
+	}
+	indent := "\t"
+	if cfg.Mode&printer.UseSpaces != 0 {
+		indent = strings.Repeat(" ", cfg.Tabwidth)
+	}
+
+	width := opts.Width
+	if width == 0 {
+		width = defaultWidth
+	}
+
+	var res []byte
+	if opts.FromProperties {
+		res, err = propertiesToHOCON(src, cfg)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s:%s", filename, err)
+		}
+	} else {
+		if opts.FixBraces {
+			fixed, pos, err := fixBraces(src, cfg.Tabwidth)
+			if err != nil {
+				return nil, false, withFilename(err, filename)
+			}
+			if pos.IsValid() {
+				pos.Filename = filename
+				warn(warning{pos, "{ is not closed; adding } at the end of the file", codeSyntax})
+			}
+			src = fixed
+		}
+		fixed, fixes, err := fixCommas(lx, src)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+		if len(fixes) > 0 {
+			if !opts.FixCommas {
+				return nil, false, withFilename(fixes.Err(), filename)
+			}
+			for _, f := range fixes {
+				f.Pos.Filename = filename
+				warn(warning{f.Pos, "removing " + f.Msg, f.Code})
+			}
+			src = fixed
+		}
+		if opts.InlineIncludes > 0 {
+			if src, err = inlineIncludes(filename, src, opts.InlineIncludes, includePath(opts.IncludePath), indent); err != nil {
+				return nil, false, withFilename(err, filename)
+			}
+		}
+		if opts.Rewrite != "" {
+			rules, err := parseRewriteRules(opts.Rewrite)
+			if err != nil {
+				return nil, false, err
+			}
+			if src, err = rewriteKeys(src, rules); err != nil {
+				return nil, false, withFilename(err, filename)
+			}
+		}
+		if opts.KeyCase != "" && opts.KeyCase != keyCasePreserve {
+			if src, err = setKeyCase(src, opts.KeyCase); err != nil {
+				return nil, false, withFilename(err, filename)
+			}
+		}
+		if opts.Simplify {
+			// The value is verified from here on, so fields that
+			// simplify drops are verified on their own.
+			simple, err := simplify(src, opts.KeyPaths)
+			if err != nil {
+				return nil, false, withFilename(err, filename)
+			}
+			if opts.Verify {
+				if err := checkValue(src, simple); err != nil {
+					return nil, false, withFilename(err, filename)
+				}
+			}
+			src = simple
+		} else if opts.KeyPaths != "" && opts.KeyPaths != keyPathsKeep {
+			if src, err = setKeyPaths(src, opts.KeyPaths); err != nil {
+				return nil, false, withFilename(err, filename)
+			}
+		}
+		if opts.UnicodeNFC || opts.UnicodeNFCStrings {
+			if src, err = normalizeUnicode(src, opts.UnicodeNFCStrings); err != nil {
+				return nil, false, withFilename(err, filename)
+			}
+		}
+		if !opts.Strict {
+			comments, err := blockComments(lx, src)
+			if err != nil {
+				return nil, false, withFilename(err, filename)
+			}
+			for _, c := range comments {
+				c.Pos.Filename = filename
+				warn(warning{c.Pos, c.Msg + "; keeping it as text", c.Code})
+			}
+		}
+		if err := checkRoot(lx, src); err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+		if opts.Strict {
+			if err := checkStrict(src); err != nil {
+				return nil, false, withFilename(err, filename)
+			}
+		}
+		if err := checkSyntax(lx, src); err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+		if opts.WarnDuplicates {
+			if err := warnDuplicates(warn, filename, src); err != nil {
+				return nil, false, err
+			}
+		}
+		if opts.WarnConcat {
+			if err := warnConcats(warn, filename, src); err != nil {
+				return nil, false, err
+			}
+		}
+		res, err = joinSeparators(lx, src)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+		res, err = quoteIncludes(lx, res)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+		res, err = normalizeSpaces(lx, res)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+		if opts.Reprint {
+			res, err = reprint(res, indent, width, cfg.Tabwidth)
+			if err != nil {
+				return nil, false, withFilename(err, filename)
+			}
+		} else {
+			res, err = format(lx, res, cfg)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+		if err := checkContentTabs(lx, warn, filename, src, res); err != nil {
+			return nil, false, err
+		}
+		res, err = tightenPaths(lx, warn, filename, res)
+		if err != nil {
+			return nil, false, err
+		}
+		if opts.NormalizeAll {
+			if res, err = spaceSeparators(lx, res); err != nil {
+				return nil, false, withFilename(err, filename)
+			}
+			if res, err = spaceComments(lx, res); err != nil {
+				return nil, false, withFilename(err, filename)
+			}
+		}
+	}
+
+	// -normalize-all quotes nothing, so substitutions in keys stay as
+	// they are written.
+	if !opts.NormalizeAll {
+		res, err = quoteSubstKeys(lx, res)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	// Hoisting comments brings the values written after them to their
+	// separators, where the passes below find them.
+	res, err = hoistValueComments(lx, res)
+	if err != nil {
+		return nil, false, withFilename(err, filename)
+	}
+
+	if opts.TrimSubsts {
+		res, err = trimSubsts(res)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	if opts.SnapshotEnv {
+		res, err = snapshotEnv(res, os.LookupEnv)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	res, err = quoteReserved(lx, res, opts.UnitClassifier)
+	if err != nil {
+		return nil, false, withFilename(err, filename)
+	}
+
+	if opts.FixQuotes {
+		res, err = fixQuotes(res, opts.UnitClassifier)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	broken, err := breakFields(lx, res)
+	if err != nil {
+		return nil, false, withFilename(err, filename)
+	}
+	if !bytes.Equal(broken, res) && !opts.FromProperties && !opts.Reprint {
+		// The lines after a field moved onto a line of its own are
+		// indented by what encloses it there.
+		if broken, err = format(lx, broken, cfg); err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+	res = broken
+
+	res, err = expandMultilineObjects(lx, res, indent)
+	if err != nil {
+		return nil, false, withFilename(err, filename)
+	}
+
+	res, err = reindentArrays(lx, res, indent)
+	if err != nil {
+		return nil, false, withFilename(err, filename)
+	}
+
+	if !opts.KeepJSONValues {
+		res, err = hoconizeJSON(lx, res)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	res, err = normalizeObjectEq(lx, res, opts.ObjectEq)
+	if err != nil {
+		return nil, false, withFilename(err, filename)
+	}
+
+	if opts.Separators != "" && opts.Separators != separatorsKeep {
+		res, err = setSeparators(res, opts.Separators)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	res, err = normalizeEmpty(lx, res, opts.Empty)
+	if err != nil {
+		return nil, false, withFilename(err, filename)
+	}
+
+	blank := opts.MaxBlank
+	if blank == 0 {
+		blank = defaultMaxBlank
+	} else if blank < 0 {
+		blank = 0
+	}
+	res, err = limitBlankLines(lx, res, blank)
+	if err != nil {
+		return nil, false, withFilename(err, filename)
+	}
+
+	if opts.ContinuationIndent != "" {
+		res, err = wrapArrays(res, opts.ContinuationIndent, width, cfg.Tabwidth, indent)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	if opts.ArrayPerLine != 0 {
+		res, err = regroupArrays(res, opts.ArrayPerLine, width, cfg.Tabwidth)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	if opts.ArrayColumns > 0 {
+		res, err = columnArrays(res, opts.ArrayColumns, indent)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	if opts.Commas == commasSmart {
+		res, err = smartCommas(lx, res)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	if opts.Comments != "" && opts.Comments != commentsPreserve {
+		res, err = setCommentMarkers(res, opts.Comments)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	if opts.WrapComments {
+		res, err = wrapComments(res, width, cfg.Tabwidth)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	if opts.AlignComments {
+		res, err = alignComments(res, cfg.Tabwidth, opts.AlignThreshold)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	if opts.IncludeSpacing {
+		res, err = spaceIncludes(res)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	if opts.RootBraces != "" {
+		res, err = setRootBraces(res, opts.RootBraces, indent)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	if opts.NormalizeAll {
+		// touch removes trailing white space and blank lines at the
+		// end; setEOL puts back CRLF line endings if they are wanted.
+		res, err = touch(res)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	if globs := preserveGlobs(opts.Preserve); len(globs) > 0 && !opts.FromProperties {
+		res, err = restorePreserved(src, res, globs)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	if opts.ModifiedBlocksOnly {
+		res, err = restoreCanonicalBlocks(src, res)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	if !opts.FromProperties {
+		res, err = restoreOffRegions(lx, src, res)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+		if opts.RootBraces == rootBracesRequire {
+			res, err = closeRootAfterOff(src, res)
+			if err != nil {
+				return nil, false, withFilename(err, filename)
+			}
+		}
+	}
+
+	// Sorting and filtering change the value on purpose, so the result
+	// is verified before them.
+	if opts.Verify && !opts.FromProperties {
+		want, err := verifyInput(filename, src, opts.SnapshotEnv)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := checkValue(want, res); err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	// Transformed values change on purpose too.
+	if opts.ValueTransformer != nil {
+		res, err = transformValues(res, opts.ValueTransformer)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	// Sorting and filtering come last, so that the passes that copy
+	// parts of src find the same keys in res.
+	if opts.SortArrays != "" {
+		sorts, err := parseArraySorts(opts.SortArrays)
+		if err != nil {
+			return nil, false, err
+		}
+		res, err = sortArrays(warn, filename, res, sorts)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+	if opts.SortKeys {
+		first, err := parseSortFirst(opts.SortFirst)
+		if err != nil {
+			return nil, false, err
+		}
+		res, err = sortKeys(res, first)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+	if opts.IncludeKeys != "" || opts.ExcludeKeys != "" {
+		res, err = filterKeys(res, keyFilter{preserveGlobs(opts.IncludeKeys), preserveGlobs(opts.ExcludeKeys), opts.KeepEmpty})
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+		res, err = normalizeEmpty(lx, res, opts.Empty)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+		res, err = limitBlankLines(lx, res, blank)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	if opts.NoLoseComments {
+		if err := checkComments(src, res, opts.FromProperties); err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	if opts.Verify && (opts.ValueTransformer != nil || opts.UnitClassifier != nil) {
+		if err := checkIdempotent(filename, res, opts); err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	if opts.Explain {
+		res, err = explainTypes(res, opts.UnitClassifier)
+		if err != nil {
+			return nil, false, withFilename(err, filename)
+		}
+	}
+
+	return res, useCRLF(src, opts.EOL), nil
+}
+
+// Main runs the hoconfmt command, reporting v as its version, and
+// exits with its status.
+func Main(v string) {
+	version = v
+	// call hoconfmtMain in a separate function
+	// so that it can use defer and have them
+	// run before the exit.
+	hoconfmtMain()
+	os.Exit(exitCode)
+}
+
+func hoconfmtMain() {
+	commandLine.Usage = usage
+	commandLine.Parse(os.Args[1:])
+
+	if *showVers {
+		fmt.Printf("hoconfmt %s %s %s/%s\n", version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+		return
+	}
+	if *selfTest {
+		if !runSelfTest(os.Stdout) {
+			exitCode = 1
+		}
+		return
+	}
+
+	if *diffLines < 0 {
+		report(fmt.Errorf("invalid -diff-context %d: must not be negative", *diffLines))
+		return
+	}
+	if *arrayPerLine < -1 {
+		report(fmt.Errorf("invalid -array-per-line %d: must be -1 or more", *arrayPerLine))
+		return
+	}
+	if *arrayColumns < 0 {
+		report(fmt.Errorf("invalid -array-columns %d: must not be negative", *arrayColumns))
+		return
+	}
+	if *maxBlank < 0 {
+		report(fmt.Errorf("invalid -max-blank %d: must not be negative", *maxBlank))
+		return
+	}
+	if *maxErrors < 0 {
+		report(fmt.Errorf("invalid -max-errors %d: must not be negative", *maxErrors))
+		return
+	}
+	if *parallel < 1 {
+		report(fmt.Errorf("invalid -j %d: must be 1 or more", *parallel))
+		return
+	}
+	if *rewriteFile != "" {
+		if err := rewriteRules.load(*rewriteFile); err != nil {
+			report(err)
+			return
+		}
+	}
+	if err := checkExcludes(); err != nil {
+		report(err)
+		return
+	}
+	if err := checkProfile(*profile); err != nil {
+		report(err)
+		return
+	}
+	if err := checkIndentFlags(); err != nil {
+		report(err)
+		return
+	}
+	if err := flagOptions().check(); err != nil {
+		report(err)
+		return
+	}
+	if err := checkStdinFormat(*stdinFormat); err != nil {
+		report(err)
+		return
+	}
+	if *fromProperties && *write {
+		report(errors.New("cannot use -w with -from-properties; use -o to name the output file"))
+		return
+	}
+	if *jsonDiff && (*list || *write || *doDiff) {
+		report(errors.New("cannot use -json-diff with -l, -w or -d"))
+		return
+	}
+	if *diffStat && (*list || *write || *doDiff || *jsonDiff || *split) {
+		report(errors.New("cannot use -stat with -l, -w, -d, -json-diff or -split"))
+		return
+	}
+	if *split && (*list || *write || *doDiff || *jsonDiff) {
+		report(errors.New("cannot use -split with -l, -w, -d or -json-diff"))
+		return
+	}
+	if *split && commandLine.NArg() > 1 {
+		report(errors.New("cannot use -split with multiple input files"))
+		return
+	}
+	if *indexFile != "" && commandLine.NArg() > 1 {
+		report(errors.New("cannot use -index with multiple input files"))
+		return
+	}
+	if *backup {
+		if !*write {
+			report(errors.New("cannot use -backup without -w"))
+			return
+		}
+		if *backupExt == "" {
+			report(errors.New("invalid -backup-suffix: must not be empty"))
+			return
+		}
+	}
+	if (*mergeMode || *mergeGlob != "") && (*list || *write || *doDiff || *jsonDiff || *split) {
+		report(errors.New("cannot use -merge or -merge-glob with -l, -w, -d, -json-diff or -split"))
+		return
+	}
+	if *outFile != "" {
+		if *write {
+			report(errors.New("cannot use -o with -w"))
+			return
+		}
+		if commandLine.NArg() > 1 {
+			report(errors.New("cannot use -o with multiple input files"))
+			return
+		}
+	}
+
+	if *outDir != "" {
+		if *list || *write || *doDiff || *diffStat || *jsonDiff || *split || *outFile != "" {
+			report(errors.New("cannot use -out-dir with -l, -w, -d, -stat, -json-diff, -split or -o"))
+			return
+		}
+		if commandLine.NArg() == 0 {
+			report(errors.New("-out-dir needs a directory or file argument"))
+			return
+		}
+	}
+
+	if *noLoseComments && (*showStats || *blameKeys || *compareTo != "" || *showTodos || *lintSeps || *checkIncl || *checkSubst || *summary || *printTree) {
+		fmt.Fprintln(os.Stderr, "hoconfmt: ignoring -no-lose-comments: reports do not reproduce the input")
+	}
+
+	stop, err := startProfiles()
+	if err != nil {
+		report(err)
+		return
+	}
+	defer func() {
+		if err := stop(); err != nil {
+			report(err)
+		}
+	}()
+
+	if *summary {
+		s := summarizeEncodings(commandLine.Args())
+		if commandLine.NArg() == 0 {
+			src, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				report(err)
+				return
+			}
+			s.add(stdinFilename(), src)
+		}
+		if err := printSummary(os.Stdout, s); err != nil {
+			report(err)
+		}
+		return
+	}
+
+	if *outDir != "" {
+		writeOutDir(commandLine.Args(), *outDir)
+		return
+	}
+
+	if *mergeMode {
+		if commandLine.NArg() == 0 {
+			report(errors.New("-merge needs the files to merge"))
+			return
+		}
+		if *warnDups {
+			for _, w := range mergeTypeChanges(commandLine.Args()) {
+				fmt.Fprintln(os.Stderr, w)
+			}
+		}
+		opts, err := fileOptions(commandLine.Arg(0))
+		if err == nil {
+			err = printMerged(os.Stdout, commandLine.Args(), flagEnvironment(), opts, *printJSON)
+		}
+		if err != nil {
+			report(err)
+		}
+		return
+	}
+	if *mergeGlob != "" {
+		if commandLine.NArg() > 0 {
+			report(errors.New("cannot use -merge-glob with file arguments"))
+			return
+		}
+		filenames, err := globFiles(*mergeGlob)
+		if err == nil && *warnDups {
+			for _, w := range mergeTypeChanges(filenames) {
+				fmt.Fprintln(os.Stderr, w)
+			}
+		}
+		var opts Options
+		if err == nil {
+			opts, err = fileOptions(*mergeGlob)
+		}
+		if err == nil {
+			err = printMerged(os.Stdout, filenames, flagEnvironment(), opts, *printJSON)
+		}
+		if err != nil {
+			report(err)
+		}
+		return
+	}
+
+	if *serveMode {
+		if err := serve(os.Stdin, os.Stdout); err != nil {
+			report(err)
+		}
+		return
+	}
+
+	if *lspMode {
+		if err := serveLSP(os.Stdin, os.Stdout); err != nil {
+			report(err)
+		}
+		return
+	}
+
+	args := commandLine.Args()
+	if *filesFrom != "" {
+		names, err := readFileList(*filesFrom)
+		if err != nil {
+			report(err)
+			return
+		}
+		args = append(args, names...)
+	} else if len(args) == 0 || len(args) == 1 && args[0] == "-" {
+		if err := processFile(stdinFilename(), os.Stdin, os.Stdout); err != nil {
+			report(err)
+		}
+		return
+	}
+
+	var jobs []fileJob
+	for _, path := range args {
+		switch dir, err := os.Stat(path); {
+		case err != nil:
+			jobs = append(jobs, fileJob{err: err})
+		case dir.IsDir():
+			if *outFile != "" {
+				jobs = append(jobs, fileJob{err: fmt.Errorf("cannot use -o with directory %s", path)})
+				continue
+			}
+			if *split {
+				jobs = append(jobs, fileJob{err: fmt.Errorf("cannot use -split with directory %s", path)})
+				continue
+			}
+			if *indexFile != "" {
+				jobs = append(jobs, fileJob{err: fmt.Errorf("cannot use -index with directory %s", path)})
+				continue
+			}
+			jobs = append(jobs, confFiles(path)...)
+		default:
+			jobs = append(jobs, fileJob{path: path})
+		}
+	}
+	defer catchInterrupt()()
+	if *filesFrom != "" {
+		processFileList(os.Stdout, os.Stderr, jobs, *parallel)
+		return
+	}
+	processFiles(os.Stdout, os.Stderr, jobs, *parallel)
+}
+
+// A fileJob is a file to format, or an error met while looking for the
+// files, which is reported in its place.
+type fileJob struct {
+	path string
+	err  error
+}
+
+// confFiles returns the files that isConfFile accepts in the tree
+// rooted at root, in lexical order, leaving out what -exclude skips,
+// and the errors of walking it.
+func confFiles(root string) []fileJob {
+	var jobs []fileJob
+	filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			jobs = append(jobs, fileJob{err: err})
+		case excluded(root, path, f):
+			if f.IsDir() {
+				return filepath.SkipDir
+			}
+		case isConfFile(f):
+			jobs = append(jobs, fileJob{path: path})
+		}
+		return nil
+	})
+	return jobs
+}
+
+// processFiles formats the files of jobs, n at a time, writing their
+// output to stdout and their errors to stderr. The output and the
+// errors of each file are held until those of the files before it are
+// written, so that they come out in the order of jobs whatever the
+// order the files are done in. Once interrupted, by an interrupt signal
+// that catchInterrupt caught, it starts no more files, finishes those
+// it is on, and reports how many were done, with exit status 130.
+func processFiles(stdout, stderr io.Writer, jobs []fileJob, n int) {
+	type result struct {
+		out, errOut bytes.Buffer
+		skipped     bool // left out after an interrupt
+	}
+	done := make([]chan *result, len(jobs))
+	for i := range done {
+		done[i] = make(chan *result, 1)
+	}
+	next := make(chan int)
+	go func() {
+		for i := range jobs {
+			next <- i
+		}
+		close(next)
+	}()
+	if n < 1 {
+		n = 1
+	}
+	for w := 0; w < n; w++ {
+		go func() {
+			for i := range next {
+				r := new(result)
+				if isInterrupted() {
+					r.skipped = true
+					done[i] <- r
+					continue
+				}
+				if err := processJob(jobs[i], &r.out, &r.errOut); err != nil {
+					reportTo(&r.errOut, err)
+				}
+				done[i] <- r
+			}
+		}()
+	}
+	processed := 0
+	for _, c := range done {
+		r := <-c
+		if !r.skipped {
+			processed++
+		}
+		stdout.Write(r.out.Bytes())
+		stderr.Write(r.errOut.Bytes())
+	}
+	if processed < len(jobs) {
+		fmt.Fprintf(stderr, "interrupted: stopped after %d of %d files\n", processed, len(jobs))
+		setExitCode(130)
+	}
+}
+
+// processJob formats the file of job as processFileTo does, or returns
+// the error of job. A panic met while formatting the file becomes its
+// error, so that a bug that one input sets off cannot end the run and
+// leave the other files unformatted.
+func processJob(job fileJob, out, errOut io.Writer) (err error) {
+	if job.err != nil {
+		return job.err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: internal error: %v", job.path, r)
+		}
+	}()
+	return processFileTo(job.path, nil, out, errOut)
+}
+
+// walkDir formats the .conf files in the tree rooted at path. An error
+// in one file is reported and the others are still formatted.
+func walkDir(path string) {
+	processFiles(os.Stdout, os.Stderr, confFiles(path), *parallel)
+}
+
+// diff writes the unified diff of the input b1 of filename and its
+// formatted version b2 to out, with as many lines of context as
+// -diff-context asks for. The input is dated by the modification time
+// of filename, and its formatted version by the current time.
+func diff(out io.Writer, filename string, b1, b2 []byte) error {
+	now := time.Now()
+	mtime := now // of standard input
+	if fi, err := os.Stat(filename); err == nil {
+		mtime = fi.ModTime()
+	}
+	return unifiedDiff(out, filename, filepath.Join("hoconfmt", filename), mtime, now, b1, b2, *diffLines)
+}
+
+// A diffCounter passes a unified diff through to w, counting the lines
+// it inserts and deletes.
+type diffCounter struct {
+	w        io.Writer
+	ins, del int
+	midLine  bool // the last write did not end a line
+	inHunks  bool // past the file headers
+}
+
+func (c *diffCounter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if !c.midLine {
+			switch {
+			case b == '@':
+				c.inHunks = true
+			case b == '+' && c.inHunks:
+				c.ins++
+			case b == '-' && c.inHunks:
+				c.del++
+			}
+		}
+		c.midLine = b != '\n'
+	}
+	return c.w.Write(p)
+}
+
+// String returns the counts the way git diff --stat does.
+func (c *diffCounter) String() string {
+	plural := func(n int, s string) string {
+		if n != 1 {
+			s += "s"
+		}
+		return fmt.Sprintf("%d %s", n, s)
+	}
+	return plural(c.ins, "insertion") + "(+), " + plural(c.del, "deletion") + "(-)"
+}
+
+// format indents every line of src by its nesting depth: lines inside
+// an object or array that is spread over several lines get one unit of
+// cfg more than the line that opens it, and the line holding its
+// closing brace or bracket gets the same as that line. Brackets opened
+// on the same line, as in [{, add a single level, and so do objects
+// whose first field follows the brace on its line, as in a {b = 1. The
+// lines of an array whose first element follows its bracket on the
+// same line, such as the continuation lines of a wrapped array, keep
+// their alignment: they move by as much as the line that opens it.
+// Blank lines and the lines inside multi-line strings are copied
+// verbatim.
+//
+// The first line holding code sets the level of the top of the file,
+// so that an indented fragment stays indented. Its tabs count as one
+// level each; spaces are ignored unless there are no tabs, in which
+// case every Tabwidth spaces count as one level, and fewer as one.
+func format(lx *lexer, src []byte, cfg printer.Config) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	unit := "\t"
+	if cfg.Mode&printer.UseSpaces != 0 {
+		unit = strings.Repeat(" ", cfg.Tabwidth)
+	}
+
+	type open struct {
+		aligned bool   // an array whose first element follows the bracket on its line
+		inner   string // indentation of the lines inside, unless aligned
+		oldLead string // indentation of the opening line in src
+		newLead string // and in the result
+	}
+	var stack []open
+	top := "" // indentation of the top level
+	seen := false
+	inComment := false // inside a /* */ comment, which is kept as text
+	// nest updates the stack for the brackets of code, which is
+	// indented by old in src and by lead in the result.
+	nest := func(code []tok, old, lead string) {
+		for i, t := range code {
+			switch {
+			case t.kind == tokUnquoted:
+				inComment = inBlockComment(t.lit, inComment)
+			case inComment:
+			case t.kind == tokLBrace || t.kind == tokLBracket:
+				o := open{oldLead: old, newLead: lead, inner: lead + unit}
+				o.aligned = t.kind == tokLBracket && !endsLine(code[i+1:]) && !opensLine(code[i+1:])
+				stack = append(stack, o)
+			case t.kind == tokRBrace || t.kind == tokRBracket:
+				if len(stack) > 0 {
+					stack = stack[:len(stack)-1]
+				}
+			}
+		}
+	}
+	res := make([]byte, 0, len(src))
+	for _, l := range splitLines(toks) {
+		if l.blank() || inComment {
+			res = l.appendTo(res)
+			nest(l.toks, leadingSpace(l), leadingSpace(l))
+			continue
+		}
+		old := leadingSpace(l)
+		code := l.toks
+		if old != "" {
+			code = code[1:]
+		}
+		if !seen {
+			seen = true
+			top = strings.Repeat(unit, indentLevel(old, cfg.Tabwidth))
+		}
+
+		lead := top
+		if len(stack) > 0 {
+			o := stack[len(stack)-1]
+			switch {
+			case code[0].kind == tokRBrace || code[0].kind == tokRBracket:
+				lead = o.newLead
+			case !o.aligned:
+				lead = o.inner
+			case strings.HasPrefix(old, o.oldLead):
+				lead = o.newLead + old[len(o.oldLead):]
+			default:
+				lead = old
+			}
+		}
+		res = append(res, lead...)
+		res = srcLine{toks: code}.appendTo(res)
+		nest(code, old, lead)
+	}
+
+	// A file whose last line has no newline gets one; a file of only
+	// white space is left as it is.
+	if seen && len(src) > 0 && src[len(src)-1] != '\n' {
+		res = append(res, '\n')
+	}
+	return res, nil
+}
+
+// indentLevel returns the nesting level of a line indented by lead.
+func indentLevel(lead string, tabwidth int) int {
+	level := strings.Count(lead, "\t")
+	if spaces := strings.Count(lead, " "); level == 0 && spaces > 0 {
+		level = 1
+		if tabwidth > 0 && spaces > tabwidth {
+			level = spaces / tabwidth
+		}
+	}
+	return level
+}
+
+// inBlockComment reports whether a /* */ comment is open after the
+// unquoted text s, given whether one is open before it.
+func inBlockComment(s string, open bool) bool {
+	for {
+		marker := "/*"
+		if open {
+			marker = "*/"
+		}
+		i := strings.Index(s, marker)
+		if i < 0 {
+			return open
+		}
+		open, s = !open, s[i+2:]
+	}
+}
+
+// opensLine reports whether toks starts with brackets that open an
+// object or array ending its line, as the { of [{ does.
+func opensLine(toks []tok) bool {
+	for i, t := range toks {
+		switch t.kind {
+		case tokSpace:
+		case tokLBrace, tokLBracket:
+			return endsLine(toks[i+1:]) || opensLine(toks[i+1:])
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
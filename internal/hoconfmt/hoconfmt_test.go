@@ -0,0 +1,949 @@
+package hoconfmt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+var update = commandLine.Bool("update", false, "update .golden files")
+
+// hoconfmtFlags looks for a comment of the form
+//
+//	//hoconfmt flags
+//
+// within the first maxLines lines of the given file,
+// and returns the flags string, if any. Otherwise it
+// returns the empty string.
+func hoconfmtFlags(filename string, maxLines int) string {
+	f, err := os.Open(filename)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	const prefix = "//hoconfmt "
+	s := bufio.NewScanner(f)
+	for i := 0; i < maxLines && s.Scan(); i++ {
+		if line := s.Text(); strings.HasPrefix(line, prefix) {
+			return strings.TrimSpace(line[len(prefix):])
+		}
+	}
+	return ""
+}
+
+// setFlags sets the command line flags listed in the //hoconfmt
+// comment of filename and returns a function restoring their defaults.
+func setFlags(t *testing.T, filename string) (reset func()) {
+	var set []*flag.Flag
+	reset = func() {
+		for _, f := range set {
+			f.Value.Set(f.DefValue)
+		}
+	}
+	for _, arg := range strings.Fields(hoconfmtFlags(filename, 20)) {
+		elts := strings.SplitN(strings.TrimPrefix(arg, "-"), "=", 2)
+		name, value := elts[0], "true"
+		if len(elts) == 2 {
+			value = elts[1]
+		}
+		f := commandLine.Lookup(name)
+		if f == nil || strings.HasPrefix(name, "test.") || name == "update" {
+			t.Errorf("%s: unrecognized flag name: %s", filename, name)
+			continue
+		}
+		if err := f.Value.Set(value); err != nil {
+			t.Errorf("%s: invalid flag %s: %s", filename, arg, err)
+			continue
+		}
+		set = append(set, f)
+	}
+	return reset
+}
+
+func runTest(t *testing.T, in, out string) {
+	defer setFlags(t, in)()
+
+	var buf bytes.Buffer
+	err := processFile(in, nil, &buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got := buf.Bytes(); !bytes.Equal(got, expected) {
+		if *update {
+			if in != out {
+				if err := ioutil.WriteFile(out, got, 0666); err != nil {
+					t.Error(err)
+				}
+				return
+			}
+			// in == out: don't accidentally destroy input
+			t.Errorf("WARNING: -update did not rewrite input file %s", in)
+		}
+
+		if in == out {
+			t.Errorf("hoconfmt is not idempotent on %s (see %s.hoconfmt)", in, in)
+		} else {
+			t.Errorf("(hoconfmt %s) != %s (see %s.hoconfmt)", in, out, in)
+		}
+		var d bytes.Buffer
+		if err := diff(&d, in, expected, got); err == nil {
+			t.Errorf("%s", d.Bytes())
+		}
+		if err := ioutil.WriteFile(in+".hoconfmt", got, 0666); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestRewrite processes testdata/*.input files and compares them to the
+// corresponding testdata/*.golden files. The hoconfmt flags used to process
+// a file must be provided via a comment of the form
+//
+//	//hoconfmt flags
+//
+// in the processed file within the first 20 lines, if any. Each golden
+// file is then formatted again with its own flags to check that the
+// result is stable.
+func TestRewrite(t *testing.T) {
+	// determine input files
+	match, err := filepath.Glob("testdata/*.input")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, in := range match {
+		out := in // for files where input and output are identical
+		if strings.HasSuffix(in, ".input") {
+			out = in[:len(in)-len(".input")] + ".golden"
+		}
+		runTest(t, in, out)
+		if in != out {
+			// Check idempotence
+			runTest(t, out, out)
+		}
+	}
+}
+
+func TestCRLF(t *testing.T) {
+	const input = "testdata/crlf.input"   // must contain CR/LF's
+	const golden = "testdata/crlf.golden" // must not contain any CR's
+
+	data, err := ioutil.ReadFile(input)
+	if err != nil {
+		t.Error(err)
+	}
+	if bytes.Index(data, []byte("\r\n")) < 0 {
+		t.Errorf("%s contains no CR/LF's", input)
+	}
+
+	data, err = ioutil.ReadFile(golden)
+	if err != nil {
+		t.Error(err)
+	}
+	if bytes.Index(data, []byte("\r")) > 0 {
+		t.Errorf("%s contains CR's", golden)
+	}
+}
+
+// BenchmarkDiff measures diffing a multi-megabyte file with changes
+// scattered throughout, as produced when reformatting generated configs.
+func BenchmarkDiff(b *testing.B) {
+	var b1, b2 bytes.Buffer
+	for i := 0; i < 100000; i++ {
+		fmt.Fprintf(&b1, "key%d = \"some value %d\"\n", i, i)
+		if i%1000 == 0 {
+			fmt.Fprintf(&b2, "key%d=\"some value %d\"\n", i, i)
+		} else {
+			fmt.Fprintf(&b2, "key%d = \"some value %d\"\n", i, i)
+		}
+	}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(b1.Len()))
+	for i := 0; i < b.N; i++ {
+		if err := diff(ioutil.Discard, "bench.conf", b1.Bytes(), b2.Bytes()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestOutFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const input = "testdata/dottedkeys.input"
+	name := filepath.Join(dir, "sub", "out.conf")
+	*outFile = name
+	defer func() { *outFile, *mkdirs = "", false }()
+
+	var buf bytes.Buffer
+	*mkdirs = false
+	if err := processFile(input, nil, &buf); err == nil {
+		t.Errorf("expected error writing %s without -mkdir", name)
+	}
+
+	*mkdirs = true
+	if err := processFile(input, nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("unexpected output on stdout with -o: %q", buf.Bytes())
+	}
+
+	got, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile("testdata/dottedkeys.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("-o wrote:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBanners(t *testing.T) {
+	*banners = true
+	defer func() { *banners = false }()
+
+	files := []string{"testdata/dottedkeys.input", "testdata/samelinefields.input"}
+	var buf bytes.Buffer
+	for _, f := range files {
+		if err := processFile(f, nil, &buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	toks, err := tokenize(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for _, l := range splitLines(toks) {
+		if text := strings.TrimSpace(string(l.bytes())); strings.HasPrefix(text, "# =====") {
+			if !l.commentOnly() {
+				t.Errorf("banner %q is not a comment line", text)
+			}
+			found = append(found, text)
+		}
+	}
+	want := []string{"# ===== testdata/dottedkeys.input =====", "# ===== testdata/samelinefields.input ====="}
+	if strings.Join(found, "\n") != strings.Join(want, "\n") {
+		t.Errorf("banners = %q, want %q", found, want)
+	}
+	if !strings.HasPrefix(buf.String(), want[0]+"\n") {
+		t.Errorf("output does not start with the first banner:\n%s", buf.Bytes())
+	}
+	if err := checkRoot(nil, buf.Bytes()); err != nil {
+		t.Errorf("concatenated output does not parse: %v", err)
+	}
+	if got := banner("a\nb = 1"); strings.Contains(got, "\n") {
+		t.Errorf("banner(%q) = %q spans lines", "a\nb = 1", got)
+	}
+}
+
+func TestBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	orig, err := ioutil.ReadFile("testdata/samelinefields.input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile("testdata/samelinefields.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, "a.conf")
+	bak := name + ".bak"
+	*write, *backup = true, true
+	defer func() { *write, *backup, *backupOvr = false, false, false }()
+
+	format := func() error {
+		if err := ioutil.WriteFile(name, orig, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return processFile(name, nil, ioutil.Discard)
+	}
+	if err := format(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ioutil.ReadFile(name); !bytes.Equal(got, want) {
+		t.Errorf("-w wrote:\n%s\nwant:\n%s", got, want)
+	}
+	if got, _ := ioutil.ReadFile(bak); !bytes.Equal(got, orig) {
+		t.Errorf("-backup saved:\n%s\nwant:\n%s", got, orig)
+	}
+
+	// An existing backup is kept, and the file is not rewritten.
+	if err := format(); err == nil {
+		t.Errorf("no error with existing backup %s", bak)
+	}
+	if got, _ := ioutil.ReadFile(name); !bytes.Equal(got, orig) {
+		t.Errorf("file rewritten although the backup failed")
+	}
+	*backupOvr = true
+	if err := format(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ioutil.ReadFile(bak); !bytes.Equal(got, orig) {
+		t.Errorf("-backup-overwrite saved:\n%s\nwant:\n%s", got, orig)
+	}
+
+	// Files that are already formatted get no backup.
+	os.Remove(bak)
+	if err := processFile(name, nil, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(bak); !os.IsNotExist(err) {
+		t.Errorf("backup written for an unchanged file")
+	}
+}
+
+type fakeFileInfo struct {
+	os.FileInfo
+	name  string
+	isDir bool
+}
+
+func (fi fakeFileInfo) Name() string { return fi.name }
+func (fi fakeFileInfo) IsDir() bool  { return fi.isDir }
+
+func TestProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	*cpuProfile = filepath.Join(dir, "cpu.prof")
+	*memProfile = filepath.Join(dir, "mem.prof")
+	defer func() { *cpuProfile, *memProfile = "", "" }()
+
+	stop, err := startProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := processFile("testdata/dottedkeys.input", nil, ioutil.Discard); err != nil {
+		t.Error(err)
+	}
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{*cpuProfile, *memProfile} {
+		fi, err := os.Stat(name)
+		if err != nil {
+			t.Error(err)
+		} else if fi.Size() == 0 {
+			t.Errorf("%s is empty", name)
+		}
+	}
+}
+
+func TestIndentString(t *testing.T) {
+	for _, test := range []struct {
+		indent string
+		opts   Options
+		src    string
+		want   string
+	}{
+		{"  ", Options{FromProperties: true}, "a.b.c=1\na.d=2\n", "a {\n  b {\n    c = 1\n  }\n  d = 2\n}\n"},
+		{"\t", Options{FromProperties: true}, "a.b.c=1\na.d=2\n", "a {\n\tb {\n\t\tc = 1\n\t}\n\td = 2\n}\n"},
+		{"  ", Options{RootBraces: rootBracesRequire}, "a {\n  b = 1\n}\n", "{\n  a {\n    b = 1\n  }\n}\n"},
+		{"\t", Options{RootBraces: rootBracesRequire}, "a {\n\tb = 1\n}\n", "{\n\ta {\n\t\tb = 1\n\t}\n}\n"},
+		{"  ", Options{RootBraces: rootBracesOmit}, "{\n  a {\n    b = 1\n  }\n}\n", "a {\n  b = 1\n}\n"},
+	} {
+		opts := test.opts
+		opts.Indent = test.indent
+		got, err := formatFile("a.conf", []byte(test.src), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("indent %q, %q: got %q, want %q", test.indent, test.src, got, test.want)
+		}
+		again, err := formatFile("a.conf", got, Options{Indent: test.indent})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(again, got) {
+			t.Errorf("indent %q: formatting %q again gives %q", test.indent, got, again)
+		}
+	}
+}
+
+func TestFinalNewline(t *testing.T) {
+	for _, test := range []struct {
+		src, want string
+	}{
+		{"a = 1", "a = 1\n"},
+		{"include \"a.conf\"", "include \"a.conf\"\n"},
+		{"  a = 1", "    a = 1\n"},
+		{"a = \"x\" # note", "a = \"x\" # note\n"},
+		{"a {\n    b = 1\n}", "a {\n    b = 1\n}\n"},
+		{"a = \"\"\"x\ny\"\"\"", "a = \"\"\"x\ny\"\"\"\n"},
+		{"a = 1\n", "a = 1\n"},
+		{"", ""},
+	} {
+		got, err := formatFile("a.conf", []byte(test.src), DefaultOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("formatFile(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestFormatIndentation(t *testing.T) {
+	spaces := printer.Config{Mode: printer.UseSpaces, Tabwidth: 4}
+	tabs := printer.Config{Tabwidth: 8}
+	for _, test := range []struct {
+		cfg       printer.Config
+		src, want string
+	}{
+		{spaces, "a {\nb {\n    c = 1\n      }\n  }\n", "a {\n    b {\n        c = 1\n    }\n}\n"},
+		{tabs, "a {\n  b = [\n 1\n  ]\n}\n", "a {\n\tb = [\n\t\t1\n\t]\n}\n"},
+		{spaces, "\n  b {\nc = 2\n}\n", "\n    b {\n        c = 2\n    }\n"}, // an indented fragment
+		{spaces, "a {\n  l = [{\n  x = 1\n  }]\n}\n", "a {\n    l = [{\n        x = 1\n    }]\n}\n"},
+		{spaces, "a {\n  b = [1,\n       2]\n}\n", "a {\n    b = [1,\n         2]\n}\n"}, // keeps alignment
+		{spaces, "a {b=1\nc=2\n}\n", "a {b=1\n    c=2\n}\n"},
+		{tabs, "a {\n  b {c = 1\n      d = 2 }\n}\n", "a {\n\tb {c = 1\n\t\td = 2 }\n}\n"},
+		{spaces, "a {\n  s = \"\"\"x\n  y\"\"\"\n}\n", "a {\n    s = \"\"\"x\n  y\"\"\"\n}\n"},
+		{spaces, "a {\n  /* x {\n  y */\n}\n", "a {\n    /* x {\n  y */\n}\n"},
+		{spaces, "a {\n\n  \n b = 1\n}\n", "a {\n\n  \n    b = 1\n}\n"},
+	} {
+		got, err := format(nil, []byte(test.src), test.cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("format(%q) = %q, want %q", test.src, got, test.want)
+		}
+		if again, _ := format(nil, got, test.cfg); string(again) != string(got) {
+			t.Errorf("format(%q) is not idempotent: %q", got, again)
+		}
+	}
+}
+
+func TestIsConfFile(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		{"application.conf", false, true},
+		{"application.json", false, false},
+		{".hidden.conf", false, false},
+		{"dir.conf", true, false},
+		{"C:\\odd\\name.conf", false, true},
+	} {
+		if got := isConfFile(fakeFileInfo{name: test.name, isDir: test.isDir}); got != test.want {
+			t.Errorf("isConfFile(%q, dir=%v) = %v, want %v", test.name, test.isDir, got, test.want)
+		}
+	}
+}
+
+func TestDiffHeader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Use a relative path so the header is predictable on every platform.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	name := filepath.Join("sub", "a.conf")
+	if err := os.Mkdir("sub", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(name, []byte("  a = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	*doDiff = true
+	defer func(code int) { *doDiff = false; exitCode = code }(exitCode)
+	var buf bytes.Buffer
+	if err := processFile(name, nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	header := strings.SplitN(buf.String(), "\n", 2)[0]
+	sep := string(filepath.Separator)
+	want := "diff sub" + sep + "a.conf hoconfmt" + sep + "sub" + sep + "a.conf"
+	if header != want {
+		t.Errorf("diff header = %q, want %q", header, want)
+	}
+}
+
+func TestDiffContext(t *testing.T) {
+	var b1, b2 bytes.Buffer
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&b1, "key%d = %d\n", i, i)
+		if i == 10 {
+			fmt.Fprintf(&b2, "key%d=%d\n", i, i)
+		} else {
+			fmt.Fprintf(&b2, "key%d = %d\n", i, i)
+		}
+	}
+
+	defer func(n int) { *diffLines = n }(*diffLines)
+	for _, n := range []int{0, 1, 3, 5} {
+		*diffLines = n
+		var d bytes.Buffer
+		if err := diff(&d, "context.conf", b1.Bytes(), b2.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		context := 0
+		for _, line := range strings.Split(d.String(), "\n") {
+			if strings.HasPrefix(line, " ") {
+				context++
+			}
+		}
+		if context != 2*n {
+			t.Errorf("-diff-context %d: got %d context lines, want %d\n%s", n, context, 2*n, d.Bytes())
+		}
+	}
+}
+
+func TestDiffCounter(t *testing.T) {
+	b1 := []byte("a = 1\n-- b\nc = 3\nd = 4\n")
+	b2 := []byte("a = 1\nc = 3\n++ x\n+++ y\nd = 4\n")
+	c := &diffCounter{w: ioutil.Discard}
+	if err := diff(c, "counter.conf", b1, b2); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.String(), "2 insertions(+), 1 deletion(-)"; got != want {
+		t.Errorf("counts = %q, want %q", got, want)
+	}
+}
+
+func TestWalkDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const src, want = "a = [ ]", "a = []\n"
+	files := map[string]string{
+		"a.conf":         src,
+		"sub/b.conf":     src,
+		"sub/bad.conf":   "a = }",
+		"sub/c.txt":      src,
+		"sub/.hide.conf": src,
+	}
+	for name, text := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	*write = true
+	defer func(code int) { *write = false; exitCode = code }(exitCode)
+	exitCode = 0
+	walkDir(dir)
+	if exitCode != 2 {
+		t.Errorf("exit code = %d, want 2 for the bad file", exitCode)
+	}
+	for name, text := range files {
+		if name == "a.conf" || name == "sub/b.conf" {
+			text = want // formatted, despite the error in bad.conf
+		}
+		got, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != text {
+			t.Errorf("%s = %q, want %q", name, got, text)
+		}
+	}
+}
+
+func TestProcessFilesOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var jobs []fileJob
+	var wantOut, wantErr strings.Builder
+	for i := 0; i < 50; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("f%02d.conf", i))
+		src := fmt.Sprintf("k%d = [ %s ]", i, strings.Repeat("1, ", i*20))
+		if i%7 == 3 {
+			src = "a = }"
+		}
+		if err := ioutil.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		jobs = append(jobs, fileJob{path: name})
+		if i%7 == 3 {
+			fmt.Fprintf(&wantErr, "%s:", name)
+		} else {
+			fmt.Fprintf(&wantOut, "k%d = [", i)
+		}
+	}
+	jobs = append(jobs, fileJob{err: errors.New("no such file")})
+	wantErr.WriteString("no such file")
+
+	defer func(code int) { exitCode = code }(exitCode)
+	var stdout, stderr bytes.Buffer
+	processFiles(&stdout, &stderr, jobs, 8)
+	got := regexp.MustCompile(`(?m)^k[0-9]+ = \[`).FindAllString(stdout.String(), -1)
+	if s := strings.Join(got, ""); s != wantOut.String() {
+		t.Errorf("output in the order %q, want %q", s, wantOut.String())
+	}
+	got = regexp.MustCompile(`(?m)^(\S+\.conf:|no such file)`).FindAllString(stderr.String(), -1)
+	if s := strings.Join(got, ""); s != wantErr.String() {
+		t.Errorf("errors in the order %q, want %q", s, wantErr.String())
+	}
+}
+
+func TestProcessJobPanic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "a.conf")
+	if err := ioutil.WriteFile(name, []byte("a = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writing the result to a nil writer panics; the panic becomes the
+	// error of the file instead of ending the run.
+	var errOut bytes.Buffer
+	err = processJob(fileJob{path: name}, nil, &errOut)
+	if err == nil || !strings.HasPrefix(err.Error(), name+": internal error: ") {
+		t.Errorf("processJob = %v, want an internal error of %s", err, name)
+	}
+}
+
+func TestListExitCode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	formatted := filepath.Join(dir, "formatted.conf")
+	unformatted := filepath.Join(dir, "unformatted.conf")
+	for name, src := range map[string]string{formatted: "a = 1\n", unformatted: "a = [ ]\n"} {
+		if err := ioutil.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func(code int) { *list, *doDiff, *quiet = false, false, false; exitCode = code }(exitCode)
+	for _, mode := range []*bool{list, doDiff} {
+		*list, *doDiff, *quiet = false, false, true
+		*mode = true
+		for _, test := range []struct {
+			name string
+			code int
+		}{
+			{formatted, 0},
+			{unformatted, 1},
+		} {
+			exitCode = 0
+			var buf bytes.Buffer
+			if err := processFile(test.name, nil, &buf); err != nil {
+				t.Fatal(err)
+			}
+			if exitCode != test.code {
+				t.Errorf("-l=%v -d=%v on %s: exit code = %d, want %d", *list, *doDiff, test.name, exitCode, test.code)
+			}
+			if *list {
+				if want := map[int]string{0: "", 1: unformatted + "\n"}[test.code]; buf.String() != want {
+					t.Errorf("-l on %s printed %q, want %q", test.name, buf.String(), want)
+				}
+			}
+		}
+	}
+}
+
+func TestDiffStat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := []struct{ name, src string }{
+		{"a.conf", "a = [ ]\nb {\n}\n"},
+		{"b.conf", "b = 1\n"}, // formatted already
+		{"c.conf", "c = 1"},
+	}
+	*diffStat = true
+	defer func(code int) { *diffStat = false; exitCode = code }(exitCode)
+	exitCode = 0
+	var buf bytes.Buffer
+	for _, f := range files {
+		name := filepath.Join(dir, f.name)
+		if err := ioutil.WriteFile(name, []byte(f.src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := processFile(name, nil, &buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := filepath.Join(dir, "a.conf") + ": 2 insertions(+), 3 deletions(-)\n" +
+		filepath.Join(dir, "c.conf") + ": 1 insertion(+), 1 deletion(-)\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	if exitCode != 1 {
+		t.Errorf("exit code = %d, want 1", exitCode)
+	}
+}
+
+// BenchmarkFormatLongLine formats a file consisting of a single 1MB
+// line, as found in generated configs, to make sure no step of the
+// formatting is quadratic in the length of a line. It fails if a run
+// allocates more than maxAlloc times the size of the file. A run takes
+// about 80 times, most of it for the tokens, which the passes share,
+// and the syntax tree of the check; a pass that splits the text again
+// adds about 20.
+func BenchmarkFormatLongLine(b *testing.B) {
+	const maxAlloc = 100
+	var src bytes.Buffer
+	src.WriteString("data = [")
+	for i := 0; src.Len() < 1<<20; i++ {
+		fmt.Fprintf(&src, "%d, {id = %d}, ", i, i)
+	}
+	src.WriteString("]\n")
+
+	b.ReportAllocs()
+	b.SetBytes(int64(src.Len()))
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 0; i < b.N; i++ {
+		if _, err := formatFile("long.conf", src.Bytes(), DefaultOptions()); err != nil {
+			b.Fatal(err)
+		}
+	}
+	runtime.ReadMemStats(&after)
+	if perRun := (after.TotalAlloc - before.TotalAlloc) / uint64(b.N); perRun > maxAlloc*uint64(src.Len()) {
+		b.Errorf("a run allocates %d bytes, more than %d times the %d bytes of the file", perRun, maxAlloc, src.Len())
+	}
+}
+
+// BenchmarkFormatLargeArray formats an array of a million elements,
+// one per line, as found in generated configs, as the modes that
+// compare or write the result do. Every formatting step holds its whole
+// input and output in memory, so the bytes allocated per operation are
+// a multiple of the file size; this keeps an eye on that multiple.
+func BenchmarkFormatLargeArray(b *testing.B) {
+	src := largeArray()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(src)))
+	for i := 0; i < b.N; i++ {
+		if _, err := formatFile("ids.conf", src, DefaultOptions()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkStreamLargeArray is BenchmarkFormatLargeArray for plain
+// standard output, which streams the elements of the array. It fails if
+// a run allocates more than maxAlloc bytes, however large the array.
+func BenchmarkStreamLargeArray(b *testing.B) {
+	const maxAlloc = 1 << 20
+	src := largeArray()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 0; i < b.N; i++ {
+		if err := streamFile(ioutil.Discard, "ids.conf", src, DefaultOptions()); err != nil {
+			b.Fatal(err)
+		}
+	}
+	runtime.ReadMemStats(&after)
+	if perRun := (after.TotalAlloc - before.TotalAlloc) / uint64(b.N); perRun > maxAlloc {
+		b.Errorf("a run allocates %d bytes, more than %d", perRun, maxAlloc)
+	}
+}
+
+// largeArray returns a document holding an array of a million numbers,
+// one per line.
+func largeArray() []byte {
+	var src bytes.Buffer
+	src.WriteString("ids = [\n")
+	for i := 0; i < 1000000; i++ {
+		fmt.Fprintf(&src, "    %d\n", i)
+	}
+	src.WriteString("]\n")
+	return src.Bytes()
+}
+
+// FuzzFormat formats arbitrary input with the default options. Input
+// that cannot be formatted must be rejected with an error rather than a
+// panic, formatted output must have the value of the input, as -verify
+// compares them, and it must be stable under formatting again. The
+// corpus is seeded with the testdata files, and the inputs it has
+// failed on are kept in testdata/fuzz; run it with
+//
+//	go test -run FuzzFormat -fuzz FuzzFormat
+func FuzzFormat(f *testing.F) {
+	match, err := filepath.Glob("testdata/*.input")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, in := range match {
+		src, err := ioutil.ReadFile(in)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(src)
+	}
+
+	f.Fuzz(func(t *testing.T, src []byte) {
+		res, err := formatFile("fuzz.conf", src, DefaultOptions())
+		if err != nil {
+			return
+		}
+		want, err := verifyInput("fuzz.conf", splitLoneCRs(src), false)
+		if err != nil {
+			t.Fatalf("formatted input does not tokenize: %v", err)
+		}
+		if err := checkValue(want, res); err != nil {
+			t.Fatalf("formatting changes the value: %v\n%q\nformats to\n%q", err, src, res)
+		}
+		again, err := formatFile("fuzz.conf", res, DefaultOptions())
+		if err != nil {
+			t.Fatalf("formatted output does not format again: %v\n%q", err, res)
+		}
+		if !bytes.Equal(again, res) {
+			t.Fatalf("formatting is not idempotent:\n%q\nformats to\n%q", res, again)
+		}
+	})
+}
+
+func TestMaxErrors(t *testing.T) {
+	// Errors on 30 lines, two of them on the first.
+	var list errorList
+	list.Add(token.Position{Filename: "a.conf", Line: 1, Column: 9}, codeSyntax, "unterminated string")
+	for line := 1; line <= 30; line++ {
+		list.Add(token.Position{Filename: "a.conf", Line: line, Column: 5}, codeSyntax, "unterminated string")
+	}
+
+	defer func(max int, all bool) { *maxErrors, *allErrors = max, all }(*maxErrors, *allErrors)
+	for _, test := range []struct {
+		max   int
+		all   bool
+		lines int
+		more  string
+	}{
+		{10, false, 10, "... and 20 more errors\n"},
+		{3, false, 3, "... and 27 more errors\n"},
+		{0, false, 30, ""},
+		{3, true, 31, ""},
+	} {
+		*maxErrors, *allErrors = test.max, test.all
+		var out bytes.Buffer
+		printErrors(&out, append(errorList(nil), list...))
+		got := out.String()
+		if test.more != "" {
+			if !strings.HasSuffix(got, test.more) {
+				t.Errorf("-max-errors %d: output does not end in %q:\n%s", test.max, test.more, got)
+			}
+			got = strings.TrimSuffix(got, test.more)
+		}
+		if n := strings.Count(got, "\n"); n != test.lines {
+			t.Errorf("-max-errors %d -e=%v: %d errors reported, want %d", test.max, test.all, n, test.lines)
+		}
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "a.conf")
+	// A key without a value on each of 12 lines, and a separator without
+	// a key on the last.
+	var src strings.Builder
+	for i := 0; i < 12; i++ {
+		fmt.Fprintf(&src, "k%d\n", i)
+	}
+	src.WriteString("a = 1 = 2\n")
+	if err := ioutil.WriteFile(name, []byte(src.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(max int, all bool, code int) { *maxErrors, *allErrors, exitCode = max, all, code }(*maxErrors, *allErrors, exitCode)
+	for _, test := range []struct {
+		all   bool
+		lines int
+		more  string
+	}{
+		{false, 10, "... and 3 more errors\n"},
+		{true, 13, ""},
+	} {
+		*maxErrors, *allErrors, exitCode = 10, test.all, 0
+		var stdout, stderr bytes.Buffer
+		processFiles(&stdout, &stderr, []fileJob{{path: name}}, 1)
+		got := stderr.String()
+		if !strings.HasPrefix(got, name+":1:1: unexpected k0, expected a key\n") {
+			t.Errorf("-e=%v: errors do not start with the position of the first:\n%s", test.all, got)
+		}
+		if test.all && !strings.HasSuffix(got, name+":13:7: unexpected =, expected a key\n") {
+			t.Errorf("-e: errors do not end with the separator without a key:\n%s", got)
+		}
+		if !strings.HasSuffix(got, test.more) {
+			t.Errorf("-e=%v: errors do not end in %q:\n%s", test.all, test.more, got)
+		}
+		if n := strings.Count(strings.TrimSuffix(got, test.more), "\n"); n != test.lines {
+			t.Errorf("-e=%v: %d errors reported, want %d", test.all, n, test.lines)
+		}
+		if exitCode != 2 {
+			t.Errorf("-e=%v: exit code = %d, want 2", test.all, exitCode)
+		}
+		if stdout.Len() > 0 {
+			t.Errorf("-e=%v: output %q for a file with errors", test.all, stdout.String())
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package hoconfmt
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ignoreFileName is the name of the files listing the files and
+// directories to skip in directories, as -exclude does.
+const ignoreFileName = ".hoconfmtignore"
+
+// An ignoreRule is a line of a .hoconfmtignore file.
+type ignoreRule struct {
+	glob    string
+	negate  bool // the line starts with !, bringing back what it matches
+	dirOnly bool // the glob ends in /, matching only directories
+}
+
+// ignoreRules caches the rules of the .hoconfmtignore file of each
+// directory, as a directory walk reads them many times.
+var ignoreRules = struct {
+	sync.Mutex
+	dirs map[string][]ignoreRule
+}{dirs: map[string][]ignoreRule{}}
+
+// readIgnoreFile returns the rules of the .hoconfmtignore file in dir,
+// or none if there is no such file or it cannot be read. Blank lines
+// and lines starting with # are skipped.
+func readIgnoreFile(dir string) []ignoreRule {
+	ignoreRules.Lock()
+	defer ignoreRules.Unlock()
+	if rules, ok := ignoreRules.dirs[dir]; ok {
+		return rules
+	}
+	var rules []ignoreRule
+	src, err := ioutil.ReadFile(filepath.Join(dir, ignoreFileName))
+	if err == nil {
+		for _, line := range strings.Split(string(src), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			var r ignoreRule
+			if strings.HasPrefix(line, "!") {
+				r.negate, line = true, line[1:]
+			}
+			if strings.HasSuffix(line, "/") {
+				r.dirOnly, line = true, strings.TrimRight(line, "/")
+			}
+			if r.glob = line; r.glob != "" {
+				rules = append(rules, r)
+			}
+		}
+	}
+	ignoreRules.dirs[dir] = rules
+	return rules
+}
+
+// ignored reports whether the file or directory f at p, found while
+// walking the tree rooted at root, is to be skipped by the
+// .hoconfmtignore files of root and of the directories between root
+// and p. As in a .gitignore file, a glob without a slash, such as
+// *.gen.conf, matches the name of a file or directory anywhere below
+// the directory of the ignore file, and one with a slash, such as
+// deploy/*/secrets or /local.conf, the path below that directory, as
+// matchFilePath matches it. A glob ending in / matches directories
+// only, and a line starting with ! brings back what earlier lines, or
+// the files of directories above, skip. The last line that matches
+// decides. A file in a skipped directory cannot be brought back, as the
+// directory is not walked.
+func ignored(root, p string, f os.FileInfo) bool {
+	rel, err := filepath.Rel(root, p)
+	if err != nil || rel == "." {
+		return false
+	}
+	elems := strings.Split(filepath.ToSlash(rel), "/")
+	skip := false
+	dir := root
+	for i := range elems {
+		below := strings.Join(elems[i:], "/")
+		for _, r := range readIgnoreFile(dir) {
+			if r.dirOnly && !f.IsDir() {
+				continue
+			}
+			var ok bool
+			if strings.Contains(r.glob, "/") {
+				ok = matchFilePath(strings.TrimPrefix(r.glob, "/"), below)
+			} else {
+				ok, _ = path.Match(r.glob, f.Name())
+			}
+			if ok {
+				skip = !r.negate
+			}
+		}
+		dir = filepath.Join(dir, elems[i])
+	}
+	return skip
+}
@@ -0,0 +1,56 @@
+package hoconfmt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIgnoreFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		".hoconfmtignore":                "# generated and local files\n*.gen.conf\n/local.conf\nbuild/\n!keep.gen.conf\n",
+		"a.conf":                         "",
+		"a.gen.conf":                     "",
+		"keep.gen.conf":                  "",
+		"local.conf":                     "",
+		"build/out.conf":                 "",
+		"sub/local.conf":                 "",
+		"sub/b.gen.conf":                 "",
+		"sub/.hoconfmtignore":            "!b.gen.conf\ndeploy/*/secrets\n",
+		"sub/deploy/prod/secrets.conf":   "",
+		"sub/deploy/prod/secrets/s.conf": "",
+		"sub/vendor/v.conf":              "",
+	}
+	for name, text := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// With -exclude, both skip files.
+	*exclude = "vendor"
+	defer func() { *exclude = "" }()
+	var got []string
+	for _, j := range confFiles(dir) {
+		if j.err != nil {
+			t.Fatal(j.err)
+		}
+		rel, _ := filepath.Rel(dir, j.path)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := []string{"a.conf", "keep.gen.conf", "sub/b.gen.conf", "sub/deploy/prod/secrets.conf", "sub/local.conf"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
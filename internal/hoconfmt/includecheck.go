@@ -0,0 +1,161 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// includeExts are the extensions looked for when an include target has
+// none, as HOCON reads name.conf, name.json and name.properties for an
+// include of name.
+var includeExts = []string{".conf", ".json", ".properties"}
+
+// A missingInclude is an include statement whose target cannot be
+// found.
+type missingInclude struct {
+	File     string `json:"file"` // holding the statement
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Kind     string `json:"kind"` // "file" or "classpath"
+	Target   string `json:"target"`
+	Required bool   `json:"required"`
+}
+
+// An includeReport is the result of -check-includes for one file.
+type includeReport struct {
+	File    string           `json:"file"`
+	Missing []missingInclude `json:"missing"`
+}
+
+// includePath splits the -include-path list into its directories.
+func includePath(list string) []string {
+	var dirs []string
+	for _, d := range filepath.SplitList(list) {
+		if d != "" {
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+// missingIncludes returns the include statements of src, read from
+// filename, and of the files it includes in turn, whose target cannot
+// be found, in the order they are reached. A file include is looked for
+// relative to the directory of the including file and then in each of
+// dirs; a classpath include only in dirs, which stand for the roots of
+// the class path. A target without an extension is found if any of
+// includeExts added to it is. url(...) includes are not checked.
+func missingIncludes(filename string, src []byte, dirs []string) ([]missingInclude, error) {
+	list := []missingInclude{}
+	seen := map[string]bool{}
+	var check func(filename string, src []byte, depth int) error
+	check = func(filename string, src []byte, depth int) error {
+		if depth > maxIncludeDepth {
+			return fmt.Errorf("%s: includes nested more than %d deep", filename, maxIncludeDepth)
+		}
+		if abs, err := filepath.Abs(filename); err == nil {
+			if seen[abs] {
+				return nil
+			}
+			seen[abs] = true
+		}
+		toks, err := tokenize(src)
+		if err != nil {
+			return withFilename(err, filename)
+		}
+		for _, l := range splitLines(toks) {
+			// Includes inside objects are checked as well.
+			if !isInclude(srcLine{toks: l.toks}) {
+				continue
+			}
+			kind, name, required, ok := includeResource(l)
+			if !ok || kind == "url" {
+				continue
+			}
+			path, found := findInclude(kind, name, filename, dirs)
+			if !found {
+				pos := position(src, l.toks[firstToken(l.toks)].off)
+				list = append(list, missingInclude{filename, pos.Line, pos.Column, kind, name, required})
+				continue
+			}
+			if filepath.Ext(path) == ".properties" {
+				continue
+			}
+			inc, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if err := check(path, inc, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := check(filename, src, 0); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// findInclude returns the file that the include of the resource name of
+// the given kind in the file from refers to, and whether there is one.
+func findInclude(kind, name, from string, dirs []string) (string, bool) {
+	var candidates []string
+	switch {
+	case kind == "file" && filepath.IsAbs(name):
+		candidates = []string{name}
+	case kind == "file":
+		candidates = append(candidates, filepath.Join(filepath.Dir(from), name))
+		fallthrough
+	default:
+		for _, d := range dirs {
+			candidates = append(candidates, filepath.Join(d, strings.TrimPrefix(name, "/")))
+		}
+	}
+	for _, c := range candidates {
+		names := []string{c}
+		if filepath.Ext(c) == "" {
+			names = nil
+			for _, ext := range includeExts {
+				names = append(names, c+ext)
+			}
+		}
+		for _, n := range names {
+			if fi, err := os.Stat(n); err == nil && !fi.IsDir() {
+				return n, true
+			}
+		}
+	}
+	return "", false
+}
+
+// printIncludes writes the report of -check-includes.
+func printIncludes(out io.Writer, r includeReport) error {
+	if *jsonReport {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", data)
+		return err
+	}
+	for _, m := range r.Missing {
+		target := fmt.Sprintf("%q", m.Target)
+		if m.Kind != "file" {
+			target = m.Kind + "(" + target + ")"
+		}
+		what := "include"
+		if m.Required {
+			what = "required include"
+		}
+		if _, err := fmt.Fprintf(out, "%s:%d:%d: %s %s not found\n", m.File, m.Line, m.Column, what, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
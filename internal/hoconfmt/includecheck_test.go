@@ -0,0 +1,86 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMissingIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	lib := filepath.Join(dir, "lib")
+	files := map[string]string{
+		"common.conf":         "include \"nested/gone.conf\"\ninclude \"app\" # a cycle\n",
+		"defaults.json":       "{}\n",
+		"lib/reference.conf":  "a = 1\n",
+		"lib/shared.conf":     "b = 2\n",
+		"local.properties":    "c=3\n",
+		"nested/.placeholder": "",
+	}
+	for name, src := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const src = `include "common.conf"
+include "defaults"
+include file("local.properties")
+include "shared.conf"
+include classpath("reference.conf")
+include required(classpath("missing.conf"))
+include url("http://example.com/x.conf")
+server {
+    include "server-overrides"
+}
+`
+	app := filepath.Join(dir, "app.conf")
+	if err := ioutil.WriteFile(app, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := missingIncludes(app, []byte(src), []string{lib})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []missingInclude{
+		{filepath.Join(dir, "common.conf"), 1, 1, "file", "nested/gone.conf", false},
+		{app, 6, 1, "classpath", "missing.conf", true},
+		{app, 9, 5, "file", "server-overrides", false},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("missing include %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// Without the include path, the classpath and shared includes are
+	// missing too.
+	if got, err = missingIncludes(app, []byte(src), nil); err != nil {
+		t.Fatal(err)
+	} else if len(got) != 5 {
+		t.Errorf("without -include-path: got %d missing includes, want 5: %v", len(got), got)
+	}
+
+	var out bytes.Buffer
+	if err := printIncludes(&out, includeReport{"app.conf", want[1:]}); err != nil {
+		t.Fatal(err)
+	}
+	text := app + ":6:1: required include classpath(\"missing.conf\") not found\n" +
+		app + ":9:5: include \"server-overrides\" not found\n"
+	if out.String() != text {
+		t.Errorf("report:\n%s\nwant:\n%s", out.String(), text)
+	}
+}
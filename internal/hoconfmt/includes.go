@@ -0,0 +1,156 @@
+package hoconfmt
+
+import "strings"
+
+// isInclude reports whether l starts with a top-level include
+// statement.
+func isInclude(l srcLine) bool {
+	if l.depth != 0 {
+		return false
+	}
+	for i, t := range l.toks {
+		if t.kind == tokSpace {
+			continue
+		}
+		if t.kind != tokUnquoted || t.lit != "include" || i+2 >= len(l.toks) || l.toks[i+1].kind != tokSpace {
+			return false
+		}
+		switch next := l.toks[i+2]; next.kind {
+		case tokString:
+			return true
+		case tokUnquoted:
+			for _, fn := range includeFuncs {
+				if strings.HasPrefix(next.lit, fn+"(") {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// spaceIncludes makes sure every run of top-level include statements is
+// separated from the surrounding lines by a blank line. Comment lines
+// directly above an include stay attached to it. Existing blank lines
+// are kept and never doubled.
+func spaceIncludes(src []byte) ([]byte, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	lines := splitLines(toks)
+
+	// Mark the runs of includes, extending each run upwards over the
+	// comments that introduce it.
+	inRun := make([]bool, len(lines))
+	for i, l := range lines {
+		if !isInclude(l) {
+			continue
+		}
+		inRun[i] = true
+		for j := i - 1; j >= 0 && !inRun[j] && lines[j].depth == 0 && lines[j].commentOnly(); j-- {
+			inRun[j] = true
+		}
+	}
+
+	var res []byte
+	for i, l := range lines {
+		if i > 0 && inRun[i] != inRun[i-1] && !l.blank() && !lines[i-1].blank() {
+			res = append(res, '\n')
+		}
+		res = l.appendTo(res)
+	}
+	return res, nil
+}
+
+// includeFuncs are the forms an include statement can wrap its target
+// in.
+var includeFuncs = []string{"required", "file", "url", "classpath"}
+
+// quoteIncludes quotes the target of an include statement written
+// without quotes, as in
+//
+//	include file.conf
+//	include required(file.conf)
+//
+// which some older tools accept. Without the quotes the name would read
+// as a key, and a key without a value is an error. A target holding a
+// quoted string or a substitution, or followed by a separator, as in
+// include = 1, is left alone.
+func quoteIncludes(lx *lexer, src []byte) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, 0, len(src))
+	var open []tokenKind // enclosing { and [
+	atField := true      // at the start of a field in an object
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		inObject := len(open) == 0 || open[len(open)-1] == tokLBrace
+		if atField && inObject && t.kind == tokUnquoted && t.lit == "include" && fieldSep(toks, i) == 0 {
+			if j := unquotedInclude(toks, i); j > 0 {
+				atField = false
+				var target strings.Builder
+				for _, t := range toks[i+2 : j] {
+					target.WriteString(t.lit)
+				}
+				res = append(res, t.lit+toks[i+1].lit+quoteIncludeTarget(target.String())...)
+				i = j - 1
+				continue
+			}
+		}
+
+		switch t.kind {
+		case tokLBrace, tokLBracket:
+			open = append(open, t.kind)
+			atField = t.kind == tokLBrace
+		case tokRBrace, tokRBracket:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			atField = true
+		case tokNewline, tokComma:
+			atField = true
+		case tokSpace, tokComment:
+		default:
+			atField = false
+		}
+		res = append(res, t.lit...)
+	}
+	return res, nil
+}
+
+// unquotedInclude returns the end of the unquoted target of the include
+// statement starting at toks[i], after any white space that follows it,
+// or 0 if the statement has no such target.
+func unquotedInclude(toks []tok, i int) int {
+	if i+2 >= len(toks) || toks[i+1].kind != tokSpace || toks[i+2].kind != tokUnquoted {
+		return 0
+	}
+	j := i + 2
+	for j < len(toks) && (toks[j].kind == tokUnquoted || toks[j].kind == tokSpace) {
+		j++
+	}
+	if j < len(toks) && toks[j].kind != tokNewline && toks[j].kind != tokComment && toks[j].kind != tokComma && toks[j].kind != tokRBrace {
+		return 0
+	}
+	for j > i+2 && toks[j-1].kind == tokSpace {
+		j--
+	}
+	return j
+}
+
+// quoteIncludeTarget quotes the unquoted include target s, keeping the
+// functions it is wrapped in outside the quotes.
+func quoteIncludeTarget(s string) string {
+	for _, fn := range includeFuncs {
+		if strings.HasPrefix(s, fn+"(") && strings.HasSuffix(s, ")") {
+			inner := strings.TrimSpace(s[len(fn)+1 : len(s)-1])
+			return fn + "(" + quoteIncludeTarget(inner) + ")"
+		}
+	}
+	return quoteString(s)
+}
@@ -0,0 +1,87 @@
+package hoconfmt
+
+import "testing"
+
+func TestSpaceIncludes(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		// back-to-back includes stay together
+		{"include \"a.conf\"\ninclude \"b.conf\"\nkey = 1\n",
+			"include \"a.conf\"\ninclude \"b.conf\"\n\nkey = 1\n"},
+		// keys on both sides
+		{"a = 1\ninclude required(file(\"x.conf\"))\nb = 2\n",
+			"a = 1\n\ninclude required(file(\"x.conf\"))\n\nb = 2\n"},
+		// existing blank lines are not doubled
+		{"a = 1\n\ninclude \"x.conf\"\n\nb = 2\n",
+			"a = 1\n\ninclude \"x.conf\"\n\nb = 2\n"},
+		// comments above an include stay attached to it
+		{"a = 1\n# shared settings\ninclude \"x.conf\"\n# about b\nb = 2",
+			"a = 1\n\n# shared settings\ninclude \"x.conf\"\n\n# about b\nb = 2"},
+		// nested includes and keys named include are left alone
+		{"a {\n    include \"x.conf\"\n    b = 1\n}\ninclude = 3\n",
+			"a {\n    include \"x.conf\"\n    b = 1\n}\ninclude = 3\n"},
+		// includes inside multi-line strings are not includes
+		{"s = \"\"\"\ninclude \"x.conf\"\n\"\"\"\nb = 2\n",
+			"s = \"\"\"\ninclude \"x.conf\"\n\"\"\"\nb = 2\n"},
+	} {
+		got, err := spaceIncludes([]byte(test.in))
+		if err != nil {
+			t.Errorf("spaceIncludes(%q): %s", test.in, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("spaceIncludes(%q) =\n%q\nwant\n%q", test.in, got, test.want)
+			continue
+		}
+		// idempotence
+		if again, _ := spaceIncludes(got); string(again) != string(got) {
+			t.Errorf("spaceIncludes is not idempotent on %q: got %q", got, again)
+		}
+	}
+}
+
+func TestQuoteIncludes(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"include file.conf\n", "include \"file.conf\"\n"},
+		{"include conf/app.conf # shared\n", "include \"conf/app.conf\" # shared\n"},
+		{"include required(file.conf)\n", "include required(\"file.conf\")\n"},
+		{"include required(file( a.conf ))\n", "include required(file(\"a.conf\"))\n"},
+		{"a { include b.conf }\n", "a { include \"b.conf\" }\n"},
+		// Quoted targets, keys named include and values are left alone.
+		{"include \"x.conf\"\n", "include \"x.conf\"\n"},
+		{"include required(\"x.conf\")\n", "include required(\"x.conf\")\n"},
+		{"include = 1\ninclude.a = 2\n", "include = 1\ninclude.a = 2\n"},
+		{"a = [include x]\nb = include x\n", "a = [include x]\nb = include x\n"},
+		{"include ${dir}.conf\n", "include ${dir}.conf\n"},
+	} {
+		got, err := quoteIncludes(nil, []byte(test.in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("quoteIncludes(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+// TestUnquotedIncludeKeys checks that the file name of an unquoted
+// include does not end up as a key.
+func TestUnquotedIncludeKeys(t *testing.T) {
+	res, err := formatFile("a.conf", []byte("include file.conf\na = 1\n"), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "include \"file.conf\"\na = 1\n"; string(res) != want {
+		t.Errorf("got %q, want %q", res, want)
+	}
+	cfg, err := flattenConfig(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg) != 1 || cfg["a"] != (flatValue{"number", "1"}) {
+		t.Errorf("keys of %q: %v, want only a", res, cfg)
+	}
+}
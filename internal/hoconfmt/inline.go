@@ -0,0 +1,159 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"fmt"
+	"go/printer"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// inlineIncludes returns src, read from filename, with every top-level
+// include statement whose file is smaller than max bytes replaced by
+// the text of the file, for -inline-includes-under. Files are looked for
+// as -check-includes looks for them, in the directory of the including
+// file and then in dirs; braces around the root object of an inlined
+// file are dropped, and a .properties file is converted as
+// -from-properties converts it. The includes of an inlined file are
+// inlined in turn, and a file in another directory whose own includes
+// are not all inlined is kept as an include statement, since they name
+// files relative to it. A trailing comment of an inlined statement is
+// kept on a line of its own above the text.
+//
+// url(...) includes are kept, and so are files that are not found,
+// unless the include is required(...), which is an error, as is a file
+// that includes itself.
+func inlineIncludes(filename string, src []byte, max int, dirs []string, indent string) ([]byte, error) {
+	res, _, err := inlineFile(filename, src, max, dirs, indent, nil)
+	return res, err
+}
+
+// inlineFile does the work of inlineIncludes and reports whether every
+// include statement of src was inlined. parents holds the absolute
+// paths of the files including src.
+func inlineFile(filename string, src []byte, max int, dirs []string, indent string, parents []string) ([]byte, bool, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, false, err
+	}
+	parents = append(parents[:len(parents):len(parents)], absPath(filename))
+	var errs errorList
+	fail := func(off int, msg string) { errs.Add(position(src, off), codeInclude, msg) }
+
+	// The fields inside braces around the root object are at the top
+	// level too.
+	braced := 0
+	if open, _ := rootBraces(toks); open >= 0 {
+		braced = 1
+	}
+
+	var res []byte
+	all, last := true, 0
+	for _, l := range splitLines(toks) {
+		if l.depth < braced || !isInclude(srcLine{l.toks, l.depth - braced}) {
+			continue
+		}
+		// The statement runs from include to the end of its target, or
+		// to a closing root brace on its line.
+		stmt := l.toks
+		for i, t := range stmt {
+			if t.kind == tokRBrace {
+				stmt = stmt[:i]
+				break
+			}
+		}
+		start, end, comment := -1, -1, ""
+		for _, t := range stmt {
+			switch t.kind {
+			case tokSpace, tokNewline, tokComma:
+			case tokComment:
+				comment = t.lit
+			default:
+				if start < 0 {
+					start = t.off
+				}
+				end = t.off + len(t.lit)
+			}
+		}
+		kind, name, required, ok := includeResource(srcLine{toks: stmt})
+		if !ok || kind == "url" {
+			all = false
+			continue
+		}
+		path, found := findInclude(kind, name, filename, dirs)
+		if !found {
+			if required {
+				fail(start, "cannot find the included file "+name)
+			}
+			all = false
+			continue
+		}
+		text, small, every, err := inlineText(path, max, dirs, indent, parents)
+		if err != nil {
+			fail(start, err.Error())
+			continue
+		}
+		all = all && small && every
+		if !small || !every && filepath.Dir(absPath(path)) != filepath.Dir(absPath(filename)) {
+			continue
+		}
+		res = append(res, src[last:start]...)
+		if comment != "" {
+			res = append(append(res, comment...), '\n')
+		}
+		res = append(res, text...)
+		last = end
+		if comment != "" {
+			for last < len(src) && src[last] != '\n' {
+				last++
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, false, errs
+	}
+	return append(res, src[last:]...), all, nil
+}
+
+// inlineText returns the text that inlines the file path, without its
+// root braces or its last newline, if the file is small enough to
+// inline, and whether its own include statements were all inlined.
+func inlineText(path string, max int, dirs []string, indent string, parents []string) (text []byte, small, all bool, err error) {
+	for _, p := range parents {
+		if p == absPath(path) {
+			return nil, false, false, fmt.Errorf("cannot include %s: its includes form a cycle", filepath.Base(path))
+		}
+	}
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false, false, err
+	}
+	if len(src) >= max {
+		return nil, false, false, nil
+	}
+	if filepath.Ext(path) == ".properties" {
+		if src, err = propertiesToHOCON(src, printer.Config{Mode: printerMode, Tabwidth: tabWidth}); err != nil {
+			return nil, false, false, fmt.Errorf("%s:%v", path, err)
+		}
+	}
+	text, all, err = inlineFile(path, src, max, dirs, indent, parents)
+	if err != nil {
+		return nil, false, false, withFilename(err, path)
+	}
+	toks, err := tokenize(text)
+	if err != nil {
+		return nil, false, false, withFilename(err, path)
+	}
+	for _, t := range toks {
+		if t.kind == tokLBracket {
+			return nil, false, false, fmt.Errorf("cannot inline %s, which holds an array", path)
+		}
+		if t.kind != tokSpace && t.kind != tokNewline && t.kind != tokComment {
+			break
+		}
+	}
+	if open, close := rootBraces(toks); open >= 0 {
+		text = unbraceRoot(toks, open, close, indent)
+	}
+	return bytes.TrimRight(text, "\r\n"), true, all, nil
+}
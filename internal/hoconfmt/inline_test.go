@@ -0,0 +1,91 @@
+package hoconfmt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInlineIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"ten.conf":         "port = 80\n", // 10 bytes
+		"eleven.conf":      "port = 443\n",
+		"braced.conf":      "{\n    a = 1\n    include \"ten\"\n}\n",
+		"db.properties":    "db.host=h\n",
+		"list.conf":        "[1]\n",
+		"self.conf":        "include \"self.conf\"\n",
+		"lib/nested.conf":  "b = 2\ninclude \"../eleven.conf\"\n",
+		"lib/far.conf":     "b = 2\ninclude \"../braced.conf\"\n",
+		"lib/sibling.conf": "c = 3\ninclude \"leaf.conf\"\n",
+		"lib/leaf.conf":    "d = 4\n",
+	}
+	for name, text := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app := filepath.Join(dir, "app.conf")
+
+	tests := []struct {
+		src  string
+		max  int
+		want string
+	}{
+		// At the size boundary: a file is inlined if it is smaller than
+		// the limit.
+		{"include \"ten.conf\"\n", 11, "port = 80\n"},
+		{"include \"ten.conf\"\n", 10, "include \"ten.conf\"\n"},
+		{"include \"eleven.conf\"\n", 11, "include \"eleven.conf\"\n"},
+		{"a = 1\ninclude \"ten\" # the port\nb = 2\n", 11, "a = 1\n# the port\nport = 80\nb = 2\n"},
+		{"include \"braced.conf\"\n", 100, "a = 1\nport = 80\n"},
+		{"include \"db.properties\"\n", 100, "db {\n    host = \"h\"\n}\n"},
+		// Includes that are kept.
+		{"include \"nope.conf\"\n", 100, "include \"nope.conf\"\n"},
+		{"include url(\"http://x/a.conf\")\n", 100, "include url(\"http://x/a.conf\")\n"},
+		{"a { include \"ten.conf\" }\n", 100, "a { include \"ten.conf\" }\n"},
+		// A file in another directory is inlined only with all its
+		// includes: far.conf is 31 bytes, and braced.conf 32.
+		{"include \"lib/nested.conf\"\n", 100, "b = 2\nport = 443\n"},
+		{"include \"lib/far.conf\"\n", 32, "include \"lib/far.conf\"\n"},
+		{"include \"lib/sibling.conf\"\n", 100, "c = 3\nd = 4\n"},
+	}
+	for _, tt := range tests {
+		res, err := inlineIncludes(app, []byte(tt.src), tt.max, nil, "    ")
+		if err != nil {
+			t.Errorf("inlining %q under %d bytes: %v", tt.src, tt.max, err)
+			continue
+		}
+		if string(res) != tt.want {
+			t.Errorf("inlining %q under %d bytes = %q, want %q", tt.src, tt.max, res, tt.want)
+		}
+	}
+
+	for _, tt := range []struct{ src, want string }{
+		{"include required(\"nope.conf\")", app + ":1:1: cannot find the included file nope.conf"},
+		{"include \"self.conf\"", app + ":1:1: " + filepath.Join(dir, "self.conf") + ":1:1: cannot include self.conf: its includes form a cycle"},
+		{"include \"list.conf\"", app + ":1:1: cannot inline " + filepath.Join(dir, "list.conf") + ", which holds an array"},
+	} {
+		if _, err := inlineIncludes(app, []byte(tt.src), 100, nil, "\t"); err == nil || withFilename(err, app).Error() != tt.want {
+			t.Errorf("inlining %q: got error %v, want %s", tt.src, err, tt.want)
+		}
+	}
+
+	// Formatted together with the rest of the file.
+	opts := DefaultOptions()
+	opts.InlineIncludes = 100
+	res, err := formatFile(app, []byte("x = 1\ninclude \"braced.conf\"\n"), opts)
+	if want := "x = 1\na = 1\nport = 80\n"; err != nil || string(res) != want {
+		t.Errorf("-inline-includes-under 100 = %q, %v, want %q", res, err, want)
+	}
+}
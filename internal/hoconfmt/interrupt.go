@@ -0,0 +1,39 @@
+package hoconfmt
+
+import (
+	"os"
+	"os/signal"
+	"sync/atomic"
+)
+
+// interrupted is set once hoconfmt gets an interrupt signal while it
+// formats several files. processFiles then starts no more files, but
+// lets those it is on finish, so that their writes, which -w does
+// through a temporary file, either complete or never begin.
+var interrupted int32
+
+// catchInterrupt sets interrupted on the first interrupt signal, instead
+// of ending the program, until the returned function is called. A
+// second signal ends it as usual.
+func catchInterrupt() (stop func()) {
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		select {
+		case <-c:
+			atomic.StoreInt32(&interrupted, 1)
+			signal.Stop(c)
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(c)
+		close(done)
+	}
+}
+
+// isInterrupted reports whether an interrupt signal has been caught.
+func isInterrupted() bool {
+	return atomic.LoadInt32(&interrupted) != 0
+}
@@ -0,0 +1,67 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestInterrupt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var jobs []fileJob
+	for _, name := range []string{"a.conf", "b.conf", "c.conf"} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte("a = [ ]\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		jobs = append(jobs, fileJob{path: path})
+	}
+
+	*write = true
+	atomic.StoreInt32(&interrupted, 1)
+	defer func(code int) {
+		*write = false
+		atomic.StoreInt32(&interrupted, 0)
+		exitCode = code
+	}(exitCode)
+	exitCode = 0
+
+	var stdout, stderr bytes.Buffer
+	processFiles(&stdout, &stderr, jobs, 2)
+	if want := "interrupted: stopped after 0 of 3 files\n"; stderr.String() != want {
+		t.Errorf("standard error %q, want %q", stderr.String(), want)
+	}
+	if exitCode != 130 {
+		t.Errorf("exit code = %d, want 130", exitCode)
+	}
+	// No file was started, so none was written and no temporary file is
+	// left.
+	names, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range names {
+		if strings.Contains(fi.Name(), ".hoconfmt-") {
+			t.Errorf("temporary file %s left", fi.Name())
+		}
+		if src, _ := ioutil.ReadFile(filepath.Join(dir, fi.Name())); string(src) != "a = [ ]\n" {
+			t.Errorf("%s written after the interrupt: %q", fi.Name(), src)
+		}
+	}
+
+	// The handler only sets the flag, and stopping it leaves it unset.
+	atomic.StoreInt32(&interrupted, 0)
+	catchInterrupt()()
+	if isInterrupted() {
+		t.Error("interrupted without a signal")
+	}
+}
@@ -0,0 +1,139 @@
+package hoconfmt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A textEdit replaces Length bytes of the original file at byte offset
+// Offset with Replacement.
+type textEdit struct {
+	Offset      int    `json:"offset"`
+	Length      int    `json:"length"`
+	Replacement string `json:"replacement"`
+}
+
+// spliceEdits applies edits, sorted by offset and not overlapping, to
+// src.
+func spliceEdits(src []byte, edits []textEdit) []byte {
+	var res []byte
+	last := 0
+	for _, e := range edits {
+		res = append(res, src[last:e.Offset]...)
+		res = append(res, e.Replacement...)
+		last = e.Offset + e.Length
+	}
+	return append(res, src[last:]...)
+}
+
+// jsonDiffReport is the output of -json-diff for one file.
+type jsonDiffReport struct {
+	File  string     `json:"file"`
+	Edits []textEdit `json:"edits"`
+}
+
+// textEdits returns the edits that turn b1 into b2, sorted by offset and
+// not overlapping. They are taken from the hunks of the same diff -d
+// prints, without context lines, so every edit replaces whole lines.
+func textEdits(b1, b2 []byte) ([]textEdit, error) {
+	if bytes.Equal(b1, b2) {
+		return []textEdit{}, nil
+	}
+	var out bytes.Buffer
+	if err := unifiedDiff(&out, "a", "b", time.Time{}, time.Time{}, b1, b2, 0); err != nil {
+		return nil, fmt.Errorf("computing diff: %s", err)
+	}
+
+	lines1, lines2 := lineStarts(b1), lineStarts(b2)
+	edits := []textEdit{}
+	sc := bufio.NewScanner(&out)
+	sc.Buffer(nil, out.Len()+1)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "@@ ") {
+			continue
+		}
+		var minus, plus string
+		if _, err := fmt.Sscanf(line, "@@ %s %s @@", &minus, &plus); err != nil {
+			return nil, fmt.Errorf("malformed diff hunk %q", line)
+		}
+		from1, to1, err := hunkRange(minus, "-", len(lines1)-1)
+		if err != nil {
+			return nil, err
+		}
+		from2, to2, err := hunkRange(plus, "+", len(lines2)-1)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, textEdit{
+			Offset:      lines1[from1],
+			Length:      lines1[to1] - lines1[from1],
+			Replacement: string(b2[lines2[from2]:lines2[to2]]),
+		})
+	}
+	return edits, sc.Err()
+}
+
+// lineStarts returns the byte offsets at which the lines of b start,
+// followed by len(b).
+func lineStarts(b []byte) []int {
+	starts := []int{0}
+	for i, c := range b {
+		if c == '\n' && i+1 < len(b) {
+			starts = append(starts, i+1)
+		}
+	}
+	if len(b) == 0 {
+		return starts
+	}
+	return append(starts, len(b))
+}
+
+// hunkRange converts a range of a unified diff hunk header, such as
+// "-3,2" or "+7", into the zero-based range of lines [from, to). A
+// range of no lines, as in "-4,0", stands for the position after line
+// 4.
+func hunkRange(s, sign string, nlines int) (from, to int, err error) {
+	if !strings.HasPrefix(s, sign) {
+		return 0, 0, fmt.Errorf("malformed diff range %q", s)
+	}
+	s = s[1:]
+	count := 1
+	if i := strings.IndexByte(s, ','); i >= 0 {
+		if count, err = strconv.Atoi(s[i+1:]); err != nil {
+			return 0, 0, fmt.Errorf("malformed diff range %q", sign+s)
+		}
+		s = s[:i]
+	}
+	start, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed diff range %q", sign+s)
+	}
+	if count > 0 {
+		start--
+	}
+	if start < 0 || start+count > nlines {
+		return 0, 0, fmt.Errorf("diff range %q out of bounds", sign+s)
+	}
+	return start, start + count, nil
+}
+
+// printJSONDiff writes the -json-diff report of filename.
+func printJSONDiff(out io.Writer, filename string, src, res []byte) error {
+	edits, err := textEdits(src, res)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(jsonDiffReport{filename, edits})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", data)
+	return err
+}
@@ -0,0 +1,80 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// applyEdits applies edits to src, checking that they are sorted and do
+// not overlap.
+func applyEdits(t *testing.T, src []byte, edits []textEdit) []byte {
+	var res []byte
+	last := 0
+	for _, e := range edits {
+		if e.Offset < last {
+			t.Fatalf("edit %+v overlaps or precedes the previous one, which ends at %d", e, last)
+		}
+		res = append(res, src[last:e.Offset]...)
+		res = append(res, e.Replacement...)
+		last = e.Offset + e.Length
+	}
+	return append(res, src[last:]...)
+}
+
+func TestTextEdits(t *testing.T) {
+	for _, test := range []struct{ b1, b2 string }{
+		{"", ""},
+		{"a = 1\n", "a = 1\n"},
+		{"", "a = 1\n"},
+		{"a = 1\n", ""},
+		{"a = 1", "a = 1\n"},
+		{"a = 1\nb = 2\nc = 3\n", "a = 1\nc = 4\nd = 5\n"},
+		{"x {\n  a = 1\n}\n\n\n", "x {\n    a = 1\n}\n"},
+		{"a\nb\nc\nd\ne\n", "z\nb\nc\nd\ne\ny\n"},
+	} {
+		edits, err := textEdits([]byte(test.b1), []byte(test.b2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := applyEdits(t, []byte(test.b1), edits); string(got) != test.b2 {
+			t.Errorf("applying %+v to %q gives %q, want %q", edits, test.b1, got, test.b2)
+		}
+	}
+}
+
+func TestJSONDiff(t *testing.T) {
+	*jsonDiff = true
+	defer func() { *jsonDiff = false }()
+
+	inputs, err := filepath.Glob("testdata/*.input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range inputs {
+		src, err := ioutil.ReadFile(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.HasPrefix(src, []byte("//hoconfmt")) {
+			continue // needs flags
+		}
+		var buf bytes.Buffer
+		if err := processFile(in, nil, &buf); err != nil {
+			t.Fatal(err)
+		}
+		var report jsonDiffReport
+		if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+			t.Fatalf("%s: %v: %s", in, err, buf.Bytes())
+		}
+		want, err := formatFile(in, src, DefaultOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := applyEdits(t, src, report.Edits); !bytes.Equal(got, want) {
+			t.Errorf("%s: applying the edits gives\n%s\nwant\n%s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// hoconizeJSON rewrites the values of src that are written as strict
+// JSON, as in
+//
+//	config = {"a": 1, "b": [2, 3]}
+//
+// in the style of the surrounding HOCON:
+//
+//	config = { a = 1, b = [2, 3] }
+//
+// Keys are unquoted where that reads back as the same key and ':'
+// becomes " = ". Commas, line breaks and indentation inside the value
+// are kept, leaving the commas at the ends of lines to -commas. A document that is a single
+// JSON object or array is treated the same way. Values that mix in any
+// HOCON syntax, such as comments, unquoted strings or substitutions,
+// are left alone.
+func hoconizeJSON(lx *lexer, src []byte) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &jsonWriter{toks: toks, res: make([]byte, 0, len(src))}
+	atValue := true // at the start of the document or after a separator
+	for i := 0; i < len(toks); {
+		t := toks[i]
+		if atValue && (t.kind == tokLBrace || t.kind == tokLBracket) {
+			if end := jsonValue(toks, i); end > 0 && valueEnds(toks, end) {
+				i = w.value(i)
+				atValue = false
+				continue
+			}
+		}
+		switch t.kind {
+		case tokSpace, tokNewline, tokComment:
+		case tokSep:
+			atValue = true
+		default:
+			atValue = false
+		}
+		w.res = append(w.res, t.lit...)
+		i++
+	}
+	return w.res, nil
+}
+
+// valueEnds reports whether a value ending just before toks[i] is the
+// whole value of its field, rather than part of a concatenation.
+func valueEnds(toks []tok, i int) bool {
+	for ; i < len(toks); i++ {
+		switch toks[i].kind {
+		case tokSpace:
+			continue
+		case tokNewline, tokComment, tokComma, tokRBrace, tokRBracket:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// skipJSONSpace returns the index of the first token at or after i that
+// is neither a space nor a newline.
+func skipJSONSpace(toks []tok, i int) int {
+	for i < len(toks) && (toks[i].kind == tokSpace || toks[i].kind == tokNewline) {
+		i++
+	}
+	return i
+}
+
+// jsonValue returns the index just past the strict JSON value starting
+// at toks[i], or -1 if there is none.
+func jsonValue(toks []tok, i int) int {
+	i = skipJSONSpace(toks, i)
+	if i == len(toks) {
+		return -1
+	}
+	switch t := toks[i]; t.kind {
+	case tokString:
+		return i + 1
+	case tokUnquoted:
+		if t.lit == "true" || t.lit == "false" || t.lit == "null" || numberLiteral.MatchString(t.lit) {
+			return i + 1
+		}
+	case tokLBrace, tokLBracket:
+		obj := t.kind == tokLBrace
+		closing := tokRBracket
+		if obj {
+			closing = tokRBrace
+		}
+		i = skipJSONSpace(toks, i+1)
+		if i < len(toks) && toks[i].kind == closing {
+			return i + 1
+		}
+		for {
+			if obj {
+				if i == len(toks) || toks[i].kind != tokString {
+					return -1
+				}
+				i = skipJSONSpace(toks, i+1)
+				if i == len(toks) || toks[i].lit != ":" {
+					return -1
+				}
+				i++
+			}
+			if i = jsonValue(toks, i); i < 0 {
+				return -1
+			}
+			i = skipJSONSpace(toks, i)
+			if i == len(toks) {
+				return -1
+			}
+			switch toks[i].kind {
+			case closing:
+				return i + 1
+			case tokComma:
+				i = skipJSONSpace(toks, i+1)
+				continue
+			}
+			return -1
+		}
+	}
+	return -1
+}
+
+// A jsonWriter rewrites JSON values checked by jsonValue.
+type jsonWriter struct {
+	toks []tok
+	res  []byte
+}
+
+// space consumes the white space starting at toks[i]. If it contains a
+// line break it is copied, and nl is true; otherwise it is dropped.
+func (w *jsonWriter) space(i int) (next int, nl bool) {
+	j := i
+	for ; j < len(w.toks) && (w.toks[j].kind == tokSpace || w.toks[j].kind == tokNewline); j++ {
+		nl = nl || w.toks[j].kind == tokNewline
+	}
+	if nl {
+		for _, t := range w.toks[i:j] {
+			w.res = append(w.res, t.lit...)
+		}
+	}
+	return j, nl
+}
+
+func (w *jsonWriter) value(i int) int {
+	t := w.toks[i]
+	if t.kind != tokLBrace && t.kind != tokLBracket {
+		w.res = append(w.res, t.lit...)
+		return i + 1
+	}
+
+	obj := t.kind == tokLBrace
+	pad := "" // between the brackets and elements on the same line
+	if obj {
+		pad = " "
+	}
+	w.res = append(w.res, t.lit...)
+	i, nl := w.space(i + 1)
+	if k := w.toks[i].kind; k == tokRBrace || k == tokRBracket {
+		w.res = append(w.res, w.toks[i].lit...)
+		return i + 1
+	}
+	if !nl {
+		w.res = append(w.res, pad...)
+	}
+	for {
+		if obj {
+			w.res = append(w.res, jsonKey(w.toks[i].lit)...)
+			i = skipJSONSpace(w.toks, i+1) + 1 // the ':'
+			i = skipJSONSpace(w.toks, i)
+			w.res = append(w.res, " = "...)
+		}
+		i = w.value(i)
+
+		// Look past the white space before a comma, which goes right
+		// after the element. Commas at the ends of lines are for the
+		// -commas pass.
+		j := skipJSONSpace(w.toks, i)
+		if w.toks[j].kind == tokComma {
+			w.res = append(w.res, ',')
+			var nl bool
+			if i, nl = w.space(j + 1); !nl {
+				w.res = append(w.res, ' ')
+			}
+			continue
+		}
+		if i, nl = w.space(i); !nl {
+			w.res = append(w.res, pad...)
+		}
+		w.res = append(w.res, w.toks[i].lit...)
+		return i + 1
+	}
+}
+
+// jsonKey returns the JSON string lit as a HOCON key, unquoted if that
+// reads back as the same key. Keys holding a '-', such as the header
+// names of headers { "Content-Type" = "text/plain" }, keep their quotes:
+// they name the entries of a map rather than settings, and are written
+// quoted in such maps.
+func jsonKey(lit string) string {
+	var k string
+	if err := json.Unmarshal([]byte(lit), &k); err != nil || k == "include" || strings.Contains(k, "-") || hoconKey(k) != k {
+		return lit
+	}
+	return k
+}
@@ -0,0 +1,107 @@
+package hoconfmt
+
+import "testing"
+
+func TestHoconizeJSON(t *testing.T) {
+	for _, test := range []struct {
+		src, want string
+	}{
+		{`config = {"a":1,"b":[2,3]}` + "\n", `config = { a = 1, b = [2, 3] }` + "\n"},
+		{`c = {"a": {"b": {"c": [true, null, "x"]}}}` + "\n", `c = { a = { b = { c = [true, null, "x"] } } }` + "\n"},
+		{"c = {\n    \"a\": 1,\n    \"b\": [\n        2,\n        3\n    ]\n}\n", "c = {\n    a = 1,\n    b = [\n        2,\n        3\n    ]\n}\n"},
+		{`c = [1,` + "\n" + `2]` + "\n", `c = [1,` + "\n" + `2]` + "\n"},
+		{`c = {"a.b": 1, "include": 2, "": 3, "x y": 4}` + "\n", `c = { "a.b" = 1, "include" = 2, "" = 3, "x y" = 4 }` + "\n"},
+		{`h = {"Content-Type": "application/json", "X-Request-ID": 1, "Accept Language": "en", "accept": 2}` + "\n", `h = { "Content-Type" = "application/json", "X-Request-ID" = 1, "Accept Language" = "en", accept = 2 }` + "\n"},
+		{`c = {}` + "\n", `c = {}` + "\n"},
+		{`{"a": 1, "b": {"c": -1.5e3}}` + "\n", `{ a = 1, b = { c = -1.5e3 } }` + "\n"},
+		{`l = [{"a": 1}, {"b": 2}]` + "\n", `l = [{ a = 1 }, { b = 2 }]` + "\n"},
+		{`a { "b": {"c": 1} }` + "\n", `a { "b": { c = 1 } }` + "\n"},
+
+		// Not strict JSON.
+		{`c = {a: 1}` + "\n", `c = {a: 1}` + "\n"},
+		{`c = {"a": x}` + "\n", `c = {"a": x}` + "\n"},
+		{`c = {"a" = 1}` + "\n", `c = {"a" = 1}` + "\n"},
+		{`c = {"a": ${x}}` + "\n", `c = {"a": ${x}}` + "\n"},
+		{`c = [1, 2,]` + "\n", `c = [1, 2,]` + "\n"},
+		{"c = {\n    \"a\": 1 # one\n}\n", "c = {\n    \"a\": 1 # one\n}\n"},
+		{`c = {"a": 1} {"b": 2}` + "\n", `c = {"a": 1} {"b": 2}` + "\n"},
+	} {
+		got, err := hoconizeJSON(nil, []byte(test.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.src, got, test.want)
+		}
+		if again, _ := hoconizeJSON(nil, got); string(again) != string(got) {
+			t.Errorf("%s: not idempotent: got\n%s", test.src, again)
+		}
+	}
+}
+
+// TestJSONIsHOCON formats valid JSON documents that exercise the corners
+// of HOCON's JSON superset and checks that each is accepted, formats
+// idempotently and keeps its effective values.
+func TestJSONIsHOCON(t *testing.T) {
+	for _, src := range []string{
+		`{"a": 1, "a": {"b": 2}}`,               // an object replaces a number
+		`{"a": {"b": 2}, "a": 1}`,               // and a number an object
+		`{"a": {"b": 1}, "a": {"b": {"c": 2}}}`, // objects merge
+		`{"a": "x", "a": {"b": 1}, "a": {"c": 2}}`,
+		`{"a": [[[[]]]], "b": {"c": {"d": {}}}}`, // nested empty values
+		`{"a": {}, "a": {}}`,
+		`[]`,
+		`{}`,
+		`{"": 1, "a.b": 2, "a": {"b": 3}, "include": 4}`,
+		`{"key with spaces": 1, "#": 2, "//": 3, "$": 4, "a-b": 5}`,
+		`{"true": 1, "null": 2, "10": 3}`,
+		`{"\u0000": 1, "\u00a0": 2, "\u2028": 3, "\ufeff": 4}`,
+		`{"a": "\u0041\/\b\f\r", "b": "${x}", "c": "true", "d": "1", "e": "a//b"}`,
+		`{"a": 1E5, "b": -0, "c": 0.0e-0, "d": null, "e": false}`,
+		`{"a" : [ 1 , { } , [ ] , "s" ] }`,
+		`{"a": [{"a": [{"a": {}}]}]}`,
+		"{\n  \"a\": 1,\n  \"b\": [\n    1,\n    2\n  ]\n}",
+		"{\r\n\"a\"\r\n:\r\n1\r\n}\r\n",
+	} {
+		res, err := formatFile("test.json", []byte(src), DefaultOptions())
+		if err != nil {
+			t.Errorf("%q: %v", src, err)
+			continue
+		}
+		if again, err := formatFile("test.json", res, DefaultOptions()); err != nil || string(again) != string(res) {
+			t.Errorf("%q: formatting %q again gives %q, %v", src, res, again, err)
+		}
+		// flattenConfig reads fields as written, and separators on their
+		// own line are only joined by formatting.
+		joined, err := joinSeparators(nil, []byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		before, err := flattenConfig(joined)
+		if err != nil {
+			t.Fatal(err)
+		}
+		after, err := flattenConfig(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changes := compareConfigs(before, after); len(changes) > 0 {
+			t.Errorf("%q: formatting to %q changes %v", src, res, changes)
+		}
+	}
+}
+
+func TestJSONDuplicateKeys(t *testing.T) {
+	cfg, err := flattenConfig([]byte(`{"a": 1, "a": {"b": 2}, "c": {"d": 1}, "c": {"e": 2}, "f": {"g": 1}, "f": 2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p, want := range map[string]string{"a": "object", "a.b": "number", "c.d": "number", "c.e": "number", "f": "number"} {
+		if cfg[p].kind != want {
+			t.Errorf("%s is %q, want %q", p, cfg[p].kind, want)
+		}
+	}
+	if _, ok := cfg["f.g"]; ok {
+		t.Errorf("f.g survives f = 2")
+	}
+}
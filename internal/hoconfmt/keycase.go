@@ -0,0 +1,224 @@
+package hoconfmt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Values of the -key-case flag.
+const (
+	keyCasePreserve = "preserve"
+	keyCaseLower    = "lower" // maxpoolsize
+	keyCaseUpper    = "upper" // MAXPOOLSIZE
+	keyCaseCamel    = "camel" // maxPoolSize
+)
+
+func checkKeyCase(style string) error {
+	switch style {
+	case "", keyCasePreserve, keyCaseLower, keyCaseUpper, keyCaseCamel:
+		return nil
+	}
+	return fmt.Errorf("invalid -key-case %q: must be %s, %s, %s or %s", style, keyCasePreserve, keyCaseLower, keyCaseUpper, keyCaseCamel)
+}
+
+// setKeyCase renames the unquoted keys of src to the case style, for
+// -key-case, and points the substitutions of the renamed paths to the
+// new ones. Quoted keys, such as "Example.COM", keep their case, so
+// quoting a key exempts it. With keyCaseCamel the words of a key,
+// separated by - or _ or by a change of case, are joined with all but
+// the first capitalized, so max_pool-size and MaxPoolSize both become
+// maxPoolSize. Substitutions of paths that src does not set, such as
+// environment variables, are left alone.
+//
+// Renaming two keys of one object to the same name would merge their
+// values, and renaming a key to the path of a substitution that refers
+// to something else, such as ${HOME}, would change its value, so both
+// are reported as errors instead.
+func setKeyCase(src []byte, style string) ([]byte, error) {
+	doc, err := parseDocument(src)
+	if err != nil {
+		return nil, err
+	}
+	c := &keyCaser{src: src, style: style, renamed: map[string][]string{}, from: map[string][]string{}}
+	switch root := doc.root.(type) {
+	case *objectNode:
+		c.object(root, nil, nil)
+	case *arrayNode:
+		c.array(root, nil, nil)
+	}
+	if len(c.errs) > 0 {
+		c.errs.Sort()
+		return nil, c.errs
+	}
+
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	var errs errorList
+	for _, t := range toks {
+		if t.kind != tokSubst {
+			continue
+		}
+		s := trimSubst(t.lit)
+		optional := strings.HasPrefix(s, "${?")
+		p := pathElems(strings.TrimPrefix(s[2:len(s)-1], "?"))
+		for i := len(p); i > 0; i-- {
+			to, ok := c.renamed[pathKey(p[:i])]
+			if !ok {
+				// A key renamed to the path would take it over.
+				if old, ok := c.from[pathKey(p[:i])]; ok {
+					errs.Add(position(src, t.off), codeRename, fmt.Sprintf("renaming %s to %s would change what %s refers to", renderPath(old), renderPath(p[:i]), t.lit))
+					break
+				}
+				continue
+			}
+			if to := append(append([]string{}, to...), p[i:]...); pathKey(to) != pathKey(p) {
+				s = "${"
+				if optional {
+					s += "?"
+				}
+				c.edits = append(c.edits, textEdit{t.off, len(t.lit), s + renderPath(to) + "}"})
+			}
+			break
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	sort.Slice(c.edits, func(i, j int) bool { return c.edits[i].Offset < c.edits[j].Offset })
+	return spliceEdits(src, c.edits), nil
+}
+
+// A keyCaser renames the keys of a syntax tree for setKeyCase. renamed
+// holds the new path of every path of src, by pathKey, and from the
+// path each new one was renamed from, to find collisions.
+type keyCaser struct {
+	src     []byte
+	style   string
+	renamed map[string][]string
+	from    map[string][]string
+	edits   []textEdit
+	errs    errorList
+}
+
+// pathKey returns a map key for the path p.
+func pathKey(p []string) string {
+	return strings.Join(p, "\x00")
+}
+
+// object renames the keys of o, whose path is prefix, prefix being
+// renamed to to.
+func (c *keyCaser) object(o *objectNode, prefix, to []string) {
+	for _, it := range o.items {
+		f, ok := it.node.(*fieldNode)
+		if !ok {
+			continue
+		}
+		key := append([]tok(nil), f.key...)
+		for i, t := range key {
+			if t.kind != tokUnquoted {
+				continue
+			}
+			elems := strings.Split(t.lit, ".")
+			for j, e := range elems {
+				elems[j] = c.rename(e)
+			}
+			if lit := strings.Join(elems, "."); lit != t.lit {
+				key[i].lit = lit
+				c.edits = append(c.edits, textEdit{t.off, len(t.lit), lit})
+			}
+		}
+		_, newPath := keyPath(append(key, tok{kind: tokSep}), 0)
+
+		path := append(prefix[:len(prefix):len(prefix)], f.path...)
+		newTo := append(to[:len(to):len(to)], newPath...)
+		for i := len(prefix) + 1; i <= len(path); i++ {
+			old, renamed := path[:i], newTo[:i]
+			if o, ok := c.from[pathKey(renamed)]; ok && pathKey(o) != pathKey(old) {
+				c.errs.Add(position(c.src, f.offset()), codeRename, fmt.Sprintf("%s and %s would both be named %s", renderPath(o), renderPath(old), renderPath(renamed)))
+				break
+			}
+			c.from[pathKey(renamed)] = old
+			c.renamed[pathKey(old)] = renamed
+		}
+		c.value(f.value, path, newTo)
+	}
+}
+
+func (c *keyCaser) array(a *arrayNode, prefix, to []string) {
+	for i, it := range a.items {
+		if v, ok := it.node.(*valueNode); ok {
+			// The objects of an array are apart from each other.
+			elem := fmt.Sprintf("[%d]", i)
+			c.value(v, append(prefix[:len(prefix):len(prefix)], elem), append(to[:len(to):len(to)], elem))
+		}
+	}
+}
+
+func (c *keyCaser) value(v *valueNode, path, to []string) {
+	for _, part := range v.parts {
+		switch n := part.node.(type) {
+		case *objectNode:
+			c.object(n, path, to)
+		case *arrayNode:
+			c.array(n, path, to)
+		}
+	}
+}
+
+// rename returns the unquoted path element e in the case style.
+func (c *keyCaser) rename(e string) string {
+	switch c.style {
+	case keyCaseLower:
+		return strings.ToLower(e)
+	case keyCaseUpper:
+		return strings.ToUpper(e)
+	case keyCaseCamel:
+		words := keyWords(e)
+		if len(words) == 0 {
+			return e
+		}
+		var b strings.Builder
+		b.WriteString(strings.ToLower(words[0]))
+		for _, w := range words[1:] {
+			r, n := utf8.DecodeRuneInString(w)
+			b.WriteRune(unicode.ToUpper(r))
+			b.WriteString(strings.ToLower(w[n:]))
+		}
+		return b.String()
+	}
+	return e
+}
+
+// keyWords splits a key into its words: at - and _, before an upper
+// case letter following a lower case one or a digit, and before the
+// last letter of a run of upper case ones followed by a lower case
+// one, so that HTTPServer is HTTP and Server.
+func keyWords(e string) []string {
+	var words []string
+	rs := []rune(e)
+	start := 0
+	for i, r := range rs {
+		switch {
+		case r == '-' || r == '_':
+			if i > start {
+				words = append(words, string(rs[start:i]))
+			}
+			start = i + 1
+		case i > start && unicode.IsUpper(r) && (unicode.IsLower(rs[i-1]) || unicode.IsDigit(rs[i-1])),
+			i > start && unicode.IsUpper(r) && unicode.IsUpper(rs[i-1]) && i+1 < len(rs) && unicode.IsLower(rs[i+1]):
+			words = append(words, string(rs[start:i]))
+			start = i
+		}
+	}
+	if start < len(rs) {
+		words = append(words, string(rs[start:]))
+	}
+	return words
+}
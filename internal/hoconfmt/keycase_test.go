@@ -0,0 +1,84 @@
+package hoconfmt
+
+import "testing"
+
+func TestSetKeyCase(t *testing.T) {
+	const src = `DB {
+    Max_Pool-Size = 10
+    HTTPServer.Port = 8080
+    "Example.COM" = 1
+}
+alias = ${DB.HTTPServer.Port}
+user = ${?USER}
+list = [{ Inner_Key = 1 }]
+`
+	tests := []struct{ style, want string }{
+		{keyCaseLower, `db {
+    max_pool-size = 10
+    httpserver.port = 8080
+    "Example.COM" = 1
+}
+alias = ${db.httpserver.port}
+user = ${?USER}
+list = [{ inner_key = 1 }]
+`},
+		{keyCaseCamel, `db {
+    maxPoolSize = 10
+    httpServer.port = 8080
+    "Example.COM" = 1
+}
+alias = ${db.httpServer.port}
+user = ${?USER}
+list = [{ innerKey = 1 }]
+`},
+		{keyCasePreserve, src},
+	}
+	for _, tt := range tests {
+		res, err := setKeyCase([]byte(src), tt.style)
+		if err != nil {
+			t.Errorf("%s: %v", tt.style, err)
+			continue
+		}
+		if string(res) != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.style, res, tt.want)
+		}
+	}
+}
+
+func TestSetKeyCaseErrors(t *testing.T) {
+	tests := []struct{ style, src, want string }{
+		{keyCaseLower, "Port = 1\nport = 2", "2:1: Port and port would both be named port"},
+		{keyCaseLower, "a { Port = 1 }\na.PORT = 2", "2:1: a.Port and a.PORT would both be named a.port"},
+		{keyCaseUpper, "path = ${PATH}", "1:8: renaming path to PATH would change what ${PATH} refers to"},
+	}
+	for _, tt := range tests {
+		_, err := setKeyCase([]byte(tt.src), tt.style)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s %q: got %v, want %s", tt.style, tt.src, err, tt.want)
+		}
+	}
+
+	// Keys that differ only in case may stay apart if one is quoted.
+	if _, err := setKeyCase([]byte("\"Port\" = 1\nport = 2\n"), keyCaseLower); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestKeyWords(t *testing.T) {
+	for _, tt := range []struct {
+		key  string
+		want string
+	}{
+		{"max_pool-size", "maxPoolSize"},
+		{"MaxPoolSize", "maxPoolSize"},
+		{"HTTPServer", "httpServer"},
+		{"ipv4Address", "ipv4Address"},
+		{"v2", "v2"},
+		{"__", "__"},
+	} {
+		c := &keyCaser{style: keyCaseCamel}
+		if got := c.rename(tt.key); got != tt.want {
+			t.Errorf("rename(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"path"
+	"strings"
+)
+
+// A keyFilter selects the key paths that appear in the output, as set
+// by -include-keys, -exclude-keys and -keep-empty.
+type keyFilter struct {
+	include   []string // globs; nil includes every path
+	exclude   []string
+	keepEmpty bool
+}
+
+// excluded reports whether p or one of its parents matches an exclude
+// glob.
+func (k keyFilter) excluded(p []string) bool {
+	for n := 1; n <= len(p); n++ {
+		if matchPath(k.exclude, p[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+// wanted reports whether the value at p is part of the output: p is not
+// excluded, and p or one of its parents matches an include glob.
+func (k keyFilter) wanted(p []string) bool {
+	if k.excluded(p) {
+		return false
+	}
+	if k.include == nil {
+		return true
+	}
+	for n := 1; n <= len(p); n++ {
+		if matchPath(k.include, p[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+// holdsWanted reports whether an include glob may match a path inside
+// the object at p, so that the object must be kept for the fields it
+// holds.
+func (k keyFilter) holdsWanted(p []string) bool {
+	for _, g := range k.include {
+		elems := strings.Split(g, ".")
+		if len(elems) <= len(p) {
+			continue
+		}
+		match := true
+		for i, e := range elems[:len(p)] {
+			if ok, _ := path.Match(e, p[i]); !ok {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// filterKeys removes the fields of src whose key paths k does not want.
+// Paths are those of the merged document, so a key is selected the same
+// way whether it is written as a.b = 1 or inside a { b = 1 }. An object
+// whose path is not wanted is only kept for the wanted fields inside
+// it, and an object left with no fields by the filtering is removed as
+// well, unless k.keepEmpty is set. Comment lines
+// directly above a removed field and its trailing comment go with it.
+// Include statements are always kept.
+func filterKeys(src []byte, k keyFilter) ([]byte, error) {
+	if k.include == nil && k.exclude == nil {
+		return src, nil
+	}
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	type object struct {
+		field
+		close    int // index of the closing brace
+		children int // number of fields directly inside
+		kept     int // number of those that are kept
+	}
+	var stack []*object // objects enclosing the current field
+	drop := make([]bool, len(toks))
+	remove := func(f field, end int) {
+		r := fieldSpan(toks, f.key, end)
+		for i := r.start; i < r.end; i++ {
+			drop[i] = true
+		}
+	}
+	// finish decides about the objects that end before token i.
+	finish := func(i int) {
+		for len(stack) > 0 && stack[len(stack)-1].close < i {
+			o := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			keep := o.kept > 0 || k.keepEmpty || o.children == 0 && k.wanted(o.path)
+			if !keep {
+				remove(o.field, o.close+1)
+			}
+			if len(stack) > 0 && keep {
+				stack[len(stack)-1].kept++
+			}
+		}
+	}
+	skip := 0 // fields before this token are inside a removed object
+	walkFields(toks, func(f field) {
+		if f.key < skip {
+			return
+		}
+		finish(f.key)
+		var parent *object
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+			parent.children++
+		}
+		if f.object {
+			o := &object{field: f, close: matchingBracket(toks, f.value)}
+			if k.excluded(f.path) || !k.wanted(f.path) && !k.holdsWanted(f.path) {
+				remove(f, o.close+1)
+				skip = o.close + 1
+				return
+			}
+			stack = append(stack, o)
+			return
+		}
+		if !k.wanted(f.path) {
+			remove(f, f.end)
+		} else if parent != nil {
+			parent.kept++
+		}
+	})
+	finish(len(toks))
+
+	var res []byte
+	for i, t := range toks {
+		if !drop[i] {
+			res = append(res, t.lit...)
+		}
+	}
+	// Removing the first or last fields may leave the blank lines that
+	// separated them at the start or end of the file.
+	if len(src) > 0 && src[0] != '\n' && src[0] != '\r' {
+		res = bytes.TrimLeft(res, "\r\n")
+	}
+	if trimmed := bytes.TrimRight(res, " \t\r\n"); len(trimmed) > 0 && bytes.HasSuffix(src, []byte("\n")) {
+		res = append(trimmed, '\n')
+	}
+	return res, nil
+}
+
+// fieldSpan returns the range of tokens to drop to remove the field
+// from toks[start] to just before toks[end]: a following comma, and if
+// the field is alone on its lines, those whole lines with its trailing
+// comment and the comment lines directly above it. Blank lines left
+// next to each other are for limitBlankLines to shorten.
+func fieldSpan(toks []tok, start, end int) span {
+	j := end
+	for j < len(toks) && toks[j].kind == tokSpace {
+		j++
+	}
+	comma := j < len(toks) && toks[j].kind == tokComma
+	if comma {
+		end = j + 1
+		for end < len(toks) && toks[end].kind == tokSpace {
+			end++
+		}
+		j = end
+	}
+	if j < len(toks) && toks[j].kind == tokComment {
+		j++
+	}
+	i := start
+	for i > 0 && toks[i-1].kind == tokSpace {
+		i--
+	}
+	if !comma && i > 0 && toks[i-1].kind == tokComma {
+		// No comma follows: drop the one before, as in { a = 1, b = 2 }.
+		start = i - 1
+	}
+	if (i == 0 || toks[i-1].kind == tokNewline) && (j == len(toks) || toks[j].kind == tokNewline) {
+		// Whole lines: take the comment lines above along.
+		for i > 0 {
+			k := i - 1 // the newline ending the line above
+			l := k
+			for l > 0 && toks[l-1].kind != tokNewline {
+				l--
+			}
+			line := toks[l:k]
+			for len(line) > 0 && line[0].kind == tokSpace {
+				line = line[1:]
+			}
+			if len(line) != 1 || line[0].kind != tokComment {
+				break
+			}
+			i = l
+		}
+		if j < len(toks) {
+			j++
+		}
+		return span{i, j}
+	}
+	return span{start, end}
+}
@@ -0,0 +1,131 @@
+package hoconfmt
+
+import "testing"
+
+func TestFilterKeys(t *testing.T) {
+	// The source is formatted already, so only the filtering changes it.
+	const src = `# Settings.
+
+app.name = demo
+
+# The database.
+db {
+    host = localhost
+    port = 5432 # default
+    pool { min = 1, max = 10 }
+}
+db.user = admin
+
+http {
+    port = 8080
+}
+include "extra.conf"
+`
+	for _, test := range []struct {
+		include, exclude string
+		keepEmpty        bool
+		want             string
+	}{
+		// Only db, wherever its fields are written.
+		{"db", "", false, `# Settings.
+
+# The database.
+db {
+    host = localhost
+    port = 5432 # default
+    pool { min = 1, max = 10 }
+}
+db.user = admin
+
+include "extra.conf"
+`},
+		// Fields below a glob; objects around them are kept for them.
+		{"db.port,db.*.max", "", false, `# Settings.
+
+# The database.
+db {
+    port = 5432 # default
+    pool { max = 10 }
+}
+
+include "extra.conf"
+`},
+		{"", "db.pool,app,http.port", false, `# Settings.
+
+# The database.
+db {
+    host = localhost
+    port = 5432 # default
+}
+db.user = admin
+
+include "extra.conf"
+`},
+		{"", "db.pool,app,http.port", true, `# Settings.
+
+# The database.
+db {
+    host = localhost
+    port = 5432 # default
+}
+db.user = admin
+
+http {}
+include "extra.conf"
+`},
+		// Excludes win over includes.
+		{"db.*", "db.pool.min,db.[hu]*", false, `# Settings.
+
+# The database.
+db {
+    port = 5432 # default
+    pool { max = 10 }
+}
+
+include "extra.conf"
+`},
+		{"nothing", "", false, "# Settings.\n\ninclude \"extra.conf\"\n"},
+	} {
+		opts := DefaultOptions()
+		opts.IncludeKeys, opts.ExcludeKeys, opts.KeepEmpty = test.include, test.exclude, test.keepEmpty
+		got, err := formatFile("a.conf", []byte(src), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("include %q, exclude %q, keep empty %v:\n%s\nwant:\n%s", test.include, test.exclude, test.keepEmpty, got, test.want)
+		}
+	}
+}
+
+func TestFilterKeysQuoted(t *testing.T) {
+	// Globs name quoted keys by their content.
+	const src = `headers {
+    "Content-Type" = "application/json"
+    "X Forwarded For" = proxy
+    "content-length" = 10
+}
+`
+	opts := DefaultOptions()
+	opts.ExcludeKeys = "headers.X Forwarded For,headers.content-*"
+	got, err := formatFile("a.conf", []byte(src), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "headers {\n    \"Content-Type\" = \"application/json\"\n}\n"; string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFilterKeysEmpty(t *testing.T) {
+	for _, src := range []string{"", "\n", "# only a comment\n"} {
+		got, err := filterKeys([]byte(src), keyFilter{exclude: []string{"a"}})
+		if err != nil {
+			t.Errorf("filterKeys(%q): %v", src, err)
+			continue
+		}
+		if string(got) != src {
+			t.Errorf("filterKeys(%q) = %q", src, got)
+		}
+	}
+}
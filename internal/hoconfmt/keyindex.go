@@ -0,0 +1,48 @@
+package hoconfmt
+
+import (
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// keyIndex returns the paths of the values of the document src after
+// all its fields are merged, one per line and sorted element by
+// element. Objects are not listed themselves, except empty ones, whose
+// paths would be lost otherwise.
+func keyIndex(src []byte) ([]byte, error) {
+	cfg, err := flattenConfig(src)
+	if err != nil {
+		return nil, err
+	}
+	// Every object on the path of a value is in cfg, so an object has
+	// fields if it is the parent of another path.
+	parents := map[string]bool{}
+	for p := range cfg {
+		if elems := pathElems(p); len(elems) > 1 {
+			parents[renderPath(elems[:len(elems)-1])] = true
+		}
+	}
+	var paths []string
+	for p, v := range cfg {
+		if v.kind != "object" || !parents[p] {
+			paths = append(paths, p)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool { return lessPath(paths[i], paths[j]) })
+	var b strings.Builder
+	for _, p := range paths {
+		b.WriteString(p)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String()), nil
+}
+
+// writeKeyIndex writes the key index of src to the -index file.
+func writeKeyIndex(filename string, src []byte) error {
+	index, err := keyIndex(src)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, index, 0644)
+}
@@ -0,0 +1,48 @@
+package hoconfmt
+
+import "testing"
+
+func TestKeyIndex(t *testing.T) {
+	const src = `app {
+    name = demo
+    tags = [a, b]
+}
+db {
+    host = localhost
+    pool { min = 1 }
+}
+db.pool.max = 10
+db.host = "db.internal" // replaces the host above
+cache = {}
+"x.y" = 1
+app.name = other
+legacy { a = 1 }
+legacy = off
+`
+	got, err := keyIndex([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `app.name
+app.tags
+cache
+db.host
+db.pool.max
+db.pool.min
+legacy
+"x.y"
+`
+	if string(got) != want {
+		t.Errorf("keyIndex =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestKeyIndexQuotedObjects(t *testing.T) {
+	got, err := keyIndex([]byte("\"a.b\" { c = 1 }\n\"a.b.c\" = {}\na { \"b.c\" = {} }\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a.\"b.c\"\n\"a.b\".c\n\"a.b.c\"\n"; string(got) != want {
+		t.Errorf("keyIndex =\n%s\nwant\n%s", got, want)
+	}
+}
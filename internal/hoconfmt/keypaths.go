@@ -0,0 +1,360 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Values of the -key-paths flag.
+const (
+	keyPathsKeep   = "keep"
+	keyPathsDotted = "dotted" // a.b = 1
+	keyPathsNested = "nested" // a { b = 1 }
+)
+
+func checkKeyPaths(style string) error {
+	switch style {
+	case "", keyPathsKeep, keyPathsDotted, keyPathsNested:
+		return nil
+	}
+	return fmt.Errorf("invalid -key-paths %q: must be %s, %s or %s", style, keyPathsKeep, keyPathsDotted, keyPathsNested)
+}
+
+// setKeyPaths writes the fields of src with paths of one style, for
+// -key-paths. With keyPathsDotted an object with fields becomes the
+// fields themselves, with its key in front of theirs:
+//
+//	a {                 a.b = 1
+//	    b = 1           a.c.d = 2
+//	    c { d = 2 }
+//	}
+//
+// and with keyPathsNested a dotted key becomes an object, merged into
+// an object of the same key above it in the same object:
+//
+//	a { b = 1 }         a {
+//	a.c.d = 2               b = 1
+//	                        c {
+//	                            d = 2
+//	                        }
+//	                    }
+//
+// Fields keep their order, so the last value of a path still wins: a
+// field only merges into an object above it if nothing between them
+// sets the key otherwise, as a = 1 or an include statement may. The
+// comment lines directly above a field that moves go with it, and the
+// comments inside an object that is dissolved stay where they were.
+// An object holding an include statement, and one appended with +=,
+// stays an object. The objects and arrays that change are written
+// with one item per line, for formatting to indent; the rest of src is
+// left as it is.
+func setKeyPaths(src []byte, style string) ([]byte, error) {
+	doc, err := parseDocument(src)
+	if err != nil {
+		return nil, err
+	}
+	k := &keyPather{src: src, style: style, dirty: map[node]bool{}}
+	return k.rewrite(doc), nil
+}
+
+// rewrite rewrites the fields of doc, parsed from k.src, and returns
+// the result.
+func (k *keyPather) rewrite(doc *document) []byte {
+	var off, end int
+	switch root := doc.root.(type) {
+	case *objectNode:
+		k.object(root)
+		off, end = root.off, root.end
+	case *arrayNode:
+		k.array(root)
+		off, end = root.off, root.end
+	}
+	if !k.dirty[doc.root] {
+		return k.src
+	}
+
+	var b bytes.Buffer
+	b.Write(k.src[:off])
+	if o, ok := doc.root.(*objectNode); ok && !o.braces {
+		k.writeItems(&b, o.items)
+	} else {
+		k.writeNode(&b, doc.root)
+	}
+	b.Write(k.src[end:])
+	return b.Bytes()
+}
+
+// A keyPather rewrites the fields of a syntax tree for setKeyPaths and
+// simplify. The objects and arrays in dirty are written from their
+// items, and the others copied from the source. With collapse, fields
+// whose value is an object of one field become that field, after
+// nesting.
+type keyPather struct {
+	src      []byte
+	style    string
+	collapse bool
+	dirty    map[node]bool
+}
+
+// object rewrites the fields of o, and those of the objects inside it,
+// and reports whether anything changed.
+func (k *keyPather) object(o *objectNode) bool {
+	var changed bool
+	if k.style == keyPathsNested {
+		o.items, changed = k.nest(o.items)
+	} else {
+		o.items, changed = k.flatten(o.items, nil)
+	}
+	for _, it := range o.items {
+		if f, ok := it.node.(*fieldNode); ok && k.value(f.value) {
+			changed = true
+		}
+	}
+	if k.collapse && k.collapseFields(o.items) {
+		changed = true
+	}
+	if changed {
+		k.dirty[o] = true
+	}
+	return k.dirty[o]
+}
+
+func (k *keyPather) array(a *arrayNode) bool {
+	for _, it := range a.items {
+		if v, ok := it.node.(*valueNode); ok && k.value(v) {
+			k.dirty[a] = true
+		}
+	}
+	return k.dirty[a]
+}
+
+func (k *keyPather) value(v *valueNode) bool {
+	changed := false
+	for _, part := range v.parts {
+		switch n := part.node.(type) {
+		case *objectNode:
+			changed = k.object(n) || changed
+		case *arrayNode:
+			changed = k.array(n) || changed
+		}
+	}
+	return changed
+}
+
+// nest turns the dotted fields of items into objects and merges the
+// objects of a key into the first of them, as long as no other field or
+// include statement comes between them.
+func (k *keyPather) nest(items []item) ([]item, bool) {
+	var out, above []item // above: the comment lines above the next field
+	flush := func() {
+		out = append(out, above...)
+		above = nil
+	}
+	groups := map[string]*objectNode{} // the objects fields merge into
+	changed := false
+	for _, it := range items {
+		if it.blank {
+			flush()
+		}
+		f, ok := it.node.(*fieldNode)
+		if !ok {
+			if _, ok := it.node.(*includeNode); ok {
+				flush()
+				out = append(out, it)
+				groups = map[string]*objectNode{}
+				continue
+			}
+			above = append(above, it)
+			continue
+		}
+		head := f.path[0]
+		block := len(f.path) == 1 && f.sep != "+=" && f.value.object() != nil
+		if len(f.path) == 1 && !block {
+			flush()
+			out = append(out, it)
+			delete(groups, head)
+			continue
+		}
+
+		g := groups[head]
+		if g == nil {
+			flush()
+			if block {
+				g = f.value.object()
+				out = append(out, it)
+			} else {
+				g = &objectNode{braces: true, items: []item{nestedField(it, f)}}
+				out = append(out, item{node: &fieldNode{
+					key:   keyTokens([]string{head}),
+					path:  f.path[:1],
+					value: &valueNode{parts: []valuePart{{node: g}}},
+				}, blank: it.blank})
+				k.dirty[g] = true
+				changed = true
+			}
+			groups[head] = g
+			continue
+		}
+
+		// The field merges into g, with the comments above it.
+		blank := it.blank
+		if len(above) > 0 {
+			blank = above[0].blank
+		}
+		moved := append(above, nestedField(it, f))
+		above = nil
+		if block {
+			moved = append(moved[:len(moved)-1], f.value.object().items...)
+			if it.comment != nil {
+				moved = append(moved, item{node: it.comment})
+			}
+		}
+		if len(moved) > 0 {
+			moved[0].blank = blank && len(g.items) > 0
+		}
+		g.items = append(g.items, moved...)
+		k.dirty[g] = true
+		changed = true
+	}
+	flush()
+	return out, changed
+}
+
+// nestedField returns the item of the field f one level down: f without
+// the first element of its path.
+func nestedField(it item, f *fieldNode) item {
+	if len(f.path) == 1 {
+		return it
+	}
+	it.node = &fieldNode{key: keyTokens(f.path[1:]), path: f.path[1:], sep: f.sep, value: f.value}
+	it.blank = false
+	return it
+}
+
+// flatten replaces the fields of items whose value is an object with
+// that object's items, prefix being the path of the object items are
+// in.
+func (k *keyPather) flatten(items []item, prefix []string) ([]item, bool) {
+	var out []item
+	changed := false
+	for _, it := range items {
+		f, ok := it.node.(*fieldNode)
+		if !ok {
+			out = append(out, it)
+			continue
+		}
+		path := append(prefix[:len(prefix):len(prefix)], f.path...)
+		if o := f.value.object(); o != nil && f.sep != "+=" && len(o.items) > 0 && !holdsInclude(o) {
+			inner, _ := k.flatten(o.items, path)
+			inner[0].blank = it.blank
+			out = append(out, inner...)
+			if it.comment != nil {
+				out = append(out, item{node: it.comment})
+			}
+			changed = true
+			continue
+		}
+		if len(prefix) > 0 {
+			it.node = &fieldNode{key: keyTokens(path), path: path, sep: f.sep, value: f.value}
+		}
+		out = append(out, it)
+	}
+	return out, changed
+}
+
+func holdsInclude(o *objectNode) bool {
+	for _, it := range o.items {
+		if _, ok := it.node.(*includeNode); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// keyTokens returns the tokens of a key for the path p.
+func keyTokens(p []string) []tok {
+	return []tok{{kind: tokUnquoted, lit: renderPath(p)}}
+}
+
+// writeItems writes items one per line.
+func (k *keyPather) writeItems(b *bytes.Buffer, items []item) {
+	for _, it := range items {
+		if it.blank {
+			b.WriteByte('\n')
+		}
+		switch n := it.node.(type) {
+		case *commentNode:
+			b.WriteString(n.text)
+		case *includeNode:
+			b.WriteString("include ")
+			for _, t := range n.target {
+				b.WriteString(t.lit)
+			}
+		case *fieldNode:
+			b.WriteString(n.keyText())
+			switch n.sep {
+			case "":
+				b.WriteByte(' ')
+			case ":":
+				b.WriteString(": ")
+			default:
+				b.WriteString(" " + n.sep + " ")
+			}
+			k.writeValue(b, n.value)
+		case *valueNode:
+			k.writeValue(b, n)
+		}
+		if it.comment != nil {
+			b.WriteString(" " + it.comment.text)
+		}
+		b.WriteByte('\n')
+	}
+}
+
+// writeValue writes v, copying it from the source if none of its parts
+// changed.
+func (k *keyPather) writeValue(b *bytes.Buffer, v *valueNode) {
+	dirty := false
+	for _, part := range v.parts {
+		dirty = dirty || part.node != nil && k.dirty[part.node]
+	}
+	if !dirty {
+		b.Write(k.src[v.offset():v.end()])
+		return
+	}
+	for i, part := range v.parts {
+		if i > 0 {
+			b.WriteString(part.space)
+		}
+		if part.node != nil {
+			k.writeNode(b, part.node)
+		} else {
+			b.WriteString(part.tok.lit)
+		}
+	}
+}
+
+// writeNode writes the object or array n, from its items if it changed.
+func (k *keyPather) writeNode(b *bytes.Buffer, n node) {
+	switch n := n.(type) {
+	case *objectNode:
+		switch {
+		case !k.dirty[n]:
+			b.Write(k.src[n.off:n.end])
+		case len(n.items) == 0:
+			b.WriteString("{}")
+		default:
+			b.WriteString("{\n")
+			k.writeItems(b, n.items)
+			b.WriteByte('}')
+		}
+	case *arrayNode:
+		if !k.dirty[n] {
+			b.Write(k.src[n.off:n.end])
+			return
+		}
+		b.WriteString("[\n")
+		k.writeItems(b, n.items)
+		b.WriteByte(']')
+	}
+}
@@ -0,0 +1,80 @@
+package hoconfmt
+
+import "testing"
+
+func TestSetKeyPaths(t *testing.T) {
+	// Both forms set db.port; the later one must still win.
+	const src = `db {
+    host = localhost # the host
+    port = 5432
+}
+
+# moved with its field
+db.port = 5433
+name = demo
+`
+	tests := []struct{ style, want string }{
+		{keyPathsNested, `db {
+    host = localhost # the host
+    port = 5432
+
+    # moved with its field
+    port = 5433
+}
+name = demo
+`},
+		{keyPathsDotted, `db.host = localhost # the host
+db.port = 5432
+
+# moved with its field
+db.port = 5433
+name = demo
+`},
+	}
+	for _, tt := range tests {
+		opts := Options{KeyPaths: tt.style}
+		res, err := formatFile("a.conf", []byte(src), opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.style, err)
+		}
+		if string(res) != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.style, res, tt.want)
+		}
+		again, err := formatFile("a.conf", res, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.style, err)
+		}
+		if string(again) != tt.want {
+			t.Errorf("%s: formatting the result again gave\n%s", tt.style, again)
+		}
+		want, _ := canonical([]byte(src))
+		got, err := canonical(res)
+		if err != nil || string(got) != string(want) {
+			t.Errorf("%s: the result means\n%s\nwant\n%s", tt.style, got, want)
+		}
+	}
+}
+
+func TestSetKeyPathsOverrides(t *testing.T) {
+	// A field that replaces a, and an include that may, keep the
+	// objects on either side apart.
+	const src = "a.b = 1\na = 2\na.c = 3\ninclude \"x.conf\"\na.d = 4\na.e = 5\n"
+	const want = "a {\nb = 1\n}\na = 2\na {\nc = 3\n}\ninclude \"x.conf\"\na {\nd = 4\ne = 5\n}\n"
+	res, err := setKeyPaths([]byte(src), keyPathsNested)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != want {
+		t.Errorf("got %q, want %q", res, want)
+	}
+
+	// Objects holding an include statement stay objects.
+	const inc = "a {\n    include \"x.conf\"\n    b { c = 1 }\n}\n"
+	res, err = setKeyPaths([]byte(inc), keyPathsDotted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a {\ninclude \"x.conf\"\nb.c = 1\n}\n"; string(res) != want {
+		t.Errorf("got %q, want %q", res, want)
+	}
+}
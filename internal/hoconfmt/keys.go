@@ -0,0 +1,133 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// quoteSubstKeys quotes the substitutions that appear in keys. HOCON
+// only evaluates substitutions in values, so in
+//
+//	${name}.port = 8080
+//
+// the ${name} is literal text of the key, and it is written as
+// "${name}".port to say so.
+func quoteSubstKeys(lx *lexer, src []byte) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, 0, len(src))
+	var open []tokenKind // enclosing { and [
+	atField := true      // at the start of a field in an object
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		inObject := len(open) == 0 || open[len(open)-1] == tokLBrace
+		if atField && inObject && t.kind != tokSpace && t.kind != tokNewline && t.kind != tokComment {
+			atField = false
+			if end := substKey(toks, i); end > 0 {
+				for j := i; j < end; {
+					k := j + 1
+					if toks[j].kind == tokSubst || toks[j].kind == tokString {
+						for k < end && (toks[k].kind == tokSubst || toks[k].kind == tokString) {
+							k++
+						}
+					}
+					res = append(res, quoteSubsts(toks[j:k])...)
+					j = k
+				}
+				i = end - 1
+				continue
+			}
+		}
+
+		switch t.kind {
+		case tokLBrace, tokLBracket:
+			open = append(open, t.kind)
+			atField = t.kind == tokLBrace
+		case tokRBrace, tokRBracket:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			atField = true
+		case tokNewline, tokComma:
+			atField = true
+		}
+		res = append(res, t.lit...)
+	}
+	return res, nil
+}
+
+// quoteSubsts returns the text of toks, a token of a key or a run of
+// strings and substitutions with nothing between them, with the
+// substitutions quoted. A run holding a substitution becomes a single
+// string, as "a"${b} becomes "a${b}": the quotes of a substitution
+// quoted on its own would run into those of the strings it touches.
+// Text that is not valid UTF-8 is left as it is, since a quoted string
+// would read its invalid bytes as U+FFFD, and so is a run with a string
+// that does not decode, as one holding a raw form feed.
+func quoteSubsts(toks []tok) string {
+	var joined, each strings.Builder
+	join := false // the run holds a substitution
+	raw := false  // a string of the run does not decode
+	for _, t := range toks {
+		join = join || t.kind == tokSubst
+	}
+	for _, t := range toks {
+		s := t.lit
+		switch t.kind {
+		case tokSubst:
+			each.WriteString(quoteString(t.lit))
+		case tokString:
+			if err := json.Unmarshal([]byte(t.lit), &s); err != nil {
+				raw = true
+			}
+			each.WriteString(t.lit)
+		default:
+			each.WriteString(t.lit)
+		}
+		joined.WriteString(s)
+	}
+	switch {
+	case raw || !utf8.ValidString(joined.String()):
+		var b strings.Builder
+		for _, t := range toks {
+			b.WriteString(t.lit)
+		}
+		return b.String()
+	case join:
+		return quoteString(joined.String())
+	}
+	return each.String()
+}
+
+// substKey returns the end of the key starting at toks[i] if it contains
+// a substitution and is followed by a separator or an object, and 0
+// otherwise.
+func substKey(toks []tok, i int) int {
+	hasSubst := false
+	end := 0
+	for j := i; j < len(toks); j++ {
+		switch t := toks[j]; t.kind {
+		case tokSubst:
+			hasSubst = true
+			end = j + 1
+		case tokUnquoted, tokString:
+			if t.lit == "include" && j == i {
+				return 0
+			}
+			end = j + 1
+		case tokSpace:
+		case tokSep, tokLBrace:
+			if hasSubst {
+				return end
+			}
+			return 0
+		default:
+			return 0
+		}
+	}
+	return 0
+}
@@ -0,0 +1,344 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"go/token"
+)
+
+// tokenKind identifies the lexical class of a token.
+type tokenKind int
+
+const (
+	tokSpace     tokenKind = iota // run of white space other than newlines
+	tokNewline                    // "\n"
+	tokComment                    // "# ..." or "// ...", up to the newline
+	tokString                     // "..."
+	tokMultiline                  // """..."""
+	tokSubst                      // ${path} or ${?path}
+	tokLBrace                     // {
+	tokRBrace                     // }
+	tokLBracket                   // [
+	tokRBracket                   // ]
+	tokComma                      // ,
+	tokSep                        // =, : or +=
+	tokUnquoted                   // any other run of text
+)
+
+// A tok is a single lexical element of HOCON source. Concatenating the
+// lit fields of all tokens of a source yields the source again.
+type tok struct {
+	kind tokenKind
+	off  int // byte offset in the source
+	lit  string
+}
+
+// A lexer splits the texts of one formatting run into tokens. Most
+// passes of a run leave the text as they find it, so it keeps the
+// tokens of the last text it split for the next pass to share instead
+// of splitting the same text again. Each run has a lexer of its own,
+// which goes away with it. A nil *lexer splits every text afresh.
+type lexer struct {
+	text string
+	toks []tok
+}
+
+// tokenize is tokenize, sharing the tokens of the text l split last if
+// src is that text. Callers must not change the tokens.
+func (l *lexer) tokenize(src []byte) ([]tok, error) {
+	if l == nil {
+		return tokenize(src)
+	}
+	if l.toks != nil && l.text == string(src) {
+		return l.toks, nil
+	}
+	text, toks, err := splitTokens(src)
+	if err != nil {
+		return toks, err
+	}
+	// Appending to the shared tokens must not write over them.
+	l.text, l.toks = text, toks[:len(toks):len(toks)]
+	return l.toks, nil
+}
+
+// tokenize splits src into tokens. It does not check the syntax beyond
+// what is needed to find token boundaries; the only errors are
+// unterminated strings and substitutions.
+func tokenize(src []byte) ([]tok, error) {
+	_, toks, err := splitTokens(src)
+	return toks, err
+}
+
+// splitTokens is tokenize, also returning the copy of src that the
+// literals of the tokens are slices of.
+func splitTokens(src []byte) (string, []tok, error) {
+	// Token literals are slices of a single copy of the source, and the
+	// tokens are counted first, so long inputs cost neither an
+	// allocation per token nor the regrowth of the token slice.
+	n := 0
+	for i := 0; i < len(src); n++ {
+		_, i = scanToken(src, i, nil)
+	}
+	text := string(src)
+	toks := make([]tok, 0, n)
+	var errs errorList
+	for i := 0; i < len(src); {
+		start := i
+		var kind tokenKind
+		kind, i = scanToken(src, i, &errs)
+		toks = append(toks, tok{kind, start, text[start:i]})
+	}
+	if len(errs) > 0 {
+		return text, toks, errs
+	}
+	return text, toks, nil
+}
+
+// scanToken returns the kind of the token starting at src[i] and the
+// offset just past it. Unterminated strings and substitutions are
+// added to errs unless it is nil.
+func scanToken(src []byte, i int, errs *errorList) (tokenKind, int) {
+	start := i
+	kind := tokUnquoted
+	switch c := src[i]; {
+	case c == '\n':
+		kind = tokNewline
+		i++
+	case spaceLen(src[i:]) > 0:
+		kind = tokSpace
+		for n := spaceLen(src[i:]); n > 0; n = spaceLen(src[i:]) {
+			i += n
+		}
+	case c == '#' || bytes.HasPrefix(src[i:], []byte("//")):
+		kind = tokComment
+		for i < len(src) && src[i] != '\n' {
+			i++
+		}
+	case bytes.HasPrefix(src[i:], []byte(`"""`)):
+		kind = tokMultiline
+		end := bytes.Index(src[i+3:], []byte(`"""`))
+		if end < 0 {
+			if errs != nil {
+				errs.Add(position(src, start), codeSyntax, "unterminated multi-line string")
+			}
+			i = len(src)
+			break
+		}
+		i += 3 + end + 3
+		// Extra quotes before the closing """ belong to the string.
+		for i < len(src) && src[i] == '"' {
+			i++
+		}
+	case c == '"':
+		kind = tokString
+		i = scanString(src, i)
+		if i < 0 {
+			if errs != nil {
+				errs.Add(position(src, start), codeSyntax, "unterminated string")
+			}
+			i = start + 1
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		}
+	case c == '$' && i+1 < len(src) && src[i+1] == '{':
+		kind = tokSubst
+		i += 2
+		for i < len(src) && src[i] != '}' && src[i] != '\n' {
+			if src[i] == '"' {
+				j := scanString(src, i)
+				if j < 0 {
+					// A quote in a path must be closed, or the
+					// subst ends at a different } once lines
+					// are joined.
+					break
+				}
+				i = j
+				continue
+			}
+			i++
+		}
+		if i == len(src) || src[i] != '}' {
+			if errs != nil {
+				errs.Add(position(src, start), codeSyntax, "unterminated substitution")
+			}
+			break
+		}
+		i++
+	case c == '{':
+		kind = tokLBrace
+		i++
+	case c == '}':
+		kind = tokRBrace
+		i++
+	case c == '[':
+		kind = tokLBracket
+		i++
+	case c == ']':
+		kind = tokRBracket
+		i++
+	case c == ',':
+		kind = tokComma
+		i++
+	case c == '=' || c == ':':
+		kind = tokSep
+		i++
+	case c == '+' && i+1 < len(src) && src[i+1] == '=':
+		kind = tokSep
+		i += 2
+	default:
+		i++
+		for i < len(src) && !endsUnquoted(src[i:]) {
+			i++
+		}
+		// An unquoted URL such as http://host:8080/path is one
+		// value, even though it contains a ':' and a "//".
+		if isScheme(src[start:i]) && bytes.HasPrefix(src[i:], []byte("://")) {
+			for i += 3; i < len(src) && !endsURL(src[i:]); i++ {
+			}
+		}
+	}
+	return kind, i
+}
+
+// scanString returns the offset just past the quoted string starting
+// at src[i], or -1 if the string is not terminated on the same line.
+func scanString(src []byte, i int) int {
+	for i++; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		case '\n':
+			return -1
+		}
+	}
+	return -1
+}
+
+// endsUnquoted reports whether an unquoted run of text ends at the
+// start of src.
+func endsUnquoted(src []byte) bool {
+	switch c := src[0]; c {
+	case '"', '{', '}', '[', ']', ',', '=', ':', '#', '\n':
+		return true
+	case '$':
+		return len(src) > 1 && src[1] == '{'
+	case '+':
+		return len(src) > 1 && src[1] == '='
+	case '/':
+		return len(src) > 1 && src[1] == '/'
+	default:
+		return spaceLen(src) > 0
+	}
+}
+
+// firstToken returns the index of the first token of toks that is not
+// white space or a comment, or len(toks).
+func firstToken(toks []tok) int {
+	for i, t := range toks {
+		switch t.kind {
+		case tokSpace, tokNewline, tokComment:
+			continue
+		}
+		return i
+	}
+	return len(toks)
+}
+
+// isScheme reports whether s is a URL scheme, such as "http".
+func isScheme(s []byte) bool {
+	for i, c := range s {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && ('0' <= c && c <= '9' || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+// endsURL reports whether an unquoted URL ends at the start of src: at
+// white space, at a character that is never part of an unquoted value,
+// at a comment introduced by '#', or at a substitution.
+func endsURL(src []byte) bool {
+	switch c := src[0]; c {
+	case '"', '{', '}', '[', ']', ',', '#', '\n':
+		return true
+	case '$':
+		return len(src) > 1 && src[1] == '{'
+	default:
+		return spaceLen(src) > 0
+	}
+}
+
+// position returns the line and column of offset off in src.
+func position(src []byte, off int) token.Position {
+	line := 1 + bytes.Count(src[:off], []byte("\n"))
+	col := off - bytes.LastIndexByte(src[:off], '\n')
+	return token.Position{Offset: off, Line: line, Column: col}
+}
+
+// A srcLine is one line of tokens, ending with a newline token unless
+// it is the last line. Newlines inside multi-line strings do not end a
+// line.
+type srcLine struct {
+	toks  []tok
+	depth int // nesting depth of braces and brackets at the start of the line
+}
+
+// splitLines groups toks into lines.
+func splitLines(toks []tok) []srcLine {
+	var lines []srcLine
+	depth, start := 0, 0
+	lineDepth := 0
+	for i, t := range toks {
+		switch t.kind {
+		case tokLBrace, tokLBracket:
+			depth++
+		case tokRBrace, tokRBracket:
+			if depth > 0 {
+				depth--
+			}
+		case tokNewline:
+			lines = append(lines, srcLine{toks[start : i+1], lineDepth})
+			start, lineDepth = i+1, depth
+		}
+	}
+	if start < len(toks) {
+		lines = append(lines, srcLine{toks[start:], lineDepth})
+	}
+	return lines
+}
+
+// first returns the first token of l that is not white space, or nil.
+func (l srcLine) first() *tok {
+	for i := range l.toks {
+		if k := l.toks[i].kind; k != tokSpace && k != tokNewline {
+			return &l.toks[i]
+		}
+	}
+	return nil
+}
+
+// blank reports whether l contains only white space.
+func (l srcLine) blank() bool { return l.first() == nil }
+
+// commentOnly reports whether the only content of l is a comment.
+func (l srcLine) commentOnly() bool {
+	t := l.first()
+	return t != nil && t.kind == tokComment
+}
+
+func (l srcLine) bytes() []byte {
+	return l.appendTo(nil)
+}
+
+// appendTo appends the text of l to b and returns the extended slice.
+func (l srcLine) appendTo(b []byte) []byte {
+	for _, t := range l.toks {
+		b = append(b, t.lit...)
+	}
+	return b
+}
@@ -0,0 +1,52 @@
+package hoconfmt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	const src = "a.b = ${?x} \"s\\\"\" # c\nm = \"\"\"\nx\"\"\"\"\n" +
+		"l += [1, {k: v}] // d\nurl = http:x\n" +
+		"u = http://h:80/p?q=1#f\nv = [ftp://h/x,${y}]\n"
+	toks, err := tokenize([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lits []string
+	var b strings.Builder
+	for _, tk := range toks {
+		b.WriteString(tk.lit)
+		if tk.kind != tokSpace && tk.kind != tokNewline {
+			lits = append(lits, tk.lit)
+		}
+	}
+	if b.String() != src {
+		t.Errorf("tokens do not reproduce the source: %q", b.String())
+	}
+
+	want := []string{
+		"a.b", "=", "${?x}", `"s\""`, "# c",
+		"m", "=", "\"\"\"\nx\"\"\"\"",
+		"l", "+=", "[", "1", ",", "{", "k", ":", "v", "}", "]", "// d",
+		"url", "=", "http", ":", "x",
+		"u", "=", "http://h:80/p?q=1", "#f",
+		"v", "=", "[", "ftp://h/x", ",", "${y}", "]",
+	}
+	if strings.Join(lits, "|") != strings.Join(want, "|") {
+		t.Errorf("got tokens\n%q\nwant\n%q", lits, want)
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	for _, src := range []string{
+		"a = \"open\n",
+		"a = \"\"\"open\n",
+		"a = ${open\n",
+	} {
+		if _, err := tokenize([]byte(src)); err == nil {
+			t.Errorf("tokenize(%q): expected error", src)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// lintLines is the number of offending lines -lint-separators lists
+// for each mix of styles.
+const lintLines = 5
+
+// A styleUse counts the uses of one style, such as the '=' separator,
+// and records the first lines using it.
+type styleUse struct {
+	Style string `json:"style"`
+	Count int    `json:"count"`
+	Lines []int  `json:"lines"` // at most lintLines
+}
+
+// styleLint is the result of -lint-separators for one file. Each list is
+// ordered by decreasing count, so when styles are mixed the first one
+// is the file's usual style and the others are the departures from it.
+type styleLint struct {
+	File       string     `json:"file"`
+	Separators []styleUse `json:"separators"` // '=' and ':', not counting +=
+	Comments   []styleUse `json:"comments"`   // '#' and "//"
+}
+
+// mixed reports whether l found more than one style of separator or of
+// comment.
+func (l styleLint) mixed() bool {
+	return len(l.Separators) > 1 || len(l.Comments) > 1
+}
+
+// lintStyles counts the separator and comment styles of src. Only the
+// separators between a key and its value count; a ':' inside a value
+// such as a = b:c is part of the value.
+func lintStyles(src []byte) (styleLint, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return styleLint{}, err
+	}
+
+	var seps, comments []styleUse
+	var open []tokenKind // enclosing { and [
+	atField := true      // at the start of a field in an object
+	line := 1
+	for i, t := range toks {
+		inObject := len(open) == 0 || open[len(open)-1] == tokLBrace
+		if atField && inObject && (t.kind == tokUnquoted || t.kind == tokString) {
+			if j := fieldSep(toks, i); j > 0 && toks[j].lit != "+=" {
+				seps = addUse(seps, toks[j].lit, line+strings.Count(string(lineText(toks[i:j])), "\n"))
+			}
+		}
+
+		switch t.kind {
+		case tokLBrace, tokLBracket:
+			open = append(open, t.kind)
+			atField = t.kind == tokLBrace
+		case tokRBrace, tokRBracket:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			atField = true
+		case tokNewline, tokComma:
+			atField = true
+		case tokComment:
+			style := "#"
+			if strings.HasPrefix(t.lit, "//") {
+				style = "//"
+			}
+			comments = addUse(comments, style, line)
+		case tokSpace:
+		default:
+			atField = false
+		}
+		line += strings.Count(t.lit, "\n")
+	}
+	return styleLint{Separators: sortUses(seps), Comments: sortUses(comments)}, nil
+}
+
+func addUse(uses []styleUse, style string, line int) []styleUse {
+	for i := range uses {
+		if uses[i].Style == style {
+			uses[i].Count++
+			if len(uses[i].Lines) < lintLines {
+				uses[i].Lines = append(uses[i].Lines, line)
+			}
+			return uses
+		}
+	}
+	return append(uses, styleUse{style, 1, []int{line}})
+}
+
+// sortUses orders uses by decreasing count, keeping the order of first
+// appearance between equal counts.
+func sortUses(uses []styleUse) []styleUse {
+	for i := 1; i < len(uses); i++ {
+		for j := i; j > 0 && uses[j].Count > uses[j-1].Count; j-- {
+			uses[j], uses[j-1] = uses[j-1], uses[j]
+		}
+	}
+	return uses
+}
+
+// printLint writes the report of -lint-separators. The text report only
+// mentions files that mix styles; the JSON report has a line for every
+// file.
+func printLint(out io.Writer, l styleLint) error {
+	if *jsonReport {
+		if l.Separators == nil {
+			l.Separators = []styleUse{}
+		}
+		if l.Comments == nil {
+			l.Comments = []styleUse{}
+		}
+		data, err := json.Marshal(l)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", data)
+		return err
+	}
+	for _, mix := range []struct {
+		what string
+		uses []styleUse
+	}{
+		{"separators", l.Separators},
+		{"comment styles", l.Comments},
+	} {
+		if len(mix.uses) < 2 {
+			continue
+		}
+		var counts []string
+		for _, u := range mix.uses {
+			counts = append(counts, fmt.Sprintf("%d %q", u.Count, u.Style))
+		}
+		// There are only two styles of each, so the second one is the
+		// departure.
+		odd := mix.uses[1]
+		var lines []string
+		for _, n := range odd.Lines {
+			lines = append(lines, fmt.Sprint(n))
+		}
+		if len(lines) < odd.Count {
+			lines = append(lines, "...")
+		}
+		word := "line"
+		if odd.Count > 1 {
+			word = "lines"
+		}
+		if _, err := fmt.Fprintf(out, "%s: mixed %s: %s (%q on %s %s)\n",
+			l.File, mix.what, strings.Join(counts, ", "), odd.Style, word, strings.Join(lines, ", ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
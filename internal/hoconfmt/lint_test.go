@@ -0,0 +1,81 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestLintStylesConsistent(t *testing.T) {
+	const src = `# settings
+a = 1
+b {
+    c = "x:y"
+    d = ${a}
+    e += 2
+}
+url = "http://host"
+`
+	got, err := lintStyles([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.mixed() {
+		t.Errorf("consistent file reported as mixed: %+v", got)
+	}
+	want := []styleUse{{"=", 4, []int{2, 4, 5, 8}}}
+	if !reflect.DeepEqual(got.Separators, want) {
+		t.Errorf("separators = %+v, want %+v", got.Separators, want)
+	}
+
+	var buf bytes.Buffer
+	if err := printLint(&buf, got); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("unexpected report for a consistent file: %s", buf.Bytes())
+	}
+}
+
+func TestLintStylesMixed(t *testing.T) {
+	const src = `# settings
+a = 1
+b: 2
+c {
+    // nested
+    d = 3
+    e: x:y
+}
+f = 4
+`
+	got, err := lintStyles([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.File = "a.conf"
+	if !got.mixed() {
+		t.Fatalf("mixed file not reported: %+v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := printLint(&buf, got); err != nil {
+		t.Fatal(err)
+	}
+	const want = `a.conf: mixed separators: 3 "=", 2 ":" (":" on lines 3, 7)
+a.conf: mixed comment styles: 1 "#", 1 "//" ("//" on line 5)
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	*jsonReport = true
+	defer func() { *jsonReport = false }()
+	buf.Reset()
+	if err := printLint(&buf, got); err != nil {
+		t.Fatal(err)
+	}
+	const wantJSON = `{"file":"a.conf","separators":[{"style":"=","count":3,"lines":[2,6,9]},{"style":":","count":2,"lines":[3,7]}],"comments":[{"style":"#","count":1,"lines":[1]},{"style":"//","count":1,"lines":[5]}]}` + "\n"
+	if buf.String() != wantJSON {
+		t.Errorf("got %s, want %s", buf.String(), wantJSON)
+	}
+}
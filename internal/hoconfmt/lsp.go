@@ -0,0 +1,329 @@
+package hoconfmt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// -lsp speaks the Language Server Protocol over standard input and
+// output, so that editors can format HOCON files and show their syntax
+// errors through their LSP client, with a single hoconfmt process for
+// the session. Documents are synced in full. The server handles
+//
+//	textDocument/formatting       edits formatting the whole document
+//	textDocument/rangeFormatting  the edits that touch the given lines
+//	textDocument/publishDiagnostics  sent on every open and change
+//
+// along with the lifecycle messages. Formatting uses the options of the
+// command line and .hoconfmt.conf rather than those of the editor, so
+// that the result is the same as running hoconfmt on the files.
+
+// An lspMessage is a JSON-RPC request, notification or response.
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"` // nil for notifications
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *lspError        `json:"error,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC and LSP error codes.
+const (
+	lspMethodNotFound = -32601
+	lspInvalidParams  = -32602
+	lspRequestFailed  = -32803
+)
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"` // in UTF-16 code units
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"` // 1 for errors, 2 for warnings
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+	Code     string   `json:"code"` // as in diagnostic
+}
+
+type lspDocumentParams struct {
+	TextDocument struct {
+		URI  string `json:"uri"`
+		Text string `json:"text"`
+	} `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+	Range *lspRange `json:"range"`
+}
+
+// An lspServer holds the documents the editor has open, by URI.
+type lspServer struct {
+	w        *bufio.Writer
+	docs     map[string]string
+	shutdown bool
+}
+
+// serveLSP runs a language server session, reading messages from in and
+// writing to out, until the client sends exit.
+func serveLSP(in io.Reader, out io.Writer) error {
+	r := textproto.NewReader(bufio.NewReader(in))
+	s := &lspServer{w: bufio.NewWriter(out), docs: map[string]string{}}
+	for {
+		msg, err := readLSPMessage(r)
+		if err == io.EOF {
+			return errors.New("lsp: input closed without exit")
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("lsp: exit without shutdown")
+			}
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+		if err := s.w.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// readLSPMessage reads a message framed by a Content-Length header.
+func readLSPMessage(r *textproto.Reader) (*lspMessage, error) {
+	header, err := r.ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("lsp: reading header: %s", err)
+	}
+	n, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("lsp: malformed Content-Length %q", header.Get("Content-Length"))
+	}
+	// As for -serve, the length is the client's word: read the body as
+	// it comes rather than allocating it up front.
+	var body bytes.Buffer
+	if _, err := io.CopyN(&body, r.R, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("lsp: reading message: %s", err)
+	}
+	msg := new(lspMessage)
+	if err := json.Unmarshal(body.Bytes(), msg); err != nil {
+		return nil, fmt.Errorf("lsp: %s", err)
+	}
+	return msg, nil
+}
+
+func (s *lspServer) write(msg lspMessage) error {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return err
+}
+
+// handle answers a request or acts on a notification.
+func (s *lspServer) handle(msg *lspMessage) error {
+	var p lspDocumentParams
+	if len(msg.Params) > 0 {
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return s.reply(msg, nil, &lspError{lspInvalidParams, err.Error()})
+		}
+	}
+	uri := p.TextDocument.URI
+	switch msg.Method {
+	case "initialize":
+		return s.reply(msg, map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":                1, // full
+				"documentFormattingProvider":      true,
+				"documentRangeFormattingProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "hoconfmt", "version": version},
+		}, nil)
+	case "shutdown":
+		s.shutdown = true
+		return s.reply(msg, nil, nil)
+	case "textDocument/didOpen":
+		s.docs[uri] = p.TextDocument.Text
+		return s.publishDiagnostics(uri)
+	case "textDocument/didChange":
+		if n := len(p.ContentChanges); n > 0 {
+			s.docs[uri] = p.ContentChanges[n-1].Text
+		}
+		return s.publishDiagnostics(uri)
+	case "textDocument/didClose":
+		delete(s.docs, uri)
+		return s.publishDiagnostics(uri)
+	case "textDocument/formatting", "textDocument/rangeFormatting":
+		text, ok := s.docs[uri]
+		if !ok {
+			return s.reply(msg, nil, &lspError{lspInvalidParams, "document is not open: " + uri})
+		}
+		opts, err := fileOptions(uriFilename(uri))
+		var res []byte
+		if err == nil {
+			res, err = formatFile(uriFilename(uri), []byte(text), opts)
+		}
+		if err != nil {
+			return s.reply(msg, nil, &lspError{lspRequestFailed, err.Error()})
+		}
+		edits := lineEdits(text, string(res))
+		if p.Range != nil {
+			edits = editsInRange(edits, *p.Range)
+		}
+		return s.reply(msg, edits, nil)
+	}
+	if msg.ID != nil {
+		return s.reply(msg, nil, &lspError{lspMethodNotFound, "method not supported: " + msg.Method})
+	}
+	return nil // initialized, didSave and other notifications
+}
+
+// reply answers the request msg with result or e. Notifications are not
+// answered.
+func (s *lspServer) reply(msg *lspMessage, result interface{}, e *lspError) error {
+	if msg.ID == nil {
+		return nil
+	}
+	resp := lspMessage{ID: msg.ID, Error: e}
+	if e == nil {
+		resp.Result = mustMarshal(result)
+	}
+	return s.write(resp)
+}
+
+// publishDiagnostics sends the errors and warnings of formatting the
+// document at uri, or an empty list for a closed document.
+func (s *lspServer) publishDiagnostics(uri string) error {
+	diags := []lspDiagnostic{}
+	if text, ok := s.docs[uri]; ok {
+		lines := strings.Split(text, "\n")
+		var r checkReport
+		if opts, err := fileOptions(uriFilename(uri)); err != nil {
+			r.Diagnostics = []diagnostic{{Severity: "error", Message: err.Error(), Code: codeOther}}
+		} else {
+			r = checkFormat(uriFilename(uri), []byte(text), opts, false)
+		}
+		for _, d := range r.Diagnostics {
+			pos := lspPosition{}
+			if d.Line > 0 && d.Line <= len(lines) {
+				pos = lspPosition{d.Line - 1, utf16Len(lines[d.Line-1][:min(d.Column-1, len(lines[d.Line-1]))])}
+			}
+			severity := 1
+			if d.Severity == "warning" {
+				severity = 2
+			}
+			diags = append(diags, lspDiagnostic{lspRange{pos, pos}, severity, "hoconfmt", d.Message, d.Code})
+		}
+	}
+	return s.write(lspMessage{
+		Method: "textDocument/publishDiagnostics",
+		Params: mustMarshal(map[string]interface{}{"uri": uri, "diagnostics": diags}),
+	})
+}
+
+func mustMarshal(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// uriFilename returns the file name of a file: URI, which the options
+// that depend on the file, such as -editorconfig, use. Other URIs are
+// returned as they are.
+func uriFilename(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// lineEdits returns the edits that turn old into new, each replacing
+// whole lines, so that the editor keeps its cursor and marks on the
+// lines formatting leaves alone.
+func lineEdits(old, new string) []lspTextEdit {
+	a, b := splitDiffLines([]byte(old)), splitDiffLines([]byte(new))
+	del, ins := lineChanges(a, b)
+	edits := []lspTextEdit{}
+	for _, h := range diffHunks(del, ins, 0) {
+		edits = append(edits, lspTextEdit{
+			Range:   lspRange{linePosition(a, h.from1), linePosition(a, h.to1)},
+			NewText: strings.Join(b[h.from2:h.to2], ""),
+		})
+	}
+	return edits
+}
+
+// linePosition returns the position of the start of line i of lines,
+// or of the end of the text if line i follows a last line without a
+// newline.
+func linePosition(lines []string, i int) lspPosition {
+	if i > 0 && i == len(lines) && !strings.HasSuffix(lines[i-1], "\n") {
+		return lspPosition{i - 1, utf16Len(lines[i-1])}
+	}
+	return lspPosition{i, 0}
+}
+
+// editsInRange returns the edits of edits that touch the lines of r.
+func editsInRange(edits []lspTextEdit, r lspRange) []lspTextEdit {
+	in := []lspTextEdit{}
+	for _, e := range edits {
+		if e.Range.End.Line >= r.Start.Line && e.Range.Start.Line <= r.End.Line {
+			in = append(in, e)
+		}
+	}
+	return in
+}
+
+// utf16Len returns the length of s in UTF-16 code units, in which LSP
+// counts characters.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 && r != utf8.RuneError {
+			n++
+		}
+		n++
+	}
+	return n
+}
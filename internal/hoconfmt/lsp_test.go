@@ -0,0 +1,145 @@
+package hoconfmt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// lspSession frames the messages as an LSP client sends them.
+func lspSession(msgs ...string) *strings.Reader {
+	var b strings.Builder
+	for _, m := range msgs {
+		fmt.Fprintf(&b, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	return strings.NewReader(b.String())
+}
+
+// lspReplies reads the messages the server wrote to out.
+func lspReplies(t *testing.T, out []byte) []*lspMessage {
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(out)))
+	var msgs []*lspMessage
+	for {
+		msg, err := readLSPMessage(r)
+		if err != nil {
+			return msgs
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func TestServeLSP(t *testing.T) {
+	in := lspSession(
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{}}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///app.conf","languageId":"hocon","version":1,"text":"x = 1\n\"é\" = }\n"}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"file:///app.conf","version":2},"contentChanges":[{"text":"a = 1\nb = [ ]\nc = 2\nd = [ ]"}]}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"textDocument/formatting","params":{"textDocument":{"uri":"file:///app.conf"},"options":{"tabSize":4,"insertSpaces":true}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"textDocument/rangeFormatting","params":{"textDocument":{"uri":"file:///app.conf"},"range":{"start":{"line":0,"character":0},"end":{"line":1,"character":3}},"options":{}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"textDocument/hover","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didClose","params":{"textDocument":{"uri":"file:///app.conf"}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	)
+	var out bytes.Buffer
+	if err := serveLSP(in, &out); err != nil {
+		t.Fatal(err)
+	}
+	replies := lspReplies(t, out.Bytes())
+	want := []string{
+		`{"capabilities":{"documentFormattingProvider":true,"documentRangeFormattingProvider":true,"textDocumentSync":1},"serverInfo":{"name":"hoconfmt","version":"devel"}}`,
+		`{"diagnostics":[{"range":{"start":{"line":1,"character":6},"end":{"line":1,"character":6}},"severity":1,"source":"hoconfmt","message":"unexpected }","code":"syntax"}],"uri":"file:///app.conf"}`,
+		`{"diagnostics":[],"uri":"file:///app.conf"}`,
+		`[{"range":{"start":{"line":1,"character":0},"end":{"line":2,"character":0}},"newText":"b = []\n"},{"range":{"start":{"line":3,"character":0},"end":{"line":3,"character":7}},"newText":"d = []\n"}]`,
+		`[{"range":{"start":{"line":1,"character":0},"end":{"line":2,"character":0}},"newText":"b = []\n"}]`,
+		`method not supported: textDocument/hover`,
+		`{"diagnostics":[],"uri":"file:///app.conf"}`,
+		`null`,
+	}
+	if len(replies) != len(want) {
+		t.Fatalf("got %d messages, want %d:\n%s", len(replies), len(want), out.Bytes())
+	}
+	for i, msg := range replies {
+		var got string
+		switch {
+		case msg.Error != nil:
+			got = msg.Error.Message
+		case msg.Method != "":
+			got = string(msg.Params)
+		default:
+			got = string(msg.Result)
+		}
+		if got != want[i] {
+			t.Errorf("message %d:\ngot  %s\nwant %s", i, got, want[i])
+		}
+	}
+}
+
+func TestServeLSPExitWithoutShutdown(t *testing.T) {
+	var out bytes.Buffer
+	if err := serveLSP(lspSession(`{"jsonrpc":"2.0","method":"exit"}`), &out); err == nil {
+		t.Error("exit without shutdown: expected error")
+	}
+	if err := serveLSP(lspSession(), &out); err == nil {
+		t.Error("closed input: expected error")
+	}
+	huge := strings.NewReader("Content-Length: 9223372036854775807\r\n\r\n{}")
+	if err := serveLSP(huge, &out); err == nil || !strings.Contains(err.Error(), "unexpected EOF") {
+		t.Errorf("message shorter than its huge Content-Length: got %v, want an unexpected EOF", err)
+	}
+}
+
+func TestLineEdits(t *testing.T) {
+	for _, test := range []struct {
+		old, new string
+	}{
+		{"a\nb\nc\n", "a\nb\nc\n"},
+		{"a\nb\nc\n", "a\nx\nc\n"},
+		{"a\nb", "a\nb\n"},
+		{"", "a\n"},
+		{"a\n\n\nb\n", "a\n\nb\n"},
+		{"😀 = 1", "\"😀\" = 1\n"},
+	} {
+		got := applyTextEdits(test.old, lineEdits(test.old, test.new))
+		if got != test.new {
+			t.Errorf("edits of %q to %q give %q", test.old, test.new, got)
+		}
+	}
+	if data, _ := json.Marshal(lineEdits("a\n", "a\n")); string(data) != "[]" {
+		t.Errorf("edits of equal texts = %s, want []", data)
+	}
+}
+
+// applyTextEdits applies edits, which are in order and do not overlap, to
+// text as an editor would.
+func applyTextEdits(text string, edits []lspTextEdit) string {
+	offset := func(p lspPosition) int {
+		lines := strings.SplitAfter(text, "\n")
+		n := 0
+		for _, l := range lines[:p.Line] {
+			n += len(l)
+		}
+		units := 0
+		for i, r := range lines[p.Line] {
+			if units >= p.Character {
+				return n + i
+			}
+			units += utf16Len(string(r))
+		}
+		return n + len(lines[p.Line])
+	}
+	var b strings.Builder
+	last := 0
+	for _, e := range edits {
+		start, end := offset(e.Range.Start), offset(e.Range.End)
+		b.WriteString(text[last:start])
+		b.WriteString(e.NewText)
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
@@ -0,0 +1,114 @@
+package hoconfmt
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// confSuffixes returns the file name suffixes of the files formatted
+// in directories: .conf and those of -ext. A suffix given without its
+// leading dot gets one.
+func confSuffixes() []string {
+	suffixes := []string{".conf"}
+	for _, s := range strings.Split(*confExt, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		if !strings.HasPrefix(s, ".") {
+			s = "." + s
+		}
+		suffixes = append(suffixes, s)
+	}
+	return suffixes
+}
+
+// excludeGlobs returns the globs of -exclude.
+func excludeGlobs() []string {
+	var globs []string
+	for _, g := range strings.Split(*exclude, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			globs = append(globs, strings.TrimSuffix(g, "/"))
+		}
+	}
+	return globs
+}
+
+// checkExcludes reports a malformed -exclude glob.
+func checkExcludes() error {
+	for _, g := range excludeGlobs() {
+		if _, err := path.Match(g, ""); err != nil {
+			return fmt.Errorf("invalid -exclude glob %q: %s", g, err)
+		}
+	}
+	return nil
+}
+
+// excluded reports whether the file or directory f at p, found while
+// walking the tree rooted at root, is to be skipped for -exclude, or
+// by the .hoconfmtignore files, as ignored decides. An -exclude glob
+// without a slash, such as vendor or *.gen.conf, is matched against the
+// name; one with a slash, such as deploy/*/secrets or vendor/**,
+// against the slash-separated path below root, as matchFilePath
+// matches it. root itself, named on the command line, is never
+// excluded.
+func excluded(root, p string, f os.FileInfo) bool {
+	if p == root {
+		return false
+	}
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, g := range excludeGlobs() {
+		if strings.Contains(g, "/") {
+			if matchFilePath(g, rel) {
+				return true
+			}
+		} else if ok, _ := path.Match(g, f.Name()); ok {
+			return true
+		}
+	}
+	return ignored(root, p, f)
+}
+
+// matchFilePath reports whether the slash-separated path p matches the
+// glob g, whose elements are matched as path.Match matches them, except
+// that an element ** matches any number of elements of p, or none. So
+// vendor/** matches vendor and everything in it, and **/gen/*.conf the
+// .conf files of every gen directory.
+func matchFilePath(g, p string) bool {
+	return matchElems(strings.Split(g, "/"), strings.Split(p, "/"))
+}
+
+func matchElems(g, p []string) bool {
+	for ; len(g) > 0; g, p = g[1:], p[1:] {
+		if g[0] == "**" {
+			for i := 0; i <= len(p); i++ {
+				if matchElems(g[1:], p[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(p) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(g[0], p[0]); !ok {
+			return false
+		}
+	}
+	return len(p) == 0
+}
+
+// stdinFilename returns the name standard input goes by: that of
+// -stdin-filename, or <standard input>.
+func stdinFilename() string {
+	if *stdinName != "" {
+		return *stdinName
+	}
+	return "<standard input>"
+}
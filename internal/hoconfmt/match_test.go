@@ -0,0 +1,118 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfSuffixes(t *testing.T) {
+	*confExt = ".hocon, conf.tmpl,"
+	defer func() { *confExt = "" }()
+	for _, test := range []struct {
+		name string
+		want bool
+	}{
+		{"app.conf", true},
+		{"app.hocon", true},
+		{"application.prod.conf.tmpl", true},
+		{"app.tmpl", false},
+		{".app.hocon", false},
+	} {
+		if got := isConfFile(fakeFileInfo{name: test.name}); got != test.want {
+			t.Errorf("isConfFile(%q) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMatchFilePath(t *testing.T) {
+	for _, test := range []struct {
+		glob, path string
+		want       bool
+	}{
+		{"deploy/*/secrets", "deploy/prod/secrets", true},
+		{"deploy/*/secrets", "deploy/prod/eu/secrets", false},
+		{"vendor/**", "vendor", true},
+		{"vendor/**", "vendor/a/b.conf", true},
+		{"vendor/**", "sub/vendor/a.conf", false},
+		{"**/gen/*.conf", "gen/a.conf", true},
+		{"**/gen/*.conf", "a/b/gen/c.conf", true},
+		{"**/gen/*.conf", "a/gen/b/c.conf", false},
+		{"a/**/z", "a/z", true},
+		{"a/**/z", "a/b/c/z", true},
+		{"a/**/z", "a/b/c", false},
+		{"generated/*.conf", "generated/x.conf", true},
+		{"generated/*.conf", "generated", false},
+	} {
+		if got := matchFilePath(test.glob, test.path); got != test.want {
+			t.Errorf("matchFilePath(%q, %q) = %v, want %v", test.glob, test.path, got, test.want)
+		}
+	}
+}
+
+func TestConfFilesExclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{
+		"a.conf",
+		"a.gen.conf",
+		"vendor/lib.conf",
+		"sub/vendor/lib.conf",
+		"deploy/prod/secrets/s.conf",
+		"deploy/prod/app.conf",
+		"sub/b.conf",
+	} {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	*exclude = "vendor, *.gen.conf, deploy/**/secrets/"
+	defer func() { *exclude = "" }()
+	if err := checkExcludes(); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, j := range confFiles(dir) {
+		if j.err != nil {
+			t.Fatal(j.err)
+		}
+		rel, _ := filepath.Rel(dir, j.path)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := []string{"a.conf", "deploy/prod/app.conf", "sub/b.conf"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// A directory named on the command line is walked even if it
+	// matches.
+	if jobs := confFiles(filepath.Join(dir, "vendor")); len(jobs) != 1 {
+		t.Errorf("vendor named directly: got %v", jobs)
+	}
+
+	*exclude = "[a"
+	if err := checkExcludes(); err == nil {
+		t.Error("checkExcludes accepted a malformed glob")
+	}
+}
+
+func TestStdinFilename(t *testing.T) {
+	*stdinName = filepath.Join("conf", "app.conf")
+	defer func() { *stdinName = "" }()
+	err := processFile(stdinFilename(), bytes.NewBufferString("a = }\n"), ioutil.Discard)
+	if err == nil || !strings.HasPrefix(err.Error(), *stdinName+":1:5:") {
+		t.Errorf("error %v does not name %s", err, *stdinName)
+	}
+}
@@ -0,0 +1,386 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// A concatenation is a value whose parts cannot be joined without
+// resolving the substitutions among them, as in ${base} { port = 1 }
+// or "http://"${host}. Its parts are Substs, strings, []Values and
+// Objects; strings hold the white space between the parts around
+// them. off is the offset of the value in the source.
+type concatenation struct {
+	parts []Value
+	off   int
+}
+
+// mergeDocument parses src and merges its fields the way HOCON merges
+// them into the value of the document: an Object, or a []Value for a
+// root array. A later value for a path replaces an earlier one, an
+// object merges into an earlier object, and += appends to an earlier
+// array or makes an array of its value. Concatenations are joined as
+// far as they can be without resolving substitutions: text into a
+// string, arrays into one array and objects into one object.
+// Substitutions stay Substs, or parts of a concatenation, and include
+// statements, which cannot be merged without following them, are
+// errors.
+func mergeDocument(src []byte) (Value, error) {
+	return (&merger{src: src}).document()
+}
+
+// document merges the document m.src.
+func (m *merger) document() (Value, error) {
+	return m.documentOver(nil)
+}
+
+// documentOver merges the document m.src over base, the fields of the
+// documents before it, as its fields would merge over earlier fields of
+// the same document. A root array replaces base.
+func (m *merger) documentOver(base Object) (Value, error) {
+	doc, err := parseDocument(m.src)
+	if err != nil {
+		return nil, err
+	}
+	var v Value
+	switch root := doc.root.(type) {
+	case *objectNode:
+		v = m.fields(append(Object{}, base...), root)
+	case *arrayNode:
+		v = m.array(root)
+	}
+	if len(m.errs) > 0 {
+		m.errs.Sort()
+		return nil, m.errs
+	}
+	return v, nil
+}
+
+// A merger builds the value of a document from its syntax tree. If
+// includes is set, an include statement becomes a field with a null
+// value of the object holding it, keyed by includeKey and the text of
+// the statement, instead of an error, to compare documents without
+// following their includes. If resolving is set, a substitution or a
+// concatenation that replaces an earlier value of a field is kept as an
+// override of it, for resolveDocument; overrides does that alone, for
+// the forms of a document that depend on what it overrides. If env is
+// set, include
+// statements are followed to the files -check-includes finds for them,
+// from filename and env.dirs, and the fields of those files merged in
+// their place.
+type merger struct {
+	src       []byte
+	includes  bool
+	resolving bool
+	overrides bool
+	env       *environment
+	filename  string
+	parents   []string // the absolute paths of the files including src
+	depth     int      // the number of objects around the fields merged
+	errs      errorList
+}
+
+// includeKey starts the keys of the fields that stand for include
+// statements.
+const includeKey = "\x00"
+
+func (m *merger) fail(off int, code, msg string) {
+	m.errs.Add(position(m.src, off), code, msg)
+}
+
+func (m *merger) object(o *objectNode) Object {
+	m.depth++
+	defer func() { m.depth-- }()
+	return m.fields(Object{}, o)
+}
+
+// fields merges the fields of o into obj.
+func (m *merger) fields(obj Object, o *objectNode) Object {
+	for _, it := range o.items {
+		switch n := it.node.(type) {
+		case *includeNode:
+			if m.env != nil {
+				obj = m.include(obj, n)
+				continue
+			}
+			if !m.includes {
+				m.fail(n.off, codeInclude, "cannot merge an include statement without following it")
+				continue
+			}
+			text := "include "
+			for _, t := range n.target {
+				if t.kind != tokSpace {
+					text += t.lit
+				}
+			}
+			obj = append(obj, Field{includeKey + text, nil})
+		case *fieldNode:
+			if m.depth == 0 && !m.resolving && m.selfReference(obj, n) {
+				continue
+			}
+			obj = m.set(obj, n.path, m.value(n.value), n.sep == "+=", n.offset())
+		}
+	}
+	return obj
+}
+
+// selfReference reports whether n, a field outside any object, sets its
+// path to a substitution of the path itself, as in a = ${a}, after obj
+// has set it. That leaves the value as it is. When resolving, such a
+// field is an override like any other substitution.
+func (m *merger) selfReference(obj Object, n *fieldNode) bool {
+	p := n.value.parts[0]
+	if len(n.value.parts) != 1 || p.node != nil || p.tok.kind != tokSubst || n.sep == "+=" {
+		return false
+	}
+	s := m.part(p).(Subst)
+	if renderPath(pathElems(s.Path)) != renderPath(n.path) {
+		return false
+	}
+	_, ok := lookupPath(obj, n.path)
+	return ok
+}
+
+func (m *merger) array(a *arrayNode) []Value {
+	elems := []Value{}
+	for _, it := range a.items {
+		if v, ok := it.node.(*valueNode); ok {
+			elems = append(elems, m.value(v))
+		}
+	}
+	return elems
+}
+
+// value returns the value of v, joining the parts of a concatenation
+// that can be joined. The parts of a concatenation that are numbers,
+// booleans or null count as text.
+func (m *merger) value(v *valueNode) Value {
+	if len(v.parts) == 1 {
+		return m.part(v.parts[0])
+	}
+	var parts []Value
+	for i, p := range v.parts {
+		part := m.part(p)
+		if s, ok := textOf(part); ok {
+			part = s
+		}
+		if i == 0 || p.space == "" {
+			parts = joinPart(parts, part)
+			continue
+		}
+		// White space counts between text and substitutions only.
+		last := parts[len(parts)-1]
+		if isText(p.tok) && p.node == nil && !isObject(last) && !isArray(last) {
+			parts = joinPart(parts, p.space)
+		}
+		parts = joinPart(parts, part)
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return concatenation{parts, v.offset()}
+}
+
+// part returns the value of a single part of a value.
+func (m *merger) part(p valuePart) Value {
+	switch n := p.node.(type) {
+	case *objectNode:
+		return m.object(n)
+	case *arrayNode:
+		return m.array(n)
+	}
+	if p.tok.kind == tokSubst {
+		path := strings.TrimSpace(p.tok.lit[2 : len(p.tok.lit)-1])
+		if strings.HasPrefix(path, "?") {
+			return Subst{Path: strings.TrimSpace(path[1:]), Optional: true}
+		}
+		return Subst{Path: path}
+	}
+	return scalarValue(p.tok)
+}
+
+// joinPart adds v to the parts of a concatenation, joining it with the
+// last one if both are text, arrays or objects. The text of a part
+// that is not a string, such as the number 10 in 10s, is its literal.
+func joinPart(parts []Value, v Value) []Value {
+	if len(parts) == 0 {
+		return append(parts, v)
+	}
+	last := &parts[len(parts)-1]
+	switch l := (*last).(type) {
+	case []Value:
+		if a, ok := v.([]Value); ok {
+			*last = append(l[:len(l):len(l)], a...)
+			return parts
+		}
+	case Object:
+		if o, ok := v.(Object); ok {
+			*last = mergeObjects(append(Object{}, l...), o)
+			return parts
+		}
+	case Subst, concatenation, override:
+	default:
+		if s, ok := textOf(v); ok {
+			t, _ := textOf(l)
+			*last = t + s
+			return parts
+		}
+	}
+	return append(parts, v)
+}
+
+// textOf returns the text of a string, number, boolean or null value
+// in a concatenation.
+func textOf(v Value) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return string(v), true
+	case bool:
+		if v {
+			return "true", true
+		}
+		return "false", true
+	case nil:
+		return "null", true
+	}
+	return "", false
+}
+
+func isArray(v Value) bool {
+	_, ok := v.([]Value)
+	return ok
+}
+
+// scalarValue returns the value of the text token t standing alone: a
+// boolean, null or a number if it is unquoted and spells one, and a
+// string otherwise.
+func scalarValue(t tok) Value {
+	if t.kind == tokUnquoted {
+		switch {
+		case t.lit == "true" || t.lit == "false":
+			return t.lit == "true"
+		case t.lit == "null":
+			return nil
+		case numberLiteral.MatchString(t.lit):
+			return json.Number(t.lit)
+		}
+	}
+	return stringValue(t)
+}
+
+// set sets path in obj to v. An object merges into an object already
+// there, and is merged later into a substitution there, which may
+// stand for one. If appending, v is appended to an array already
+// there. off is the offset of the field's key, for errors.
+func (m *merger) set(obj Object, path []string, v Value, appending bool, off int) Object {
+	at := -1
+	for i, f := range obj {
+		if f.Key == path[0] {
+			at = i
+		}
+	}
+	var old Value
+	if at >= 0 {
+		old = obj[at].Value
+	}
+	switch {
+	case len(path) > 1:
+		switch o := old.(type) {
+		case override:
+			v = o.extend(m.set(nil, path[1:], v, appending, off), off)
+		case Subst, concatenation:
+			v = concatenation{joinPart(concatParts(o), m.set(nil, path[1:], v, appending, off)), off}
+		default:
+			sub, _ := old.(Object)
+			v = m.set(sub, path[1:], v, appending, off)
+		}
+	case appending:
+		switch o := old.(type) {
+		case []Value:
+			v = append(o[:len(o):len(o)], v)
+		case Subst, concatenation, override:
+			v = concatenation{joinPart(concatParts(o), []Value{v}), off}
+		case nil:
+			if at >= 0 {
+				m.fail(off, codeOther, "cannot append to "+path[0]+", which is null")
+				return obj
+			}
+			v = []Value{v}
+		default:
+			m.fail(off, codeOther, "cannot append to "+path[0]+", which is not an array")
+			return obj
+		}
+	default:
+		switch n := v.(type) {
+		case Object:
+			switch o := old.(type) {
+			case Object:
+				if !m.resolving {
+					v = mergeObjects(o, n)
+					break
+				}
+				// Field by field, so that the fields of n keep
+				// the values they override.
+				for _, f := range n {
+					o = m.set(o, []string{f.Key}, f.Value, false, off)
+				}
+				v = o
+			case override:
+				v = o.extend(n, off)
+			case Subst, concatenation:
+				v = concatenation{joinPart(concatParts(o), n), off}
+			}
+		case Subst, concatenation:
+			if (m.resolving || m.overrides) && at >= 0 {
+				v = override{v, old, off}
+			}
+		}
+	}
+	if at >= 0 {
+		obj[at].Value = v
+		return obj
+	}
+	return append(obj, Field{path[0], v})
+}
+
+// extend returns o with the object n merged into its value, as a later
+// field merges n into the field o sets. Resolving it gives what the
+// fields would: if the value of o is undefined, n is left, and merges
+// into prev if that is an object.
+func (o override) extend(n Object, off int) override {
+	return override{concatenation{joinPart(concatParts(o.value), n), off}, o.prev, o.off}
+}
+
+// concatParts returns the parts of v, a Subst or a concatenation.
+func concatParts(v Value) []Value {
+	if c, ok := v.(concatenation); ok {
+		return append([]Value(nil), c.parts...)
+	}
+	return []Value{v}
+}
+
+// mergeObjects merges the fields of n into o, the fields of objects
+// found in both merging in turn.
+func mergeObjects(o, n Object) Object {
+	for _, f := range n {
+		at := -1
+		for i, g := range o {
+			if g.Key == f.Key {
+				at = i
+			}
+		}
+		if at < 0 {
+			o = append(o, f)
+			continue
+		}
+		old, oldOK := o[at].Value.(Object)
+		if nv, ok := f.Value.(Object); ok && oldOK {
+			o[at].Value = mergeObjects(old, nv)
+			continue
+		}
+		o[at].Value = f.Value
+	}
+	return o
+}
@@ -0,0 +1,46 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMergeDocument(t *testing.T) {
+	tests := []struct {
+		src  string
+		want Value
+	}{
+		{"a.b = 1\na { c = 2 }\na.b = 3", Object{{"a", Object{{"b", json.Number("3")}, {"c", json.Number("2")}}}}},
+		{"a = { b = 1 }\na = 2", Object{{"a", json.Number("2")}}},
+		{"a = [1]\na += 2\nb += x", Object{{"a", []Value{json.Number("1"), json.Number("2")}}, {"b", []Value{"x"}}}},
+		{"a = [1] [2]\nb = x 10 true", Object{{"a", []Value{json.Number("1"), json.Number("2")}}, {"b", "x 10 true"}}},
+		{"a = ${b}\na { c = 1 }", Object{{"a", concatenation{[]Value{Subst{Path: "b"}, Object{{"c", json.Number("1")}}}, 9}}}},
+		{"a = ${?b} c", Object{{"a", concatenation{[]Value{Subst{Path: "b", Optional: true}, " c"}, 4}}}},
+		{"a.b = 1\na.b = ${a.b}\nc = ${?c}", Object{{"a", Object{{"b", json.Number("1")}}}, {"c", Subst{Path: "c", Optional: true}}}},
+	}
+	for _, tt := range tests {
+		v, err := mergeDocument([]byte(tt.src))
+		if err != nil {
+			t.Errorf("mergeDocument(%q): %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, tt.want) {
+			t.Errorf("mergeDocument(%q) = %#v, want %#v", tt.src, v, tt.want)
+		}
+	}
+}
+
+func TestMergeDocumentErrors(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"a = null\na += 1", "2:1: cannot append to a, which is null"},
+		{"a = 1\na += 1", "2:1: cannot append to a, which is not an array"},
+		{"a { include \"b.conf\" }", "1:5: cannot merge an include statement without following it"},
+	}
+	for _, tt := range tests {
+		_, err := mergeDocument([]byte(tt.src))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("mergeDocument(%q) = %v, want %s", tt.src, err, tt.want)
+		}
+	}
+}
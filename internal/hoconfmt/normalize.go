@@ -0,0 +1,139 @@
+package hoconfmt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// spaceSeparators puts exactly one space on each side of the separator
+// between a key and its value, so that a=1 and b  :  2 become a = 1
+// and b : 2. The separator itself is kept; a separator at the end of
+// its line gets no space after it.
+func spaceSeparators(lx *lexer, src []byte) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, 0, len(src))
+	var open []tokenKind // enclosing { and [
+	atField := true      // at the start of a field in an object
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		inObject := len(open) == 0 || open[len(open)-1] == tokLBrace
+		if atField && inObject && (t.kind == tokUnquoted || t.kind == tokString) {
+			if j := fieldSep(toks, i); j > 0 {
+				atField = false
+				k := j
+				for k > i && toks[k-1].kind == tokSpace {
+					k--
+				}
+				for _, t := range toks[i:k] {
+					res = append(res, t.lit...)
+				}
+				res = append(res, ' ')
+				res = append(res, toks[j].lit...)
+				k = j + 1
+				for k < len(toks) && toks[k].kind == tokSpace {
+					k++
+				}
+				if k < len(toks) && toks[k].kind != tokNewline {
+					res = append(res, ' ')
+				}
+				i = k - 1
+				continue
+			}
+		}
+
+		switch t.kind {
+		case tokLBrace, tokLBracket:
+			open = append(open, t.kind)
+			atField = t.kind == tokLBrace
+		case tokRBrace, tokRBracket:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			atField = true
+		case tokNewline, tokComma:
+			atField = true
+		case tokSpace, tokComment:
+		default:
+			atField = false
+		}
+		res = append(res, t.lit...)
+	}
+	return res, nil
+}
+
+// spaceComments inserts a space after the marker of a comment that
+// starts right away with an ASCII letter or digit, so that #note
+// becomes # note. Banners such as #### or //---, and hoconfmt's own
+// directives, are left as they are.
+func spaceComments(lx *lexer, src []byte) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, 0, len(src))
+	for _, t := range toks {
+		if t.kind == tokComment {
+			marker := "#"
+			if strings.HasPrefix(t.lit, "//") {
+				marker = "//"
+			}
+			text := t.lit[len(marker):]
+			if isWordByte(text, 0) && !strings.HasPrefix(text, "hoconfmt") {
+				res = append(res, marker+" "+text...)
+				continue
+			}
+		}
+		res = append(res, t.lit...)
+	}
+	return res, nil
+}
+
+// Values of the -comment-style flag.
+const (
+	commentsPreserve = "preserve" // as written
+	commentsHash     = "hash"     // #
+	commentsSlash    = "slash"    // //
+)
+
+func checkCommentStyle(style string) error {
+	switch style {
+	case "", commentsPreserve, commentsHash, commentsSlash:
+		return nil
+	}
+	return fmt.Errorf("invalid -comment-style %q: must be %s, %s or %s", style, commentsPreserve, commentsHash, commentsSlash)
+}
+
+// setCommentMarkers starts every comment of src with the marker of
+// style, commentsHash or commentsSlash, whether it is on a line of its
+// own or after a value. Only the marker changes, so #### becomes //###
+// under commentsSlash. A // comment directly after other text gets a
+// space before it: in a = x/#note or a = http:#note, writing // right
+// away would make the comment part of the value.
+func setCommentMarkers(src []byte, style string) ([]byte, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := "//", "#"
+	if style == commentsSlash {
+		from, to = "#", "//"
+	}
+	var res []byte
+	for i, t := range toks {
+		if t.kind == tokComment && strings.HasPrefix(t.lit, from) {
+			if style == commentsSlash && i > 0 && toks[i-1].kind != tokSpace && toks[i-1].kind != tokNewline {
+				res = append(res, ' ')
+			}
+			res = append(res, to+t.lit[len(from):]...)
+			continue
+		}
+		res = append(res, t.lit...)
+	}
+	return res, nil
+}
@@ -0,0 +1,100 @@
+package hoconfmt
+
+import "testing"
+
+func TestSpaceSeparators(t *testing.T) {
+	for _, test := range []struct {
+		src, want string
+	}{
+		{"a=1\n", "a = 1\n"},
+		{"a  :  2\n", "a : 2\n"},
+		{"a+=x\n", "a += x\n"},
+		{"a = {\n    b=c:d\n}\n", "a = {\n    b = c:d\n}\n"},
+		{"\"k\"=[{x=1}]\n", "\"k\" = [{x = 1}]\n"},
+		{"a =\n", "a =\n"},
+		// Separators inside values are part of the value.
+		{"a = \"x=y\"\nb = [\"c:d\", e=f]\n", "a = \"x=y\"\nb = [\"c:d\", e=f]\n"},
+	} {
+		got, err := spaceSeparators(nil, []byte(test.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("spaceSeparators(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestSpaceComments(t *testing.T) {
+	for _, test := range []struct {
+		src, want string
+	}{
+		{"#note\n", "# note\n"},
+		{"a = 1 //note\n", "a = 1 // note\n"},
+		{"# note\n", "# note\n"},
+		{"#####\n//---\n#!x\n", "#####\n//---\n#!x\n"},
+		{"#hoconfmt:off\n//hoconfmt -w\n", "#hoconfmt:off\n//hoconfmt -w\n"},
+		{"#\n", "#\n"},
+	} {
+		got, err := spaceComments(nil, []byte(test.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("spaceComments(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestSetCommentMarkers(t *testing.T) {
+	for _, test := range []struct {
+		style, src, want string
+	}{
+		{commentsHash, "// note\na = 1 // why\n", "# note\na = 1 # why\n"},
+		{commentsSlash, "# note\na = 1 # why\n", "// note\na = 1 // why\n"},
+		{commentsHash, "# note\n", "# note\n"},
+		{commentsSlash, "####\n", "//###\n"},
+		{commentsHash, "a = \"x // y\"\nb = \"\"\"# z\n// w\"\"\"\n", "a = \"x // y\"\nb = \"\"\"# z\n// w\"\"\"\n"},
+		{commentsHash, "url = http://host/path // home\n", "url = http://host/path # home\n"},
+		{commentsSlash, "a = x/#note\nb = http:#note\nc = 1#note\n", "a = x/ //note\nb = http: //note\nc = 1 //note\n"},
+	} {
+		got, err := setCommentMarkers([]byte(test.src), test.style)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("setCommentMarkers(%q, %s) = %q, want %q", test.src, test.style, got, test.want)
+		}
+		again, err := setCommentMarkers(got, test.style)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(again) != string(got) {
+			t.Errorf("setCommentMarkers(%q, %s) is not idempotent: %q", got, test.style, again)
+		}
+	}
+
+	if err := checkCommentStyle("c"); err == nil {
+		t.Error("checkCommentStyle accepted c")
+	}
+}
+
+func TestNormalizeAllTwice(t *testing.T) {
+	const src = "a.${?b}.c: 1\nd.\"${e}\": 2\nf=3\n"
+	opts := DefaultOptions()
+	opts.NormalizeAll = true
+	once, err := formatFile("a.conf", []byte(src), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a.${?b}.c: 1\nd.\"${e}\" : 2\nf = 3\n"; string(once) != want {
+		t.Errorf("formatting %q = %q, want %q", src, once, want)
+	}
+	twice, err := formatFile("a.conf", once, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(twice) != string(once) {
+		t.Errorf("formatting %q again = %q", once, twice)
+	}
+}
@@ -0,0 +1,100 @@
+package hoconfmt
+
+import "fmt"
+
+// Values of the -object-eq flag.
+const (
+	objectEqOmit    = "omit"    // key { ... }
+	objectEqRequire = "require" // key = { ... }
+)
+
+func checkObjectEq(style string) error {
+	switch style {
+	case "", objectEqOmit, objectEqRequire:
+		return nil
+	}
+	return fmt.Errorf("invalid -object-eq %q: must be %s or %s", style, objectEqOmit, objectEqRequire)
+}
+
+// normalizeObjectEq rewrites the fields of src whose value is an object
+// to the given style: with the separator omitted (key { ... }) or
+// required (key = { ... }); the empty style omits it. A ':' separator
+// counts as a separator. Fields using += and fields whose value merely
+// contains an object, as in a concatenation, are left alone.
+func normalizeObjectEq(lx *lexer, src []byte, style string) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, 0, len(src))
+	var open []tokenKind // enclosing { and [
+	atField := true      // at the start of a field in an object
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		inObject := len(open) == 0 || open[len(open)-1] == tokLBrace
+		if atField && inObject && (t.kind == tokUnquoted || t.kind == tokString) {
+			keyEnd, brace, hasSep := objectField(toks, i)
+			if brace > 0 && hasSep == (style != objectEqRequire) {
+				for _, k := range toks[i:keyEnd] {
+					res = append(res, k.lit...)
+				}
+				if style != objectEqRequire {
+					res = append(res, ' ')
+				} else {
+					res = append(res, " = "...)
+				}
+				i = brace - 1
+				atField = false
+				continue
+			}
+		}
+
+		switch t.kind {
+		case tokLBrace, tokLBracket:
+			open = append(open, t.kind)
+			atField = t.kind == tokLBrace
+		case tokRBrace, tokRBracket:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			atField = true
+		case tokNewline, tokComma:
+			atField = true
+		case tokSpace, tokComment:
+		default:
+			atField = false
+		}
+		res = append(res, t.lit...)
+	}
+	return res, nil
+}
+
+// objectField examines the field starting with the key at toks[i]. If
+// its value starts with an opening brace, objectField returns the end
+// of the key, the index of the brace and whether a '=' or ':' separator
+// stands between them; otherwise brace is 0.
+func objectField(toks []tok, i int) (keyEnd, brace int, hasSep bool) {
+	j := i
+	for j < len(toks) {
+		switch toks[j].kind {
+		case tokUnquoted, tokString:
+			j++
+			keyEnd = j
+			continue
+		case tokSpace:
+			j++
+			continue
+		}
+		break
+	}
+	if j < len(toks) && toks[j].kind == tokSep && toks[j].lit != "+=" {
+		hasSep = true
+		for j++; j < len(toks) && toks[j].kind == tokSpace; j++ {
+		}
+	}
+	if j < len(toks) && toks[j].kind == tokLBrace {
+		return keyEnd, j, hasSep
+	}
+	return 0, 0, false
+}
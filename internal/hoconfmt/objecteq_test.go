@@ -0,0 +1,57 @@
+package hoconfmt
+
+import "testing"
+
+func TestNormalizeObjectEq(t *testing.T) {
+	const src = `a {
+    b = {
+        c: { d = 1 }
+        e = { f = 2 }
+    }
+    g += { h = 3 }
+    i = ${x} { j = 4 }
+    list = [ { k = { l = 5 } } ]
+    "quoted key" = {}
+    m = 1 n { }
+}
+`
+	for _, test := range []struct {
+		style, want string
+	}{
+		{objectEqOmit, `a {
+    b {
+        c { d = 1 }
+        e { f = 2 }
+    }
+    g += { h = 3 }
+    i = ${x} { j = 4 }
+    list = [ { k { l = 5 } } ]
+    "quoted key" {}
+    m = 1 n { }
+}
+`},
+		{objectEqRequire, `a = {
+    b = {
+        c: { d = 1 }
+        e = { f = 2 }
+    }
+    g += { h = 3 }
+    i = ${x} { j = 4 }
+    list = [ { k = { l = 5 } } ]
+    "quoted key" = {}
+    m = 1 n { }
+}
+`},
+	} {
+		got, err := normalizeObjectEq(nil, []byte(src), test.style)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("-object-eq %s: got\n%s\nwant\n%s", test.style, got, test.want)
+		}
+		if again, _ := normalizeObjectEq(nil, got, test.style); string(again) != string(got) {
+			t.Errorf("-object-eq %s is not idempotent: got\n%s", test.style, again)
+		}
+	}
+}
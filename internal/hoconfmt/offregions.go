@@ -0,0 +1,155 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Comments that turn formatting off and on again.
+const (
+	formatOff = "hoconfmt:off"
+	formatOn  = "hoconfmt:on"
+)
+
+// offRegions returns the regions of src that are protected from
+// formatting. A region runs from the start of the line holding a
+// "# hoconfmt:off" or "// hoconfmt:off" comment to the end of the line
+// holding the next "hoconfmt:on" comment. An off comment without a
+// matching on comment protects the rest of the file; an on comment
+// outside a region is ignored.
+func offRegions(lx *lexer, src []byte) ([]span, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []span
+	start := -1
+	for _, t := range toks {
+		if t.kind != tokComment {
+			continue
+		}
+		text := strings.TrimPrefix(strings.TrimPrefix(t.lit, "#"), "//")
+		switch strings.TrimSpace(text) {
+		case formatOff:
+			if start < 0 {
+				start = bytes.LastIndexByte(src[:t.off], '\n') + 1
+			}
+		case formatOn:
+			if start >= 0 {
+				end := t.off + len(t.lit)
+				if end < len(src) && src[end] == '\n' {
+					end++
+				}
+				regions = append(regions, span{start, end})
+				start = -1
+			}
+		}
+	}
+	if start >= 0 {
+		regions = append(regions, span{start, len(src)})
+	}
+	return regions, nil
+}
+
+// endsOff reports whether src ends inside a hoconfmt:off region, one
+// with no hoconfmt:on comment after it.
+func endsOff(src []byte) bool {
+	toks, err := tokenize(src)
+	if err != nil {
+		return false
+	}
+	off := false
+	for _, t := range toks {
+		if t.kind != tokComment {
+			continue
+		}
+		switch strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(t.lit, "#"), "//")) {
+		case formatOff:
+			off = true
+		case formatOn:
+			off = false
+		}
+	}
+	return off
+}
+
+// restoreOffRegions copies the protected regions of src into the
+// formatted res, replacing their formatted form.
+func restoreOffRegions(lx *lexer, src, res []byte) ([]byte, error) {
+	before, err := offRegions(lx, src)
+	if err != nil || len(before) == 0 {
+		return res, err
+	}
+	after, err := offRegions(lx, res)
+	if err != nil {
+		return nil, err
+	}
+	if len(before) != len(after) {
+		return nil, fmt.Errorf("formatting changed the %s regions: %d before, %d after", formatOff, len(before), len(after))
+	}
+	var prev span
+	for i := range before {
+		if i > 0 {
+			prev = span{before[i-1].end, after[i-1].end}
+		}
+		if err := alignOffLine(src, res, &before[i], &after[i], prev); err != nil {
+			return nil, err
+		}
+	}
+	return restoreSpans(src, res, before, after), nil
+}
+
+// alignOffLine widens the regions b of src and a of res back by whole
+// lines until the text before the off comment is the same in both. A
+// region starts at the line of its comment, and formatting may have
+// joined earlier lines into that one, as it does for a } after the
+// comment's {. The regions stay after the ends of the previous ones,
+// min.start in src and min.end in res. A line that does not tokenize on
+// its own, as in the middle of a multi-line string, is left as it is.
+func alignOffLine(src, res []byte, b, a *span, min span) error {
+	for {
+		want, err := offLineLits(src[b.start:])
+		if err != nil {
+			return nil
+		}
+		got, err := offLineLits(res[a.start:])
+		if err != nil {
+			return nil
+		}
+		switch {
+		case strings.Join(want, " ") == strings.Join(got, " "):
+			return nil
+		case len(want) < len(got) && b.start > min.start:
+			b.start = bytes.LastIndexByte(src[:b.start-1], '\n') + 1
+		case len(want) >= len(got) && a.start > min.end:
+			a.start = bytes.LastIndexByte(res[:a.start-1], '\n') + 1
+		default:
+			return fmt.Errorf("formatting moved text into the line of a %s comment", formatOff)
+		}
+	}
+}
+
+// offLineLits returns the tokens of src up to its first off comment,
+// but for white space.
+func offLineLits(src []byte) ([]string, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	var lits []string
+	for _, t := range toks {
+		switch t.kind {
+		case tokSpace, tokNewline:
+			continue
+		case tokComment:
+			text := strings.TrimPrefix(strings.TrimPrefix(t.lit, "#"), "//")
+			if strings.TrimSpace(text) == formatOff {
+				return lits, nil
+			}
+		}
+		lits = append(lits, t.lit)
+	}
+	return lits, nil
+}
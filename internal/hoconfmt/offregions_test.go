@@ -0,0 +1,59 @@
+package hoconfmt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOffRegions(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		want []string
+	}{
+		{"a = 1\n# hoconfmt:off\nb  =  2\n# hoconfmt:on\nc = 3\n", []string{"# hoconfmt:off\nb  =  2\n# hoconfmt:on\n"}},
+		{"a {\n  //hoconfmt:off\n  b = 1\n  // hoconfmt:on\n}\n", []string{"  //hoconfmt:off\n  b = 1\n  // hoconfmt:on\n"}},
+		// An off without an on runs to the end of the file.
+		{"a = 1\n# hoconfmt:off\nb = 2", []string{"# hoconfmt:off\nb = 2"}},
+		// A second off inside a region and an on outside are ignored.
+		{"# hoconfmt:on\n# hoconfmt:off\n# hoconfmt:off\n# hoconfmt:on\na = 1\n", []string{"# hoconfmt:off\n# hoconfmt:off\n# hoconfmt:on\n"}},
+		// Only whole comments count as markers.
+		{"# see hoconfmt:off\na = \"# hoconfmt:off\"\n", nil},
+	} {
+		regions, err := offRegions(nil, []byte(test.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range regions {
+			got = append(got, test.src[r.start:r.end])
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%q: got %q, want %q", test.src, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%q: got %q, want %q", test.src, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestOffCommentAfterJoinedLines(t *testing.T) {
+	// Formatting joins the { and } into {}, but both lines are kept as
+	// written.
+	for _, src := range []string{
+		"{\n}#hoconfmt:off",
+		"a = 1\nb {\n}  # hoconfmt:off\nc   = 2\n",
+	} {
+		got, err := formatFile("x.conf", []byte(src), DefaultOptions())
+		if err != nil {
+			t.Errorf("formatFile(%q): %v", src, err)
+			continue
+		}
+		if !strings.HasSuffix(string(got), src[strings.Index(src, "{"):]) {
+			t.Errorf("formatFile(%q) = %q, want the lines from the { kept", src, got)
+		}
+	}
+}
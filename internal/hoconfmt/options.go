@@ -0,0 +1,496 @@
+package hoconfmt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Options control how Format formats a file. The zero value gives
+// the default formatting; every formatting flag sets one field.
+type Options struct {
+	// Touch only fixes line endings, trailing white space and the
+	// final newline, and ignores every other option (-touch).
+	Touch bool
+
+	// Reprint lays the document out again from its syntax tree instead
+	// of reindenting its lines: every field, include and array element
+	// that is not in an array kept on one line goes on a line of its
+	// own, with canonical spacing (-reprint). The other options apply
+	// to the result.
+	Reprint bool
+
+	// EditorConfig takes indentation settings from the nearest
+	// .editorconfig file (-editorconfig).
+	EditorConfig bool
+
+	// Indent is the indentation of one nesting level wherever hoconfmt
+	// indents: one or more spaces, or a single tab. It takes precedence
+	// over EditorConfig. The empty string means four spaces, or the
+	// .editorconfig setting (-indent-string).
+	Indent string
+
+	// FromProperties reads the input as a Java .properties file and
+	// converts it to HOCON (-from-properties).
+	FromProperties bool
+
+	// NormalizeAll applies the normalizations that never change what a
+	// document means, on top of the default formatting: one space on
+	// each side of every key/value separator, a space after comment
+	// markers that are directly followed by a word, no trailing white
+	// space, and no blank lines at the end of the file
+	// (-normalize-all). Blank lines are collapsed to MaxBlank, the file
+	// ends in a newline, and object separators follow ObjectEq either
+	// way. Nothing is quoted or unquoted, not even the substitutions in
+	// keys that the default formatting quotes, and no value is
+	// rewritten.
+	NormalizeAll bool
+
+	// FixBraces adds a missing final } when it can only belong at the
+	// end of the file (-fix-braces).
+	FixBraces bool
+
+	// FixCommas removes leading and doubled commas, as in [,1] and
+	// [1,,2], with a warning for each; without it they are errors
+	// (-fix-commas).
+	FixCommas bool
+
+	// FixQuotes removes the quotes that keys and field values do not
+	// need and adds those they do, writes strings of several lines as
+	// multi-line strings, and spaces durations and sizes consistently
+	// (-fix-quotes).
+	FixQuotes bool
+
+	// Strict rejects the constructs that are accepted by default but
+	// that the HOCON specification does not allow: include targets
+	// without quotes, separators on a line of their own, and /* */
+	// block comments (-strict).
+	Strict bool
+
+	// KeepJSONValues leaves values written as strict JSON as they are
+	// instead of giving them the style of the rest of the file
+	// (-keep-json-values).
+	KeepJSONValues bool
+
+	// TrimSubsts removes the white space around the paths of
+	// substitutions, as in ${ a.b } (-trim-substs).
+	TrimSubsts bool
+
+	// SnapshotEnv replaces optional substitutions of environment
+	// variables that are set, such as ${?PORT}, with their current
+	// value and a comment naming the substitution (-snapshot-env).
+	SnapshotEnv bool
+
+	// ObjectEq is the separator style for keys whose value is an
+	// object: "omit" or "require". The empty string means "omit"
+	// (-object-eq).
+	ObjectEq string
+
+	// Separators is the separator style of fields whose value is not
+	// an object: "keep", "equals" or "colon". The empty string means
+	// "keep" (-separators).
+	Separators string
+
+	// KeyCase renames unquoted keys, and the substitutions of their
+	// paths, to one case: "preserve", "lower", "upper" or "camel". The
+	// empty string means "preserve" (-key-case).
+	KeyCase string
+
+	// UnicodeNFC rewrites keys, unquoted values and the paths of
+	// substitutions to Unicode normalization form C, so that keys that
+	// look the same are the same (-unicode-normalize).
+	// UnicodeNFCStrings rewrites quoted and multi-line strings too,
+	// changing the bytes of their values, and implies UnicodeNFC
+	// (-unicode-normalize-strings).
+	UnicodeNFC        bool
+	UnicodeNFCStrings bool
+
+	// KeyPaths writes the fields inside objects with paths of one style:
+	// "keep", "dotted" (a.b = 1) or "nested" (a { b = 1 }). The empty
+	// string means "keep" (-key-paths).
+	KeyPaths string
+
+	// Simplify merges the objects of a key, writes fields whose value
+	// is an object of one field as a dotted path, or as KeyPaths says,
+	// and drops the fields that set a path to the value it already has
+	// (-s).
+	Simplify bool
+
+	// Commas is the comma style: "keep", or "smart" to keep only the
+	// commas between elements on the same line. The empty string means
+	// "keep" (-commas).
+	Commas string
+
+	// Comments is the comment marker style: "preserve", "hash" for #
+	// or "slash" for //. The empty string means "preserve"
+	// (-comment-style).
+	Comments string
+
+	// WrapComments breaks comment lines that are wider than Width
+	// (-wrap-comments).
+	WrapComments bool
+
+	// Width is the line width comments and arrays are wrapped to.
+	// Zero means 80 (-width).
+	Width int
+
+	// ContinuationIndent wraps arrays that are wider than Width and
+	// places the continuation lines under the first element ("align")
+	// or one indent deeper than the line ("indent"). The empty string leaves arrays alone
+	// (-continuation-indent).
+	ContinuationIndent string
+
+	// ArrayPerLine regroups the elements of arrays spread over several
+	// lines whose elements are single numbers, strings or words: a
+	// positive value puts up to that many on each line, and a negative
+	// one as many as fit in Width. Zero leaves them as written
+	// (-array-per-line, where 0 fits them to the width and -1, the
+	// default, leaves them).
+	ArrayPerLine int
+
+	// ArrayColumns lays out arrays of more than that many numbers,
+	// strings or words, and matrices of such arrays, in right-aligned
+	// columns of that many elements to a row. Zero leaves them
+	// (-array-columns).
+	ArrayColumns int
+
+	// Empty is the layout of empty objects and arrays: "compact" or
+	// "expanded". The empty string means "compact" (-empty).
+	Empty string
+
+	// MaxBlank is the number of consecutive blank lines kept; longer
+	// runs are shortened. Zero means 1, and a negative
+	// value keeps no blank lines at all (-max-blank).
+	MaxBlank int
+
+	// AlignComments lines up the trailing comments of consecutive
+	// lines (-align-comments).
+	AlignComments bool
+
+	// AlignThreshold leaves the lines whose code is more than this many
+	// columns wider than the median line of their group out of the
+	// comment column. Zero aligns every line (-align-threshold).
+	AlignThreshold int
+
+	// IncludeSpacing surrounds top-level include statements with blank
+	// lines (-include-spacing).
+	IncludeSpacing bool
+
+	// InlineIncludes replaces the top-level include statements whose
+	// file is smaller than this many bytes with the text of the file, if
+	// it is not 0 (-inline-includes-under).
+	InlineIncludes int
+
+	// IncludePath lists the directories, separated as in $PATH, where
+	// InlineIncludes looks for files besides the directory of the
+	// including file (-include-path).
+	IncludePath string
+
+	// EOL is the line ending of the output: "lf", "crlf" or "keep".
+	// The empty string means "lf" (-eol).
+	EOL string
+
+	// Preserve is a comma-separated list of key path globs, such as
+	// "scripts.*,tls.cert". The values of matching keys are copied
+	// byte for byte (-preserve).
+	Preserve string
+
+	// SortArrays is a comma-separated list of path=field pairs naming
+	// arrays whose objects are sorted by one of their fields
+	// (-sort-array).
+	SortArrays string
+
+	// SortKeys orders the fields of every object by key, byte by byte,
+	// keeping the fields around include statements and blank lines in
+	// their runs (-sort).
+	SortKeys bool
+
+	// SortFirst is a comma-separated list of keys that SortKeys puts
+	// before the others of each object holding them, in the order of the
+	// list (-sort-first).
+	SortFirst string
+
+	// IncludeKeys and ExcludeKeys are comma-separated lists of key
+	// path globs, written like Preserve, that select the fields of the
+	// output. If IncludeKeys is set, only the keys matching it and what
+	// is inside them are kept; keys matching ExcludeKeys are removed,
+	// with what is inside them, in any case. Objects left empty by the
+	// removals go too, unless KeepEmpty is set (-include-keys,
+	// -exclude-keys, -keep-empty).
+	IncludeKeys string
+	ExcludeKeys string
+	KeepEmpty   bool
+
+	// Rewrite holds rules, one per line, such as "a.b -> c.d", that
+	// move the value at one key path and what is inside it to another
+	// before formatting, leaving the rest of the file as it is (-r,
+	// -r-file).
+	Rewrite string
+
+	// WarnDuplicates prints a warning for every field that replaces
+	// the value of an earlier field with the same key path, and for
+	// every include statement whose values later fields or includes
+	// all replace, or a key that a file and a file it includes make one
+	// an object and the other a value (-warn-duplicates).
+	WarnDuplicates bool
+
+	// WarnConcat prints a warning for every array element that joins
+	// several values with white space, as in [a b], which is one
+	// concatenated value and often a missing comma (-warn-concat).
+	WarnConcat bool
+
+	// ModifiedBlocksOnly keeps the top-level blocks that formatting
+	// would change only in white space as they are written, so that
+	// only the blocks needing real changes are rewritten
+	// (-only-format-modified-blocks).
+	ModifiedBlocksOnly bool
+
+	// NoLoseComments fails with an error if a comment of the input
+	// does not appear in the result (-no-lose-comments).
+	NoLoseComments bool
+
+	// Verify fails with an error naming the first field whose value
+	// the result would change, drop or add, compared with the input
+	// (-verify, and always with -w). With a ValueTransformer or a
+	// UnitClassifier it also fails if formatting the result again would
+	// change it, as an extension that does not give the same result
+	// twice makes it.
+	Verify bool
+
+	// RootBraces adds ("require") or removes ("omit") the braces
+	// around the whole document. The empty string keeps them as
+	// written (-root-braces).
+	RootBraces string
+
+	// Explain adds a # type: comment to the line of every field, naming
+	// the type of its value (-explain).
+	Explain bool
+
+	// UnitClassifier recognizes units besides those of durations,
+	// periods and sizes, which are always recognized, so that values
+	// such as 10 req/s are kept as typed values. Nil recognizes no
+	// others. It is set by programs using the library rather than by a
+	// flag.
+	UnitClassifier UnitClassifier
+
+	// ValueTransformer rewrites the values of fields after they are
+	// verified, such as to write host names in lower case. Nil leaves
+	// them. It is set by programs using the library rather than by a
+	// flag.
+	ValueTransformer ValueTransformer
+
+	// Warnings receives the warnings about the input, such as a comma
+	// removed by FixCommas, as lines of text. Nil means standard
+	// error. It is set by the caller rather than by a flag.
+	Warnings io.Writer
+}
+
+// defaultWidth is the default line width.
+const defaultWidth = 80
+
+// DefaultOptions returns the options of a hoconfmt run without flags.
+func DefaultOptions() Options {
+	return Options{ObjectEq: objectEqOmit, Separators: separatorsKeep, KeyCase: keyCasePreserve, KeyPaths: keyPathsKeep, Width: defaultWidth, Empty: emptyCompact, Commas: commasKeep, Comments: commentsPreserve, MaxBlank: defaultMaxBlank, EOL: eolLF}
+}
+
+// flagOptions returns the options selected on the command line: those
+// of the -profile, if there is one, overridden by the flags given.
+func flagOptions() Options {
+	opts := Options{
+		Touch:              *touchOnly,
+		Reprint:            *reprintAll,
+		EditorConfig:       *useEditorConfig,
+		Indent:             indentOption(),
+		FromProperties:     *fromProperties,
+		NormalizeAll:       *normalizeAll,
+		FixBraces:          *fixBrace,
+		FixCommas:          *fixComma,
+		FixQuotes:          *fixQuote,
+		Strict:             *strict,
+		KeepJSONValues:     *keepJSON,
+		TrimSubsts:         *tightSubst,
+		SnapshotEnv:        *snapshotEnvs,
+		ObjectEq:           *objectEq,
+		Separators:         *sepStyle,
+		KeyCase:            *keyCase,
+		UnicodeNFC:         *unicodeNorm,
+		UnicodeNFCStrings:  *unicodeStrs,
+		KeyPaths:           *keyPaths,
+		Simplify:           *simplifyAll,
+		Commas:             *commaStyle,
+		Comments:           *commentStyle,
+		WrapComments:       *wrapComment,
+		Width:              lineWidth.width(os.Stdout),
+		ContinuationIndent: *contIndent,
+		ArrayPerLine:       arrayPerLineOption(*arrayPerLine),
+		ArrayColumns:       *arrayColumns,
+		Empty:              *emptyStyle,
+		MaxBlank:           maxBlankOption(*maxBlank),
+		AlignComments:      *alignComment,
+		AlignThreshold:     *alignLimit,
+		IncludeSpacing:     *includeSpacing,
+		InlineIncludes:     *inlineUnder,
+		IncludePath:        *inclPath,
+		EOL:                *eol,
+		Preserve:           *preserve,
+		SortArrays:         *sortArray,
+		SortKeys:           *sortFields,
+		SortFirst:          *sortFirst,
+		IncludeKeys:        *includeKeys,
+		ExcludeKeys:        *excludeKeys,
+		KeepEmpty:          *keepEmpty,
+		Rewrite:            rewriteRules.String(),
+		WarnDuplicates:     *warnDups,
+		WarnConcat:         *warnConcat,
+		ModifiedBlocksOnly: *blocksOnly,
+		NoLoseComments:     *noLoseComments,
+		Verify:             *verifyValue || *write,
+		RootBraces:         *rootBrace,
+		Explain:            *explain,
+	}
+	if p, ok := profileOptions(*profile); ok {
+		opts = applyProfile(opts, p, optionGiven)
+	}
+	return opts
+}
+
+// indentOption returns the Indent option of -indent-string, or of
+// -usetabs and -tabwidth, which say the same as -indent-string='\t'
+// and -indent-string with that many spaces.
+func indentOption() string {
+	switch {
+	case *indentTabs:
+		return "\t"
+	case *indentWidth > 0:
+		return strings.Repeat(" ", *indentWidth)
+	}
+	return strings.ReplaceAll(*indentStr, `\t`, "\t")
+}
+
+// optionGiven is flagGiven for the options of the flags, where
+// -tabwidth and -usetabs set the indentation that -indent-string sets.
+func optionGiven(name string) bool {
+	if name == "indent-string" && (flagGiven("tabwidth") || flagGiven("usetabs")) {
+		return true
+	}
+	return flagGiven(name)
+}
+
+// checkIndentFlags reports an invalid -tabwidth and the use of more than
+// one of the flags that set the indentation.
+func checkIndentFlags() error {
+	if flagGiven("tabwidth") && *indentWidth < 1 {
+		return fmt.Errorf("invalid -tabwidth %d: must be 1 or more", *indentWidth)
+	}
+	n := 0
+	for _, name := range []string{"indent-string", "tabwidth", "usetabs"} {
+		if flagGiven(name) {
+			n++
+		}
+	}
+	if n > 1 {
+		return errors.New("cannot use more than one of -indent-string, -tabwidth and -usetabs")
+	}
+	return nil
+}
+
+// maxBlankOption returns the MaxBlank option of -max-blank=n, where 0
+// keeps no blank lines.
+func maxBlankOption(n int) int {
+	if n == 0 {
+		return -1
+	}
+	return n
+}
+
+// arrayPerLineOption returns the ArrayPerLine option of
+// -array-per-line=n, where 0 fits the elements to the width and -1
+// leaves them.
+func arrayPerLineOption(n int) int {
+	switch {
+	case n == 0:
+		return -1
+	case n < 0:
+		return 0
+	}
+	return n
+}
+
+// check reports invalid values and combinations of options.
+func (o Options) check() error {
+	if err := checkObjectEq(o.ObjectEq); err != nil {
+		return err
+	}
+	if err := checkEmpty(o.Empty); err != nil {
+		return err
+	}
+	if err := checkCommas(o.Commas); err != nil {
+		return err
+	}
+	if err := checkSeparators(o.Separators); err != nil {
+		return err
+	}
+	if err := checkKeyCase(o.KeyCase); err != nil {
+		return err
+	}
+	if err := checkKeyPaths(o.KeyPaths); err != nil {
+		return err
+	}
+	if err := checkCommentStyle(o.Comments); err != nil {
+		return err
+	}
+	if err := checkContinuationIndent(o.ContinuationIndent); err != nil {
+		return err
+	}
+	if err := checkEOL(o.EOL); err != nil {
+		return err
+	}
+	if err := checkPreserve(o.Preserve); err != nil {
+		return err
+	}
+	if err := checkSortFirst(o.SortFirst); err != nil {
+		return err
+	}
+	if err := checkSortArrays(o.SortArrays); err != nil {
+		return err
+	}
+	if err := checkGlobs("-include-keys", o.IncludeKeys); err != nil {
+		return err
+	}
+	if err := checkGlobs("-exclude-keys", o.ExcludeKeys); err != nil {
+		return err
+	}
+	if err := checkRootBraces(o.RootBraces); err != nil {
+		return err
+	}
+	if _, err := parseRewriteRules(o.Rewrite); err != nil {
+		return err
+	}
+	if o.Indent != "" && o.Indent != "\t" && strings.Trim(o.Indent, " ") != "" {
+		return fmt.Errorf("invalid -indent-string %q: must be spaces or a single tab", o.Indent)
+	}
+	if o.AlignThreshold < 0 {
+		return fmt.Errorf("invalid -align-threshold %d: must not be negative", o.AlignThreshold)
+	}
+	if o.Width < 0 {
+		return fmt.Errorf("invalid -width %d: must not be negative", o.Width)
+	}
+	if o.FromProperties && o.Touch {
+		return errors.New("cannot use -touch with -from-properties")
+	}
+	if o.Strict && (o.FixBraces || o.FixCommas) {
+		return errors.New("cannot use -fix-braces or -fix-commas with -strict")
+	}
+	if o.Rewrite != "" && (o.Touch || o.FromProperties) {
+		return errors.New("cannot use -r with -touch or -from-properties")
+	}
+	if o.Strict && o.FromProperties {
+		return errors.New("cannot use -strict with -from-properties")
+	}
+	if o.FromProperties && o.ModifiedBlocksOnly {
+		return errors.New("cannot use -only-format-modified-blocks with -from-properties")
+	}
+	return nil
+}
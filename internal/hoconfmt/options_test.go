@@ -0,0 +1,102 @@
+package hoconfmt
+
+import "testing"
+
+func TestZeroOptions(t *testing.T) {
+	const src = "a = {\n    b = {\"c\": 1}\n}\n"
+	zero, err := formatFile("a.conf", []byte(src), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	def, err := formatFile("a.conf", []byte(src), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(zero) != string(def) {
+		t.Errorf("zero Options give\n%s\nDefaultOptions give\n%s", zero, def)
+	}
+	if err := (Options{}).check(); err != nil {
+		t.Errorf("zero Options are invalid: %v", err)
+	}
+}
+
+func TestFlagOptions(t *testing.T) {
+	// Without flags, the command line selects the default options.
+	if got, want := flagOptions(), DefaultOptions(); got != want {
+		t.Errorf("flagOptions() = %+v, want %+v", got, want)
+	}
+
+	defer setFlags(t, "testdata/objecteq.input")()
+	if got := flagOptions(); got.ObjectEq != objectEqRequire || !got.IncludeSpacing {
+		t.Errorf("flagOptions() = %+v, want ObjectEq %s and IncludeSpacing", got, objectEqRequire)
+	}
+}
+
+func TestOptionsCheck(t *testing.T) {
+	for _, opts := range []Options{
+		{ObjectEq: "always"},
+		{RootBraces: "keep"},
+		{ContinuationIndent: "hang"},
+		{Indent: " \t"},
+		{Indent: "\t\t"},
+		{Indent: "x"},
+		{IncludeKeys: "a.[b"},
+		{ExcludeKeys: "[a"},
+		{Touch: true, FromProperties: true},
+		{SortArrays: "servers"},
+		{SortFirst: "app.name"},
+		{AlignThreshold: -1},
+		{Strict: true, FixCommas: true},
+		{Strict: true, FromProperties: true},
+	} {
+		if err := opts.check(); err == nil {
+			t.Errorf("%+v: no error", opts)
+		}
+	}
+}
+
+func TestIndentFlags(t *testing.T) {
+	set := func(flags ...string) {
+		for _, name := range []string{"indent-string", "tabwidth", "usetabs", "profile"} {
+			f := commandLine.Lookup(name)
+			f.Value.Set(f.DefValue)
+		}
+		for i := 0; i < len(flags); i += 2 {
+			if err := commandLine.Lookup(flags[i]).Value.Set(flags[i+1]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	defer set()
+
+	for _, test := range []struct {
+		flags  []string
+		indent string
+	}{
+		{[]string{"usetabs", "true"}, "\t"},
+		{[]string{"tabwidth", "2"}, "  "},
+		{[]string{"indent-string", `\t`}, "\t"},
+		{[]string{"profile", "lightbend", "usetabs", "true"}, "\t"},
+		{[]string{"profile", "lightbend", "tabwidth", "8"}, "        "},
+	} {
+		set(test.flags...)
+		if err := checkIndentFlags(); err != nil {
+			t.Errorf("%q: %v", test.flags, err)
+		}
+		if got := flagOptions().Indent; got != test.indent {
+			t.Errorf("%q: Indent = %q, want %q", test.flags, got, test.indent)
+		}
+	}
+
+	for _, flags := range [][]string{
+		{"tabwidth", "-2"},
+		{"usetabs", "true", "tabwidth", "2"},
+		{"indent-string", "  ", "usetabs", "true"},
+		{"indent-string", "  ", "tabwidth", "2"},
+	} {
+		set(flags...)
+		if err := checkIndentFlags(); err == nil {
+			t.Errorf("%q: no error", flags)
+		}
+	}
+}
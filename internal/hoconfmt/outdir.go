@@ -0,0 +1,76 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+)
+
+// writeOutDir formats the files named by paths into dir, for -out-dir.
+// Directories are walked recursively for files that isConfFile accepts
+// and -exclude does not skip, and each is written to its path relative
+// to the directory under dir, creating subdirectories as needed. A file
+// named directly is written under its base name. dir itself is skipped
+// if it lies inside a walked directory. Copies are written atomically
+// with the permissions of their originals. Files that cannot be
+// formatted are reported and leave no copy.
+func writeOutDir(paths []string, dir string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		report(err)
+		return
+	}
+	out, err := os.Stat(dir)
+	if err != nil {
+		report(err)
+		return
+	}
+	mirror := func(path, rel string, perm os.FileMode) {
+		var buf bytes.Buffer
+		if err := processFile(path, nil, &buf); err != nil {
+			report(err)
+			return
+		}
+		target := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			report(err)
+			return
+		}
+		if err := writeFileAtomicPerm(target, buf.Bytes(), perm); err != nil {
+			report(err)
+		}
+	}
+	for _, root := range paths {
+		fi, err := os.Stat(root)
+		if err != nil {
+			report(err)
+			continue
+		}
+		if !fi.IsDir() {
+			mirror(root, filepath.Base(root), fi.Mode().Perm())
+			continue
+		}
+		err = filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+			switch {
+			case err != nil:
+				report(err)
+			case f.IsDir() && os.SameFile(f, out):
+				return filepath.SkipDir
+			case excluded(root, path, f):
+				if f.IsDir() {
+					return filepath.SkipDir
+				}
+			case isConfFile(f):
+				rel, err := filepath.Rel(root, path)
+				if err != nil {
+					report(err)
+					return nil
+				}
+				mirror(path, rel, f.Mode().Perm())
+			}
+			return nil
+		})
+		if err != nil {
+			report(err)
+		}
+	}
+}
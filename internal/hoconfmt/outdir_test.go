@@ -0,0 +1,89 @@
+package hoconfmt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestWriteOutDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	files := map[string]string{
+		"a.conf":             "a = [ ]\n",
+		"sub/b.conf":         "b {\n}\n",
+		"sub/deeper/c.conf":  "c = 3",
+		"sub/notes.txt":      "not a config\n",
+		"sub/.hidden.conf":   "h=1\n",
+		"sub/out/stale.conf": "x=1\n", // inside the output directory
+	}
+	for name, text := range files {
+		name = filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Chmod(filepath.Join(src, "a.conf"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(src, "sub", "out")
+	writeOutDir([]string{src}, out)
+	if exitCode != 0 {
+		t.Fatalf("exit code %d", exitCode)
+	}
+
+	got := map[string]string{}
+	err = filepath.Walk(out, func(path string, f os.FileInfo, err error) error {
+		if err != nil || f.IsDir() {
+			return err
+		}
+		text, err := ioutil.ReadFile(path)
+		rel, _ := filepath.Rel(out, path)
+		got[filepath.ToSlash(rel)] = string(text)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"a.conf":            "a = []\n",
+		"sub/b.conf":        "b {}\n",
+		"sub/deeper/c.conf": "c = 3\n",
+		"stale.conf":        "x=1\n",
+	}
+	if !reflect.DeepEqual(got, want) {
+		var names []string
+		for name := range got {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		t.Errorf("out-dir holds %q:\n%q\nwant\n%q", names, got, want)
+	}
+
+	if fi, err := os.Stat(filepath.Join(out, "a.conf")); err != nil {
+		t.Error(err)
+	} else if fi.Mode().Perm() != 0600 {
+		t.Errorf("a.conf copied with mode %v, want %v", fi.Mode().Perm(), os.FileMode(0600))
+	}
+
+	// Unchanged sources.
+	for name, text := range files {
+		b, err := ioutil.ReadFile(filepath.Join(src, filepath.FromSlash(name)))
+		if err != nil || string(b) != text {
+			t.Errorf("%s changed to %q (%v)", name, b, err)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package hoconfmt
+
+import "os"
+
+// copyOwner does nothing: file owners are only copied on Unix systems.
+func copyOwner(f *os.File, fi os.FileInfo) {}
@@ -0,0 +1,17 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package hoconfmt
+
+import (
+	"os"
+	"syscall"
+)
+
+// copyOwner gives f the owner and group of the file described by fi.
+// It fails harmlessly for a user allowed to change neither, in which
+// case f keeps the user's own.
+func copyOwner(f *os.File, fi os.FileInfo) {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		f.Chown(int(st.Uid), int(st.Gid))
+	}
+}
@@ -0,0 +1,512 @@
+package hoconfmt
+
+import (
+	"strings"
+)
+
+// A document is the syntax tree of a HOCON file, as built by
+// parseDocument. Its root is an *objectNode or an *arrayNode. Comments
+// before and after the braces or brackets of a root written with them
+// are kept in before and after; the first item of after is blank if a
+// blank line follows the root.
+type document struct {
+	before, after []item
+	blank         bool // a blank line comes between before and the root
+	root          node
+}
+
+// A node is an element of a syntax tree: an *objectNode, *arrayNode,
+// *fieldNode, *includeNode, *valueNode or *commentNode.
+type node interface {
+	offset() int // byte offset of the node's first token in the source
+}
+
+// An item is a line of an object or an array: a field or an include
+// statement of an object, a value of an array, or a comment on a line
+// of its own.
+type item struct {
+	node    node
+	comment *commentNode // comment after the node, on the same line
+	blank   bool         // a blank line comes before the item
+}
+
+// An objectNode is an object written with braces, or the root object
+// of a document without them.
+type objectNode struct {
+	off    int  // offset of the {, or of the first item without braces
+	end    int  // offset of the byte after the }, or of the end
+	braces bool // false for a root object written without braces
+	items  []item
+}
+
+// An arrayNode is an array. Its items are *valueNodes and
+// *commentNodes.
+type arrayNode struct {
+	off, end  int // offsets of the [ and of the byte after the ]
+	items     []item
+	multiline bool // a line break follows the [
+}
+
+// A fieldNode is a key and its value. A key written directly before
+// the brace of an object has no separator.
+type fieldNode struct {
+	key   []tok    // tokens of the key, without the white space after it
+	path  []string // elements of the key's path
+	sep   string   // "=", ":", "+=" or ""
+	value *valueNode
+}
+
+// An includeNode is an include statement.
+type includeNode struct {
+	off    int
+	target []tok // the tokens after include, as in required("a.conf")
+}
+
+// A valueNode is a value: a single string, number, substitution,
+// object or array, or the concatenation of several.
+type valueNode struct {
+	parts []valuePart
+}
+
+// A valuePart is one part of a value: a text token, or an object or
+// array.
+type valuePart struct {
+	space string // white space between the part and the one before
+	tok   tok    // the text token, or the opening brace or bracket
+	node  node   // the *objectNode or *arrayNode, or nil for text
+}
+
+// A commentNode is a # or // comment.
+type commentNode struct {
+	off  int
+	text string
+}
+
+func (n *objectNode) offset() int  { return n.off }
+func (n *arrayNode) offset() int   { return n.off }
+func (n *fieldNode) offset() int   { return n.key[0].off }
+func (n *includeNode) offset() int { return n.off }
+func (n *valueNode) offset() int   { return n.parts[0].tok.off }
+func (n *commentNode) offset() int { return n.off }
+
+// keyText returns the key of n as written, such as a."b.c".
+func (n *fieldNode) keyText() string {
+	var b strings.Builder
+	for _, t := range n.key {
+		b.WriteString(t.lit)
+	}
+	return b.String()
+}
+
+// end returns the offset of the byte after the last part of n.
+func (n *valueNode) end() int {
+	last := n.parts[len(n.parts)-1]
+	switch p := last.node.(type) {
+	case *objectNode:
+		return p.end
+	case *arrayNode:
+		return p.end
+	}
+	return last.tok.off + len(last.tok.lit)
+}
+
+// object returns the object that is the whole value of n, or nil.
+func (n *valueNode) object() *objectNode {
+	if len(n.parts) == 1 {
+		o, _ := n.parts[0].node.(*objectNode)
+		return o
+	}
+	return nil
+}
+
+// parseDocument parses src into its syntax tree. It accepts what
+// formatting accepts: separators on a line after their key, comments
+// between a separator and its value, which become comment lines above
+// the field, and doubled commas between items. Mismatched brackets and
+// content after the root are reported as checkRoot reports them; other
+// syntax errors, such as a key without a value, are reported with
+// their position.
+func parseDocument(src []byte) (*document, error) {
+	return (*lexer)(nil).parse(src)
+}
+
+// parse is parseDocument with the tokens of src taken from l.
+func (l *lexer) parse(src []byte) (*document, error) {
+	if err := checkRoot(l, src); err != nil {
+		return nil, err
+	}
+	toks, err := l.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{src: src, toks: toks}
+	doc := &document{}
+	first := firstToken(toks)
+	switch {
+	case first < len(toks) && toks[first].kind == tokLBrace:
+		doc.before, doc.blank = p.comments(first)
+		p.i = first + 1
+		doc.root = p.object(toks[first].off, true)
+		doc.after, _ = p.comments(len(toks))
+	case first < len(toks) && toks[first].kind == tokLBracket:
+		doc.before, doc.blank = p.comments(first)
+		p.i = first + 1
+		doc.root = p.array(toks[first].off)
+		doc.after, _ = p.comments(len(toks))
+	default:
+		doc.root = p.object(0, false)
+	}
+	if len(doc.before) > 0 {
+		doc.before[0].blank = false
+	}
+	if len(p.errs) > 0 {
+		p.errs.Sort()
+		return nil, p.errs
+	}
+	return doc, nil
+}
+
+// A parser builds a document from the tokens of its source. i is the
+// index of the next token to read.
+type parser struct {
+	src  []byte
+	toks []tok
+	i    int
+	errs errorList
+}
+
+func (p *parser) fail(off int, code, msg string) {
+	p.errs.Add(position(p.src, off), code, msg)
+}
+
+// peek returns the kind of the token at the index i, or -1 past the
+// end.
+func (p *parser) peek(i int) tokenKind {
+	if i < len(p.toks) {
+		return p.toks[i].kind
+	}
+	return -1
+}
+
+// skipSpace returns the index of the first token at or after i that is
+// not a space.
+func (p *parser) skipSpace(i int) int {
+	for p.peek(i) == tokSpace {
+		i++
+	}
+	return i
+}
+
+// comments returns the comment lines from the index p.i up to the
+// index end, for the comments around the braces of a root, and whether
+// a blank line follows the last of them. A comment right after the
+// root is not on a line of its own, but is still written on one.
+func (p *parser) comments(end int) ([]item, bool) {
+	var items []item
+	newlines := 0
+	for ; p.i < end; p.i++ {
+		switch t := p.toks[p.i]; t.kind {
+		case tokNewline:
+			newlines++
+		case tokComment:
+			items = append(items, item{node: &commentNode{t.off, t.lit}, blank: newlines > 1})
+			newlines = 0
+		}
+	}
+	return items, len(items) > 0 && newlines > 1
+}
+
+// object parses the items of an object from p.i up to its closing
+// brace, or up to the end of the source for a root object without
+// braces.
+func (p *parser) object(off int, braces bool) *objectNode {
+	o := &objectNode{off: off, braces: braces}
+	newlines := 0
+	open := -1 // index of the item whose line has not ended
+	for p.i < len(p.toks) {
+		t := p.toks[p.i]
+		switch t.kind {
+		case tokSpace, tokComma:
+			p.i++
+			continue
+		case tokNewline:
+			newlines++
+			open = -1
+			p.i++
+			continue
+		case tokComment:
+			c := &commentNode{t.off, t.lit}
+			if open >= 0 {
+				o.items[open].comment = c
+				open = -1
+			} else {
+				o.items = append(o.items, item{node: c, blank: newlines > 1 && len(o.items) > 0})
+			}
+			newlines = 0
+			p.i++
+			continue
+		case tokRBrace, tokRBracket:
+			if t.kind == tokRBrace && braces {
+				o.end = t.off + 1
+				p.i++
+				return o
+			}
+			p.fail(t.off, codeSyntax, "unexpected "+t.lit)
+			p.i++
+			continue
+		}
+		blank := newlines > 1 && len(o.items) > 0
+		newlines = 0
+		start := p.i
+		var n node
+		var above []item // comments moved from between a separator and its value
+		if t.kind == tokUnquoted && t.lit == "include" && fieldSep(p.toks, p.i) == 0 && p.peek(p.skipSpace(p.i+1)) != tokLBrace {
+			n = p.include()
+		} else {
+			n, above = p.field()
+		}
+		if n == nil {
+			p.i = start
+			if p.skipLine(); p.i == start {
+				p.i++
+			}
+			continue
+		}
+		if len(above) > 0 {
+			above[0].blank = blank
+			o.items = append(o.items, above...)
+			blank = false
+		}
+		o.items = append(o.items, item{node: n, blank: blank})
+		open = len(o.items) - 1
+	}
+	if braces {
+		p.fail(off, codeSyntax, "{ is not closed")
+	}
+	o.end = len(p.src)
+	return o
+}
+
+// skipLine moves p.i past the tokens of a line that could not be
+// parsed, up to its newline or the closing bracket of its object.
+func (p *parser) skipLine() {
+	depth := 0
+	for ; p.i < len(p.toks); p.i++ {
+		switch p.toks[p.i].kind {
+		case tokLBrace, tokLBracket:
+			depth++
+		case tokRBrace, tokRBracket:
+			if depth == 0 {
+				return
+			}
+			depth--
+		case tokNewline:
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
+
+// include parses the include statement at p.i.
+func (p *parser) include() node {
+	n := &includeNode{off: p.toks[p.i].off}
+	i := p.skipSpace(p.i + 1)
+	for ; i < len(p.toks); i++ {
+		switch p.toks[i].kind {
+		case tokNewline, tokComma, tokComment, tokRBrace:
+		default:
+			n.target = append(n.target, p.toks[i])
+			continue
+		}
+		break
+	}
+	for len(n.target) > 0 && n.target[len(n.target)-1].kind == tokSpace {
+		n.target = n.target[:len(n.target)-1]
+	}
+	if len(n.target) == 0 {
+		p.fail(n.off, codeInclude, "include has no file")
+		return nil
+	}
+	p.i = i
+	return n
+}
+
+// field parses the field at p.i. It returns the comments found between
+// the separator and the value as items to place above the field.
+func (p *parser) field() (node, []item) {
+	start := p.toks[p.i]
+	j, path := keyPath(p.toks, p.i)
+	if j < 0 {
+		// A separator may stand on the line after its key, and an
+		// array may follow its key without one.
+		i := p.i
+		for i < len(p.toks) && (isText(p.toks[i]) || p.toks[i].kind == tokSpace) {
+			i++
+		}
+		k := i
+		for p.peek(k) == tokNewline || p.peek(k) == tokSpace {
+			k++
+		}
+		if k == i && p.peek(k) == tokLBracket || k > i && p.peek(k) == tokSep {
+			j, path = keyPath(append(p.toks[p.i:i:i], tok{kind: tokSep}), 0)
+		}
+		if j < 0 {
+			p.fail(start.off, codeSyntax, "unexpected "+start.lit+", expected a key")
+			return nil, nil
+		}
+		j = k
+	}
+	f := &fieldNode{path: path}
+	end := j
+	for end > p.i && (p.toks[end-1].kind == tokSpace || p.toks[end-1].kind == tokNewline) {
+		end--
+	}
+	if end == p.i {
+		// A separator or brace with no key before it, as in = 1.
+		p.fail(start.off, codeSyntax, "unexpected "+start.lit+", expected a key")
+		return nil, nil
+	}
+	f.key = p.toks[p.i:end]
+
+	var above []item
+	if p.toks[j].kind == tokSep {
+		f.sep = p.toks[j].lit
+		j++
+		for ; j < len(p.toks); j++ {
+			switch t := p.toks[j]; t.kind {
+			case tokSpace, tokNewline:
+				continue
+			case tokComment:
+				above = append(above, item{node: &commentNode{t.off, t.lit}})
+				continue
+			}
+			break
+		}
+	}
+	p.i = j
+	f.value = p.value(true)
+	if f.value == nil {
+		p.fail(start.off, codeSyntax, renderPath(path)+" has no value")
+		return nil, nil
+	}
+	return f, above
+}
+
+// value parses the value at p.i, which may be a concatenation. It
+// returns nil if there is no value at p.i. The value of a field ends
+// before a key that follows an object or array on the same line, as
+// in a { x = 1 } b = 2, which is read as two fields.
+func (p *parser) value(field bool) *valueNode {
+	v := &valueNode{}
+	space := ""
+loop:
+	for p.i < len(p.toks) {
+		t := p.toks[p.i]
+		switch {
+		case t.kind == tokSpace:
+			if len(v.parts) > 0 {
+				space = t.lit
+			}
+			p.i++
+			continue
+		case t.kind == tokLBrace:
+			p.i++
+			v.parts = append(v.parts, valuePart{space, t, p.object(t.off, true)})
+		case t.kind == tokLBracket:
+			p.i++
+			v.parts = append(v.parts, valuePart{space, t, p.array(t.off)})
+		case field && isText(t) && len(v.parts) > 0 && v.parts[len(v.parts)-1].node != nil && p.startsField(p.i):
+			break loop
+		case isText(t), t.kind == tokSep && len(v.parts) > 0 && space == "":
+			// A : or = inside a value, as in https://${HOST}:8443,
+			// is text.
+			p.i++
+			v.parts = append(v.parts, valuePart{space: space, tok: t})
+		default:
+			break loop
+		}
+		space = ""
+	}
+	if len(v.parts) == 0 {
+		return nil
+	}
+
+	// Substitutions may stand for anything, but objects, arrays and
+	// text do not concatenate with each other.
+	var objects, arrays, text bool
+	for _, part := range v.parts {
+		switch part.node.(type) {
+		case *objectNode:
+			objects = true
+		case *arrayNode:
+			arrays = true
+		default:
+			text = text || part.tok.kind != tokSubst
+		}
+	}
+	switch {
+	case objects && arrays:
+		p.fail(v.offset(), codeConcatenation, "cannot concatenate an object and an array")
+	case (objects || arrays) && text:
+		p.fail(v.offset(), codeConcatenation, "cannot concatenate an object or array with text")
+	}
+	return v
+}
+
+// startsField reports whether a key followed by a separator or an
+// object starts at toks[i].
+func (p *parser) startsField(i int) bool {
+	j, _ := keyPath(p.toks, i)
+	return j >= 0
+}
+
+// array parses the elements of an array from p.i up to its closing
+// bracket.
+func (p *parser) array(off int) *arrayNode {
+	a := &arrayNode{off: off}
+	newlines := 0
+	open := -1 // index of the element whose line has not ended
+	for p.i < len(p.toks) {
+		t := p.toks[p.i]
+		switch t.kind {
+		case tokSpace, tokComma:
+			p.i++
+			continue
+		case tokNewline:
+			newlines++
+			a.multiline = a.multiline || len(a.items) == 0
+			open = -1
+			p.i++
+			continue
+		case tokComment:
+			c := &commentNode{t.off, t.lit}
+			if open >= 0 {
+				a.items[open].comment = c
+				open = -1
+			} else {
+				a.items = append(a.items, item{node: c, blank: newlines > 1 && len(a.items) > 0})
+			}
+			newlines = 0
+			p.i++
+			continue
+		case tokRBracket:
+			a.end = t.off + 1
+			p.i++
+			return a
+		}
+		blank := newlines > 1 && len(a.items) > 0
+		newlines = 0
+		v := p.value(false)
+		if v == nil {
+			p.fail(t.off, codeSyntax, "unexpected "+t.lit+" in array")
+			p.i++
+			continue
+		}
+		a.items = append(a.items, item{node: v, blank: blank})
+		open = len(a.items) - 1
+	}
+	p.fail(off, codeSyntax, "[ is not closed")
+	return a
+}
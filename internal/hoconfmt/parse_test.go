@@ -0,0 +1,64 @@
+package hoconfmt
+
+import "testing"
+
+func TestParseDocument(t *testing.T) {
+	const src = `# top
+a.b = 1, c: [x, { d = 2 }] # note
+
+e += ${f} " g"
+include required("h.conf")
+`
+	doc, err := parseDocument([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, ok := doc.root.(*objectNode)
+	if !ok || root.braces {
+		t.Fatalf("root is %#v, want an object without braces", doc.root)
+	}
+	items := root.items
+	if len(items) != 5 {
+		t.Fatalf("got %d items, want 5", len(items))
+	}
+	if c, ok := items[0].node.(*commentNode); !ok || c.text != "# top" {
+		t.Errorf("item 0 is %#v, want the comment # top", items[0].node)
+	}
+	ab, ok := items[1].node.(*fieldNode)
+	if !ok || ab.keyText() != "a.b" || ab.sep != "=" || len(ab.path) != 2 || items[1].comment != nil {
+		t.Errorf("item 1 is %#v, want the field a.b", items[1].node)
+	}
+	c, ok := items[2].node.(*fieldNode)
+	if !ok || c.keyText() != "c" || c.sep != ":" || items[2].comment == nil || items[2].comment.text != "# note" {
+		t.Errorf("item 2 is %#v, want the field c with a comment", items[2].node)
+	} else if arr, ok := c.value.parts[0].node.(*arrayNode); !ok || len(arr.items) != 2 || arr.multiline {
+		t.Errorf("c holds %#v, want a one-line array of two elements", c.value.parts[0].node)
+	}
+	e, ok := items[3].node.(*fieldNode)
+	if !ok || e.sep != "+=" || !items[3].blank || len(e.value.parts) != 2 || e.value.parts[1].space != " " {
+		t.Errorf("item 3 is %#v, want the concatenation e after a blank line", items[3].node)
+	}
+	if inc, ok := items[4].node.(*includeNode); !ok || len(inc.target) != 3 {
+		t.Errorf("item 4 is %#v, want the include", items[4].node)
+	}
+}
+
+func TestParseDocumentErrors(t *testing.T) {
+	for _, test := range []struct{ src, want string }{
+		{"a = 1\nb\n", "2:1: unexpected b, expected a key"},
+		{"= 1\n", "1:1: unexpected =, expected a key"},
+		{"a = 1 = 2\n", "1:7: unexpected =, expected a key"},
+		{"x {\n= 1\n}\n", "2:1: unexpected =, expected a key"},
+		{"a {\n    b =\n}\n", "2:5: b has no value"},
+		{"a = { b = 1 } x\n", "1:5: cannot concatenate an object or array with text"},
+		{"a = [1] { b = 1 }\n", "1:5: cannot concatenate an object and an array"},
+		{"a = [1, =]\n", "1:9: unexpected = in array"},
+		{"include\n", "1:1: include has no file"},
+		{"a = {\n", "1:5: { is not closed"},
+	} {
+		_, err := parseDocument([]byte(test.src))
+		if err == nil || err.Error() != test.want {
+			t.Errorf("parseDocument(%q) = %v, want %s", test.src, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package hoconfmt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tightenPaths removes the spaces between a substitution and the rest of
+// a path in a value, as in
+//
+//	logfile = ${HOME} /app/logs
+//
+// which becomes ${HOME}/app/logs. HOCON keeps white space inside a
+// concatenation, so such a space is part of the value; it is only
+// removed when the text next to the substitution starts or ends with a
+// '/', where a space is almost certainly a mistake. Each removal is
+// reported to warn. Spaces between two substitutions, or between a
+// substitution and a word, are left alone.
+func tightenPaths(lx *lexer, warn func(warning), filename string, src []byte) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, withFilename(err, filename)
+	}
+
+	res := make([]byte, 0, len(src))
+	for i, content := range contentSpaces(toks) {
+		t := toks[i]
+		if content && strings.Trim(t.lit, " ") == "" && pathGap(toks[i-1], toks[i+1]) {
+			pos := position(src, t.off)
+			pos.Filename = filename
+			warn(warning{pos, fmt.Sprintf("removing space inside path %s%s%s", toks[i-1].lit, t.lit, toks[i+1].lit), codeWhitespace})
+			continue
+		}
+		res = append(res, t.lit...)
+	}
+	return res, nil
+}
+
+// pathGap reports whether a space between the tokens before and after
+// it splits a path next to a substitution.
+func pathGap(before, after tok) bool {
+	switch {
+	case before.kind == tokSubst && after.kind == tokUnquoted:
+		return strings.HasPrefix(after.lit, "/")
+	case before.kind == tokUnquoted && after.kind == tokSubst:
+		return strings.HasSuffix(before.lit, "/")
+	}
+	return false
+}
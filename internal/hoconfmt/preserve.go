@@ -0,0 +1,237 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// A span is a range of byte offsets.
+type span struct{ start, end int }
+
+// preservedSpans returns the spans of the values in src whose key path
+// matches one of globs, in order. A key path is written with '.'
+// between its elements, and each element of a glob is matched against
+// one element of the path as by path.Match. Values of matching keys
+// nested in a matching value are part of the outer span. Keys of
+// objects inside arrays have no path and never match.
+func preservedSpans(src []byte, globs []string) ([]span, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	type level struct {
+		path   []string // key path of the object, nil inside arrays
+		object bool
+	}
+	stack := []level{{path: []string{}, object: true}}
+	var spans []span
+	atField := true // at the start of a field in an object
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		top := stack[len(stack)-1]
+		if atField && top.object && top.path != nil && isText(t) && t.lit != "include" {
+			atField = false
+			j, elems := keyPath(toks, i)
+			if j < 0 {
+				continue
+			}
+			p := append(append([]string{}, top.path...), elems...)
+			v := j
+			if toks[v].kind == tokSep {
+				v++
+			}
+			for v < len(toks) && toks[v].kind == tokSpace {
+				v++
+			}
+			if v == len(toks) {
+				break
+			}
+			if matchPath(globs, p) {
+				if end := valueEnd(toks, v); end > v {
+					spans = append(spans, span{toks[v].off, toks[end-1].off + len(toks[end-1].lit)})
+					i = end - 1
+					continue
+				}
+			}
+			if toks[v].kind == tokLBrace {
+				stack = append(stack, level{path: p, object: true})
+				atField = true
+				i = v
+				continue
+			}
+			i = v - 1
+			continue
+		}
+
+		switch t.kind {
+		case tokLBrace:
+			l := level{object: true}
+			if i == firstToken(toks) {
+				l.path = []string{} // braces around the whole document
+			}
+			stack = append(stack, l)
+			atField = true
+		case tokLBracket:
+			stack = append(stack, level{})
+		case tokRBrace, tokRBracket:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+			atField = true
+		case tokNewline, tokComma:
+			atField = true
+		}
+	}
+	return spans, nil
+}
+
+// keyPath parses the key starting at toks[i]. It returns the index of
+// the separator or opening brace after the key and the elements of the
+// key's path, or -1 if toks[i] does not start a field.
+func keyPath(toks []tok, i int) (int, []string) {
+	var elems []string
+	var cur strings.Builder
+	space := "" // white space after the last token: part of the key only if more follows
+	for ; i < len(toks); i++ {
+		t := toks[i]
+		if t.kind != tokSpace && t.kind != tokSep && t.kind != tokLBrace {
+			cur.WriteString(space)
+			space = ""
+		}
+		switch t.kind {
+		case tokUnquoted:
+			parts := strings.Split(t.lit, ".")
+			cur.WriteString(parts[0])
+			for _, p := range parts[1:] {
+				elems = append(elems, cur.String())
+				cur.Reset()
+				cur.WriteString(p)
+			}
+		case tokString:
+			var s string
+			if err := json.Unmarshal([]byte(t.lit), &s); err != nil {
+				s = t.lit
+			}
+			cur.WriteString(s)
+		case tokSubst:
+			cur.WriteString(t.lit)
+		case tokSpace:
+			space += t.lit
+		case tokSep, tokLBrace:
+			elems = append(elems, cur.String())
+			return i, elems
+		default:
+			return -1, nil
+		}
+	}
+	return -1, nil
+}
+
+// valueEnd returns the index just past the last token of the value
+// starting at toks[i], not counting trailing white space.
+func valueEnd(toks []tok, i int) int {
+	depth := 0
+	j := i
+loop:
+	for ; j < len(toks); j++ {
+		switch toks[j].kind {
+		case tokLBrace, tokLBracket:
+			depth++
+		case tokRBrace, tokRBracket:
+			if depth == 0 {
+				break loop
+			}
+			depth--
+		case tokNewline, tokComma, tokComment:
+			if depth == 0 {
+				break loop
+			}
+		}
+	}
+	for j > i+1 && toks[j-1].kind == tokSpace {
+		j--
+	}
+	return j
+}
+
+func matchPath(globs []string, p []string) bool {
+	for _, g := range globs {
+		elems := strings.Split(g, ".")
+		if len(elems) != len(p) {
+			continue
+		}
+		match := true
+		for i, e := range elems {
+			if ok, _ := path.Match(e, p[i]); !ok {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// preserveGlobs splits a comma-separated list of key path globs, such
+// as the value of -preserve.
+func preserveGlobs(list string) []string {
+	var globs []string
+	for _, g := range strings.Split(list, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			globs = append(globs, g)
+		}
+	}
+	return globs
+}
+
+func checkPreserve(list string) error {
+	return checkGlobs("-preserve", list)
+}
+
+// checkGlobs reports the first malformed glob in the list given to the
+// flag name.
+func checkGlobs(name, list string) error {
+	for _, g := range preserveGlobs(list) {
+		for _, e := range strings.Split(g, ".") {
+			if _, err := path.Match(e, ""); err != nil {
+				return fmt.Errorf("invalid %s pattern %q: %v", name, g, err)
+			}
+		}
+	}
+	return nil
+}
+
+// restorePreserved copies the values of src whose keys match globs into
+// the formatted res, replacing their formatted form.
+func restorePreserved(src, res []byte, globs []string) ([]byte, error) {
+	before, err := preservedSpans(src, globs)
+	if err != nil {
+		return nil, err
+	}
+	after, err := preservedSpans(res, globs)
+	if err != nil {
+		return nil, err
+	}
+	if len(before) != len(after) {
+		return nil, fmt.Errorf("cannot preserve values: %d match before formatting, %d after", len(before), len(after))
+	}
+	return restoreSpans(src, res, before, after), nil
+}
+
+// restoreSpans replaces each span after[i] of res with the span
+// before[i] of src.
+func restoreSpans(src, res []byte, before, after []span) []byte {
+	var out []byte
+	last := 0
+	for i, s := range after {
+		out = append(out, res[last:s.start]...)
+		out = append(out, src[before[i].start:before[i].end]...)
+		last = s.end
+	}
+	return append(out, res[last:]...)
+}
@@ -0,0 +1,51 @@
+package hoconfmt
+
+import "testing"
+
+func TestPreservedSpans(t *testing.T) {
+	for _, test := range []struct {
+		src   string
+		globs []string
+		want  []string
+	}{
+		{"a.b = 1\na { b = 2, c = 3 }\n", []string{"a.b"}, []string{"1", "2"}},
+		{"\"a.b\" = 1\na.b = 2\n", []string{"a.b"}, []string{"2"}},
+		{"\"a.b\" = 1\na.b = 2\n", []string{"a?b"}, []string{"1"}},
+		{"{\n    k = [ 1 ]  # note\n}\n", []string{"k"}, []string{"[ 1 ]"}},
+		{"x = [{ k = 1 }]\n", []string{"*.k", "k"}, nil},
+		{"a { b { c = 1 } }\n", []string{"a", "a.b.c"}, []string{"{ b { c = 1 } }"}},
+		{"s { t = ${x} y }\n", []string{"s.*"}, []string{"${x} y"}},
+		// Numeric keys are object keys, never array indexes.
+		{"status { 404 = a, 500: b }\n", []string{"status.404"}, []string{"a"}},
+		{"status.404 = a\n\"1.5\" = b\n1.5 = c\n", []string{"status.404", "1.5"}, []string{"a", "c"}},
+		{"list = [x, y]\nlist.0 = z\n", []string{"list.0"}, []string{"z"}},
+	} {
+		spans, err := preservedSpans([]byte(test.src), test.globs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, s := range spans {
+			got = append(got, test.src[s.start:s.end])
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%q %v: got %q, want %q", test.src, test.globs, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%q %v: got %q, want %q", test.src, test.globs, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestCheckPreserve(t *testing.T) {
+	if err := checkPreserve("a.*, b.[cd]"); err != nil {
+		t.Error(err)
+	}
+	if err := checkPreserve("a.[b"); err == nil {
+		t.Error("no error for malformed pattern")
+	}
+}
@@ -0,0 +1,90 @@
+package hoconfmt
+
+import (
+	"flag"
+	"fmt"
+)
+
+// Values of the -profile flag.
+const (
+	profileLightbend = "lightbend" // the style of the Akka and Lightbend reference.conf files
+	profileCompact   = "compact"
+	profileVerbose   = "verbose"
+)
+
+func checkProfile(name string) error {
+	if _, ok := profileOptions(name); ok || name == "" {
+		return nil
+	}
+	return fmt.Errorf("invalid -profile %q: must be %s, %s or %s", name, profileLightbend, profileCompact, profileVerbose)
+}
+
+// profileOptions returns the options of the profile name, for -profile,
+// and whether there is such a profile. Only the fields listed in
+// profileFlags are taken from a profile; the others are those of
+// DefaultOptions.
+func profileOptions(name string) (Options, bool) {
+	opts := DefaultOptions()
+	switch name {
+	case profileLightbend:
+		opts.Indent = "  "
+		opts.Separators = separatorsEquals
+		opts.Comments = commentsHash
+	case profileCompact:
+		opts.Indent = "  "
+		opts.Separators = separatorsEquals
+		opts.KeyPaths = keyPathsDotted
+		opts.Commas = commasSmart
+		opts.ArrayPerLine = -1
+		opts.MaxBlank = -1
+	case profileVerbose:
+		opts.Separators = separatorsEquals
+		opts.ObjectEq = objectEqRequire
+		opts.KeyPaths = keyPathsNested
+		opts.Empty = emptyExpanded
+		opts.ArrayPerLine = 1
+		opts.MaxBlank = 2
+		opts.IncludeSpacing = true
+	default:
+		return Options{}, false
+	}
+	return opts, true
+}
+
+// profileFlags are the flags whose options a profile sets, each with the
+// function copying its field from the profile to the options.
+var profileFlags = []struct {
+	name string
+	set  func(opts *Options, p Options)
+}{
+	{"indent-string", func(opts *Options, p Options) { opts.Indent = p.Indent }},
+	{"separators", func(opts *Options, p Options) { opts.Separators = p.Separators }},
+	{"object-eq", func(opts *Options, p Options) { opts.ObjectEq = p.ObjectEq }},
+	{"key-paths", func(opts *Options, p Options) { opts.KeyPaths = p.KeyPaths }},
+	{"comment-style", func(opts *Options, p Options) { opts.Comments = p.Comments }},
+	{"commas", func(opts *Options, p Options) { opts.Commas = p.Commas }},
+	{"empty", func(opts *Options, p Options) { opts.Empty = p.Empty }},
+	{"array-per-line", func(opts *Options, p Options) { opts.ArrayPerLine = p.ArrayPerLine }},
+	{"max-blank", func(opts *Options, p Options) { opts.MaxBlank = p.MaxBlank }},
+	{"include-spacing", func(opts *Options, p Options) { opts.IncludeSpacing = p.IncludeSpacing }},
+}
+
+// applyProfile returns opts with the fields of the profile p, except
+// those whose flag given reports as given, which take precedence.
+func applyProfile(opts, p Options, given func(name string) bool) Options {
+	for _, f := range profileFlags {
+		if !given(f.name) {
+			f.set(&opts, p)
+		}
+	}
+	return opts
+}
+
+// flagGiven reports whether the flag name was given on the command line,
+// or otherwise has a value other than its default.
+func flagGiven(name string) bool {
+	given := false
+	commandLine.Visit(func(f *flag.Flag) { given = given || f.Name == name })
+	f := commandLine.Lookup(name)
+	return given || f.Value.String() != f.DefValue
+}
@@ -0,0 +1,41 @@
+package hoconfmt
+
+import "testing"
+
+func TestFormatProfiles(t *testing.T) {
+	for _, name := range []string{profileLightbend, profileCompact, profileVerbose} {
+		opts, ok := profileOptions(name)
+		if !ok {
+			t.Errorf("no profile %s", name)
+			continue
+		}
+		if err := opts.check(); err != nil {
+			t.Errorf("profile %s: %v", name, err)
+		}
+		if err := checkProfile(name); err != nil {
+			t.Errorf("checkProfile(%s): %v", name, err)
+		}
+	}
+	if err := checkProfile(""); err != nil {
+		t.Errorf("checkProfile(\"\"): %v", err)
+	}
+	if err := checkProfile("akka"); err == nil {
+		t.Error("checkProfile(akka): no error")
+	}
+
+	// The flags given take precedence over the profile.
+	p, _ := profileOptions(profileLightbend)
+	opts := DefaultOptions()
+	opts.Indent = "\t"
+	opts.Separators = separatorsColon
+	got := applyProfile(opts, p, func(name string) bool { return name == "indent-string" })
+	if got.Indent != "\t" || got.Separators != separatorsEquals || got.Comments != commentsHash {
+		t.Errorf("lightbend with -indent-string: %+v", got)
+	}
+
+	const src = "// Pool.\nakka {\n    loggers: [a, b]\n}\n"
+	res, err := formatFile("a.conf", []byte(src), got)
+	if want := "# Pool.\nakka {\n\tloggers = [a, b]\n}\n"; err != nil || string(res) != want {
+		t.Errorf("lightbend with -indent-string: got %q, %v, want %q", res, err, want)
+	}
+}
@@ -0,0 +1,220 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// projectConfigName is the name of the file holding default flags for
+// the files of a project.
+const projectConfigName = ".hoconfmt.conf"
+
+// findProjectConfig returns the path of the .hoconfmt.conf file in the
+// directory of target or the closest directory above it, or "" if
+// there is none. target may be a file or a directory.
+func findProjectConfig(target string) (string, error) {
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	dir := abs
+	if fi, err := os.Stat(abs); err != nil || !fi.IsDir() {
+		dir = filepath.Dir(abs)
+	}
+	for ; ; dir = filepath.Dir(dir) {
+		path := filepath.Join(dir, projectConfigName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+		if filepath.Dir(dir) == dir {
+			return "", nil
+		}
+	}
+}
+
+// fileOptions returns the options for formatting filename: those of
+// the command line, with the settings of the .hoconfmt.conf file that
+// applies to filename for the flags not given on it. Each file gets the
+// settings of its own project, so files of two projects formatted in
+// one run are each formatted in the style of theirs.
+func fileOptions(filename string) (Options, error) {
+	opts := flagOptions()
+	path, err := findProjectConfig(filename)
+	if err != nil || path == "" {
+		return opts, err
+	}
+	return projectOptions(path, opts, optionGiven)
+}
+
+// projectOptions returns opts with the settings of the HOCON file at
+// path, as in
+//
+//	object-eq = require
+//	align-comments = true
+//	max-blank = 2
+//
+// except for the flags that given reports as given, which take
+// precedence. The keys are the names of projectFlags, or profile, whose
+// options the other keys of the file override. Values are strings,
+// numbers or booleans, as they would be written after the = of the
+// flag.
+func projectOptions(path string, opts Options, given func(name string) bool) (Options, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return opts, err
+	}
+	cfg, err := flattenConfig(src)
+	if err != nil {
+		return opts, withFilename(err, path)
+	}
+	values := make(map[string]string, len(cfg))
+	for name, v := range cfg {
+		if _, ok := projectFlags[name]; !ok && name != "profile" {
+			if commandLine.Lookup(name) != nil {
+				return opts, fmt.Errorf("%s: cannot set %s: only formatting options can be set in %s", path, name, projectConfigName)
+			}
+			return opts, fmt.Errorf("%s: unknown flag %s", path, name)
+		}
+		value := v.text
+		switch v.kind {
+		case "string":
+			if err := json.Unmarshal([]byte(v.text), &value); err != nil {
+				return opts, fmt.Errorf("%s: %s: %v", path, name, err)
+			}
+		case "number", "boolean":
+		default:
+			return opts, fmt.Errorf("%s: %s must be a string, number or boolean", path, name)
+		}
+		values[name] = value
+	}
+
+	if name, ok := values["profile"]; ok && !given("profile") {
+		p, ok := profileOptions(name)
+		if !ok {
+			return opts, fmt.Errorf("%s: %v", path, checkProfile(name))
+		}
+		opts = applyProfile(opts, p, func(name string) bool {
+			_, set := values[name]
+			return set || given(name)
+		})
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "profile" || given(name) {
+			continue
+		}
+		if err := projectFlags[name](&opts, values[name]); err != nil {
+			return opts, fmt.Errorf("%s: invalid value %q for %s: %v", path, values[name], name, err)
+		}
+	}
+	if err := opts.check(); err != nil {
+		return opts, fmt.Errorf("%s: %v", path, err)
+	}
+	return opts, nil
+}
+
+// projectFlags are the flags a .hoconfmt.conf file may set, each with
+// the function setting its option from the value in the file. They are
+// the flags of the formatting style. The flags choosing what hoconfmt
+// does with its input and where the result goes, such as -w, -l, -o or
+// -exclude-keys, are left out: a file checked in with the
+// configurations must not make a listing rewrite files or send them
+// elsewhere.
+var projectFlags = map[string]func(opts *Options, value string) error{
+	"touch":                       boolOption(func(o *Options) *bool { return &o.Touch }),
+	"reprint":                     boolOption(func(o *Options) *bool { return &o.Reprint }),
+	"editorconfig":                boolOption(func(o *Options) *bool { return &o.EditorConfig }),
+	"normalize-all":               boolOption(func(o *Options) *bool { return &o.NormalizeAll }),
+	"fix-braces":                  boolOption(func(o *Options) *bool { return &o.FixBraces }),
+	"fix-commas":                  boolOption(func(o *Options) *bool { return &o.FixCommas }),
+	"fix-quotes":                  boolOption(func(o *Options) *bool { return &o.FixQuotes }),
+	"strict":                      boolOption(func(o *Options) *bool { return &o.Strict }),
+	"keep-json-values":            boolOption(func(o *Options) *bool { return &o.KeepJSONValues }),
+	"trim-substs":                 boolOption(func(o *Options) *bool { return &o.TrimSubsts }),
+	"unicode-normalize":           boolOption(func(o *Options) *bool { return &o.UnicodeNFC }),
+	"unicode-normalize-strings":   boolOption(func(o *Options) *bool { return &o.UnicodeNFCStrings }),
+	"wrap-comments":               boolOption(func(o *Options) *bool { return &o.WrapComments }),
+	"align-comments":              boolOption(func(o *Options) *bool { return &o.AlignComments }),
+	"include-spacing":             boolOption(func(o *Options) *bool { return &o.IncludeSpacing }),
+	"sort":                        boolOption(func(o *Options) *bool { return &o.SortKeys }),
+	"s":                           boolOption(func(o *Options) *bool { return &o.Simplify }),
+	"warn-duplicates":             boolOption(func(o *Options) *bool { return &o.WarnDuplicates }),
+	"warn-concat":                 boolOption(func(o *Options) *bool { return &o.WarnConcat }),
+	"only-format-modified-blocks": boolOption(func(o *Options) *bool { return &o.ModifiedBlocksOnly }),
+	"no-lose-comments":            boolOption(func(o *Options) *bool { return &o.NoLoseComments }),
+	"verify":                      boolOption(func(o *Options) *bool { return &o.Verify }),
+	"explain":                     boolOption(func(o *Options) *bool { return &o.Explain }),
+	"object-eq":                   stringOption(func(o *Options) *string { return &o.ObjectEq }),
+	"separators":                  stringOption(func(o *Options) *string { return &o.Separators }),
+	"key-case":                    stringOption(func(o *Options) *string { return &o.KeyCase }),
+	"key-paths":                   stringOption(func(o *Options) *string { return &o.KeyPaths }),
+	"commas":                      stringOption(func(o *Options) *string { return &o.Commas }),
+	"comment-style":               stringOption(func(o *Options) *string { return &o.Comments }),
+	"continuation-indent":         stringOption(func(o *Options) *string { return &o.ContinuationIndent }),
+	"empty":                       stringOption(func(o *Options) *string { return &o.Empty }),
+	"include-path":                stringOption(func(o *Options) *string { return &o.IncludePath }),
+	"eol":                         stringOption(func(o *Options) *string { return &o.EOL }),
+	"preserve":                    stringOption(func(o *Options) *string { return &o.Preserve }),
+	"sort-array":                  stringOption(func(o *Options) *string { return &o.SortArrays }),
+	"sort-first":                  stringOption(func(o *Options) *string { return &o.SortFirst }),
+	"root-braces":                 stringOption(func(o *Options) *string { return &o.RootBraces }),
+	"array-columns":               intOption(func(o *Options, n int) { o.ArrayColumns = n }),
+	"align-threshold":             intOption(func(o *Options, n int) { o.AlignThreshold = n }),
+	"inline-includes-under":       intOption(func(o *Options, n int) { o.InlineIncludes = n }),
+	"max-blank":                   intOption(func(o *Options, n int) { o.MaxBlank = maxBlankOption(n) }),
+	"array-per-line":              intOption(func(o *Options, n int) { o.ArrayPerLine = arrayPerLineOption(n) }),
+	"indent-string": func(o *Options, value string) error {
+		o.Indent = strings.ReplaceAll(value, `\t`, "\t")
+		return nil
+	},
+	"width": func(o *Options, value string) error {
+		var w widthValue
+		if err := w.Set(value); err != nil {
+			return err
+		}
+		o.Width = w.width(os.Stdout)
+		return nil
+	},
+}
+
+func boolOption(field func(o *Options) *bool) func(*Options, string) error {
+	return func(o *Options, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		*field(o) = b
+		return nil
+	}
+}
+
+func stringOption(field func(o *Options) *string) func(*Options, string) error {
+	return func(o *Options, value string) error {
+		*field(o) = value
+		return nil
+	}
+}
+
+func intOption(set func(o *Options, n int)) func(*Options, string) error {
+	return func(o *Options, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		set(o, n)
+		return nil
+	}
+}
@@ -0,0 +1,130 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProjectConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const conf = `# Project style.
+object-eq = require
+align-comments = true
+max-blank = 2
+indent-string = "\t"
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, projectConfigName), []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(dir, "conf", "prod")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, target := range []string{dir, sub, filepath.Join(sub, "app.conf")} {
+		path, err := findProjectConfig(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := filepath.Join(dir, projectConfigName); path != want {
+			t.Errorf("findProjectConfig(%s) = %q, want %q", target, path, want)
+		}
+	}
+
+	// -max-blank=1 is given on the command line.
+	given := func(name string) bool { return name == "max-blank" }
+	opts := DefaultOptions()
+	opts.MaxBlank = 1
+	opts, err = projectOptions(filepath.Join(dir, projectConfigName), opts, given)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.ObjectEq != objectEqRequire || !opts.AlignComments || opts.Indent != "\t" {
+		t.Errorf("options = %+v, want those of the project file", opts)
+	}
+	if opts.MaxBlank != 1 {
+		t.Errorf("MaxBlank = %d, want the command line's 1", opts.MaxBlank)
+	}
+}
+
+// TestProjectConfigPerFile formats files of two projects in one run:
+// each gets the settings of its own .hoconfmt.conf.
+func TestProjectConfigPerFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var jobs []fileJob
+	for _, p := range []struct{ name, conf string }{
+		{"p1", "separators = colon\n"},
+		{"p2", "separators = equals\nprofile = lightbend\n"},
+	} {
+		sub := filepath.Join(dir, p.name)
+		if err := os.Mkdir(sub, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(sub, projectConfigName), []byte(p.conf), 0644); err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.Join(sub, "x.conf")
+		if err := ioutil.WriteFile(name, []byte("a {\nb = 1\n}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		jobs = append(jobs, fileJob{path: name})
+	}
+
+	var stdout, stderr bytes.Buffer
+	processFiles(&stdout, &stderr, jobs, 2)
+	if stderr.Len() > 0 {
+		t.Fatal(stderr.String())
+	}
+	if want := "a {\n    b: 1\n}\na {\n  b = 1\n}\n"; stdout.String() != want {
+		t.Errorf("output %q, want %q", stdout.String(), want)
+	}
+}
+
+func TestProjectConfigErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, projectConfigName)
+	for _, test := range []struct{ conf, want string }{
+		{"no-such-flag = 1\n", "unknown flag no-such-flag"},
+		{"max-blank = lots\n", `invalid value "lots" for max-blank: must be a number`},
+		{"sort = maybe\n", `invalid value "maybe" for sort: must be true or false`},
+		{"preserve = [a, b]\n", "preserve must be a string, number or boolean"},
+		{"object-eq = sometimes\n", `invalid -object-eq "sometimes"`},
+		{"profile = tiny\n", `invalid -profile "tiny"`},
+		// Flags choosing what hoconfmt does or where it writes cannot
+		// come from a file checked in with the configurations.
+		{"w = true\n", "cannot set w: only formatting options can be set in .hoconfmt.conf"},
+		{"l = true\n", "cannot set l: only formatting options"},
+		{"o = \"/some/path\"\n", "cannot set o: only formatting options"},
+		{"d = true\n", "cannot set d: only formatting options"},
+		{"backup = true\n", "cannot set backup: only formatting options"},
+		{"serve = true\n", "cannot set serve: only formatting options"},
+		{"exclude-keys = secrets\n", "cannot set exclude-keys: only formatting options"},
+	} {
+		if err := ioutil.WriteFile(path, []byte(test.conf), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := projectOptions(path, DefaultOptions(), func(string) bool { return false })
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: error %v, want one containing %q", test.conf, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package hoconfmt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/printer"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// A propNode is one element of the object tree built from the dotted
+// keys of a .properties file. Children are kept in order of first
+// appearance so the converted file follows the original layout.
+type propNode struct {
+	name     string
+	comments []string // comment lines preceding the key, without markers
+	value    *string  // set for leaves
+	line     int      // line of the last assignment to value
+	children []*propNode
+}
+
+// child returns the child of n with the given name, creating it if
+// necessary. It reports whether the child was created.
+func (n *propNode) child(name string) (*propNode, bool) {
+	for _, c := range n.children {
+		if c.name == name {
+			return c, false
+		}
+	}
+	c := &propNode{name: name}
+	n.children = append(n.children, c)
+	return c, true
+}
+
+// propertiesToHOCON converts the .properties source src into a HOCON
+// document. Dotted keys become nested objects, comments are carried
+// over to the key that follows them, and values are quoted unless they
+// are numbers or booleans. Indentation follows cfg.
+func propertiesToHOCON(src []byte, cfg printer.Config) ([]byte, error) {
+	root := &propNode{}
+	var comments []string
+
+	lines := bufio.NewScanner(bytes.NewReader(src))
+	lines.Buffer(nil, len(src)+1)
+	lineno := 0
+	for lines.Scan() {
+		lineno++
+		line := strings.TrimLeft(lines.Text(), " \t\f")
+		if line == "" {
+			continue
+		}
+		if line[0] == '#' || line[0] == '!' {
+			comments = append(comments, strings.TrimSpace(line[1:]))
+			continue
+		}
+
+		// Join continuation lines: a line ending in an odd number of
+		// backslashes continues on the next line, whose leading
+		// whitespace is dropped.
+		start := lineno
+		for continued(line) && lines.Scan() {
+			lineno++
+			line = line[:len(line)-1] + strings.TrimLeft(lines.Text(), " \t\f")
+		}
+
+		key, value, err := splitProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("%d: %s", start, err)
+		}
+
+		// Comments go with the outermost object the key introduces,
+		// so a comment heading a group of keys stays above the group.
+		n, first := root, (*propNode)(nil)
+		for _, name := range strings.Split(key, ".") {
+			if n.value != nil {
+				return nil, fmt.Errorf("%d: key %q conflicts with value assigned on line %d", start, key, n.line)
+			}
+			var created bool
+			if n, created = n.child(name); created && first == nil {
+				first = n
+			}
+		}
+		if len(n.children) > 0 {
+			return nil, fmt.Errorf("%d: key %q is already used as an object", start, key)
+		}
+		n.value = &value
+		n.line = start
+		if first == nil {
+			first = n
+		}
+		first.comments = append(first.comments, comments...)
+		comments = nil
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	indent := "\t"
+	if cfg.Mode&printer.UseSpaces != 0 {
+		indent = strings.Repeat(" ", cfg.Tabwidth)
+	}
+	for _, c := range root.children {
+		c.print(&buf, indent, 0)
+	}
+	for _, c := range comments {
+		printPropComment(&buf, "", c)
+	}
+	return buf.Bytes(), nil
+}
+
+func (n *propNode) print(buf *bytes.Buffer, indent string, depth int) {
+	prefix := strings.Repeat(indent, depth)
+	for _, c := range n.comments {
+		printPropComment(buf, prefix, c)
+	}
+	if n.value != nil {
+		fmt.Fprintf(buf, "%s%s = %s\n", prefix, hoconKey(n.name), hoconValue(*n.value))
+		return
+	}
+	fmt.Fprintf(buf, "%s%s {\n", prefix, hoconKey(n.name))
+	for _, c := range n.children {
+		c.print(buf, indent, depth+1)
+	}
+	fmt.Fprintf(buf, "%s}\n", prefix)
+}
+
+func printPropComment(buf *bytes.Buffer, prefix, comment string) {
+	if comment == "" {
+		fmt.Fprintf(buf, "%s#\n", prefix)
+		return
+	}
+	fmt.Fprintf(buf, "%s# %s\n", prefix, comment)
+}
+
+func continued(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitProperty splits a logical .properties line into its unescaped
+// key and value. The key ends at the first unescaped '=', ':' or
+// whitespace; a single separator and the whitespace around it are
+// dropped.
+func splitProperty(line string) (key, value string, err error) {
+	i := 0
+	for i < len(line) {
+		c := line[i]
+		if c == '\\' {
+			i += 2
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			break
+		}
+		i++
+	}
+	if i > len(line) {
+		i = len(line)
+	}
+	rawKey, rest := line[:i], strings.TrimLeft(line[i:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	if key, err = unescapeProperty(rawKey); err != nil {
+		return "", "", err
+	}
+	if value, err = unescapeProperty(rest); err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+func unescapeProperty(s string) (string, error) {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch c = s[i]; c {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("malformed \\u escape in %q", s)
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("malformed \\u escape in %q", s)
+			}
+			i += 4
+			// A character outside the Basic Multilingual Plane is
+			// written as two escapes of its UTF-16 surrogates.
+			if utf16.IsSurrogate(rune(r)) && i+6 < len(s) && s[i+1:i+3] == `\u` {
+				if r2, err := strconv.ParseUint(s[i+3:i+7], 16, 16); err == nil {
+					if c := utf16.DecodeRune(rune(r), rune(r2)); c != unicode.ReplacementChar {
+						b.WriteRune(c)
+						i += 6
+						continue
+					}
+				}
+			}
+			b.WriteRune(rune(r))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+var numberLiteral = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// hoconValue renders a .properties value as a HOCON value: numbers and
+// booleans are left bare, everything else becomes a quoted string.
+func hoconValue(v string) string {
+	if v == "true" || v == "false" || numberLiteral.MatchString(v) {
+		return v
+	}
+	return quoteString(v)
+}
+
+// hoconKey renders a single path element, quoting it when it would not
+// be read back as the same unquoted key. Control characters and Unicode
+// white space, which HOCON counts as white space too, are only kept in
+// quoted keys.
+func hoconKey(k string) string {
+	if k == "" || strings.Contains(k, "//") || strings.ContainsAny(k, forbiddenUnquoted) || !utf8.ValidString(k) || strings.IndexFunc(k, unquotedSpace) >= 0 {
+		return quoteString(k)
+	}
+	return k
+}
+
+// unquotedSpace reports whether r may not appear in an unquoted key
+// because it is a control character or white space.
+func unquotedSpace(r rune) bool {
+	return unicode.IsControl(r) || unicode.IsSpace(r) || unicode.Is(unicode.Zs, r) || r == '\uFEFF'
+}
+
+// forbiddenUnquoted lists the characters that may not appear in an
+// unquoted HOCON string, plus '.', which separates path elements, and
+// whitespace.
+const forbiddenUnquoted = "$\"{}[]:=,+#`^?!@*&\\. \t\n\r\f\v"
+
+// quoteString renders s as a JSON-style quoted string.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"go/printer"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestFromProperties(t *testing.T) {
+	*fromProperties = true
+	runTest(t, "testdata/application.properties", "testdata/application.properties.golden")
+	*fromProperties = false
+
+	// The converted output is itself formatted HOCON.
+	runTest(t, "testdata/application.properties.golden", "testdata/application.properties.golden")
+}
+
+func TestSplitProperty(t *testing.T) {
+	for _, test := range []struct {
+		line, key, value string
+	}{
+		{"a=b", "a", "b"},
+		{"a = b", "a", "b"},
+		{"a:b", "a", "b"},
+		{"a b", "a", "b"},
+		{"a\t=  b c ", "a", "b c "},
+		{"a", "a", ""},
+		{`a\=b=c`, "a=b", "c"},
+		{`a\ b = c`, "a b", "c"},
+		{`k = \u0041\t\\`, "k", "A\t\\"},
+		{"a==b", "a", "=b"},
+		{`k = \uD83D\uDE00!`, "k", "😀!"},
+	} {
+		key, value, err := splitProperty(test.line)
+		if err != nil {
+			t.Errorf("splitProperty(%q): %s", test.line, err)
+			continue
+		}
+		if key != test.key || value != test.value {
+			t.Errorf("splitProperty(%q) = %q, %q; want %q, %q", test.line, key, value, test.key, test.value)
+		}
+	}
+}
+
+func TestPropertiesConflict(t *testing.T) {
+	for _, src := range []string{
+		"a=1\na.b=2\n",
+		"a.b=2\na=1\n",
+	} {
+		if _, err := propertiesToHOCON([]byte(src), printer.Config{Mode: printerMode, Tabwidth: tabWidth}); err == nil {
+			t.Errorf("propertiesToHOCON(%q): expected error", src)
+		}
+	}
+}
+
+func TestStdin(t *testing.T) {
+	for _, test := range []struct {
+		in, golden string
+		fromProps  bool
+	}{
+		{"testdata/dottedkeys.input", "testdata/dottedkeys.golden", false},
+		{"testdata/application.properties", "testdata/application.properties.golden", true},
+	} {
+		*fromProperties = test.fromProps
+		f, err := os.Open(test.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		err = processFile("<standard input>", f, &buf)
+		f.Close()
+		if err != nil {
+			t.Errorf("%s: %s", test.in, err)
+			continue
+		}
+		want, err := ioutil.ReadFile(test.golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("%s on standard input: got\n%s\nwant\n%s", test.in, buf.Bytes(), want)
+		}
+	}
+	*fromProperties = false
+
+	*write = true
+	defer func() { *write = false }()
+	if err := processFile("<standard input>", bytes.NewReader(nil), ioutil.Discard); err == nil {
+		t.Error("expected error using -w with standard input")
+	}
+}
@@ -0,0 +1,294 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// unitValue matches a number followed by a unit, with or without white
+// space between them, as in 10s, 10 seconds or 512MiB. A unit starts
+// with a letter, and those of a UnitClassifier may go on with other
+// characters, as in 10 req/s.
+var unitValue = regexp.MustCompile(`^(-?[0-9]+(?:\.[0-9]+)?)[ \t]*([A-Za-z][^ \t]*)$`)
+
+// valueUnits are the units of durations, periods and sizes that HOCON
+// libraries read, with the kind of value each is a unit of.
+var valueUnits = map[string]string{}
+
+func init() {
+	for kind, units := range map[string]string{
+		"duration": `ns nano nanos nanosecond nanoseconds us micro micros microsecond microseconds
+			ms milli millis millisecond milliseconds s second seconds m minute minutes
+			h hour hours d day days`,
+		"period": `w week weeks mo month months y year years`,
+		"size": `B b byte bytes K k Ki KiB kB kilobyte kilobytes kibibyte kibibytes
+			M Mi MiB MB megabyte megabytes mebibyte mebibytes
+			G g Gi GiB GB gigabyte gigabytes gibibyte gibibytes
+			T t Ti TiB TB terabyte terabytes tebibyte tebibytes
+			P p Pi PiB PB petabyte petabytes pebibyte pebibytes`,
+	} {
+		for _, u := range strings.Fields(units) {
+			valueUnits[u] = kind
+		}
+	}
+}
+
+// A UnitClassifier recognizes units besides those of durations,
+// periods and sizes, for dialects of HOCON that add their own, such as
+// req/s in 10 req/s. A number followed by such a unit is a typed value,
+// as a duration is: formatting leaves it as it is written, neither
+// quoting it nor spacing it as -fix-quotes spaces durations, and
+// -explain names its kind.
+type UnitClassifier interface {
+	// UnitKind returns the kind of value that a number followed by
+	// unit is, such as "rate", or "" if unit is not a unit.
+	UnitKind(unit string) string
+}
+
+// Units is a UnitClassifier of the units it maps to their kind.
+type Units map[string]string
+
+// UnitKind returns the kind of unit in u.
+func (u Units) UnitKind(unit string) string {
+	return u[unit]
+}
+
+// customUnit returns the kind of the value text if it is a number
+// followed by a unit that units, and not HOCON, recognizes, and ""
+// otherwise.
+func customUnit(text string, units UnitClassifier) string {
+	if units == nil {
+		return ""
+	}
+	m := unitValue.FindStringSubmatch(text)
+	if m == nil || valueUnits[m[2]] != "" {
+		return ""
+	}
+	return units.UnitKind(m[2])
+}
+
+// fixQuotes makes the quoting of src consistent, for -fix-quotes:
+//
+//	"port" = 8080               port = 8080
+//	"name" = "orders"           name = orders
+//	log dir = "/var/log"        "log dir" = "/var/log"
+//	motd = "a\nb\nc"            motd = """a
+//	                            b
+//	                            c"""
+//	timeout = 10seconds         timeout = 10 seconds
+//	ttl = 5 s                   ttl = 5s
+//
+// Key elements lose their quotes where they read back as the same key,
+// except those holding a '-', such as "Content-Type", which name the
+// entries of maps and keep them as jsonKey does, and a leading
+// "include", which would start an include statement. Elements holding white
+// space are quoted. A field value that is a single quoted string loses
+// its quotes if it starts with a letter and would read back as the same
+// string, not as a number, boolean or null; one holding two or more
+// line breaks becomes a multi-line string if it can be written as one.
+// A number and a unit make a value with a spelled-out unit written
+// after one space, and an abbreviated one written right after the
+// number; one of the units of units is left as it is. Array elements are left as they are, since unquoted elements
+// join when written next to each other.
+func fixQuotes(src []byte, units UnitClassifier) ([]byte, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []byte
+	var open []tokenKind // enclosing { and [
+	atField := true      // at the start of a field in an object
+	atValue := false     // at the start of the value of a field
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		inObject := len(open) == 0 || open[len(open)-1] == tokLBrace
+		if atField && inObject && (t.kind == tokUnquoted || t.kind == tokString) && !(t.kind == tokUnquoted && t.lit == "include") {
+			if end := keyEnd(toks, i); end > 0 {
+				res = append(res, fixKeyQuotes(toks[i:end])...)
+				atField = false
+				i = end - 1
+				continue
+			}
+		}
+		if atValue {
+			if text, end := fixValueQuotes(toks, i, units); end > 0 {
+				res = append(res, text...)
+				atValue = false
+				i = end - 1
+				continue
+			}
+		}
+
+		switch t.kind {
+		case tokLBrace, tokLBracket:
+			open = append(open, t.kind)
+			atField, atValue = t.kind == tokLBrace, false
+		case tokRBrace, tokRBracket:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			atField, atValue = true, false
+		case tokNewline, tokComma:
+			atField, atValue = inObject, false
+		case tokSep:
+			atField, atValue = false, inObject
+		case tokSpace, tokComment:
+		default:
+			atField, atValue = false, false
+		}
+		res = append(res, t.lit...)
+	}
+	return res, nil
+}
+
+// keyEnd returns the index just past the last text token of the key
+// starting at toks[i], or 0 if toks[i] does not start a key followed by
+// a separator or an object.
+func keyEnd(toks []tok, i int) int {
+	end := 0
+	for j := i; j < len(toks); j++ {
+		switch toks[j].kind {
+		case tokUnquoted, tokString:
+			end = j + 1
+		case tokSpace:
+		case tokSep, tokLBrace:
+			return end
+		default:
+			return 0
+		}
+	}
+	return 0
+}
+
+// fixKeyQuotes returns the key written by toks with the quoting of its
+// elements fixed.
+func fixKeyQuotes(toks []tok) string {
+	type elem struct {
+		text   strings.Builder
+		quoted bool
+	}
+	elems := []*elem{new(elem)}
+	for _, t := range toks {
+		e := elems[len(elems)-1]
+		switch t.kind {
+		case tokString:
+			var s string
+			if err := json.Unmarshal([]byte(t.lit), &s); err != nil {
+				return joinLits(toks)
+			}
+			e.text.WriteString(s)
+			e.quoted = true
+		case tokUnquoted:
+			parts := strings.Split(t.lit, ".")
+			e.text.WriteString(parts[0])
+			for _, p := range parts[1:] {
+				e = new(elem)
+				e.text.WriteString(p)
+				elems = append(elems, e)
+			}
+		default:
+			e.text.WriteString(t.lit)
+		}
+	}
+	keys := make([]string, len(elems))
+	for i, e := range elems {
+		k := e.text.String()
+		switch {
+		case e.quoted && (strings.Contains(k, "-") || i == 0 && k == "include"):
+			keys[i] = quoteString(k)
+		default:
+			keys[i] = hoconKey(k)
+		}
+	}
+	return strings.Join(keys, ".")
+}
+
+func joinLits(toks []tok) string {
+	var b strings.Builder
+	for _, t := range toks {
+		b.WriteString(t.lit)
+	}
+	return b.String()
+}
+
+// fixValueQuotes returns the value starting at toks[i] with its quoting
+// or unit spacing fixed, and the index just past it, or an end of 0 if
+// the value is left as it is.
+func fixValueQuotes(toks []tok, i int, units UnitClassifier) (string, int) {
+	t := toks[i]
+	if t.kind == tokString && valueEnds(toks, i+1) {
+		var s string
+		if err := json.Unmarshal([]byte(t.lit), &s); err != nil {
+			return "", 0
+		}
+		if unquotedValue(s) {
+			return s, i + 1
+		}
+		if m := multilineValue(s); m != "" {
+			return m, i + 1
+		}
+		return "", 0
+	}
+
+	// A number and a unit: one or two unquoted words.
+	j := i
+	for j < len(toks) && (toks[j].kind == tokUnquoted || toks[j].kind == tokSpace) {
+		j++
+	}
+	for j > i && toks[j-1].kind == tokSpace {
+		j--
+	}
+	if j == i || !valueEnds(toks, j) {
+		return "", 0
+	}
+	text := joinLits(toks[i:j])
+	if customUnit(text, units) != "" {
+		return text, j
+	}
+	m := unitValue.FindStringSubmatch(text)
+	if m == nil || valueUnits[m[2]] == "" {
+		return "", 0
+	}
+	sep := ""
+	if len(m[2]) >= 3 && strings.ToLower(m[2]) == m[2] {
+		sep = " " // a spelled-out unit
+	}
+	return m[1] + sep + m[2], j
+}
+
+// unquotedValue reports whether the string s reads back as the same
+// string when written without quotes.
+func unquotedValue(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	if s == "" || !unicode.IsLetter(r) || !utf8.ValidString(s) {
+		return false
+	}
+	switch s {
+	case "true", "false", "null", "include":
+		return false
+	}
+	if strings.Contains(s, "//") || strings.ContainsAny(s, strings.Replace(forbiddenUnquoted, ".", "", 1)) || strings.IndexFunc(s, unquotedSpace) >= 0 {
+		return false
+	}
+	return !needsQuotes(s)
+}
+
+// multilineValue returns s as a """ multi-line string if it holds two
+// or more line breaks and can be written as one, and "" otherwise. A
+// multi-line string has no escapes, so s may not hold """, end in a
+// quote, or hold control characters other than newlines and tabs.
+func multilineValue(s string) string {
+	if strings.Count(s, "\n") < 2 || strings.Contains(s, `"""`) || strings.HasSuffix(s, `"`) {
+		return ""
+	}
+	for _, r := range s {
+		if r != '\n' && r != '\t' && unicode.IsControl(r) {
+			return ""
+		}
+	}
+	return `"""` + s + `"""`
+}
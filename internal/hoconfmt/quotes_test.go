@@ -0,0 +1,101 @@
+package hoconfmt
+
+import "testing"
+
+func TestFixQuotes(t *testing.T) {
+	for _, test := range []struct {
+		src, want string
+	}{
+		// keys
+		{`"port" = 8080`, `port = 8080`},
+		{`"a"."b" { "c" = 1 }`, `a.b { c = 1 }`},
+		{`log dir = 1`, `"log dir" = 1`},
+		{`"log dir" = 1`, `"log dir" = 1`},
+		{`"a.b" = 1`, `"a.b" = 1`},
+		{`"Content-Type" = 1`, `"Content-Type" = 1`},
+		{`"include" = 1`, `"include" = 1`},
+		{`"" = 1`, `"" = 1`},
+
+		// values
+		{`name = "orders"`, `name = orders`},
+		{`path = "x/y.conf"`, `path = x/y.conf`},
+		{`name = "orders" // the queue`, `name = orders // the queue`},
+		{`t = "true"`, `t = "true"`},
+		{`n = "10"`, `n = "10"`},
+		{`url = "http://x"`, `url = "http://x"`},
+		{`s = "a b"`, `s = "a b"`},
+		{`s = "a" "b"`, `s = "a" "b"`},
+		{`l = ["a", "b"]`, `l = ["a", "b"]`},
+		{`motd = "a\nb\nc"`, "motd = \"\"\"a\nb\nc\"\"\""},
+		{`short = "a\nb"`, `short = "a\nb"`},
+		{`q = "a\nb\nc\""`, `q = "a\nb\nc\""`},
+
+		// units
+		{`timeout = 10seconds`, `timeout = 10 seconds`},
+		{`timeout = 10   seconds`, `timeout = 10 seconds`},
+		{`ttl = 5 s`, `ttl = 5s`},
+		{`size = 512 MiB`, `size = 512MiB`},
+		{`period = 1.5 ms`, `period = 1.5ms`},
+		{`x = 10 apples`, `x = 10 apples`},
+		{`l = [10 seconds]`, `l = [10 seconds]`},
+	} {
+		got, err := fixQuotes([]byte(test.src), nil)
+		if err != nil {
+			t.Errorf("fixQuotes(%q): %v", test.src, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("fixQuotes(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestUnitClassifier(t *testing.T) {
+	const src = `rate = 10 req/s
+burst = 5req/s
+quoted = "10 req/s"
+area = 10m^2
+other = 10x^2
+timeout = 10seconds
+`
+	tests := []struct {
+		units UnitClassifier
+		want  string
+	}{
+		{nil, `rate = 10 req/s # type: string
+burst = 5req/s # type: string
+quoted = "10 req/s" # type: string
+area = "10m^2" # type: string
+other = "10x^2" # type: string
+timeout = 10 seconds # type: duration
+`},
+		// The custom units keep their spacing and quotes; the built-in
+		// ones are still spaced.
+		{Units{"req/s": "rate", "m^2": "area"}, `rate = 10 req/s # type: rate
+burst = 5req/s # type: rate
+quoted = "10 req/s" # type: rate
+area = 10m^2 # type: area
+other = "10x^2" # type: string
+timeout = 10 seconds # type: duration
+`},
+	}
+	for _, tt := range tests {
+		opts := Options{FixQuotes: true, Explain: true, UnitClassifier: tt.units}
+		res, err := formatFile("a.conf", []byte(src), opts)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.units, err)
+		}
+		if string(res) != tt.want {
+			t.Errorf("%v: got\n%s\nwant\n%s", tt.units, res, tt.want)
+		}
+		again, err := formatFile("a.conf", res, opts)
+		if err != nil || string(again) != tt.want {
+			t.Errorf("%v: formatting the result again gave\n%s%v", tt.units, again, err)
+		}
+	}
+
+	// Units of the classifier do not replace the built-in ones.
+	if got := customUnit("10s", Units{"s": "rate"}); got != "" {
+		t.Errorf("customUnit(10s) = %q, want the built-in unit", got)
+	}
+}
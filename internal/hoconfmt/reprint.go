@@ -0,0 +1,241 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"strings"
+)
+
+// reprint lays out src from its syntax tree, for -reprint: one item of
+// an object or array on each line, indented by indent per level of
+// nesting, with a single space around separators other than : and
+// before trailing comments, and at most one blank line between items,
+// where the source has one. Keys, values and comments are written as
+// they are, and other commas are dropped. The parts of a concatenation
+// keep the white space between them (see partSpace), and a comment on the line of an opening brace or bracket stays
+// on that line.
+//
+// An object or array written on one line stays on one line, as in
+// { x = 1, y = 2 } and [1, 2], if its line then fits in width columns,
+// counting tabs as tabwidth. Otherwise it is laid out over several
+// lines, and its items are laid out on their own: an object or array
+// cannot be on one line if one inside it is not. Objects and arrays
+// holding comments or multi-line strings, and those written over
+// several lines, always are.
+func reprint(src []byte, indent string, width, tabwidth int) ([]byte, error) {
+	doc, err := parseDocument(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &treePrinter{src: src, indent: indent, width: width, tabwidth: tabwidth}
+	p.items(doc.before, 0)
+	if doc.blank {
+		p.b.WriteByte('\n')
+	}
+	switch root := doc.root.(type) {
+	case *objectNode:
+		if root.braces {
+			p.object(root, 0)
+			p.b.WriteByte('\n')
+		} else {
+			p.items(root.items, 0)
+		}
+	case *arrayNode:
+		p.value(&valueNode{parts: []valuePart{{node: root}}}, 0, "")
+		p.b.WriteByte('\n')
+	}
+	p.items(doc.after, 0)
+	return p.b.Bytes(), nil
+}
+
+// A treePrinter writes a syntax tree as reprint lays it out.
+type treePrinter struct {
+	src             []byte
+	b               bytes.Buffer
+	indent          string
+	width, tabwidth int
+}
+
+// items writes items one per line at the nesting depth depth, each line
+// ending in a newline.
+func (p *treePrinter) items(items []item, depth int) {
+	for _, it := range items {
+		if it.blank {
+			p.b.WriteByte('\n')
+		}
+		p.b.WriteString(strings.Repeat(p.indent, depth))
+		trailing := ""
+		if it.comment != nil {
+			trailing = " " + it.comment.text
+		}
+		p.node(it.node, depth, trailing)
+		p.b.WriteString(trailing)
+		p.b.WriteByte('\n')
+	}
+}
+
+// node writes n, followed on its line by trailing.
+func (p *treePrinter) node(n node, depth int, trailing string) {
+	switch n := n.(type) {
+	case *commentNode:
+		p.b.WriteString(n.text)
+	case *includeNode:
+		p.b.WriteString("include ")
+		for _, t := range n.target {
+			p.b.WriteString(t.lit)
+		}
+	case *fieldNode:
+		p.b.WriteString(fieldPrefix(n))
+		p.value(n.value, depth, trailing)
+	case *valueNode:
+		p.value(n, depth, trailing)
+	}
+}
+
+// fieldPrefix returns the key and separator of f as reprint writes them.
+func fieldPrefix(f *fieldNode) string {
+	switch f.sep {
+	case "":
+		return f.keyText() + " "
+	case ":":
+		return f.keyText() + ": "
+	}
+	return f.keyText() + " " + f.sep + " "
+}
+
+// value writes v, on one line if it can be and its line, followed by
+// trailing, fits in the width.
+func (p *treePrinter) value(v *valueNode, depth int, trailing string) {
+	if text, ok := p.inlineValue(v); ok && p.column()+textWidth([]tok{{lit: text + trailing}}, p.tabwidth) <= p.width {
+		p.b.WriteString(text)
+		return
+	}
+	for i, part := range v.parts {
+		if i > 0 {
+			p.b.WriteString(partSpace(v, i))
+		}
+		switch n := part.node.(type) {
+		case *objectNode:
+			p.object(n, depth)
+		case *arrayNode:
+			p.array(n, depth)
+		default:
+			p.b.WriteString(part.tok.lit)
+		}
+	}
+}
+
+// partSpace returns the white space to write before the part i of v: as
+// it is between strings and substitutions, whose value it is part of,
+// and as a single space, if any, next to an object or array.
+func partSpace(v *valueNode, i int) string {
+	space := v.parts[i].space
+	if space != "" && (v.parts[i-1].node != nil || v.parts[i].node != nil) {
+		return " "
+	}
+	return space
+}
+
+// column returns the width of the line written so far.
+func (p *treePrinter) column() int {
+	b := p.b.Bytes()
+	line := b[bytes.LastIndexByte(b, '\n')+1:]
+	return textWidth([]tok{{lit: string(line)}}, p.tabwidth)
+}
+
+func (p *treePrinter) object(o *objectNode, depth int) {
+	if len(o.items) == 0 {
+		p.b.WriteString("{}")
+		return
+	}
+	p.b.WriteByte('{')
+	p.items(p.braceComment(o.off, o.items), depth+1)
+	p.b.WriteString(strings.Repeat(p.indent, depth))
+	p.b.WriteByte('}')
+}
+
+func (p *treePrinter) array(a *arrayNode, depth int) {
+	if len(a.items) == 0 {
+		p.b.WriteString("[]")
+		return
+	}
+	p.b.WriteByte('[')
+	p.items(p.braceComment(a.off, a.items), depth+1)
+	p.b.WriteString(strings.Repeat(p.indent, depth))
+	p.b.WriteByte(']')
+}
+
+// braceComment ends the line of the opening brace or bracket at off,
+// writing after it the comment that follows it on its line, if items,
+// the items of its object or array, start with one. It returns the
+// items left to write.
+func (p *treePrinter) braceComment(off int, items []item) []item {
+	if c, ok := items[0].node.(*commentNode); ok && bytes.IndexByte(p.src[off:c.off], '\n') < 0 {
+		p.b.WriteString(" " + c.text)
+		items = items[1:]
+	}
+	p.b.WriteByte('\n')
+	return items
+}
+
+// inlineValue returns v written on one line, and whether it can be:
+// whether its objects and arrays are each written on one line in the
+// source, and hold only fields or elements without comments.
+func (p *treePrinter) inlineValue(v *valueNode) (string, bool) {
+	var b strings.Builder
+	for i, part := range v.parts {
+		if i > 0 {
+			b.WriteString(partSpace(v, i))
+		}
+		switch n := part.node.(type) {
+		case *objectNode:
+			if len(n.items) > 0 && bytes.IndexByte(p.src[n.off:n.end], '\n') >= 0 {
+				return "", false
+			}
+			b.WriteByte('{')
+			for j, it := range n.items {
+				f, ok := it.node.(*fieldNode)
+				if !ok || it.comment != nil {
+					return "", false
+				}
+				text, ok := p.inlineValue(f.value)
+				if !ok {
+					return "", false
+				}
+				if j > 0 {
+					b.WriteByte(',')
+				}
+				b.WriteString(" " + fieldPrefix(f) + text)
+			}
+			if len(n.items) > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteByte('}')
+		case *arrayNode:
+			if len(n.items) > 0 && bytes.IndexByte(p.src[n.off:n.end], '\n') >= 0 {
+				return "", false
+			}
+			b.WriteByte('[')
+			for j, it := range n.items {
+				e, ok := it.node.(*valueNode)
+				if !ok || it.comment != nil {
+					return "", false
+				}
+				text, ok := p.inlineValue(e)
+				if !ok {
+					return "", false
+				}
+				if j > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteString(text)
+			}
+			b.WriteByte(']')
+		default:
+			if part.tok.kind == tokMultiline {
+				return "", false
+			}
+			b.WriteString(part.tok.lit)
+		}
+	}
+	return b.String(), true
+}
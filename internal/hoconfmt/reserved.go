@@ -0,0 +1,99 @@
+package hoconfmt
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// reservedUnquoted lists the characters HOCON reserves outside quotes
+// that the lexer still accepts inside an unquoted run of text.
+const reservedUnquoted = "$+`^?!@*&\\"
+
+// needsQuotes reports whether the unquoted text s contains a character
+// or a "//" that HOCON does not allow outside quotes. Numbers such as
+// 1e+3 are fine, and so is text that is not valid UTF-8, whose invalid
+// bytes a quoted string would read as U+FFFD.
+func needsQuotes(s string) bool {
+	if numberLiteral.MatchString(s) || !utf8.ValidString(s) {
+		return false
+	}
+	return strings.ContainsAny(s, reservedUnquoted) || strings.Contains(s, "//")
+}
+
+// quoteReserved quotes the unquoted keys and values of src that contain
+// characters HOCON reserves, which a parser would reject or read
+// differently, as in
+//
+//	greeting = hello!
+//	url = http://example.com/?q=1
+//
+// A key element is quoted on its own, so a!b.c becomes "a!b".c. A value
+// is quoted only if it is a single unquoted word, where it clearly
+// means a string, and not a number with one of the units of units; a
+// concatenation is left as it is.
+func quoteReserved(lx *lexer, src []byte, units UnitClassifier) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, 0, len(src))
+	var open []tokenKind // enclosing { and [
+	atField := true      // at the start of a field in an object
+	atValue := false     // at the start of a value or array element
+	inValue := false     // past the separator of the current field
+	for i, t := range toks {
+		inObject := len(open) == 0 || open[len(open)-1] == tokLBrace
+		switch {
+		case t.kind != tokUnquoted:
+		case atField && inObject && isKey(toks, i):
+			res = append(res, quoteKeyElements(t.lit)...)
+			continue
+		case atValue && needsQuotes(t.lit) && valueEnds(toks, i+1) && customUnit(t.lit, units) == "":
+			res = append(res, quoteString(t.lit)...)
+			atValue = false
+			continue
+		}
+
+		switch t.kind {
+		case tokLBrace, tokLBracket:
+			open = append(open, t.kind)
+			atField, atValue = t.kind == tokLBrace, t.kind == tokLBracket
+			inValue = false
+		case tokRBrace, tokRBracket:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			atField, atValue, inValue = true, false, false
+		case tokNewline, tokComma:
+			inArray := len(open) > 0 && open[len(open)-1] == tokLBracket
+			atField, atValue, inValue = !inArray, inArray, false
+		case tokSep:
+			// A second ':' or '=' is part of the value, as in C:\x.
+			atField, atValue, inValue = false, !inValue, true
+		case tokSpace, tokComment:
+		default:
+			atField, atValue = false, false
+		}
+		res = append(res, t.lit...)
+	}
+	return res, nil
+}
+
+// isKey reports whether toks[i] starts the key of a field.
+func isKey(toks []tok, i int) bool {
+	keyEnd, _, _ := objectField(toks, i)
+	return keyEnd > 0 || isAssignment(toks, i)
+}
+
+// quoteKeyElements quotes the elements of the dotted unquoted key k
+// that need quotes.
+func quoteKeyElements(k string) string {
+	elems := strings.Split(k, ".")
+	for i, e := range elems {
+		if needsQuotes(e) {
+			elems[i] = quoteString(e)
+		}
+	}
+	return strings.Join(elems, ".")
+}
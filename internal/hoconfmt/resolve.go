@@ -0,0 +1,295 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"fmt"
+	"go/printer"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+)
+
+// An override is a substitution or a concatenation with substitutions
+// that replaces prev, an earlier value of its field, as a resolving
+// merger keeps it. The field keeps prev if value turns out to be
+// undefined, and value resolves a substitution of the field itself, as
+// in path = ${path}":/bin", to prev. off is the offset of the field.
+type override struct {
+	value Value
+	prev  Value
+	off   int
+}
+
+// undefined is what resolving an optional substitution of a path that
+// is set neither by the document nor by the environment leaves: no
+// field in an object, no element in an array and nothing in a
+// concatenation.
+type undefined struct{}
+
+func isUndefined(v Value) bool {
+	_, ok := v.(undefined)
+	return ok
+}
+
+// An environment is what -resolve resolves a document against: the
+// directories to look for includes in besides that of the including
+// file, as for -check-includes, and the environment variables, which
+// lookup returns. warn, if set, takes the warnings about values that
+// substitutions give a type that looks unintended, which strict makes
+// errors instead.
+type environment struct {
+	dirs   []string
+	lookup func(string) (string, bool)
+	warn   func(warning)
+	strict bool
+}
+
+// resolveDocument merges src, read from filename, and resolves its
+// substitutions as HOCON resolves them, for -resolve. Include
+// statements are followed, as merger.include follows them. A
+// substitution of a path that src does not set is resolved to the
+// environment variable of that name. An optional substitution that
+// stays undefined leaves nothing: a field whose value it is keeps its
+// earlier value, or is left out if it has none, and it adds no element
+// to an array and no text to a concatenation. A substitution of the
+// field holding it stands for the field's earlier value. Substitutions
+// that cannot be resolved are errors, and so are values that join
+// objects or arrays with text. A substitution that joins a number or a
+// boolean with text, making a string, or that gives its field another
+// kind of value, object, array or scalar, than the one it replaces, is
+// reported to env.warn, or as an error if env.strict is set.
+func resolveDocument(filename string, src []byte, env *environment) (Value, error) {
+	return resolveFiles([]string{filename}, [][]byte{src}, env)
+}
+
+// resolveFiles merges the documents srcs, read from filenames, in
+// order, each overriding the ones before as a later field overrides an
+// earlier one, and resolves the result as resolveDocument resolves a
+// single document, for -merge. Errors name the file they are in,
+// except those of substitutions, which may be resolved across files.
+func resolveFiles(filenames []string, srcs [][]byte, env *environment) (Value, error) {
+	var v Value
+	for i, src := range srcs {
+		var base Object
+		if i > 0 {
+			obj, ok := v.(Object)
+			if !ok {
+				return nil, fmt.Errorf("%s: cannot merge a document that is an array", filenames[0])
+			}
+			base = obj
+		}
+		doc, err := (&merger{src: src, resolving: true, env: env, filename: filenames[i]}).documentOver(base)
+		if err != nil {
+			return nil, withFilename(err, filenames[i])
+		}
+		if _, ok := doc.(Object); i > 0 && !ok {
+			return nil, fmt.Errorf("%s: cannot merge a document that is an array", filenames[i])
+		}
+		v = doc
+	}
+	// The offsets of values are only known to be in the one file there
+	// is.
+	pos := func(off int) token.Position {
+		var pos token.Position
+		if len(srcs) == 1 {
+			pos = position(srcs[0], off)
+			pos.Filename = filenames[0]
+		}
+		return pos
+	}
+	var errs errorList
+	r := &resolver{root: v, active: map[string]bool{}, env: env.lookup, prev: map[string]Value{}}
+	switch {
+	case env.strict:
+		r.typeWarning = func(off int, msg string) { errs.Add(pos(off), codeType, msg) }
+	case env.warn != nil:
+		r.typeWarning = func(off int, msg string) { env.warn(warning{pos(off), msg, codeType}) }
+	}
+	v = r.value(v, []string{})
+	if r.err != nil {
+		return nil, r.err
+	}
+	checkResolved(v, func(off int, msg string) { errs.Add(pos(off), codeConcatenation, msg) })
+	if len(errs) > 0 {
+		errs.Sort()
+		return nil, errs
+	}
+	return v, nil
+}
+
+// checkResolved calls fail with the offset of every concatenation left
+// in v once its substitutions are resolved, which joins values that
+// cannot be joined, and the reason, as the parser words it.
+func checkResolved(v Value, fail func(off int, msg string)) {
+	switch v := v.(type) {
+	case concatenation:
+		objects, arrays := false, false
+		for _, p := range v.parts {
+			objects = objects || isObject(p)
+			arrays = arrays || isArray(p)
+		}
+		if objects && arrays {
+			fail(v.off, "cannot concatenate an object and an array")
+		} else {
+			fail(v.off, "cannot concatenate an object or array with text")
+		}
+	case []Value:
+		for _, e := range v {
+			checkResolved(e, fail)
+		}
+	case Object:
+		for _, f := range v {
+			checkResolved(f.Value, fail)
+		}
+	}
+}
+
+// printResolved returns the document src, read from filename, as
+// -resolve prints it, resolved by resolveDocument against env and
+// written by writeResolved.
+func printResolved(filename string, src []byte, env *environment, opts Options, asJSON bool) ([]byte, error) {
+	v, err := resolveDocument(filename, src, env)
+	if _, ok := err.(errorList); ok {
+		return nil, withFilename(err, filename)
+	} else if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	res, err := writeResolved(v, opts, asJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return res, nil
+}
+
+// writeResolved returns v, a resolved document, as HOCON formatted by
+// FormatValue with opts, without braces around the root object, or as
+// JSON if asJSON is set, followed by a newline. An empty root object
+// is written as nothing.
+func writeResolved(v Value, opts Options, asJSON bool) ([]byte, error) {
+	if asJSON {
+		var b bytes.Buffer
+		writeJSON(&b, v, valueIndent(opts), 0)
+		b.WriteByte('\n')
+		return b.Bytes(), nil
+	}
+	res, err := FormatValue(v, opts)
+	if err != nil {
+		return nil, err
+	}
+	if obj, ok := v.(Object); ok {
+		if len(obj) == 0 {
+			return nil, nil
+		}
+		if res, err = setRootBraces(res, rootBracesOmit, valueIndent(opts)); err != nil {
+			return nil, err
+		}
+		res = bytes.TrimRight(res, "\n")
+	}
+	return append(res, '\n'), nil
+}
+
+// printMerged writes the files filenames to out merged and resolved by
+// resolveFiles against env, as writeResolved writes them, for -merge
+// and -merge-glob.
+func printMerged(out io.Writer, filenames []string, env *environment, opts Options, asJSON bool) error {
+	srcs := make([][]byte, len(filenames))
+	for i, name := range filenames {
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		srcs[i] = src
+	}
+	v, err := resolveFiles(filenames, srcs, env)
+	if err != nil {
+		return err
+	}
+	res, err := writeResolved(v, opts, asJSON)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(res)
+	return err
+}
+
+// globFiles returns the files matching pattern, for -merge-glob, sorted
+// byte by byte so that they are merged in the same order everywhere.
+// A pattern matching no file is an error.
+func globFiles(pattern string) ([]string, error) {
+	filenames, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("-merge-glob %s: %v", pattern, err)
+	}
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("-merge-glob %s: no files match", pattern)
+	}
+	sort.Strings(filenames)
+	return filenames, nil
+}
+
+// include merges the fields of the file that the include statement n
+// refers to into obj, the object holding n, as if they stood in place
+// of n, so that += in the file appends to an array of obj. The file is
+// looked for as -check-includes looks for it, and a .properties file is
+// converted as -from-properties converts it. A file that is not found is skipped
+// unless the include is required(...); url(...) includes cannot be
+// followed.
+func (m *merger) include(obj Object, n *includeNode) Object {
+	l := srcLine{toks: append([]tok{{kind: tokUnquoted, lit: "include"}}, n.target...)}
+	kind, name, required, ok := includeResource(l)
+	switch {
+	case !ok:
+		m.fail(n.off, codeInclude, "cannot follow an include statement without a quoted file name")
+		return obj
+	case kind == "url":
+		m.fail(n.off, codeInclude, "cannot follow url(...) includes")
+		return obj
+	}
+	path, found := findInclude(kind, name, m.filename, m.env.dirs)
+	if !found {
+		if required {
+			m.fail(n.off, codeInclude, "cannot find the included file "+name)
+		}
+		return obj
+	}
+	parents := append(m.parents[:len(m.parents):len(m.parents)], absPath(m.filename))
+	for _, p := range parents {
+		if p == absPath(path) {
+			m.fail(n.off, codeInclude, "cannot include "+name+": its includes form a cycle")
+			return obj
+		}
+	}
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		m.fail(n.off, codeInclude, err.Error())
+		return obj
+	}
+	if filepath.Ext(path) == ".properties" {
+		if src, err = propertiesToHOCON(src, printer.Config{Mode: printerMode, Tabwidth: tabWidth}); err != nil {
+			m.fail(n.off, codeInclude, fmt.Sprintf("%s:%v", path, err))
+			return obj
+		}
+	}
+	sub := &merger{src: src, resolving: m.resolving, env: m.env, filename: path, parents: parents}
+	v, err := sub.documentOver(obj)
+	if err != nil {
+		m.fail(n.off, codeInclude, withFilename(err, path).Error())
+		return obj
+	}
+	merged, ok := v.(Object)
+	if !ok {
+		m.fail(n.off, codeInclude, "cannot include "+path+", which holds an array")
+		return obj
+	}
+	return merged
+}
+
+// absPath returns the absolute path of name, or name if it has none.
+func absPath(name string) string {
+	if abs, err := filepath.Abs(name); err == nil {
+		return abs
+	}
+	return name
+}
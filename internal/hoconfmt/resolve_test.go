@@ -0,0 +1,315 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveDocument(t *testing.T) {
+	env := map[string]string{"HOME": "/home/u", "PORT": "8080", "PATH": "/bin"}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	tests := []struct{ src, want string }{
+		// An optional substitution as the whole value of a field.
+		{"a = ${?NOPE}\nb = 1", "b = 1"},
+		{"a = 1\na = ${?NOPE}", "a = 1"},
+		{"a { x = 1 }\na { x = ${?NOPE} }", "a {\n    x = 1\n}"},
+		{"a.x = 1\na.x = ${?NOPE}", "a {\n    x = 1\n}"},
+		{"a = 1\na = ${?PORT}", "a = \"8080\""},
+		{"a = ${?NOPE}\na = ${?PORT}", "a = \"8080\""},
+		// In a concatenation.
+		{"a = ${?NOPE}/x", "a = \"/x\""},
+		{"a = x ${?NOPE} y", "a = \"x  y\""},
+		{"a = [1] ${?NOPE}", "a = [1]"},
+		{"a = ${?NOPE}${?NOPE2}\nb = 2", "b = 2"},
+		// Inside an array.
+		{"a = [1, ${?NOPE}, 3]", "a = [1, 3]"},
+		{"a = [${?NOPE}]", "a = []"},
+		{"a = [{ x = 1, x = ${?NOPE} }]", "a = [\n    {\n        x = 1\n    }\n]"},
+		// Substitutions of the document and the environment.
+		{"h = ${HOME}/app\nport = ${PORT}", "h = \"/home/u/app\"\nport = \"8080\""},
+		{"db { host = h }\nurl = \"jdbc://\"${db.host}", "db {\n    host = \"h\"\n}\nurl = \"jdbc://h\""},
+		{"base { a = 1 }\nc = ${base} { b = 2 }\nd = ${c.b}", "base {\n    a = 1\n}\nc {\n    a = 1\n    b = 2\n}\nd = 2"},
+		{"HOME = here\nh = ${HOME}", "HOME = \"here\"\nh = \"here\""},
+		// Substitutions of the field holding them.
+		{"a = [1]\na = ${a} [2]", "a = [1, 2]"},
+		{"a = [1]\na += 2", "a = [1, 2]"},
+		{"PATH = ${PATH}\":/usr/bin\"", "PATH = \"/bin:/usr/bin\""},
+		{"p = ${?p} [x]", "p = [\"x\"]"},
+		{"a { b = 1 }\na = ${a} { c = 2 }", "a {\n    b = 1\n    c = 2\n}"},
+		// An object merging into the object it overrides.
+		{"a { x = 1 }\na = ${b}\nb { y = 1 }", "a {\n    x = 1\n    y = 1\n}\nb {\n    y = 1\n}"},
+		{"a { x = 1 }\na = ${?NOPE}\na { y = 1 }", "a {\n    x = 1\n    y = 1\n}"},
+		{"a = 1\na = ${?NOPE}\na { y = 1 }", "a {\n    y = 1\n}"},
+	}
+	for _, tt := range tests {
+		res, err := printResolved("a.conf", []byte(tt.src), &environment{lookup: lookup}, DefaultOptions(), false)
+		if err != nil {
+			t.Errorf("-resolve of %q: %v", tt.src, err)
+			continue
+		}
+		if string(res) != tt.want+"\n" {
+			t.Errorf("-resolve of %q:\n%s\nwant\n%s", tt.src, res, tt.want)
+		}
+	}
+
+	res, err := printResolved("a.conf", []byte("a = ${?NOPE}"), &environment{lookup: lookup}, DefaultOptions(), false)
+	if err != nil || len(res) != 0 {
+		t.Errorf("-resolve of a document left empty = %q, %v", res, err)
+	}
+	res, err = printResolved("a.conf", []byte("a = ${HOME}\nb = [${?NOPE}]"), &environment{lookup: lookup}, DefaultOptions(), true)
+	if want := "{\n    \"a\": \"/home/u\",\n    \"b\": []\n}\n"; err != nil || string(res) != want {
+		t.Errorf("-resolve -tojson = %q, %v, want %q", res, err, want)
+	}
+}
+
+func TestResolveDocumentErrors(t *testing.T) {
+	lookup := func(string) (string, bool) { return "", false }
+	tests := []struct{ src, want string }{
+		{"a = ${b}", "a.conf: cannot resolve ${b}: b is not set in the file or the environment"},
+		{"a = ${a}", "a.conf: cannot resolve ${a}, which refers to itself"},
+		{"a = ${b}\nb = ${a}", "a.conf: cannot resolve ${a}, which refers to itself"},
+		{"c = ${?nope}\nj = ${c}", "a.conf: cannot resolve ${c}: c is set only to optional substitutions that are not defined"},
+		{"c = ${?nope}\nj = x${c}", "a.conf: cannot resolve ${c}: c is set only to optional substitutions that are not defined"},
+		{"o { x = 1 }\na = ${o} foo", "a.conf:2:5: cannot concatenate an object or array with text"},
+		{"o { x = 1 }\na = ${o} [1]", "a.conf:2:5: cannot concatenate an object and an array"},
+		{"include required(\"b.conf\")", "a.conf:1:1: cannot find the included file b.conf"},
+		{"include url(\"http://x/b.conf\")", "a.conf:1:1: cannot follow url(...) includes"},
+	}
+	for _, tt := range tests {
+		if _, err := printResolved("a.conf", []byte(tt.src), &environment{lookup: lookup}, DefaultOptions(), false); err == nil || err.Error() != tt.want {
+			t.Errorf("-resolve of %q: got error %v, want %s", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestResolveTypeWarnings(t *testing.T) {
+	const src = `port = 8080
+url = "http://h:"${port}
+debug = true
+d = ${debug}x
+hosts = [a, b]
+host = localhost
+host = ${hosts}
+none = null
+none = ${hosts}
+home = /root
+home = ${?HOME}
+ports = [${port}"0"]
+`
+	var warnings warningList
+	env := &environment{lookup: func(name string) (string, bool) { return "/home/u", name == "HOME" }, warn: warnings.addWarning}
+	if _, err := printResolved("a.conf", []byte(src), env, DefaultOptions(), false); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"a.conf:2:7: warning: url joins the number 8080 of ${port} with text, making a string",
+		"a.conf:4:5: warning: d joins the boolean true of ${debug} with text, making a string",
+		"a.conf:7:1: warning: host was the string \"localhost\", and the substitution replacing it makes it an array of 2 elements",
+		"a.conf:12:10: warning: an array element joins the number 8080 of ${port} with text, making a string",
+	}
+	if len(warnings) != len(want) {
+		t.Fatalf("got warnings %v, want %d", warnings, len(want))
+	}
+	for i, w := range warnings {
+		if w.String() != want[i] {
+			t.Errorf("warning %d = %s, want %s", i, w, want[i])
+		}
+	}
+
+	env.strict = true
+	if _, err := printResolved("a.conf", []byte(src), env, DefaultOptions(), false); err == nil || err.Error() != "a.conf:2:7: url joins the number 8080 of ${port} with text, making a string (and 3 more errors)" {
+		t.Errorf("-resolve -strict: got error %v", err)
+	}
+}
+
+func TestResolveIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"base.conf":            "db { host = localhost, port = 5432 }\nurl = \"jdbc://\"${db.host}\":\"${db.port}\n",
+		"prod.conf":            "db.host = ${DB_HOST}\ndb { pool = 10 }\n",
+		"lib/extra.properties": "cache.ttl=60\n",
+		"self.conf":            "include \"self.conf\"\n",
+		"bad.conf":             "a = {\n",
+	}
+	for name, text := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env := &environment{
+		dirs:   []string{filepath.Join(dir, "lib")},
+		lookup: func(name string) (string, bool) { return "db.prod", name == "DB_HOST" },
+	}
+	app := filepath.Join(dir, "app.conf")
+	const src = `include "base.conf"
+include "prod"
+include "optional.conf"
+include classpath("extra")
+db.port = 5433
+`
+	res, err := printResolved(app, []byte(src), env, DefaultOptions(), false)
+	const want = `db {
+    host = "db.prod"
+    port = 5433
+    pool = 10
+}
+url = "jdbc://db.prod:5433"
+cache {
+    ttl = 60
+}
+`
+	if err != nil || string(res) != want {
+		t.Errorf("-resolve = %v\n%s\nwant\n%s", err, res, want)
+	}
+
+	// The effective value of one key, for -get with -resolve.
+	res, err = printValue(app, []byte(src), "url", env, DefaultOptions(), false)
+	if want := "\"jdbc://db.prod:5433\"\n"; err != nil || string(res) != want {
+		t.Errorf("-get url -resolve = %q, %v, want %q", res, err, want)
+	}
+	noEnv := &environment{lookup: func(string) (string, bool) { return "", false }}
+	if _, err := printValue(app, []byte(src), "url", noEnv, DefaultOptions(), false); err == nil || err.Error() != app+": cannot resolve ${DB_HOST}: DB_HOST is not set in the file or the environment" {
+		t.Errorf("-get url -resolve without DB_HOST: got error %v", err)
+	}
+
+	for _, tt := range []struct{ src, want string }{
+		{"include \"self.conf\"", app + ":1:1: " + filepath.Join(dir, "self.conf") + ":1:1: cannot include self.conf: its includes form a cycle"},
+		{"include \"bad.conf\"", app + ":1:1: " + filepath.Join(dir, "bad.conf") + ":1:5: { is not closed"},
+	} {
+		if _, err := printResolved(app, []byte(tt.src), env, DefaultOptions(), false); err == nil || err.Error() != tt.want {
+			t.Errorf("-resolve of %q: got error %v, want %s", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestPrintMerged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"base.conf":     "app { name = api, port = 8080, hosts = [a, b] }\nurl = \"http://\"${app.name}\":\"${app.port}\nlog.level = info\n",
+		"10-prod.conf":  "app { port = 80, hosts = [c] }\nlog = off\n",
+		"20-local.conf": "app.debug = true\napp.hosts += d\n",
+		"list.conf":     "[1, 2]\n",
+	}
+	var names []string
+	for _, name := range []string{"base.conf", "10-prod.conf", "20-local.conf", "list.conf"} {
+		names = append(names, filepath.Join(dir, name))
+		if err := ioutil.WriteFile(names[len(names)-1], []byte(files[name]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env := &environment{lookup: func(string) (string, bool) { return "", false }}
+
+	var b bytes.Buffer
+	if err := printMerged(&b, names[:3], env, DefaultOptions(), false); err != nil {
+		t.Fatal(err)
+	}
+	const want = `app {
+    name = "api"
+    port = 80
+    hosts = ["c", "d"]
+    debug = true
+}
+url = "http://api:80"
+log = "off"
+`
+	if b.String() != want {
+		t.Errorf("-merge of base and two overlays:\n%s\nwant\n%s", b.String(), want)
+	}
+
+	// A value replaces an object, and an object a value, whole.
+	for _, tt := range []struct {
+		srcs []string
+		want string
+	}{
+		{[]string{"a { x = 1 }", "a = 5"}, "a = 5\n"},
+		{[]string{"a = 5", "a { x = 1 }"}, "a {\n    x = 1\n}\n"},
+		{[]string{"a { x = 1 }", "a = 5", "a { y = 2 }"}, "a {\n    y = 2\n}\n"},
+		{[]string{"a { x = 1 }", "a { y = 2 }"}, "a {\n    x = 1\n    y = 2\n}\n"},
+	} {
+		srcs := make([][]byte, len(tt.srcs))
+		filenames := make([]string, len(tt.srcs))
+		for i, src := range tt.srcs {
+			srcs[i], filenames[i] = []byte(src), fmt.Sprintf("%d.conf", i)
+		}
+		v, err := resolveFiles(filenames, srcs, env)
+		if err != nil {
+			t.Errorf("-merge of %q: %v", tt.srcs, err)
+			continue
+		}
+		if res, err := writeResolved(v, DefaultOptions(), false); err != nil || string(res) != tt.want {
+			t.Errorf("-merge of %q = %q, %v, want %q", tt.srcs, res, err, tt.want)
+		}
+	}
+
+	b.Reset()
+	if err := printMerged(&b, []string{names[0], names[3]}, env, DefaultOptions(), false); err == nil || err.Error() != names[3]+": cannot merge a document that is an array" {
+		t.Errorf("-merge with a root array: got error %v", err)
+	}
+	if err := printMerged(&b, []string{names[0], filepath.Join(dir, "nope.conf")}, env, DefaultOptions(), false); err == nil {
+		t.Error("-merge of a missing file succeeded")
+	}
+}
+
+func TestMergeGlob(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Written out of order, to be merged in the order of their names.
+	files := map[string]string{
+		"30-local.conf": "db.host = localhost\nurl = \"jdbc://\"${db.host}\":\"${db.port}\n",
+		"10-base.conf":  "db { host = db.internal, port = 5432 }\nurl = ${db.host}\n",
+		"20-prod.conf":  "db { host = db.prod, pool = 10 }\n",
+		"notes.txt":     "not = merged\n",
+	}
+	for _, name := range []string{"30-local.conf", "10-base.conf", "20-prod.conf", "notes.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(files[name]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names, err := globFiles(filepath.Join(dir, "*.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := printMerged(&b, names, &environment{lookup: func(string) (string, bool) { return "", false }}, DefaultOptions(), false); err != nil {
+		t.Fatal(err)
+	}
+	const want = `db {
+    host = "localhost"
+    port = 5432
+    pool = 10
+}
+url = "jdbc://localhost:5432"
+`
+	if b.String() != want {
+		t.Errorf("-merge-glob of three fragments:\n%s\nwant\n%s", b.String(), want)
+	}
+
+	if _, err := globFiles(filepath.Join(dir, "*.json")); err == nil {
+		t.Error("-merge-glob of a pattern matching no file succeeded")
+	}
+}
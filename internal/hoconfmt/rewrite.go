@@ -0,0 +1,310 @@
+package hoconfmt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// A rewriteRule moves the value at the key path from, and everything
+// inside it, to the key path to.
+type rewriteRule struct {
+	from, to []string
+}
+
+// A rulesValue is the value of the -r flag, which may be given several
+// times.
+type rulesValue struct {
+	rules []string
+}
+
+// rulesFlag defines a flag like -r that collects its values.
+func rulesFlag(name, usage string) *rulesValue {
+	r := &rulesValue{}
+	commandLine.Var(r, name, usage)
+	return r
+}
+
+func (r *rulesValue) String() string {
+	return strings.Join(r.rules, "\n")
+}
+
+// Set adds the rule s. An empty s clears the rules, as setting the flag
+// back to its default does.
+func (r *rulesValue) Set(s string) error {
+	if s == "" {
+		r.rules = nil
+		return nil
+	}
+	if _, err := parseRewriteRules(s); err != nil {
+		return err
+	}
+	r.rules = append(r.rules, s)
+	return nil
+}
+
+// load adds the rules of the -r-file filename, one per line.
+func (r *rulesValue) load(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if _, err := parseRewriteRules(string(data)); err != nil {
+		return fmt.Errorf("%s: %s", filename, err)
+	}
+	r.rules = append(r.rules, string(data))
+	return nil
+}
+
+// parseRewriteRules parses rules written one per line as
+//
+//	akka.remote.netty.tcp.port -> akka.remote.artery.canonical.port
+//
+// Blank lines and lines starting with # or // are skipped. Keys that
+// hold dots or other special characters are quoted as in HOCON paths.
+func parseRewriteRules(s string) ([]rewriteRule, error) {
+	var rules []rewriteRule
+	sc := bufio.NewScanner(strings.NewReader(s))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		from, to, ok := strings.Cut(line, "->")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid rewrite rule %q: must be old.path -> new.path", line)
+		}
+		r := rewriteRule{pathElems(from), pathElems(to)}
+		for _, e := range append(append([]string{}, r.from...), r.to...) {
+			if e == "" {
+				return nil, fmt.Errorf("invalid rewrite rule %q: empty key in path", line)
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// hasPathPrefix reports whether the key path p starts with prefix.
+func hasPathPrefix(p, prefix []string) bool {
+	if len(p) < len(prefix) {
+		return false
+	}
+	for i, e := range prefix {
+		if p[i] != e {
+			return false
+		}
+	}
+	return true
+}
+
+// rewriteKeys applies rules to src in order, as -r does.
+func rewriteKeys(src []byte, rules []rewriteRule) ([]byte, error) {
+	for _, r := range rules {
+		var err error
+		if src, err = rewriteKey(src, r); err != nil {
+			return nil, err
+		}
+	}
+	return src, nil
+}
+
+// rewriteKey moves the fields of src at the key path r.from, or below
+// it, to r.to, and points the substitutions of those paths to the new
+// ones. Paths are those of the merged document, so the rule finds a
+// field whether it is written as a.b.c = 1 or inside a { b { c = 1 } }.
+// A key is renamed where it stands if its object is still on the new
+// path; otherwise the field, with its comments, is moved to the end of
+// the innermost object enclosing it that is, or to the end of the
+// file. An object left with no fields by the moves is removed.
+// Formatting afterwards indents the moved lines.
+//
+// A rule that rewrites fields is rejected if a key it leaves alone is
+// already set at the new path, since the values would be merged, or if
+// it would place a field after another that it comes before in src and
+// that sets the same path, since the other value would win.
+func rewriteKey(src []byte, r rewriteRule) ([]byte, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	for i, t := range toks {
+		if t.kind != tokSubst {
+			continue
+		}
+		s := trimSubst(t.lit)
+		optional := strings.HasPrefix(s, "${?")
+		p := pathElems(strings.TrimPrefix(s[2:len(s)-1], "?"))
+		if !hasPathPrefix(p, r.from) {
+			continue
+		}
+		s = "${"
+		if optional {
+			s += "?"
+		}
+		toks[i].lit = s + renderPath(append(append([]string{}, r.to...), p[len(r.from):]...)) + "}"
+	}
+
+	type object struct {
+		field
+		close    int // index of the closing brace
+		children int // number of fields directly inside
+		moved    int // number of those moved out
+	}
+	root := &object{field: field{path: []string{}}, close: len(toks)}
+	if i := firstToken(toks); i < len(toks) && toks[i].kind == tokLBrace {
+		root.close = matchingBracket(toks, i)
+	}
+	stack := []*object{root} // objects enclosing the current field
+	drop := make([]bool, len(toks))
+	keys := map[int]string{}      // new keys, by the index of the key
+	keyEnds := map[int]int{}      // index of the end of the replaced keys
+	inserts := map[int][]string{} // moved fields, by the index they go before
+	remove := func(s span) {
+		for i := s.start; i < s.end; i++ {
+			drop[i] = true
+		}
+	}
+	// finish removes the objects that end before token i if all their
+	// fields were moved out.
+	finish := func(i int) {
+		for len(stack) > 1 && stack[len(stack)-1].close < i {
+			o := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if o.children > 0 && o.moved == o.children {
+				remove(fieldSpan(toks, o.key, o.close+1))
+				stack[len(stack)-1].moved++
+			}
+		}
+	}
+	var errs errorList
+	fail := func(f field, format string, args ...interface{}) {
+		msg := fmt.Sprintf("cannot rewrite %s to %s: ", renderPath(r.from), renderPath(r.to))
+		errs.Add(position(src, toks[f.key].off), codeRename, msg+fmt.Sprintf(format, args...))
+	}
+	type placed struct {
+		to  []string
+		key int // index of the key in toks
+		at  int // index of the token the field ends up before
+	}
+	var rewritten []placed
+	// place records that the field f goes to the path to, before token
+	// at, and rejects the rule if that puts it after an earlier field of
+	// the same path.
+	place := func(f field, to []string, at int) {
+		for _, p := range rewritten {
+			if at < p.at && (hasPathPrefix(to, p.to) || hasPathPrefix(p.to, to)) {
+				fail(f, "%s would be placed before the %s from line %d, which it overrides", renderPath(to), renderPath(p.to), position(src, toks[p.key].off).Line)
+				return
+			}
+		}
+		rewritten = append(rewritten, placed{to, f.key, at})
+	}
+	var set []field // fields already at the new path, outermost only
+	setEnd := 0
+	skip := 0 // fields before this token are inside a rewritten object
+	walkFields(toks, func(f field) {
+		if f.key < skip {
+			return
+		}
+		finish(f.key)
+		parent := stack[len(stack)-1]
+		parent.children++
+		end := f.end
+		if f.object {
+			end = matchingBracket(toks, f.value) + 1
+		}
+		if f.key >= setEnd && !hasPathPrefix(f.path, r.from) && (hasPathPrefix(f.path, r.to) || !f.object && hasPathPrefix(r.to, f.path)) {
+			set = append(set, f)
+			setEnd = end
+		}
+		if !hasPathPrefix(f.path, r.from) || len(parent.path) >= len(r.from) {
+			if f.object {
+				stack = append(stack, &object{field: f, close: end - 1})
+			}
+			return
+		}
+
+		to := append(append([]string{}, r.to...), f.path[len(r.from):]...)
+		keyEnd, _ := keyPath(toks, f.key)
+		for keyEnd > f.key && toks[keyEnd-1].kind == tokSpace {
+			keyEnd--
+		}
+		skip = end
+		if len(to) > len(parent.path) && hasPathPrefix(to, parent.path) {
+			keys[f.key] = renderPath(to[len(parent.path):])
+			keyEnds[f.key] = keyEnd
+			place(f, to, f.key)
+			return
+		}
+
+		target := root
+		for _, o := range stack {
+			if len(to) > len(o.path) && hasPathPrefix(to, o.path) {
+				target = o
+			}
+		}
+		// The field goes with its comments and the white space around
+		// it, but not the commas separating it from the others.
+		s := fieldSpan(toks, f.key, end)
+		var text []byte
+		for i := s.start; i < s.end; i++ {
+			switch {
+			case i == f.key:
+				text = append(text, renderPath(to[len(target.path):])...)
+				i = keyEnd - 1
+			case toks[i].kind == tokComma && (i < f.key || i >= end):
+			default:
+				text = append(text, toks[i].lit...)
+			}
+		}
+		if s.start > 0 && toks[s.start-1].kind != tokNewline {
+			// Not whole lines: the field shares a line with others.
+			text = bytes.TrimLeft(text, " \t")
+		}
+		if !bytes.HasSuffix(text, []byte("\n")) {
+			text = append(bytes.TrimRight(text, " \t"), '\n')
+		}
+		remove(s)
+		parent.moved++
+		place(f, to, target.close)
+		inserts[target.close] = append(inserts[target.close], string(text))
+	})
+	finish(len(toks))
+	if len(rewritten) > 0 {
+		for _, f := range set {
+			fail(f, "%s is already set", renderPath(f.path))
+		}
+		errs.Sort()
+	}
+	if len(errs) > 0 {
+		return nil, errs.Err()
+	}
+
+	var res []byte
+	for i := 0; i <= len(toks); i++ {
+		if moved := inserts[i]; len(moved) > 0 {
+			// The moved lines go before the indentation of the brace.
+			trimmed := bytes.TrimRight(res, " \t")
+			indent := string(res[len(trimmed):])
+			res = trimmed
+			if len(res) > 0 && res[len(res)-1] != '\n' {
+				res = append(res, '\n')
+			}
+			res = append(res, strings.Join(moved, "")+indent...)
+		}
+		if i == len(toks) || drop[i] {
+			continue
+		}
+		if key, ok := keys[i]; ok {
+			res = append(res, key...)
+			i = keyEnds[i] - 1
+			continue
+		}
+		res = append(res, toks[i].lit...)
+	}
+	return res, nil
+}
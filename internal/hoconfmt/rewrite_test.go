@@ -0,0 +1,123 @@
+package hoconfmt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRewriteRules(t *testing.T) {
+	rules, err := parseRewriteRules("a.b -> c\n\n# comment\n// comment\n  \"x.y\".z->w  \n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rewriteRule{
+		{[]string{"a", "b"}, []string{"c"}},
+		{[]string{"x.y", "z"}, []string{"w"}},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("got %q, want %q", rules, want)
+	}
+	for _, bad := range []string{"a.b", "a ->", "-> b", "a..b -> c"} {
+		if _, err := parseRewriteRules(bad); err == nil {
+			t.Errorf("parseRewriteRules(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestRewriteKeys(t *testing.T) {
+	for _, test := range []struct {
+		rule, src, want string
+	}{
+		// renamed where they stand
+		{"a.b -> a.c", "a.b = 1\n", "a.c = 1\n"},
+		{"a.b -> a.c", "a {\n  b = 1\n}\n", "a {\n  c = 1\n}\n"},
+		{"a.b -> a.c", "a.b.x = 1\na { b { y = 2 } }\n", "a.c.x = 1\na { c { y = 2 } }\n"},
+		{"a -> b", "a : 1\na += 2\n", "b : 1\nb += 2\n"},
+		{"a.b -> \"c.d\"", "a.b = 1", "\"c.d\" = 1"},
+
+		// moved out of their object, with their comments
+		{
+			"a.b.c -> a.d",
+			"a {\n  b {\n    # c\n    c = 1 // one\n    e = 2\n  }\n}\n",
+			"a {\n  b {\n    e = 2\n  }\n    # c\n    d = 1 // one\n}\n",
+		},
+		{"a.b -> c", "a { b = 1, e = 2 }\n", "a { e = 2 }\nc = 1\n"},
+		{"a.b -> c", "a { e = 2, b = 1 }", "a { e = 2 }\nc = 1\n"},
+		{"a.b -> c", "{\n  a { b = 1 }\n  x = 2\n}\n", "{\n  x = 2\nc = 1\n}\n"},
+
+		// objects left empty are removed
+		{"a.b.c -> d", "a {\n  b {\n    c = 1\n  }\n  x = 2\n}\n", "a {\n  x = 2\n}\n    d = 1\n"},
+		{"a.b -> d", "# a\na {\n  b = 1\n}\nx = 2\n", "x = 2\n  d = 1\n"},
+
+		// substitutions follow
+		{"a.b -> c", "a.b = 1\nx = ${a.b}\ny = ${?a.b.z}\nz = ${a.bc}\n", "c = 1\nx = ${c}\ny = ${?c.z}\nz = ${a.bc}\n"},
+
+		// other keys are left alone
+		{"a.b -> c", "a.bc = 1\nx { a.b = 2 }\nl = [{ a.b = 3 }]\n", "a.bc = 1\nx { a.b = 2 }\nl = [{ a.b = 3 }]\n"},
+		{"a.b -> c", "a = { x = 1 }\n", "a = { x = 1 }\n"},
+
+		// fields setting the same path keep their order
+		{"a.b -> c", "a { b = 1 }\nx = 2\na { b = 3 }\n", "x = 2\nc = 1\nc = 3\n"},
+		{"x.a -> x.b", "x { a = 1 }\nx.a = 5\n", "x { b = 1 }\nx.b = 5\n"},
+	} {
+		rules, err := parseRewriteRules(test.rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := rewriteKeys([]byte(test.src), rules)
+		if err != nil {
+			t.Errorf("%s on %q: %v", test.rule, test.src, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%s on %q:\ngot  %q\nwant %q", test.rule, test.src, got, test.want)
+		}
+	}
+}
+
+func TestRewriteKeysRejected(t *testing.T) {
+	for _, test := range []struct {
+		rule, src, err string
+	}{
+		// moving the first value after the second would make it win
+		{"x.a -> y.a", "x { a = 1 }\nx.a = 5\n", "2:1: cannot rewrite x.a to y.a: y.a would be placed before the y.a from line 1, which it overrides"},
+		{"x -> y", "x { a { b = 1 } }\nx.a.c = 2\nz { x = 3 }\n", ""},
+		{"a.b -> c", "a { b { x = 1 } }\na.b.x = 2\n", "2:1: cannot rewrite a.b to c: c.x would be placed before the c from line 1, which it overrides"},
+
+		// the new key is set already
+		{"a -> c", "a = 1\nc = 2\n", "2:1: cannot rewrite a to c: c is already set"},
+		{"a -> c.d", "a = 1\nc { d { e = 2 } }\n", "2:5: cannot rewrite a to c.d: c.d is already set"},
+		{"a -> c.d", "c = 2\na = 1\n", "1:1: cannot rewrite a to c.d: c is already set"},
+	} {
+		rules, err := parseRewriteRules(test.rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = rewriteKeys([]byte(test.src), rules)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%s on %q: %v", test.rule, test.src, err)
+		case test.err != "" && (err == nil || err.Error() != test.err):
+			t.Errorf("%s on %q: got error %v, want %s", test.rule, test.src, err, test.err)
+		}
+	}
+}
+
+func TestRulesFlag(t *testing.T) {
+	var r rulesValue
+	for _, s := range []string{"a -> b", "c -> d"} {
+		if err := r.Set(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Set("e"); err == nil {
+		t.Error("Set accepted an invalid rule")
+	}
+	if got, want := r.String(), "a -> b\nc -> d"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	r.Set("")
+	if got := r.String(); got != "" {
+		t.Errorf("String() after reset = %q, want \"\"", got)
+	}
+}
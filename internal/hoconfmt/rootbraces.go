@@ -0,0 +1,284 @@
+package hoconfmt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Values of the -root-braces flag.
+const (
+	rootBracesOmit    = "omit"
+	rootBracesRequire = "require"
+)
+
+func checkRootBraces(style string) error {
+	switch style {
+	case "", rootBracesOmit, rootBracesRequire:
+		return nil
+	}
+	return fmt.Errorf("invalid -root-braces %q: must be %s or %s", style, rootBracesOmit, rootBracesRequire)
+}
+
+// rootBraces returns the indices of the braces around the whole
+// document in toks, or -1, -1 if the root object is not braced.
+func rootBraces(toks []tok) (open, close int) {
+	open, close = -1, -1
+	for i, t := range toks {
+		switch t.kind {
+		case tokSpace, tokNewline, tokComment:
+			continue
+		}
+		if open < 0 {
+			if t.kind != tokLBrace {
+				return -1, -1
+			}
+			open = i
+		}
+		close = i
+	}
+	if close <= open || toks[close].kind != tokRBrace {
+		return -1, -1
+	}
+	// The closing brace must match the opening one.
+	depth := 0
+	for i := open; i <= close; i++ {
+		switch toks[i].kind {
+		case tokLBrace, tokLBracket:
+			depth++
+		case tokRBrace, tokRBracket:
+			depth--
+			if depth == 0 && i != close {
+				return -1, -1
+			}
+		}
+	}
+	return open, close
+}
+
+// setRootBraces adds or removes the braces around the whole document.
+// When adding them, the body is indented by indent, and comments at the
+// top and bottom of the document that are set off from the fields by a
+// blank line stay outside the braces; when removing them, the body is
+// unindented by one level.
+func setRootBraces(src []byte, style, indent string) ([]byte, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	open, close := rootBraces(toks)
+	switch {
+	case style == rootBracesOmit && open >= 0:
+		return unbraceRoot(toks, open, close, indent), nil
+	case style == rootBracesRequire && open < 0:
+		if res := braceRoot(toks, indent); res != nil {
+			return res, nil
+		}
+	}
+	return src, nil
+}
+
+// closeRootAfterOff puts back the closing brace that -root-braces=require
+// added to src, giving res, if src ends inside a hoconfmt:off region:
+// restoring the region drops it, so it goes after the region, at the
+// end of res.
+func closeRootAfterOff(src, res []byte) ([]byte, error) {
+	if !endsOff(src) {
+		return res, nil
+	}
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	if open, _ := rootBraces(toks); open >= 0 {
+		return res, nil
+	}
+	if toks, err = tokenize(res); err != nil {
+		return nil, err
+	}
+	// The brace was added if res starts with one that is not closed.
+	depth, first := 0, -1
+	for i, t := range toks {
+		switch t.kind {
+		case tokSpace, tokNewline, tokComment:
+			continue
+		case tokLBrace, tokLBracket:
+			depth++
+		case tokRBrace, tokRBracket:
+			depth--
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+	if first < 0 || toks[first].kind != tokLBrace || depth != 1 {
+		return res, nil
+	}
+	if len(res) > 0 && res[len(res)-1] != '\n' {
+		res = append(res, '\n')
+	}
+	return append(res, "}\n"...), nil
+}
+
+func unbraceRoot(toks []tok, open, close int, indent string) []byte {
+	// Drop the braces with the white space next to them, and their
+	// lines if nothing else is on them.
+	drop := make([]bool, len(toks))
+	for _, b := range []int{open, close} {
+		drop[b] = true
+		i, j := b-1, b+1
+		for ; i >= 0 && (toks[i].kind == tokSpace || drop[i]); i-- {
+			drop[i] = true
+		}
+		for ; j < len(toks) && toks[j].kind == tokSpace; j++ {
+			drop[j] = true
+		}
+		if (i < 0 || toks[i].kind == tokNewline) && j < len(toks) && toks[j].kind == tokNewline {
+			drop[j] = true
+		}
+	}
+
+	var res []byte
+	atLineStart := true
+	for i, t := range toks {
+		if drop[i] {
+			continue
+		}
+		lit := t.lit
+		if atLineStart && t.kind == tokSpace && i > open && i < close {
+			lit = unindent(lit, indent)
+		}
+		atLineStart = t.kind == tokNewline
+		res = append(res, lit...)
+	}
+	return res
+}
+
+// unindent removes one level of indentation from the start of space:
+// indent itself, a tab, or as many spaces as indent is wide.
+func unindent(space, indent string) string {
+	switch {
+	case strings.HasPrefix(space, indent):
+		return space[len(indent):]
+	case strings.HasPrefix(space, "\t"):
+		return space[1:]
+	}
+	n := 0
+	for n < len(space) && n < len(indent) && space[n] == ' ' {
+		n++
+	}
+	return space[n:]
+}
+
+// braceRoot wraps the fields of the document in braces. It returns nil
+// if the document has no fields.
+func braceRoot(toks []tok, indent string) []byte {
+	lines := splitLines(toks)
+
+	// Leading and trailing comment and blank lines stay outside, except
+	// for comments that directly precede the first field or follow the
+	// last one.
+	first, last := 0, len(lines)
+	for first < last && (lines[first].blank() || lines[first].commentOnly()) {
+		first++
+	}
+	for last > first && (lines[last-1].blank() || lines[last-1].commentOnly()) {
+		last--
+	}
+	if first == last {
+		return nil
+	}
+	for first > 0 && lines[first-1].commentOnly() {
+		first--
+	}
+	for last < len(lines) && lines[last].commentOnly() {
+		last++
+	}
+
+	var res []byte
+	for _, l := range lines[:first] {
+		res = l.appendTo(res)
+	}
+	res = append(res, "{\n"...)
+	for _, l := range lines[first:last] {
+		if !l.blank() {
+			res = append(res, indent...)
+		}
+		res = l.appendTo(res)
+	}
+	if len(res) > 0 && res[len(res)-1] != '\n' {
+		res = append(res, '\n')
+	}
+	res = append(res, "}\n"...)
+	for _, l := range lines[last:] {
+		res = l.appendTo(res)
+	}
+	return res
+}
+
+// checkRoot reports the brackets of src that do not match and content
+// that does not belong to the root object: a closing brace or bracket
+// without an opening one or of the wrong kind, a brace or bracket that
+// is never closed, and anything but white space and comments after the
+// brace that closes a braced root object, or the bracket that closes a
+// root array. Such content usually means that the file is truncated or
+// corrupt, and it must not be passed through unnoticed. Every
+// mismatched bracket is reported, so that -e can list them all.
+func checkRoot(lx *lexer, src []byte) error {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return err
+	}
+
+	var errs errorList
+	var open []tok // enclosing { and [
+	root := ""     // "object" or "array" if the document starts with { or [
+	first := true
+	for _, t := range toks {
+		switch t.kind {
+		case tokSpace, tokNewline, tokComment:
+			continue
+		}
+		if !first && root != "" && len(open) == 0 {
+			errs.Add(position(src, t.off), codeSyntax, "unexpected "+t.lit+" after the root "+root)
+			return errs.Err()
+		}
+		switch t.kind {
+		case tokLBrace, tokLBracket:
+			if first {
+				root = "object"
+				if t.kind == tokLBracket {
+					root = "array"
+				}
+			}
+			open = append(open, t)
+		case tokRBrace, tokRBracket:
+			want := tokLBrace
+			if t.kind == tokRBracket {
+				want = tokLBracket
+			}
+			if len(open) == 0 {
+				errs.Add(position(src, t.off), codeSyntax, "unexpected "+t.lit)
+				break
+			}
+			// A bracket of the wrong kind still closes the innermost
+			// one, so that one mistake is reported once.
+			if o := open[len(open)-1]; o.kind != want {
+				errs.Add(position(src, t.off), codeSyntax, fmt.Sprintf("unexpected %s, expected %s for the %s on line %d", t.lit, closer(o.kind), o.lit, position(src, o.off).Line))
+			}
+			open = open[:len(open)-1]
+		}
+		first = false
+	}
+	for _, o := range open {
+		errs.Add(position(src, o.off), codeSyntax, o.lit+" is not closed")
+	}
+	return errs.Err()
+}
+
+// closer returns the bracket that closes an opening bracket of kind.
+func closer(kind tokenKind) string {
+	if kind == tokLBracket {
+		return "]"
+	}
+	return "}"
+}
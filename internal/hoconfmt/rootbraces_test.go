@@ -0,0 +1,163 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSetRootBraces(t *testing.T) {
+	const bare = `# top comment
+
+a = 1
+b {
+    c = """multi
+line"""
+}
+
+// bottom comment
+`
+	const braced = `# top comment
+
+{
+    a = 1
+    b {
+        c = """multi
+line"""
+    }
+}
+
+// bottom comment
+`
+	for _, test := range []struct {
+		style, src, want string
+	}{
+		{rootBracesRequire, bare, braced},
+		{rootBracesRequire, braced, braced},
+		{rootBracesOmit, braced, bare},
+		{rootBracesOmit, bare, bare},
+		{rootBracesRequire, "# a\na = 1\n# b\n", "{\n    # a\n    a = 1\n    # b\n}\n"},
+		{rootBracesRequire, "# only a comment\n", "# only a comment\n"},
+		{rootBracesOmit, "{ a = 1 }\n", "a = 1\n"},
+		{rootBracesOmit, "{}\n", ""},
+		// Not a single pair around the whole document.
+		{rootBracesOmit, "{ a = 1 } { b = 2 }\n", "{ a = 1 } { b = 2 }\n"},
+		{rootBracesOmit, "{ a = 1 }\nb = 2\n", "{ a = 1 }\nb = 2\n"},
+	} {
+		got, err := setRootBraces([]byte(test.src), test.style, "    ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("-root-braces %s on\n%s\ngot\n%s\nwant\n%s", test.style, test.src, got, test.want)
+		}
+	}
+}
+
+func TestRootBracesOffAtEnd(t *testing.T) {
+	const src = "a = 1\n# hoconfmt:off\nb   =   2\n"
+	opts := DefaultOptions()
+	opts.RootBraces = rootBracesRequire
+	res, err := formatFile("a.conf", []byte(src), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n    a = 1\n# hoconfmt:off\nb   =   2\n}\n"; string(res) != want {
+		t.Errorf("got %q, want %q", res, want)
+	}
+	again, err := formatFile("a.conf", res, opts)
+	if err != nil || !bytes.Equal(again, res) {
+		t.Errorf("formatting again = %q, %v, want it unchanged", again, err)
+	}
+}
+
+func TestCheckRootBraces(t *testing.T) {
+	for _, style := range []string{"", rootBracesOmit, rootBracesRequire} {
+		if err := checkRootBraces(style); err != nil {
+			t.Errorf("checkRootBraces(%q) = %v", style, err)
+		}
+	}
+	if err := checkRootBraces("keep"); err == nil {
+		t.Error("checkRootBraces(\"keep\") succeeded")
+	}
+}
+
+func TestCheckRoot(t *testing.T) {
+	for _, src := range []string{
+		"a = 1\n",
+		"# top\n{\n    a = 1\n}\n# bottom\n",
+		"a { b = [1, {}] }\n",
+		"[1, 2] # root array\n",
+		"",
+	} {
+		if err := checkRoot(nil, []byte(src)); err != nil {
+			t.Errorf("%q: %v", src, err)
+		}
+	}
+
+	for _, test := range []struct {
+		src, err string
+	}{
+		{"a = 1\n}\n", "2:1: unexpected }"},
+		{"a = [1]]\n", "1:8: unexpected ]"},
+		{"{\n    a = 1\n}\nb = 2\n", "4:1: unexpected b after the root object"},
+		{"{ a = 1 } }\n", "1:11: unexpected } after the root object"},
+		{"{ a = 1 } { b = 2 }\n", "1:11: unexpected { after the root object"},
+		{"[1] 2\n", "1:5: unexpected 2 after the root array"},
+		{"[1]\n[2]\n", "2:1: unexpected [ after the root array"},
+		{"[\n0{}] 0", "2:6: unexpected 0 after the root array"},
+		{"a = {\n    b = 1\n", "1:5: { is not closed"},
+		{"a {\n    b = [1}\n}\n", "2:11: unexpected }, expected ] for the [ on line 2"},
+		{"a { b = [1 }\n", "1:12: unexpected }, expected ] for the [ on line 1 (and 1 more errors)"},
+	} {
+		err := checkRoot(nil, []byte(test.src))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: got error %v, want %s", test.src, err, test.err)
+		}
+	}
+}
+
+func TestCheckRootAllErrors(t *testing.T) {
+	src := "a = 1\n" + strings.Repeat("}\n", 12) + "b = [\n"
+	err := checkRoot(nil, []byte(src))
+	list, ok := err.(errorList)
+	if !ok || len(list) != 13 {
+		t.Fatalf("got %v, want 13 errors", err)
+	}
+	if got := list[12].Error(); got != "14:5: [ is not closed" {
+		t.Errorf("last error is %q", got)
+	}
+
+	defer func(max int, all bool) { *maxErrors, *allErrors = max, all }(*maxErrors, *allErrors)
+	*maxErrors, *allErrors = 10, false
+	var out bytes.Buffer
+	printErrors(&out, err)
+	if !strings.HasSuffix(out.String(), "11:1: unexpected }\n... and 3 more errors\n") {
+		t.Errorf("without -e:\n%s", out.String())
+	}
+	*allErrors = true
+	out.Reset()
+	printErrors(&out, err)
+	if n := strings.Count(out.String(), "\n"); n != 13 {
+		t.Errorf("-e reports %d errors, want 13:\n%s", n, out.String())
+	}
+}
+
+func TestTrailingGarbage(t *testing.T) {
+	defer func() { exitCode = 0 }()
+	var out bytes.Buffer
+	err := processFile("bad.conf", strings.NewReader("a {\n    b = 1\n}\n}\n"), &out)
+	if err == nil {
+		t.Fatal("no error for stray closing brace")
+	}
+	if want := "bad.conf:4:1: unexpected }"; err.Error() != want {
+		t.Errorf("got error %q, want %q", err, want)
+	}
+	if out.Len() > 0 {
+		t.Errorf("file with trailing garbage was passed through:\n%s", out.Bytes())
+	}
+	report(err)
+	if exitCode != 2 {
+		t.Errorf("exit code %d, want 2", exitCode)
+	}
+}
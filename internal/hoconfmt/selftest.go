@@ -0,0 +1,143 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// A selfTestCase is one snippet of the corpus -selftest formats.
+type selfTestCase struct {
+	name string
+	opts func(*Options) // changes to DefaultOptions, or nil
+	src  string
+	want string
+}
+
+// selfTestCorpus holds representative snippets for the main passes, so
+// that a binary can be checked where the test suite is not available.
+var selfTestCorpus = []selfTestCase{
+	{
+		name: "formatted input",
+		src:  "# Service settings.\nname = \"demo\"\nhttp {\n    port = 8080\n}\n",
+		want: "# Service settings.\nname = \"demo\"\nhttp {\n    port = 8080\n}\n",
+	},
+	{
+		name: "final newline",
+		src:  "a = 1",
+		want: "a = 1\n",
+	},
+	{
+		name: "line endings",
+		src:  "a = 1\r\nb = 2\r\n",
+		want: "a = 1\nb = 2\n",
+	},
+	{
+		name: "json values",
+		src:  "config = {\"a\":1,\"b\":[2,3]}\n",
+		want: "config { a = 1, b = [2, 3] }\n",
+	},
+	{
+		name: "object separators",
+		opts: func(o *Options) { o.ObjectEq = objectEqRequire },
+		src:  "a {\n    b = 1\n}\n",
+		want: "a = {\n    b = 1\n}\n",
+	},
+	{
+		name: "empty values",
+		src:  "a = [ ]\nb {\n}\n",
+		want: "a = []\nb {}\n",
+	},
+	{
+		name: "value comments",
+		src:  "a = # note\n    1\n",
+		want: "# note\na = 1\n",
+	},
+	{
+		name: "fields on one line",
+		src:  "a { x = 1 } b = 2\n",
+		want: "a { x = 1 }\nb = 2\n",
+	},
+	{
+		name: "split separators",
+		src:  "a\n= 1\nb =\n2\n",
+		want: "a = 1\nb = 2\n",
+	},
+	{
+		name: "substitutions",
+		opts: func(o *Options) { o.TrimSubsts = true },
+		src:  "p = ${ a.b }\n",
+		want: "p = ${a.b}\n",
+	},
+	{
+		name: "blank lines",
+		src:  "a = 1\n\n\n\nb = 2\n",
+		want: "a = 1\n\nb = 2\n",
+	},
+	{
+		name: "root braces",
+		opts: func(o *Options) { o.RootBraces = rootBracesRequire },
+		src:  "a = 1\n",
+		want: "{\n    a = 1\n}\n",
+	},
+	{
+		name: "properties",
+		opts: func(o *Options) { o.FromProperties = true },
+		src:  "a.b=1\na.c=x\n",
+		want: "a {\n    b = 1\n    c = \"x\"\n}\n",
+	},
+	{
+		name: "protected region",
+		src:  "# hoconfmt:off\na  =  [ ]\n# hoconfmt:on\nb = [ ]\n",
+		want: "# hoconfmt:off\na  =  [ ]\n# hoconfmt:on\nb = []\n",
+	},
+}
+
+// runSelfTest formats every snippet of the corpus and writes a line for
+// each to out. A snippet passes if it formats to the expected text, the
+// result parses, and formatting the result again changes nothing. It
+// reports whether all snippets passed.
+func runSelfTest(out io.Writer) bool {
+	failed := 0
+	for _, c := range selfTestCorpus {
+		if err := c.run(); err != nil {
+			failed++
+			fmt.Fprintf(out, "FAIL %s: %v\n", c.name, err)
+			continue
+		}
+		fmt.Fprintf(out, "ok   %s\n", c.name)
+	}
+	if failed > 0 {
+		fmt.Fprintf(out, "%d of %d snippets failed\n", failed, len(selfTestCorpus))
+		return false
+	}
+	fmt.Fprintf(out, "all %d snippets passed\n", len(selfTestCorpus))
+	return true
+}
+
+func (c selfTestCase) run() error {
+	opts := DefaultOptions()
+	if c.opts != nil {
+		c.opts(&opts)
+	}
+	res, err := formatFile(c.name, []byte(c.src), opts)
+	if err != nil {
+		return err
+	}
+	if string(res) != c.want {
+		return fmt.Errorf("got %q, want %q", res, c.want)
+	}
+	if err := checkRoot(nil, res); err != nil {
+		return fmt.Errorf("result does not parse: %v", err)
+	}
+	// A converted file is HOCON now, and is formatted as such.
+	opts.FromProperties = false
+	again, err := formatFile(c.name, res, opts)
+	if err != nil {
+		return fmt.Errorf("formatting the result again: %v", err)
+	}
+	if !bytes.Equal(again, res) {
+		return fmt.Errorf("not idempotent: formatting %q again gives %q", res, again)
+	}
+	return nil
+}
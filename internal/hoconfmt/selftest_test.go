@@ -0,0 +1,29 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	var out bytes.Buffer
+	if !runSelfTest(&out) {
+		t.Errorf("self-test failed:\n%s", out.Bytes())
+	}
+}
+
+func TestSelfTestFailure(t *testing.T) {
+	defer func(corpus []selfTestCase) { selfTestCorpus = corpus }(selfTestCorpus)
+	selfTestCorpus = []selfTestCase{
+		{name: "good", src: "a = 1\n", want: "a = 1\n"},
+		{name: "bad", src: "a = [ ]\n", want: "a = [ ]\n"},
+	}
+	var out bytes.Buffer
+	if runSelfTest(&out) {
+		t.Error("self-test passed with a wrong expectation")
+	}
+	if got := out.String(); !strings.Contains(got, "ok   good\n") || !strings.Contains(got, "FAIL bad: ") || !strings.HasSuffix(got, "1 of 2 snippets failed\n") {
+		t.Errorf("unexpected report:\n%s", got)
+	}
+}
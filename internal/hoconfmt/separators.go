@@ -0,0 +1,195 @@
+package hoconfmt
+
+import "fmt"
+
+// joinSeparators puts a separator that some generators write on a line
+// of its own back on the line of its key and value, so that
+//
+//	a
+//	= 1
+//	b =
+//	2
+//
+// becomes
+//
+//	a = 1
+//	b = 2
+//
+// A key followed by '=', ':' or "+=" on a later line is read the way
+// one followed by it on the same line is, as is a value on a line after
+// its separator; the newlines can only be white space there, since a
+// field cannot start with a separator or end with one. Only white space
+// may come between them. A comment after the separator is left to
+// hoistValueComments; one between a key and its separator keeps them
+// apart. Keys of objects inside arrays are joined the same way, and so
+// are keys holding substitutions, which quoteSubstKeys quotes later.
+func joinSeparators(lx *lexer, src []byte) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, 0, len(src))
+	var open []tokenKind // enclosing { and [
+	atField := true      // at the start of a field in an object
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		inObject := len(open) == 0 || open[len(open)-1] == tokLBrace
+		if atField && inObject && (t.kind == tokUnquoted || t.kind == tokString || t.kind == tokSubst) && t.lit != "include" {
+			atField = false
+			j := i
+			for j < len(toks) && (toks[j].kind == tokUnquoted || toks[j].kind == tokString || toks[j].kind == tokSubst || toks[j].kind == tokSpace) {
+				j++
+			}
+			if k := skipNewlines(toks, j); k > j && k < len(toks) && toks[k].kind == tokSep {
+				// The key ends before the white space.
+				for j > i && toks[j-1].kind == tokSpace {
+					j--
+				}
+				for _, t := range toks[i:j] {
+					res = append(res, t.lit...)
+				}
+				res = append(res, ' ')
+				j = k
+			} else {
+				for _, t := range toks[i:j] {
+					res = append(res, t.lit...)
+				}
+			}
+			if j < len(toks) && toks[j].kind == tokSep {
+				res = append(res, toks[j].lit...)
+				j++
+				if k := skipNewlines(toks, j); k > j && k < len(toks) && startsValue(toks[k]) {
+					res = append(res, ' ')
+					j = k
+				}
+			}
+			i = j - 1
+			continue
+		}
+
+		switch t.kind {
+		case tokLBrace, tokLBracket:
+			open = append(open, t.kind)
+			atField = t.kind == tokLBrace
+		case tokRBrace, tokRBracket:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			atField = true
+		case tokNewline, tokComma:
+			atField = true
+		case tokSpace, tokComment:
+		default:
+			atField = false
+		}
+		res = append(res, t.lit...)
+	}
+	return res, nil
+}
+
+// skipNewlines returns the index of the first token from toks[i] on
+// that is not white space, if the white space holds a newline, or i if
+// it does not.
+func skipNewlines(toks []tok, i int) int {
+	j, newline := i, false
+	for j < len(toks) && (toks[j].kind == tokSpace || toks[j].kind == tokNewline) {
+		newline = newline || toks[j].kind == tokNewline
+		j++
+	}
+	if !newline {
+		return i
+	}
+	return j
+}
+
+// startsValue reports whether t can be the first token of a value.
+func startsValue(t tok) bool {
+	switch t.kind {
+	case tokUnquoted, tokString, tokMultiline, tokSubst, tokLBrace, tokLBracket:
+		return true
+	}
+	return false
+}
+
+// Values of the -separators flag.
+const (
+	separatorsKeep   = "keep"   // as written
+	separatorsEquals = "equals" // =
+	separatorsColon  = "colon"  // :
+)
+
+func checkSeparators(style string) error {
+	switch style {
+	case "", separatorsKeep, separatorsEquals, separatorsColon:
+		return nil
+	}
+	return fmt.Errorf("invalid -separators %q: must be %s, %s or %s", style, separatorsKeep, separatorsEquals, separatorsColon)
+}
+
+// setSeparators gives every field of src whose value is not an object
+// the separator of style, separatorsEquals or separatorsColon, so that
+// a: 1 and b = 2 both use one of them. A changed separator is written
+// the way it usually is, as in a = 1 and a: 1; the others keep their
+// spacing. The separator of a field whose value is an object is left to
+// normalizeObjectEq, and += is kept, as it appends rather than sets.
+func setSeparators(src []byte, style string) ([]byte, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	sep := "="
+	if style == separatorsColon {
+		sep = ":"
+	}
+	var res []byte
+	var open []tokenKind // enclosing { and [
+	atField := true      // at the start of a field in an object
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		inObject := len(open) == 0 || open[len(open)-1] == tokLBrace
+		if atField && inObject && (t.kind == tokUnquoted || t.kind == tokString) {
+			if j := fieldSep(toks, i); j > 0 && toks[j].lit != "+=" && toks[j].lit != sep {
+				v := skipJSONSpace(toks, j+1)
+				if v == len(toks) || toks[v].kind != tokLBrace || valueEnd(toks, v) != matchingBracket(toks, v)+1 {
+					atField = false
+					k := j
+					for k > i && toks[k-1].kind == tokSpace {
+						k--
+					}
+					for _, t := range toks[i:k] {
+						res = append(res, t.lit...)
+					}
+					if sep == "=" {
+						res = append(res, ' ')
+					}
+					res = append(res, sep...)
+					if j+1 < len(toks) && toks[j+1].kind != tokSpace && toks[j+1].kind != tokNewline {
+						res = append(res, ' ')
+					}
+					i = j
+					continue
+				}
+			}
+		}
+
+		switch t.kind {
+		case tokLBrace, tokLBracket:
+			open = append(open, t.kind)
+			atField = t.kind == tokLBrace
+		case tokRBrace, tokRBracket:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			atField = true
+		case tokNewline, tokComma:
+			atField = true
+		case tokSpace, tokComment:
+		default:
+			atField = false
+		}
+		res = append(res, t.lit...)
+	}
+	return res, nil
+}
@@ -0,0 +1,85 @@
+package hoconfmt
+
+import "testing"
+
+func TestJoinSeparators(t *testing.T) {
+	for _, test := range []struct {
+		src, want string
+	}{
+		{"a\n= 1\n", "a = 1\n"},
+		{"a =\n1\n", "a = 1\n"},
+		{"a\n:\n\n  1\n", "a : 1\n"},
+		{"a.b  \n+= x\n", "a.b += x\n"},
+		{"a {\n  b\n  = ${c}\n}\n", "a {\n  b = ${c}\n}\n"},
+		{"a = [{\n  b =\n  2\n}]\n", "a = [{\n  b = 2\n}]\n"},
+		{"\"k\"\n= v\n", "\"k\" = v\n"},
+		// Array elements and comments are left alone.
+		{"a = [\n  x\n]\n", "a = [\n  x\n]\n"},
+		{"a = # note\n  1\n", "a = # note\n  1\n"},
+		{"a\n# note\n= 1\n", "a\n# note\n= 1\n"},
+		// Fields on lines of their own stay apart.
+		{"a = 1\nb = 2\n", "a = 1\nb = 2\n"},
+		{"include \"x.conf\"\n", "include \"x.conf\"\n"},
+	} {
+		got, err := joinSeparators(nil, []byte(test.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("joinSeparators(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+// TestJoinSeparatorsValues checks that every value stays with its key.
+func TestJoinSeparatorsValues(t *testing.T) {
+	src := "a\n= 1\nb =\n2\nc\n:\n{\n  d\n  = 3\n}\ne = 4\n"
+	res, err := formatFile("<test>", []byte(src), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := flattenConfig(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := flatConfig{
+		"a":   {"number", "1"},
+		"b":   {"number", "2"},
+		"c":   {kind: "object"},
+		"c.d": {"number", "3"},
+		"e":   {"number", "4"},
+	}
+	if len(cfg) != len(want) {
+		t.Errorf("formatted %q to %q, with values %v, want %v", src, res, cfg, want)
+	}
+	for k, v := range want {
+		if cfg[k] != v {
+			t.Errorf("formatted %q to %q: %s = %v, want %v", src, res, k, cfg[k], v)
+		}
+	}
+}
+
+func TestSetSeparators(t *testing.T) {
+	for _, test := range []struct {
+		style, src, want string
+	}{
+		{separatorsEquals, "a: 1\nb = 2\nc : \"x\"\nd:3\n", "a = 1\nb = 2\nc = \"x\"\nd = 3\n"},
+		{separatorsColon, "a = 1\nb: [1, 2]\nc=3\nd =\n", "a: 1\nb: [1, 2]\nc: 3\nd:\n"},
+		{separatorsEquals, "a: { b: 1 }\n", "a: { b = 1 }\n"},
+		{separatorsColon, "a += 1\nb = { c = 1 } { d = 2 }\n", "a += 1\nb: { c: 1 } { d: 2 }\n"},
+		{separatorsEquals, "a = [{ b: 1 }]\nurl: \"http://x:80\"\n", "a = [{ b = 1 }]\nurl = \"http://x:80\"\n"},
+		{separatorsColon, "# a = 1\ns = \"a = 1\"\n", "# a = 1\ns: \"a = 1\"\n"},
+	} {
+		got, err := setSeparators([]byte(test.src), test.style)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("setSeparators(%q, %s) = %q, want %q", test.src, test.style, got, test.want)
+		}
+	}
+
+	if err := checkSeparators("arrow"); err == nil {
+		t.Error("checkSeparators accepted arrow")
+	}
+}
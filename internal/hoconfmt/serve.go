@@ -0,0 +1,110 @@
+package hoconfmt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The -serve protocol lets an editor keep a single hoconfmt process
+// running and format many buffers without paying process startup for
+// each one.
+//
+// On startup the server writes a greeting line naming the protocol
+// version:
+//
+//	hoconfmt-serve 1
+//
+// Each request is a header line followed by exactly <length> bytes of
+// source:
+//
+//	<length> <filename>\n
+//	<source bytes>
+//
+// The filename is informational and may contain spaces. Each response
+// is a header line followed by exactly <length> bytes of payload, which
+// is the formatted source for "ok" and an error message for "error":
+//
+//	ok <length>\n
+//	<formatted bytes>
+//
+//	error <length>\n
+//	<message bytes>
+//
+// The server exits cleanly when its input is closed between requests.
+// A malformed request header ends the session with an error, since the
+// stream can no longer be kept in sync.
+const serveVersion = 1
+
+func serve(in io.Reader, out io.Writer) error {
+	r := bufio.NewReader(in)
+	w := bufio.NewWriter(out)
+
+	fmt.Fprintf(w, "hoconfmt-serve %d\n", serveVersion)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for {
+		header, err := r.ReadString('\n')
+		if err == io.EOF && header == "" {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading request header: %s", err)
+		}
+
+		n, filename, err := parseServeHeader(header)
+		if err != nil {
+			return err
+		}
+
+		// The length is the client's word: read the source as it comes
+		// rather than allocating it up front.
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return fmt.Errorf("reading %s: %s", filename, err)
+		}
+		src := buf.Bytes()
+
+		opts, err := fileOptions(filename)
+		var res []byte
+		if err == nil {
+			res, err = formatFile(filename, src, opts)
+		}
+		if err != nil {
+			writeServeResponse(w, "error", []byte(err.Error()))
+		} else {
+			writeServeResponse(w, "ok", res)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// parseServeHeader splits a request header of the form
+// "<length> <filename>\n".
+func parseServeHeader(header string) (n int, filename string, err error) {
+	header = strings.TrimSuffix(header, "\n")
+	fields := strings.SplitN(header, " ", 2)
+	if len(fields) != 2 || fields[1] == "" {
+		return 0, "", fmt.Errorf("malformed request header %q", header)
+	}
+	n, err = strconv.Atoi(fields[0])
+	if err != nil || n < 0 {
+		return 0, "", fmt.Errorf("malformed request length %q", fields[0])
+	}
+	return n, fields[1], nil
+}
+
+func writeServeResponse(w io.Writer, status string, payload []byte) {
+	fmt.Fprintf(w, "%s %d\n", status, len(payload))
+	w.Write(payload)
+}
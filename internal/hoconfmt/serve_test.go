@@ -0,0 +1,44 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"fmt"
+	"go/printer"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	src1 := "a = 1\n"
+	src2 := "\n  b {\n    c = 2\n}\n"
+	in := strings.NewReader(
+		"6 one.conf\n" + src1 +
+			"19 dir/two words.conf\n" + src2)
+
+	var out bytes.Buffer
+	if err := serve(in, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := printer.Config{Mode: printerMode, Tabwidth: tabWidth}
+	res1, _ := format(nil, []byte(src1), cfg)
+	res2, _ := format(nil, []byte(src2), cfg)
+	want := fmt.Sprintf("hoconfmt-serve 1\nok %d\n%sok %d\n%s", len(res1), res1, len(res2), res2)
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestServeMalformedHeader(t *testing.T) {
+	for _, header := range []string{
+		"one.conf\n",
+		"-1 one.conf\n",
+		"6\n",
+		"9223372036854775807 huge.conf\n",
+	} {
+		var out bytes.Buffer
+		if err := serve(strings.NewReader(header+"a = 1\n"), &out); err == nil {
+			t.Errorf("serve(%q): expected error", header)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// splitAssignment splits the argument of -set, such as db."a=b"=1, at
+// its first = outside quotes.
+func splitAssignment(s string) (path, value string, err error) {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == '=' && !quoted:
+			if path = strings.TrimSpace(s[:i]); path != "" {
+				return path, s[i+1:], nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("invalid -set %q: must be path=value", s)
+}
+
+// valueText returns the argument value of -set as HOCON text. Numbers,
+// booleans and null are written as they are, and so are objects,
+// arrays, substitutions and quoted strings, which must be valid HOCON.
+// Anything else is a string, quoted unless it reads back the same
+// without quotes.
+func valueText(value string) (string, error) {
+	s := strings.TrimSpace(value)
+	if s != "" && (strings.ContainsAny(s[:1], `{["`) || strings.HasPrefix(s, "${")) {
+		doc, err := parseDocument([]byte("x = " + s))
+		if err != nil {
+			return "", fmt.Errorf("invalid value %s: %v", s, err)
+		}
+		if items := doc.root.(*objectNode).items; len(items) != 1 || items[0].comment != nil {
+			return "", fmt.Errorf("invalid value %s: not a single value", s)
+		}
+		return s, nil
+	}
+	if _, ok := scalarValue(tok{kind: tokUnquoted, lit: s}).(string); !ok {
+		return s, nil
+	}
+	if unquotedValue(value) {
+		return value, nil
+	}
+	return quoteString(value), nil
+}
+
+// setValue sets the value at path in the document src to value, for
+// -set, changing as little of src as it can. The last field setting
+// path gets the new value; if there is none, a field is added at the
+// end of the deepest object of the path that src writes with braces,
+// or of the document. Its key is written with the part of the path
+// below that object, dotted as in a.b = 1 or nested as in a { b = 1 },
+// whichever style most fields of src use, and with the separator most
+// of them use. indent is one level of indentation where src shows none.
+//
+// The result is merged again to make sure the value at path is the new
+// one: if a later field would override it, the field is added at the
+// end of the document instead, and if that does not work either, as
+// when earlier objects would merge into a new object, it is an error.
+func setValue(src []byte, path, value, indent string) ([]byte, error) {
+	text, err := valueText(value)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := mergeDocument([]byte("x = " + text))
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %s: %v", text, err)
+	}
+	doc, err := parseDocument(src)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := doc.root.(*objectNode)
+	if !ok {
+		return nil, fmt.Errorf("cannot set %s in an array", path)
+	}
+	s := &setter{src: src, path: pathElems(path), text: text, want: parsed.(Object)[0].Value, indent: indent}
+	s.style(root)
+
+	var tries [][]byte
+	if f, v := s.lastValue(root, nil); v != nil && (f == nil || f.sep != "+=") {
+		tries = append(tries, s.replace(f, v))
+	}
+	// A field below an array would replace it with an object.
+	cur, _ := (&merger{src: src, includes: true}).document()
+	o, prefix := s.deepestObject(root, nil)
+	if i := s.arrayBelow(cur, len(prefix)); i < 0 {
+		tries = append(tries, s.insert(o, prefix))
+	}
+	i := s.arrayBelow(cur, 0)
+	if o != root && i < 0 {
+		tries = append(tries, s.insert(root, nil))
+	}
+	if len(tries) == 0 {
+		if _, ok := lookupElems(cur, s.path[:i+1]); ok {
+			return nil, fmt.Errorf("cannot set %s: the array %s is joined from several arrays", path, renderPath(s.path[:i]))
+		}
+		return nil, fmt.Errorf("cannot set %s: the array %s has no element %s", path, renderPath(s.path[:i]), s.path[i])
+	}
+	for _, res := range tries {
+		if s.takesEffect(res) {
+			return res, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot set %s to %s: other fields of the file would change its value", path, text)
+}
+
+// A setter edits a document for setValue.
+type setter struct {
+	src    []byte
+	path   []string
+	text   string // the new value, as HOCON
+	want   Value  // the new value, merged
+	indent string
+	dotted bool   // write new fields with dotted keys
+	sep    string // the separator of new fields
+}
+
+// style chooses the key style, separator and indentation of new fields
+// from the fields of o and those inside it.
+func (s *setter) style(o *objectNode) {
+	var dotted, nested, colons, equals int
+	unit := ""
+	var object func(o *objectNode)
+	object = func(o *objectNode) {
+		if len(o.items) > 0 && o.braces && unit == "" {
+			lead, off := lineIndent(s.src, o.off), o.items[0].node.offset()
+			if ind := lineIndent(s.src, off); bytes.IndexByte(s.src[o.off:off], '\n') >= 0 && len(ind) > len(lead) && strings.HasPrefix(ind, lead) {
+				unit = ind[len(lead):]
+			}
+		}
+		for _, it := range o.items {
+			f, ok := it.node.(*fieldNode)
+			if !ok {
+				continue
+			}
+			switch {
+			case len(f.path) > 1:
+				dotted++
+			case f.value.object() != nil:
+				nested++
+			}
+			switch f.sep {
+			case ":":
+				colons++
+			case "=":
+				equals++
+			}
+			for _, part := range f.value.parts {
+				if o, ok := part.node.(*objectNode); ok {
+					object(o)
+				}
+			}
+		}
+	}
+	object(o)
+	if unit != "" {
+		s.indent = unit
+	}
+	s.dotted = dotted > nested
+	s.sep = " = "
+	if colons > equals {
+		s.sep = ": "
+	}
+}
+
+// lastValue returns the last value below o, whose path is prefix, at
+// s.path, and its field, which is nil for an array element.
+func (s *setter) lastValue(o *objectNode, prefix []string) (*fieldNode, *valueNode) {
+	var field *fieldNode
+	var last *valueNode
+	for _, it := range o.items {
+		f, ok := it.node.(*fieldNode)
+		if !ok {
+			continue
+		}
+		p := append(prefix[:len(prefix):len(prefix)], f.path...)
+		switch {
+		case pathKey(p) == pathKey(s.path):
+			field, last = f, f.value
+		case s.below(p):
+			if g, v := s.within(f.value, p); v != nil {
+				field, last = g, v
+			}
+		}
+	}
+	return field, last
+}
+
+// within returns the last value at s.path inside v, whose path is p,
+// and its field.
+func (s *setter) within(v *valueNode, p []string) (*fieldNode, *valueNode) {
+	var field *fieldNode
+	var last *valueNode
+	for _, part := range v.parts {
+		switch n := part.node.(type) {
+		case *objectNode:
+			if f, v := s.lastValue(n, p); v != nil {
+				field, last = f, v
+			}
+		case *arrayNode:
+			if len(v.parts) > 1 {
+				continue // the elements of a concatenation are numbered apart
+			}
+			i := 0
+			for _, it := range n.items {
+				e, ok := it.node.(*valueNode)
+				if !ok {
+					continue
+				}
+				if fmt.Sprint(i) == s.path[len(p)] {
+					q := append(p[:len(p):len(p)], s.path[len(p)])
+					if len(q) == len(s.path) {
+						return nil, e
+					}
+					return s.within(e, q)
+				}
+				i++
+			}
+		}
+	}
+	return field, last
+}
+
+// below reports whether p is a part of s.path above it.
+func (s *setter) below(p []string) bool {
+	return len(p) < len(s.path) && pathKey(p) == pathKey(s.path[:len(p)])
+}
+
+// deepestObject returns the deepest object below o, o itself if there
+// is none, that is the value at a part of s.path, the last of them if
+// there are several, and its path.
+func (s *setter) deepestObject(o *objectNode, prefix []string) (*objectNode, []string) {
+	best, bestPrefix := o, prefix
+	for _, it := range o.items {
+		f, ok := it.node.(*fieldNode)
+		if !ok || f.sep == "+=" {
+			continue
+		}
+		p := append(prefix[:len(prefix):len(prefix)], f.path...)
+		if s.below(p) {
+			if inner, q := s.deepestIn(f.value, p); inner != nil && len(q) >= len(bestPrefix) {
+				best, bestPrefix = inner, q
+			}
+		}
+	}
+	return best, bestPrefix
+}
+
+// deepestIn returns the deepest object at a part of s.path in v, whose
+// path is p, and its path, or nil.
+func (s *setter) deepestIn(v *valueNode, p []string) (*objectNode, []string) {
+	if len(v.parts) != 1 {
+		return nil, nil
+	}
+	switch n := v.parts[0].node.(type) {
+	case *objectNode:
+		return s.deepestObject(n, p)
+	case *arrayNode:
+		i := 0
+		for _, it := range n.items {
+			if e, ok := it.node.(*valueNode); ok {
+				if fmt.Sprint(i) == s.path[len(p)] {
+					if q := append(p[:len(p):len(p)], s.path[len(p)]); s.below(q) {
+						return s.deepestIn(e, q)
+					}
+					return nil, nil
+				}
+				i++
+			}
+		}
+	}
+	return nil, nil
+}
+
+// arrayBelow returns the length of the first part of s.path longer than
+// from, and shorter than s.path, that is an array in the merged
+// document v, or -1 if there is none.
+func (s *setter) arrayBelow(v Value, from int) int {
+	for i := from + 1; i < len(s.path); i++ {
+		if a, ok := lookupElems(v, s.path[:i]); ok && isArray(a) {
+			return i
+		}
+	}
+	return -1
+}
+
+// replace returns src with the value v, of the field f, replaced by
+// the new one.
+func (s *setter) replace(f *fieldNode, v *valueNode) []byte {
+	start := v.offset()
+	text := s.text
+	if f != nil && f.sep == "" && !strings.HasPrefix(text, "{") {
+		// key { ... } becomes key = value.
+		last := f.key[len(f.key)-1]
+		start = last.off + len(last.lit)
+		text = s.sep + text
+	}
+	return splice(s.src, start, v.end(), text)
+}
+
+// insert returns src with a field setting the rest of s.path added at
+// the end of the object o, whose path is prefix.
+func (s *setter) insert(o *objectNode, prefix []string) []byte {
+	rest := s.path[len(prefix):]
+	if len(o.items) == 0 {
+		if !o.braces {
+			res := append([]byte(nil), s.src...)
+			if len(res) > 0 && res[len(res)-1] != '\n' {
+				res = append(res, '\n')
+			}
+			return append(res, s.field(rest, "", s.dotted)+"\n"...)
+		}
+		lead := lineIndent(s.src, o.off)
+		return splice(s.src, o.off, o.end, "{\n"+lead+s.indent+s.field(rest, lead+s.indent, s.dotted)+"\n"+lead+"}")
+	}
+
+	last := o.items[len(o.items)-1]
+	end := itemEnd(last)
+	if o.braces && bytes.IndexByte(s.src[o.off:o.end], '\n') < 0 {
+		// A block on one line gets the field on the same line, dotted.
+		return splice(s.src, end, end, ", "+s.field(rest, "", true))
+	}
+	ind := lineIndent(s.src, last.node.offset())
+	at := lineEnd(s.src, end)
+	text := "\n" + ind + s.field(rest, ind, s.dotted)
+	if o.braces && at >= o.end {
+		// The closing brace is on the line of the last item.
+		at = o.end - 1
+		for at > end && (s.src[at-1] == ' ' || s.src[at-1] == '\t') {
+			at--
+		}
+		text += "\n" + lineIndent(s.src, o.off)
+		return splice(s.src, at, o.end-1, text)
+	}
+	return splice(s.src, at, at, text)
+}
+
+// field returns a field setting the path rest, written at the
+// indentation ind, with a dotted key or nested objects.
+func (s *setter) field(rest []string, ind string, dotted bool) string {
+	if len(rest) > 1 && !dotted {
+		inner := ind + s.indent
+		return canonicalKey(rest[0]) + " {\n" + inner + s.field(rest[1:], inner, false) + "\n" + ind + "}"
+	}
+	keys := make([]string, len(rest))
+	for i, k := range rest {
+		keys[i] = canonicalKey(k)
+	}
+	if strings.HasPrefix(s.text, "{") {
+		return strings.Join(keys, ".") + " " + s.text
+	}
+	return strings.Join(keys, ".") + s.sep + s.text
+}
+
+// takesEffect reports whether the value at s.path in the document res
+// is the new one.
+func (s *setter) takesEffect(res []byte) bool {
+	v, err := (&merger{src: res, includes: true}).document()
+	if err != nil {
+		return false
+	}
+	got, ok := lookupElems(v, s.path)
+	return ok && valueDiff(s.want, got, nil) == nil
+}
+
+// itemEnd returns the offset of the byte after the item it, with its
+// comment.
+func itemEnd(it item) int {
+	if it.comment != nil {
+		return it.comment.off + len(it.comment.text)
+	}
+	switch n := it.node.(type) {
+	case *fieldNode:
+		return n.value.end()
+	case *valueNode:
+		return n.end()
+	case *includeNode:
+		last := n.target[len(n.target)-1]
+		return last.off + len(last.lit)
+	case *commentNode:
+		return n.off + len(n.text)
+	}
+	return it.node.offset()
+}
+
+// splice returns src with the bytes from start to end replaced by text.
+func splice(src []byte, start, end int, text string) []byte {
+	return spliceEdits(src, []textEdit{{start, end - start, text}})
+}
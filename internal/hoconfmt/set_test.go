@@ -0,0 +1,125 @@
+package hoconfmt
+
+import "testing"
+
+func TestSetValue(t *testing.T) {
+	const src = `# App settings.
+app {
+  name = "api"   # the name
+  db {
+    host = localhost
+  }
+}
+servers = [
+  { host = a }
+]
+limits { max = 1 }
+`
+	tests := []struct{ src, set, want string }{
+		{src, "app.name=web", `# App settings.
+app {
+  name = web   # the name
+  db {
+    host = localhost
+  }
+}
+servers = [
+  { host = a }
+]
+limits { max = 1 }
+`},
+		{src, "app.db.pool.size=10", `# App settings.
+app {
+  name = "api"   # the name
+  db {
+    host = localhost
+    pool {
+      size = 10
+    }
+  }
+}
+servers = [
+  { host = a }
+]
+limits { max = 1 }
+`},
+		{src, "servers.0.port=80", `# App settings.
+app {
+  name = "api"   # the name
+  db {
+    host = localhost
+  }
+}
+servers = [
+  { host = a, port = 80 }
+]
+limits { max = 1 }
+`},
+		{src, "app.url=http://x/y", `# App settings.
+app {
+  name = "api"   # the name
+  db {
+    host = localhost
+  }
+  url = "http://x/y"
+}
+servers = [
+  { host = a }
+]
+limits { max = 1 }
+`},
+		{src, "app.db=3", `# App settings.
+app {
+  name = "api"   # the name
+  db = 3
+}
+servers = [
+  { host = a }
+]
+limits { max = 1 }
+`},
+		{"a.b = 1\na.c: 2\nx: 3\n", "a.d.e=true", "a.b = 1\na.c: 2\nx: 3\na.d.e: true\n"},
+		{"a {\n    x = 1 }\n", "a.y=[1, 2]", "a {\n    x = 1\n    y = [1, 2]\n}\n"},
+		{"a = 5\na.b = 1\n", "a=7", "a = 5\na.b = 1\na = 7\n"},
+		{"a {}\n", `a."b.c"=x y`, "a {\n    \"b.c\" = \"x y\"\n}\n"},
+		{"", "include=1", "\"include\" = 1\n"},
+		{"h = ${HOME}", "h=${USER}", "h = ${USER}"},
+		{"list = [a, b]\nmap { 0 = x }\n", "list.0=c", "list = [c, b]\nmap { 0 = x }\n"},
+		{"list = [a, b]\nmap { 0 = x }\n", "map.0=c", "list = [a, b]\nmap { 0 = c }\n"},
+		{"list = [a, b]\nmap { 0 = x }\n", "map.1=c", "list = [a, b]\nmap { 0 = x, 1 = c }\n"},
+	}
+	for _, tt := range tests {
+		path, value, err := splitAssignment(tt.set)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := setValue([]byte(tt.src), path, value, "    ")
+		if err != nil {
+			t.Errorf("-set %s: %v", tt.set, err)
+			continue
+		}
+		if string(res) != tt.want {
+			t.Errorf("-set %s:\n%s\nwant\n%s", tt.set, res, tt.want)
+		}
+	}
+}
+
+func TestSetValueErrors(t *testing.T) {
+	tests := []struct{ src, set, want string }{
+		{"a = [1]", "a.1=2", "cannot set a.1: the array a has no element 1"},
+		{"a = [1]", "a.x=2", "cannot set a.x: the array a has no element x"},
+		{"a = [1]\na += 2", "a.1=3", "cannot set a.1: the array a is joined from several arrays"},
+		{"a { x = 1 }\na { y = 1 }", "a={ z = 1 }", "cannot set a to { z = 1 }: other fields of the file would change its value"},
+		{"[1]", "a=1", "cannot set a in an array"},
+		{"a = 1", "a={", "invalid value {: 1:5: { is not closed"},
+	}
+	for _, tt := range tests {
+		path, value, _ := splitAssignment(tt.set)
+		if _, err := setValue([]byte(tt.src), path, value, "    "); err == nil || err.Error() != tt.want {
+			t.Errorf("-set %s: got error %v, want %s", tt.set, err, tt.want)
+		}
+	}
+	if _, _, err := splitAssignment(`"a=b"`); err == nil {
+		t.Error("splitAssignment accepted a path without a value")
+	}
+}
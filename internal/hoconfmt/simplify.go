@@ -0,0 +1,153 @@
+package hoconfmt
+
+import "strings"
+
+// simplify rewrites src for -s, leaving what it means as it is:
+//
+//	a { b = 1 }         a {
+//	a.c = 2                 b = 1
+//	a.b = 1                 c = 2
+//	d { e { f = 3 } }   }
+//	g = 4               d.e.f = 3
+//	g = ${g}            g = 4
+//
+// The objects of a key are merged as keyPathsNested merges them, and a
+// field whose value is an object of a single field becomes that field,
+// with its key behind the outer one. With style keyPathsNested the
+// fields stay objects instead, and with keyPathsDotted all of them
+// become dotted paths, as setKeyPaths writes them. Fields that set a
+// path to the value it already has, written the same way or, outside
+// objects, as a substitution of the path itself, are dropped, with the comment on
+// their line kept on a line of its own.
+func simplify(src []byte, style string) ([]byte, error) {
+	doc, err := parseDocument(src)
+	if err != nil {
+		return nil, err
+	}
+	k := &keyPather{src: src, style: keyPathsNested, collapse: true, dirty: map[node]bool{}}
+	switch style {
+	case keyPathsNested:
+		k.collapse = false
+	case keyPathsDotted:
+		k.style, k.collapse = keyPathsDotted, false
+	}
+	if root, ok := doc.root.(*objectNode); ok {
+		k.dropOverrides(root, nil, map[string]pathValue{})
+	}
+	return k.rewrite(doc), nil
+}
+
+// A pathValue is what the fields seen so far set a path to, for
+// dropOverrides: an object, or a value whose text is known if it holds
+// no substitutions.
+type pathValue struct {
+	object bool
+	known  bool
+	text   string
+}
+
+// dropOverrides removes the fields of o, an object at path prefix, that
+// set a path to the value defs says it already has, and records in defs
+// what the others set. Include statements may set anything, so they
+// clear defs.
+func (k *keyPather) dropOverrides(o *objectNode, prefix []string, defs map[string]pathValue) {
+	var out []item
+	for _, it := range o.items {
+		switch n := it.node.(type) {
+		case *includeNode:
+			clear(defs)
+		case *fieldNode:
+			path := append(prefix[:len(prefix):len(prefix)], n.path...)
+			key := renderPath(path)
+			if k.redundant(n, key, len(prefix) > 0, defs) {
+				if it.comment != nil {
+					out = append(out, item{node: it.comment, blank: it.blank})
+				}
+				k.dirty[o] = true
+				continue
+			}
+			for i := len(prefix) + 1; i < len(path); i++ {
+				defs[renderPath(path[:i])] = pathValue{object: true}
+			}
+			if obj := n.value.object(); obj != nil && n.sep != "+=" {
+				if !defs[key].object {
+					forgetPath(defs, key)
+				}
+				defs[key] = pathValue{object: true}
+				k.dropOverrides(obj, path, defs)
+			} else {
+				forgetPath(defs, key)
+				defs[key] = k.definition(n)
+			}
+		}
+		out = append(out, it)
+	}
+	o.items = out
+}
+
+// redundant reports whether the field f, of path key, sets it to the
+// value defs says it has. A substitution of the path itself only
+// counts outside objects, where -verify sees the value it stands for;
+// nested is set inside them.
+func (k *keyPather) redundant(f *fieldNode, key string, nested bool, defs map[string]pathValue) bool {
+	d, ok := defs[key]
+	if !ok || f.sep == "+=" {
+		return false
+	}
+	if p := f.value.parts[0]; len(f.value.parts) == 1 && p.node == nil && p.tok.kind == tokSubst {
+		if nested {
+			return false
+		}
+		s := trimSubst(p.tok.lit)
+		return renderPath(pathElems(strings.TrimPrefix(s[2:len(s)-1], "?"))) == key
+	}
+	return d.known && k.definition(f) == d
+}
+
+// definition returns what the field f, whose value is not an object,
+// sets its path to.
+func (k *keyPather) definition(f *fieldNode) pathValue {
+	text := string(k.src[f.value.offset():f.value.end()])
+	if f.sep == "+=" || strings.Contains(text, "${") {
+		return pathValue{}
+	}
+	return pathValue{known: true, text: text}
+}
+
+// forgetPath removes key and the paths below it from defs.
+func forgetPath(defs map[string]pathValue, key string) {
+	for p := range defs {
+		if p == key || strings.HasPrefix(p, key+".") {
+			delete(defs, p)
+		}
+	}
+}
+
+// collapseFields replaces each field of items whose value is an object
+// holding a single field with that field, its key behind the outer
+// one: a { b = 1 } becomes a.b = 1. The objects inside are collapsed
+// first, so a { b { c = 1 } } becomes a.b.c = 1.
+func (k *keyPather) collapseFields(items []item) bool {
+	changed := false
+	for i, it := range items {
+		f, ok := it.node.(*fieldNode)
+		if !ok || f.sep == "+=" {
+			continue
+		}
+		o := f.value.object()
+		if o == nil || len(o.items) != 1 {
+			continue
+		}
+		inner, ok := o.items[0].node.(*fieldNode)
+		if !ok || it.comment != nil && o.items[0].comment != nil {
+			continue
+		}
+		path := append(f.path[:len(f.path):len(f.path)], inner.path...)
+		items[i].node = &fieldNode{key: keyTokens(path), path: path, sep: inner.sep, value: inner.value}
+		if it.comment == nil {
+			items[i].comment = o.items[0].comment
+		}
+		changed = true
+	}
+	return changed
+}
@@ -0,0 +1,87 @@
+package hoconfmt
+
+import "testing"
+
+func TestSimplify(t *testing.T) {
+	const src = `# settings
+a { b = 1 }
+a.c = 2 # c
+a.b = 1
+d { e { f = 3 } }
+g = 4
+g = ${g} # same
+h = [1, 2]
+h = [1, 2]
+`
+	tests := []struct{ style, want string }{
+		{keyPathsKeep, `# settings
+a {
+    b = 1
+    c = 2 # c
+}
+d.e.f = 3
+g = 4
+# same
+h = [1, 2]
+`},
+		{keyPathsNested, `# settings
+a {
+    b = 1
+    c = 2 # c
+}
+d { e { f = 3 } }
+g = 4
+# same
+h = [1, 2]
+`},
+		{keyPathsDotted, `# settings
+a.b = 1
+a.c = 2 # c
+d.e.f = 3
+g = 4
+# same
+h = [1, 2]
+`},
+	}
+	for _, tt := range tests {
+		opts := Options{Simplify: true, KeyPaths: tt.style, Verify: true}
+		res, err := formatFile("a.conf", []byte(src), opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.style, err)
+		}
+		if string(res) != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.style, res, tt.want)
+		}
+		again, err := formatFile("a.conf", res, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.style, err)
+		}
+		if string(again) != tt.want {
+			t.Errorf("%s: formatting the result again gave\n%s", tt.style, again)
+		}
+		if err := checkValue([]byte(src), res); err != nil {
+			t.Errorf("%s: %v", tt.style, err)
+		}
+	}
+}
+
+func TestSimplifyKeeps(t *testing.T) {
+	// None of these fields sets a path to the value it already has.
+	for _, src := range []string{
+		"a = 5\na.b = 1\na = 5\n",
+		"a = 1\ninclude \"x.conf\"\na = 1\n",
+		"a = 1\na = 2\na = 1\n",
+		"a = ${?a}\n",
+		"a = [1]\na += [1]\n",
+		"a = ${b}\na = ${b}\nb = 1\n",
+		"a = 1\nb = ${a}\n",
+		"a {\n    b = 1 # one\n} # two\n",
+	} {
+		res, err := formatFile("a.conf", []byte(src), Options{Simplify: true})
+		if err != nil {
+			t.Errorf("%q: %v", src, err)
+		} else if string(res) != src {
+			t.Errorf("%q: got %q", src, res)
+		}
+	}
+}
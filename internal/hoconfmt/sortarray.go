@@ -0,0 +1,298 @@
+package hoconfmt
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// An arraySort names an array whose objects -sort-array orders by one
+// of their fields.
+type arraySort struct {
+	path  []string
+	field string
+}
+
+// parseArraySorts parses the comma-separated path=field pairs of
+// -sort-array. Paths and fields are quoted as in HOCON, so that
+// "a.b".c=name names the array c of the key a.b.
+func parseArraySorts(list string) ([]arraySort, error) {
+	var sorts []arraySort
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		eq := strings.Index(s, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("invalid -sort-array %q: must be path=field", s)
+		}
+		p, field := strings.TrimSpace(s[:eq]), pathElems(strings.TrimSpace(s[eq+1:]))
+		if p == "" || len(field) != 1 || field[0] == "" {
+			return nil, fmt.Errorf("invalid -sort-array %q: must be path=field", s)
+		}
+		elems := pathElems(p)
+		for _, e := range elems {
+			if e == "" {
+				return nil, fmt.Errorf("invalid -sort-array %q: empty path element", s)
+			}
+		}
+		sorts = append(sorts, arraySort{elems, field[0]})
+	}
+	return sorts, nil
+}
+
+func checkSortArrays(list string) error {
+	_, err := parseArraySorts(list)
+	return err
+}
+
+// sortArrays orders the objects of the arrays named by sorts by the
+// value of their field, numerically if both values are numbers. Objects
+// without the field go last, and objects with equal values keep their
+// order. Comment lines directly above an object and a comment after it
+// on its line move with it. Arrays with each object on lines of its
+// own, and arrays written on one line, are sorted; other arrays, arrays
+// holding anything but objects, and values that are not arrays are
+// left alone with a warning to warn.
+func sortArrays(warn func(warning), filename string, src []byte, sorts []arraySort) ([]byte, error) {
+	if len(sorts) == 0 {
+		return src, nil
+	}
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	unsorted := func(f field, why string) {
+		pos := position(src, toks[f.value].off)
+		pos.Filename = filename
+		warn(warning{pos, "-sort-array: leaving " + renderPath(f.path) + " unsorted: " + why, codeSort})
+	}
+	keys := map[int]string{} // field to sort by, by index of the opening bracket
+	paths := map[int]field{} // the field of each of those arrays
+	walkFields(toks, func(f field) {
+		key := ""
+		for _, s := range sorts {
+			if equalPaths(s.path, f.path) {
+				key = s.field
+			}
+		}
+		switch {
+		case key == "":
+		case f.object || toks[f.value].kind != tokLBracket || valueEnd(toks, f.value) != matchingBracket(toks, f.value)+1:
+			unsorted(f, "not an array")
+		default:
+			keys[f.value] = key
+			paths[f.value] = f
+		}
+	})
+
+	var res []byte
+	for i := 0; i < len(toks); i++ {
+		key, ok := keys[i]
+		if !ok {
+			res = append(res, toks[i].lit...)
+			continue
+		}
+		end := matchingBracket(toks, i)
+		sorted, ok := sortedArray(toks[i:end+1], key)
+		if !ok {
+			sorted, ok = sortedInlineArray(toks[i:end+1], key)
+		}
+		if !ok {
+			unsorted(paths[i], "its elements must all be objects, each on lines of its own or all on one line")
+			sorted = lineText(toks[i : end+1])
+		}
+		res = append(res, sorted...)
+		i = end
+	}
+	return res, nil
+}
+
+// A sortElem is an object of an array being sorted, with the value of
+// the field it is sorted by.
+type sortElem struct {
+	text   []tok // for sortedArray, the comment lines above and the object
+	suffix []tok // after the object and its comma, to the end of its line
+	key    flatValue
+	hasKey bool
+}
+
+// newSortElem returns the element for the object obj, sorted by the
+// field key.
+func newSortElem(obj []tok, key string) sortElem {
+	e := sortElem{text: obj}
+	walkFields(obj, func(f field) {
+		if len(f.path) == 1 && f.path[0] == key && !f.object {
+			e.key, e.hasKey = flatScalar(obj[f.value:f.end]), true
+		}
+	})
+	return e
+}
+
+// sortElems orders elems by their keys, numerically if both are numbers,
+// with the elements that have none last.
+func sortElems(elems []sortElem) {
+	sort.SliceStable(elems, func(i, j int) bool {
+		a, b := elems[i], elems[j]
+		if !a.hasKey || !b.hasKey {
+			return a.hasKey && !b.hasKey
+		}
+		if a.key.kind == "number" && b.key.kind == "number" {
+			x, errX := strconv.ParseFloat(a.key.text, 64)
+			y, errY := strconv.ParseFloat(b.key.text, 64)
+			if errX == nil && errY == nil {
+				return x < y
+			}
+		}
+		return a.key.text < b.key.text
+	})
+}
+
+// sortedInlineArray returns the text of the array toks, written on one
+// line, with its objects ordered by the field key. The commas and
+// spaces between the elements stay where they are. It returns false if
+// the array holds anything but objects or spans several lines.
+func sortedInlineArray(toks []tok, key string) ([]byte, bool) {
+	var elems []sortElem
+	var seps [][]tok // the tokens after each element
+	i := 1
+	for i < len(toks) && toks[i].kind == tokSpace {
+		i++
+	}
+	head := toks[:i]
+	for toks[i].kind != tokRBracket {
+		if toks[i].kind != tokLBrace {
+			return nil, false
+		}
+		end := matchingBracket(toks, i)
+		obj := toks[i : end+1]
+		for _, t := range obj {
+			if t.kind == tokNewline || t.kind == tokComment || t.kind == tokMultiline {
+				return nil, false
+			}
+		}
+		elems = append(elems, newSortElem(obj, key))
+		j := end + 1
+		for j < len(toks) && toks[j].kind == tokSpace {
+			j++
+		}
+		if j < len(toks) && toks[j].kind == tokComma {
+			j++
+			for j < len(toks) && toks[j].kind == tokSpace {
+				j++
+			}
+		}
+		if j == len(toks) || toks[j].kind != tokLBrace && toks[j].kind != tokRBracket {
+			return nil, false
+		}
+		seps = append(seps, toks[end+1:j])
+		i = j
+	}
+
+	sortElems(elems)
+	res := lineText(head)
+	for n, e := range elems {
+		res = append(res, lineText(e.text)...)
+		res = append(res, lineText(seps[n])...)
+	}
+	return append(res, toks[len(toks)-1].lit...), true
+}
+
+// sortedArray returns the text of the array toks, with each object on
+// lines of its own, with its objects ordered by the field key. It
+// returns false if the array is not laid out that way.
+func sortedArray(toks []tok, key string) ([]byte, bool) {
+	var elems []sortElem
+	var commas []bool // whether the element at each position has a comma
+
+	i := 1
+	for i < len(toks) && toks[i].kind == tokSpace {
+		i++
+	}
+	if i == len(toks) || toks[i].kind != tokNewline {
+		return nil, false
+	}
+	head := toks[:i+1]
+	start := i + 1
+	for {
+		// Skip the blank and comment lines above the element.
+		k := start
+		for {
+			l := k
+			for l < len(toks) && toks[l].kind == tokSpace {
+				l++
+			}
+			if l < len(toks) && toks[l].kind == tokComment {
+				l++
+				for l < len(toks) && toks[l].kind == tokSpace {
+					l++
+				}
+			}
+			if l == len(toks) || toks[l].kind != tokNewline {
+				break
+			}
+			k = l + 1
+		}
+		for k < len(toks) && toks[k].kind == tokSpace {
+			k++
+		}
+		if toks[k].kind == tokRBracket {
+			break
+		}
+		if toks[k].kind != tokLBrace {
+			return nil, false
+		}
+		end := matchingBracket(toks, k)
+		e := newSortElem(toks[k:end+1], key)
+		e.text = toks[start : end+1]
+		m := end + 1
+		for m < len(toks) && toks[m].kind == tokSpace {
+			m++
+		}
+		comma := m < len(toks) && toks[m].kind == tokComma
+		s := end + 1
+		if comma {
+			m++
+			s = m
+		}
+		for m < len(toks) && toks[m].kind == tokSpace {
+			m++
+		}
+		if m < len(toks) && toks[m].kind == tokComment {
+			m++
+		}
+		if m == len(toks) || toks[m].kind != tokNewline {
+			return nil, false
+		}
+		e.suffix = toks[s : m+1]
+		elems = append(elems, e)
+		commas = append(commas, comma)
+		start = m + 1
+	}
+
+	sortElems(elems)
+	res := lineText(head)
+	for i, e := range elems {
+		res = append(res, lineText(e.text)...)
+		if commas[i] {
+			res = append(res, ',')
+		}
+		res = append(res, lineText(e.suffix)...)
+	}
+	return append(res, lineText(toks[start:])...), true
+}
+
+// equalPaths reports whether the key paths p and q are the same.
+func equalPaths(p, q []string) bool {
+	if len(p) != len(q) {
+		return false
+	}
+	for i := range p {
+		if p[i] != q[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package hoconfmt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortArrays(t *testing.T) {
+	const src = `service {
+    endpoints = [
+        { name = orders, port = 8082 }
+        # The public API.
+        {
+            name = "api"
+            port = 8080
+        }
+        { port = 9000 } # no name
+        { name = billing, port = 8081 } // internal
+    ]
+    # Not sorted: not named.
+    backups = [
+        { name = z }
+        { name = a }
+    ]
+}
+weights = [
+    { w = 10 },
+    { w = 9 },
+    { w = 1.5 }
+]
+`
+	const want = `service {
+    endpoints = [
+        # The public API.
+        {
+            name = "api"
+            port = 8080
+        }
+        { name = billing, port = 8081 } // internal
+        { name = orders, port = 8082 }
+        { port = 9000 } # no name
+    ]
+    # Not sorted: not named.
+    backups = [
+        { name = z }
+        { name = a }
+    ]
+}
+weights = [
+    { w = 1.5 },
+    { w = 9 },
+    { w = 10 }
+]
+`
+	opts := DefaultOptions()
+	opts.SortArrays = "service.endpoints=name, weights=w"
+	got, err := formatFile("a.conf", []byte(src), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSortArraysInline(t *testing.T) {
+	for _, test := range []struct {
+		src, want string
+	}{
+		{"a = [{ n = 2 }, { n = 1 }]\n", "a = [{ n = 1 }, { n = 2 }]\n"},
+		{"a = [ {n: b}, {m: 0},{n: a} ]\n", "a = [ {n: a}, {n: b},{m: 0} ]\n"},
+		{"a = [{ n = 2 }, { n = 1 },]\n", "a = [{ n = 1 }, { n = 2 },]\n"},
+		{"a = []\n", "a = []\n"},
+	} {
+		got, err := sortArrays(func(w warning) { t.Errorf("%q: %s", test.src, w) }, "a.conf", []byte(test.src), []arraySort{{[]string{"a"}, "n"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("sortArrays(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestSortArraysLeftAlone(t *testing.T) {
+	for _, test := range []struct {
+		src, warning string
+	}{
+		{"a = [\n    { n = 2 }\n    3\n    { n = 1 }\n]\n", "a.conf:1:5: warning: -sort-array: leaving a unsorted: its elements must all be objects, each on lines of its own or all on one line"},
+		{"a = [\n    { n = 2 } { m = 1 }\n    { n = 1 }\n]\n", "a.conf:1:5: warning: -sort-array: leaving a unsorted: its elements must all be objects, each on lines of its own or all on one line"},
+		{"a = [{ n = 2 }, 1]\n", "a.conf:1:5: warning: -sort-array: leaving a unsorted: its elements must all be objects, each on lines of its own or all on one line"},
+		{"a = [{ n = 2 }, { n = 1 }\n]\n", "a.conf:1:5: warning: -sort-array: leaving a unsorted: its elements must all be objects, each on lines of its own or all on one line"},
+		{"a { n = 1 }\n", "a.conf:1:3: warning: -sort-array: leaving a unsorted: not an array"},
+		{"a = [{ n = 2 }] [{ n = 1 }]\n", "a.conf:1:5: warning: -sort-array: leaving a unsorted: not an array"},
+	} {
+		var warnings []string
+		got, err := sortArrays(func(w warning) { warnings = append(warnings, w.String()) }, "a.conf", []byte(test.src), []arraySort{{[]string{"a"}, "n"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.src {
+			t.Errorf("sortArrays(%q) = %q, want it unchanged", test.src, got)
+		}
+		if len(warnings) != 1 || warnings[0] != test.warning {
+			t.Errorf("sortArrays(%q) warnings = %q, want %q", test.src, warnings, test.warning)
+		}
+	}
+}
+
+func TestParseArraySorts(t *testing.T) {
+	sorts, err := parseArraySorts(`"a.b".c=name, d="x.y"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []arraySort{{[]string{"a.b", "c"}, "name"}, {[]string{"d"}, "x.y"}}
+	if !reflect.DeepEqual(sorts, want) {
+		t.Errorf("parseArraySorts = %q, want %q", sorts, want)
+	}
+	for _, list := range []string{"a", "a=", "=b", "a..b=c", "a=b.c"} {
+		if _, err := parseArraySorts(list); err == nil {
+			t.Errorf("parseArraySorts(%q): no error", list)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package hoconfmt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// parseSortFirst parses the comma-separated keys of -sort-first. Keys
+// are quoted as in HOCON, so that "a.b" names the key a.b; a path of
+// several elements is an error.
+func parseSortFirst(list string) ([]string, error) {
+	var keys []string
+	for _, k := range strings.Split(list, ",") {
+		if k = strings.TrimSpace(k); k == "" {
+			continue
+		}
+		elems := pathElems(k)
+		if len(elems) != 1 || elems[0] == "" {
+			return nil, fmt.Errorf("invalid -sort-first %q: must be a key, not a path", k)
+		}
+		keys = append(keys, elems[0])
+	}
+	return keys, nil
+}
+
+func checkSortFirst(list string) error {
+	_, err := parseSortFirst(list)
+	return err
+}
+
+// sortKeys orders the fields of the root object of src and of every
+// object in it by key, for -sort. Keys are compared byte by byte, which
+// for UTF-8 is the order of Unicode code points, so that the result does
+// not depend on the locale: uppercase letters come before lowercase
+// ones, and both before non-ASCII letters. A field is sorted by the
+// first element of its path, and fields with the same first element
+// keep their order, so that a.b = 1 and a { c = 2 } merge as they did,
+// and a key set several times, or appended to with +=, keeps its last
+// value. Comment lines directly above a field move with it. The keys of
+// first, in each object that has them, come before the others, in the
+// order of first.
+//
+// Only fields that start a line of their own and end on it, or whose
+// object value ends on it, are sorted. Blank lines, include statements
+// and anything else, such as a comment followed by a blank line or two
+// fields on one line, stay where they are and divide the fields around
+// them into runs that are sorted separately. No field moves past an
+// include statement, as the values of the included file override the
+// fields before it and are overridden by those after it. Regions turned
+// off with hoconfmt:off comments are left alone.
+func sortKeys(src []byte, first []string) ([]byte, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	regions, err := offRegions(nil, src)
+	if err != nil {
+		return nil, err
+	}
+	s := &keySorter{regions: regions, rank: map[string]int{}}
+	for i, k := range first {
+		if _, ok := s.rank[k]; !ok {
+			s.rank[k] = i - len(first) // before the keys not listed, at 0
+		}
+	}
+	return s.body(toks, true), nil
+}
+
+// A keySorter sorts the fields of objects for sortKeys.
+type keySorter struct {
+	regions []span         // the regions protected by hoconfmt:off comments
+	rank    map[string]int // the place of the keys of -sort-first
+}
+
+// less reports whether the field with the key a goes before the one
+// with the key b.
+func (s *keySorter) less(a, b string) bool {
+	if s.rank[a] != s.rank[b] {
+		return s.rank[a] < s.rank[b]
+	}
+	return a < b
+}
+
+// A sortedField is a field being sorted, with the comment lines above
+// it.
+type sortedField struct {
+	key  string // the first element of the path
+	text []byte
+}
+
+// Kinds of the lines of an object, for keySorter.body.
+const (
+	lineFixed = iota
+	lineComment
+	lineField
+)
+
+// body returns the text of toks, the fields of an object between its
+// braces, or of the whole document, with the fields sorted. atLine
+// reports whether toks starts at the start of a line, as it does for
+// the document; the rest of the line of an opening brace stays where it
+// is.
+func (s *keySorter) body(toks []tok, atLine bool) []byte {
+	var res, comments []byte
+	var run []sortedField
+	flush := func() {
+		sort.SliceStable(run, func(i, j int) bool { return s.less(run[i].key, run[j].key) })
+		for _, f := range run {
+			res = append(res, f.text...)
+		}
+		run = nil
+	}
+	lines := objectLines(toks)
+	for i, l := range lines {
+		kind, key := s.line(l, i > 0 || atLine)
+		switch kind {
+		case lineComment:
+			comments = append(comments, lineText(l)...)
+		case lineField:
+			run = append(run, sortedField{key, append(comments, s.text(l)...)})
+			comments = nil
+		default:
+			flush()
+			res = append(append(res, comments...), s.text(l)...)
+			comments = nil
+		}
+	}
+	flush()
+	return append(res, comments...)
+}
+
+// objectLines splits toks into lines at the newlines outside brackets,
+// each with its newline. The last line has none if toks does not end in
+// one.
+func objectLines(toks []tok) [][]tok {
+	var lines [][]tok
+	depth, start := 0, 0
+	for i, t := range toks {
+		switch t.kind {
+		case tokLBrace, tokLBracket:
+			depth++
+		case tokRBrace, tokRBracket:
+			depth--
+		case tokNewline:
+			if depth == 0 {
+				lines = append(lines, toks[start:i+1])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(toks) {
+		lines = append(lines, toks[start:])
+	}
+	return lines
+}
+
+// line returns the kind of the line l of an object, which starts a line
+// of the file if atLine is set, and for a field the first element of
+// its key path.
+func (s *keySorter) line(l []tok, atLine bool) (int, string) {
+	if len(l) == 0 || l[len(l)-1].kind != tokNewline || !atLine || s.protected(l) {
+		return lineFixed, ""
+	}
+	k := 0
+	for k < len(l) && l[k].kind == tokSpace {
+		k++
+	}
+	switch {
+	case l[k].kind == tokNewline:
+		return lineFixed, ""
+	case l[k].kind == tokComment:
+		return lineComment, ""
+	case !isText(l[k]) || isInclude(srcLine{toks: l}):
+		return lineFixed, ""
+	}
+	j, elems := keyPath(l, k)
+	if j < 0 {
+		return lineFixed, ""
+	}
+	v := j
+	if l[v].kind == tokSep {
+		v++
+	}
+	for v < len(l) && l[v].kind == tokSpace {
+		v++
+	}
+	if l[v].kind == tokNewline || l[v].kind == tokComment {
+		return lineFixed, ""
+	}
+	for _, t := range l[valueEnd(l, v):] {
+		switch t.kind {
+		case tokSpace, tokComma, tokComment, tokNewline:
+		default:
+			return lineFixed, "" // another field follows on the line
+		}
+	}
+	return lineField, elems[0]
+}
+
+// protected reports whether the line l is in a region turned off with
+// a hoconfmt:off comment.
+func (s *keySorter) protected(l []tok) bool {
+	start, end := l[0].off, l[len(l)-1].off+len(l[len(l)-1].lit)
+	for _, r := range s.regions {
+		if start < r.end && r.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// text returns the text of toks with the fields of the objects in it
+// sorted.
+func (s *keySorter) text(toks []tok) []byte {
+	var res []byte
+	for i := 0; i < len(toks); i++ {
+		if toks[i].kind != tokLBrace {
+			res = append(res, toks[i].lit...)
+			continue
+		}
+		end := matchingBracket(toks, i)
+		res = append(res, '{')
+		res = append(res, s.body(toks[i+1:end], false)...)
+		res = append(res, '}')
+		i = end
+	}
+	return res
+}
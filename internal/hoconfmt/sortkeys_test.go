@@ -0,0 +1,103 @@
+package hoconfmt
+
+import "testing"
+
+func TestSortKeys(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		// Byte order, whatever the locale: a locale-aware collation
+		// would put apple first and mix the cases.
+		{
+			"apple = 1\néclair = 2\nZebra = 3\nab = 4\nBanana = 5\na_b = 6\n",
+			"Banana = 5\nZebra = 3\na_b = 6\nab = 4\napple = 1\néclair = 2\n",
+		},
+		// Quoted keys are compared by what they name.
+		{"\"b\" = 1\na = 2\n", "a = 2\n\"b\" = 1\n"},
+		// Comment lines above a field move with it; a trailing comment
+		// stays on its line.
+		{"# the b\nb = 1\na = 2 # the a\n", "a = 2 # the a\n# the b\nb = 1\n"},
+		// Nested objects, and objects in arrays, are sorted too.
+		{
+			"b {\n    d = 1\n    c = 2\n}\na = [\n    {\n        y = 1\n        x = 2\n    }\n]\n",
+			"a = [\n    {\n        x = 2\n        y = 1\n    }\n]\nb {\n    c = 2\n    d = 1\n}\n",
+		},
+		// Fields with the same first path element keep their order.
+		{"b = 1\na.y = 1\na = 2\na.x = [3]\na.x += 4\n", "a.y = 1\na = 2\na.x = [3]\na.x += 4\nb = 1\n"},
+		// Blank lines and comments followed by one divide runs.
+		{"d = 1\nc = 1\n\n# section\n\nb = 1\na = 1\n", "c = 1\nd = 1\n\n# section\n\na = 1\nb = 1\n"},
+		// Fields are not moved past an include statement, which would
+		// change which of them the file's values override; only the runs
+		// on each side are sorted, and comments above it stay with it.
+		{
+			"c = 1\na = 1\n# defaults\ninclude \"base.conf\"\nb = 2\na = 2\n",
+			"a = 1\nc = 1\n# defaults\ninclude \"base.conf\"\na = 2\nb = 2\n",
+		},
+		{
+			"db {\n    url = x\n    include required(\"db.conf\")\n    port = 1\n    host = h\n}\n",
+			"db {\n    url = x\n    include required(\"db.conf\")\n    host = h\n    port = 1\n}\n",
+		},
+		// What does not start and end on a line of its own stays.
+		{"a { d = 1, c = 2 }\nb = 1, a = 2\n", "a { d = 1, c = 2 }\nb = 1, a = 2\n"},
+		{"b = 1\n# hoconfmt:off\nd = 1\nc = 1\n# hoconfmt:on\na = 1\n", "b = 1\n# hoconfmt:off\nd = 1\nc = 1\n# hoconfmt:on\na = 1\n"},
+	}
+	for _, tt := range tests {
+		res, err := sortKeys([]byte(tt.src), nil)
+		if err != nil {
+			t.Errorf("sorting %q: %v", tt.src, err)
+			continue
+		}
+		if string(res) != tt.want {
+			t.Errorf("sorting %q = %q, want %q", tt.src, res, tt.want)
+		}
+	}
+
+	opts := DefaultOptions()
+	opts.SortKeys = true
+	res, err := formatFile("a.conf", []byte("b = 1\na {\n  y: 1\n  x: 2\n}\n"), opts)
+	if want := "a {\n    x: 2\n    y: 1\n}\nb = 1\n"; err != nil || string(res) != want {
+		t.Errorf("-sort: got %q, %v, want %q", res, err, want)
+	}
+}
+
+func TestSortFirst(t *testing.T) {
+	first, err := parseSortFirst(" name, version,,\"a.b\" ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 3 || first[0] != "name" || first[1] != "version" || first[2] != "a.b" {
+		t.Fatalf("parseSortFirst = %q", first)
+	}
+
+	const src = `description = "x"
+# the version
+version = "1.0"
+dependencies {
+    zlib = 1
+    version = 2
+    name = 3
+}
+name = app
+`
+	const want = `name = app
+# the version
+version = "1.0"
+dependencies {
+    name = 3
+    version = 2
+    zlib = 1
+}
+description = "x"
+`
+	res, err := sortKeys([]byte(src), first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != want {
+		t.Errorf("sorting with name and version first:\n%s\nwant:\n%s", res, want)
+	}
+
+	if _, err := parseSortFirst("name,app.version"); err == nil {
+		t.Error("a path in -sort-first: no error")
+	}
+}
@@ -0,0 +1,200 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// A section is the text of one top-level key of a document, as moved
+// into a file of its own by -split.
+type section struct {
+	key  string // first element of the key path
+	part int    // number of sections of key before this one
+	text []byte
+}
+
+// file returns the name of the file that holds s: key.conf for the
+// first section of the key, and key.2.conf and so on for the others.
+func (s section) file() string {
+	if s.part == 0 {
+		return s.key + ".conf"
+	}
+	return fmt.Sprintf("%s.%d.conf", s.key, s.part+1)
+}
+
+// splitConfig splits the formatted document src into one section per
+// top-level key and an index that includes the section files from the
+// directory dir, written with '/' separators. Every top-level field,
+// with the comment lines directly above it, moves into the section of
+// the first element of its key path; fields that repeat a key join its
+// section, whose include stands where the key first appeared. Include
+// statements and the comments that are not attached to a field stay in
+// the index. A field after an include statement does not join a section
+// included before it, which would move it above the include and change
+// which of them overrides the other, but starts the next section of its
+// key. src must not have braces around the whole document.
+func splitConfig(src []byte, dir string) ([]byte, []section, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	lines := splitLines(toks)
+
+	var index []byte
+	var sections []section
+	seen := map[string]int{}  // index in sections of the last section of each key
+	files := map[string]int{} // index in sections of each file name
+	includes := 0             // number of include statements so far
+	since := map[string]int{} // includes before the last section of each key
+	var comments []byte       // comment lines waiting for the next field
+	newline := "\n"
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		if l.depth == 0 && l.commentOnly() {
+			comments = l.appendTo(comments)
+			continue
+		}
+		if l.depth != 0 || l.blank() || isInclude(l) {
+			if isInclude(l) {
+				includes++
+			}
+			index = append(index, comments...)
+			index = l.appendTo(index)
+			comments = nil
+			continue
+		}
+
+		key, err := sectionKey(src, l)
+		if err != nil {
+			return nil, nil, err
+		}
+		text := comments
+		comments = nil
+		j := i + 1
+		for j < len(lines) && lines[j].depth > 0 {
+			j++
+		}
+		for _, l := range lines[i:j] {
+			text = l.appendTo(text)
+		}
+		i = j - 1
+
+		part := 0
+		if n, ok := seen[key]; ok {
+			if since[key] == includes {
+				sections[n].text = append(append(sections[n].text, '\n'), text...)
+				continue
+			}
+			part = sections[n].part + 1
+		}
+		s := section{key: key, part: part, text: text}
+		if n, ok := files[s.file()]; ok {
+			return nil, nil, fmt.Errorf("cannot split: the sections %s and %s would both be written to %s", sections[n].key, key, s.file())
+		}
+		seen[key], since[key], files[s.file()] = len(sections), includes, len(sections)
+		sections = append(sections, s)
+		if last := lines[j-1].toks[len(lines[j-1].toks)-1]; last.kind == tokNewline {
+			newline = last.lit
+		}
+		index = append(index, "include "+quoteString(path.Join(dir, s.file()))+newline...)
+	}
+	index = append(index, comments...)
+
+	// Fields that joined an earlier section may leave blank lines
+	// behind them.
+	index, err = limitBlankLines(nil, index, defaultMaxBlank)
+	if err != nil {
+		return nil, nil, err
+	}
+	if trimmed := bytes.TrimRight(index, " \t\r\n"); len(trimmed) > 0 {
+		index = append(trimmed, newline...)
+	}
+	return index, sections, nil
+}
+
+// sectionKey returns the first element of the key of the field that
+// starts the line l, which must be usable as a file name.
+func sectionKey(src []byte, l srcLine) (string, error) {
+	i := 0
+	for l.toks[i].kind == tokSpace {
+		i++
+	}
+	var errs errorList
+	j, elems := keyPath(l.toks, i)
+	if j < 0 {
+		errs.Add(position(src, l.toks[i].off), codeOther, "cannot split: "+l.toks[i].lit+" does not start a field")
+		return "", errs.Err()
+	}
+	key := elems[0]
+	if key == "" || strings.HasPrefix(key, ".") || strings.ContainsAny(key, "/\\\x00") {
+		errs.Add(position(src, l.toks[i].off), codeOther, "cannot split: key "+quoteString(key)+" is not usable as a file name")
+		return "", errs.Err()
+	}
+	return key, nil
+}
+
+// writeSplit writes the sections of the formatted document res, read
+// from filename, into -split-dir and its index to the -o file, or to
+// standard output. The includes of the index name the section files
+// relative to the directory of the index, which for standard output is
+// the working directory. Existing files are only replaced with
+// -split-overwrite, and never if they are the input or the -o file;
+// every target is checked before any is written.
+func writeSplit(filename string, res []byte) ([]byte, error) {
+	base := "."
+	if *outFile != "" {
+		base = filepath.Dir(*outFile)
+	}
+	base, err := filepath.Abs(base)
+	if err != nil {
+		return nil, err
+	}
+	target, err := filepath.Abs(*splitDir)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := filepath.Rel(base, target)
+	if err != nil {
+		return nil, err
+	}
+	index, sections, err := splitConfig(res, filepath.ToSlash(dir))
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sections {
+		name := filepath.Join(*splitDir, s.file())
+		for _, p := range []string{filename, *outFile} {
+			if p != "" && samePath(name, p) {
+				return nil, fmt.Errorf("cannot split: the section %s would overwrite %s", s.key, p)
+			}
+		}
+		if _, err := os.Lstat(name); err == nil && !*splitOvr {
+			return nil, fmt.Errorf("cannot split: %s already exists (use -split-overwrite to replace it)", name)
+		}
+	}
+	if err := os.MkdirAll(*splitDir, 0755); err != nil {
+		return nil, err
+	}
+	for _, s := range sections {
+		if err := writeFileAtomic(filepath.Join(*splitDir, s.file()), s.text); err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+// samePath reports whether the paths a and b name the same file.
+func samePath(a, b string) bool {
+	fa, errA := os.Stat(a)
+	fb, errB := os.Stat(b)
+	if errA == nil && errB == nil {
+		return os.SameFile(fa, fb)
+	}
+	a, errA = filepath.Abs(a)
+	b, errB = filepath.Abs(b)
+	return errA == nil && errB == nil && a == b
+}
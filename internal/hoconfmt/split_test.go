@@ -0,0 +1,164 @@
+package hoconfmt
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitConfig(t *testing.T) {
+	const src = `# Application settings.
+
+include "defaults.conf"
+
+// The database.
+db {
+    host = localhost
+    port = 5432
+}
+
+# The HTTP server.
+http.port = 8080
+`
+	index, sections, err := splitConfig([]byte(src), "conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantIndex = `# Application settings.
+
+include "defaults.conf"
+
+include "conf/db.conf"
+
+include "conf/http.conf"
+`
+	if string(index) != wantIndex {
+		t.Errorf("index = %q, want %q", index, wantIndex)
+	}
+	want := []section{
+		{"db", 0, []byte("// The database.\ndb {\n    host = localhost\n    port = 5432\n}\n")},
+		{"http", 0, []byte("# The HTTP server.\nhttp.port = 8080\n")},
+	}
+	if len(sections) != len(want) {
+		t.Fatalf("got %d sections, want %d", len(sections), len(want))
+	}
+	for i, s := range sections {
+		if s.key != want[i].key || string(s.text) != string(want[i].text) {
+			t.Errorf("section %d = %s %q, want %s %q", i, s.key, s.text, want[i].key, want[i].text)
+		}
+	}
+
+	// Putting the sections back in place of their includes gives the
+	// original document.
+	files := map[string]string{}
+	for _, s := range sections {
+		files[path.Join("conf", s.file())] = string(s.text)
+	}
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(string(index), "\n") {
+		if name := strings.TrimSuffix(strings.TrimPrefix(line, `include "`), "\"\n"); files[name] != "" {
+			line = files[name]
+		}
+		b.WriteString(line)
+	}
+	if b.String() != src {
+		t.Errorf("inlined split = %q, want %q", b.String(), src)
+	}
+}
+
+func TestSplitConfigRepeatedKey(t *testing.T) {
+	index, sections, err := splitConfig([]byte("a = 1\n\nb = 2\n\na = 3\n"), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "include \"a.conf\"\n\ninclude \"b.conf\"\n"; string(index) != want {
+		t.Errorf("index = %q, want %q", index, want)
+	}
+	if len(sections) != 2 {
+		t.Fatalf("got %d sections, want 2", len(sections))
+	}
+	if want := "a = 1\n\na = 3\n"; string(sections[0].text) != want {
+		t.Errorf("%s = %q, want %q", sections[0].file(), sections[0].text, want)
+	}
+}
+
+// TestSplitConfigInclude checks that a field after an include stays
+// after it, so that it still overrides what the include sets.
+func TestSplitConfigInclude(t *testing.T) {
+	index, sections, err := splitConfig([]byte("a = 1\nb = 2\ninclude \"x.conf\"\na = 3\na = 4\nb = 5\n"), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantIndex = "include \"a.conf\"\ninclude \"b.conf\"\ninclude \"x.conf\"\ninclude \"a.2.conf\"\ninclude \"b.2.conf\"\n"
+	if string(index) != wantIndex {
+		t.Errorf("index = %q, want %q", index, wantIndex)
+	}
+	want := map[string]string{"a.conf": "a = 1\n", "b.conf": "b = 2\n", "a.2.conf": "a = 3\n\na = 4\n", "b.2.conf": "b = 5\n"}
+	if len(sections) != len(want) {
+		t.Fatalf("got %d sections, want %d", len(sections), len(want))
+	}
+	for _, s := range sections {
+		if string(s.text) != want[s.file()] {
+			t.Errorf("%s = %q, want %q", s.file(), s.text, want[s.file()])
+		}
+	}
+
+	if _, _, err := splitConfig([]byte("\"a.2\" = 1\na = 2\ninclude \"x.conf\"\na = 3\n"), "."); err == nil {
+		t.Error("splitConfig wrote two sections to a.2.conf")
+	}
+}
+
+func TestSplitConfigBadKey(t *testing.T) {
+	if _, _, err := splitConfig([]byte("\"a/b\" = 1\n"), "."); err == nil {
+		t.Error("splitConfig accepted a key with a '/'")
+	}
+}
+
+func TestWriteSplit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(d string) { *splitDir, *splitOvr = d, false }(*splitDir)
+	*splitDir = dir
+	input := filepath.Join(dir, "app.conf")
+	if err := ioutil.WriteFile(input, []byte("app.name = x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A section named like the input may not replace it, even with
+	// -split-overwrite.
+	*splitOvr = true
+	if _, err := writeSplit(input, []byte("app.name = x\n")); err == nil {
+		t.Error("writeSplit overwrote its input")
+	}
+	if data, _ := ioutil.ReadFile(input); string(data) != "app.name = x\n" {
+		t.Errorf("input = %q after split", data)
+	}
+
+	*splitOvr = false
+	if _, err := writeSplit("in.conf", []byte("a = 1\nb = 2\n")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "b.conf")); string(data) != "b = 2\n" {
+		t.Errorf("b.conf = %q, want %q", data, "b = 2\n")
+	}
+	// Nothing is written if any section file exists.
+	if _, err := writeSplit("in.conf", []byte("c = 3\na = 4\n")); err == nil {
+		t.Error("writeSplit replaced a.conf without -split-overwrite")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "c.conf")); err == nil {
+		t.Error("writeSplit wrote c.conf before refusing a.conf")
+	}
+	*splitOvr = true
+	if _, err := writeSplit("in.conf", []byte("a = 4\n")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "a.conf")); string(data) != "a = 4\n" {
+		t.Errorf("a.conf = %q, want %q", data, "a = 4\n")
+	}
+}
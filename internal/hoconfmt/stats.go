@@ -0,0 +1,104 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// configStats holds the structural metrics reported by -stats.
+type configStats struct {
+	File          string `json:"file"`
+	Keys          int    `json:"keys"` // key definitions, counting a.b.c = 1 once
+	MaxDepth      int    `json:"maxDepth"`
+	Substitutions int    `json:"substitutions"`
+	Includes      int    `json:"includes"`
+	Objects       int    `json:"objects"` // not counting the root object
+	Arrays        int    `json:"arrays"`
+}
+
+// collectStats computes the metrics of src.
+func collectStats(src []byte) (configStats, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return configStats{}, err
+	}
+
+	var st configStats
+	var open []tokenKind // enclosing { and [
+	atField := true      // at the start of a field in an object
+	for i, t := range toks {
+		inObject := len(open) == 0 || open[len(open)-1] == tokLBrace
+		if atField && inObject && (t.kind == tokUnquoted || t.kind == tokString) {
+			if t.lit == "include" && i+2 < len(toks) && toks[i+1].kind == tokSpace &&
+				(toks[i+2].kind == tokString || toks[i+2].kind == tokUnquoted) {
+				st.Includes++
+			} else if keyEnd, _, _ := objectField(toks, i); keyEnd > 0 || isAssignment(toks, i) {
+				st.Keys++
+			}
+		}
+
+		switch t.kind {
+		case tokLBrace, tokLBracket:
+			open = append(open, t.kind)
+			if len(open) > st.MaxDepth {
+				st.MaxDepth = len(open)
+			}
+			if t.kind == tokLBrace {
+				st.Objects++
+			} else {
+				st.Arrays++
+			}
+			atField = t.kind == tokLBrace
+		case tokRBrace, tokRBracket:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			atField = true
+		case tokNewline, tokComma:
+			atField = true
+		case tokSubst:
+			st.Substitutions++
+			atField = false
+		case tokSpace, tokComment:
+		default:
+			atField = false
+		}
+	}
+	return st, nil
+}
+
+// isAssignment reports whether the key starting at toks[i] is followed
+// by a separator.
+func isAssignment(toks []tok, i int) bool {
+	return fieldSep(toks, i) > 0
+}
+
+// fieldSep returns the index of the separator following the key that
+// starts at toks[i], or 0 if the key is not followed by one.
+func fieldSep(toks []tok, i int) int {
+	for ; i < len(toks); i++ {
+		switch toks[i].kind {
+		case tokUnquoted, tokString, tokSpace:
+			continue
+		case tokSep:
+			return i
+		}
+		return 0
+	}
+	return 0
+}
+
+func printStats(out io.Writer, st configStats) error {
+	if *jsonReport {
+		data, err := json.Marshal(st)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", data)
+		return err
+	}
+	_, err := fmt.Fprintf(out, "%s:\n\tkeys: %d\n\tmax depth: %d\n\tsubstitutions: %d\n\tincludes: %d\n\tobjects: %d\n\tarrays: %d\n",
+		st.File, st.Keys, st.MaxDepth, st.Substitutions, st.Includes, st.Objects, st.Arrays)
+	return err
+}
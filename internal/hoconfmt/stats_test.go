@@ -0,0 +1,48 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCollectStats(t *testing.T) {
+	const src = `include "base.conf"
+app {
+    name = demo
+    home = ${HOME}
+    db { url = ${?DB_URL}, pool.max = 10 }
+    servers = [ { host = a }, { host = b, tags = [x, y] } ]
+}
+"quoted key": 1
+include required(file("local.conf"))
+`
+	got, err := collectStats([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := configStats{
+		Keys:          11,
+		MaxDepth:      4,
+		Substitutions: 2,
+		Includes:      2,
+		Objects:       4,
+		Arrays:        2,
+	}
+	if got != want {
+		t.Errorf("collectStats = %+v, want %+v", got, want)
+	}
+}
+
+func TestPrintStatsJSON(t *testing.T) {
+	*jsonReport = true
+	defer func() { *jsonReport = false }()
+
+	var buf bytes.Buffer
+	if err := printStats(&buf, configStats{File: "a.conf", Keys: 1, MaxDepth: 2}); err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"file":"a.conf","keys":1,"maxDepth":2,"substitutions":0,"includes":0,"objects":0,"arrays":0}` + "\n"
+	if buf.String() != want {
+		t.Errorf("got %s, want %s", buf.String(), want)
+	}
+}
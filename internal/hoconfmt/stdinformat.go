@@ -0,0 +1,43 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Values of the -format-stdin-as flag.
+const (
+	stdinHOCON      = "hocon"
+	stdinJSON       = "json"
+	stdinProperties = "properties"
+)
+
+func checkStdinFormat(format string) error {
+	switch format {
+	case stdinHOCON, stdinJSON, stdinProperties:
+		return nil
+	}
+	return fmt.Errorf("invalid -format-stdin-as %q: must be %s, %s or %s", format, stdinHOCON, stdinJSON, stdinProperties)
+}
+
+// stdinOptions adjusts opts to read src, the contents of standard input,
+// as the given format. JSON is valid HOCON and is formatted as such, but
+// it must be valid JSON; .properties input is converted.
+func stdinOptions(src []byte, format string, opts Options) (Options, error) {
+	switch format {
+	case stdinJSON:
+		var v interface{}
+		if err := json.Unmarshal(src, &v); err != nil {
+			off := len(src)
+			if e, ok := err.(*json.SyntaxError); ok && e.Offset > 0 && int(e.Offset) <= len(src) {
+				off = int(e.Offset) - 1
+			}
+			var errs errorList
+			errs.Add(position(src, off), codeSyntax, "invalid JSON: "+err.Error())
+			return opts, errs
+		}
+	case stdinProperties:
+		opts.FromProperties = true
+	}
+	return opts, nil
+}
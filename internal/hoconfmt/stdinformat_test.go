@@ -0,0 +1,49 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFormatStdinAs(t *testing.T) {
+	defer func() { *stdinFormat = stdinHOCON }()
+	for _, test := range []struct {
+		format, in, want string
+	}{
+		{stdinHOCON, "a = 1\n", "a = 1\n"},
+		{stdinJSON, `{"a": {"b": [1, 2]}, "c": "x"}` + "\n", "{ a { b = [1, 2] }, c = \"x\" }\n"},
+		{stdinProperties, "server.port=8080\nserver.host=localhost\n", "server {\n    port = 8080\n    host = \"localhost\"\n}\n"},
+	} {
+		*stdinFormat = test.format
+		var buf bytes.Buffer
+		if err := processFile("<standard input>", strings.NewReader(test.in), &buf); err != nil {
+			t.Errorf("%s: %v", test.format, err)
+			continue
+		}
+		if buf.String() != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.format, buf.String(), test.want)
+		}
+	}
+}
+
+func TestFormatStdinAsInvalidJSON(t *testing.T) {
+	*stdinFormat = stdinJSON
+	defer func() { *stdinFormat = stdinHOCON }()
+	var buf bytes.Buffer
+	err := processFile("<standard input>", strings.NewReader("{\n  a = 1\n}\n"), &buf)
+	if err == nil || !strings.HasPrefix(err.Error(), "<standard input>:2:3: invalid JSON") {
+		t.Errorf("got error %v, want invalid JSON at 2:3", err)
+	}
+}
+
+func TestCheckStdinFormat(t *testing.T) {
+	for _, f := range []string{stdinHOCON, stdinJSON, stdinProperties} {
+		if err := checkStdinFormat(f); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := checkStdinFormat("yaml"); err == nil {
+		t.Error("no error for yaml")
+	}
+}
@@ -0,0 +1,273 @@
+package hoconfmt
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// streamMin is the number of elements from which streamFile writes an
+// array element by element.
+const streamMin = 10000
+
+// A longArray is an array of src with at least streamMin elements, each
+// a number or a plain string on a line of its own, as in generated
+// lists of ids or hosts.
+type longArray struct {
+	start  int // offset of the line of the first element
+	penult int // offset of the line of the next to last element
+	last   int // offset of the line of the last element
+}
+
+// streamFile is formatFile writing the result to w, for plain standard
+// output, where nothing compares the result with src. If src holds a
+// long array, the document is formatted with only two elements at each
+// end of it, and the elements between are written to w one by one in
+// the layout those got, so neither the formatted array nor the passes
+// over it are held in memory. Any other input, and input whose format
+// differs from that of a few of its elements, is formatted as a whole.
+func streamFile(w io.Writer, filename string, src []byte, opts Options) error {
+	if a, ok := findLongArray(src); ok && canStream(opts) {
+		bw := bufio.NewWriter(w)
+		if streamArray(bw, filename, src, a, opts) {
+			return bw.Flush()
+		}
+	}
+	res, err := formatFile(filename, src, opts)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(res)
+	return err
+}
+
+// canStream reports whether opts lay out every element of a long array
+// alike, with nothing depending on the other elements or on the key
+// path of the array.
+func canStream(opts Options) bool {
+	return !opts.Touch && !opts.Reprint && !opts.FromProperties && !opts.FixQuotes && !opts.ModifiedBlocksOnly &&
+		opts.ArrayPerLine == 0 && opts.ArrayColumns == 0 && opts.ContinuationIndent == "" &&
+		opts.SortArrays == "" && opts.Preserve == "" && opts.IncludeKeys == "" && opts.ExcludeKeys == "" &&
+		opts.Rewrite == "" && opts.EOL != eolKeep && opts.UnitClassifier == nil && opts.ValueTransformer == nil
+}
+
+// streamArray formats src, which holds the long array a, writing the
+// result to w, and reports whether it did. It writes nothing if the
+// shortened document has warnings or errors, whose positions would be
+// those of the shortened document, or if its four elements are not laid
+// out one to a line in the same way.
+func streamArray(w io.Writer, filename string, src []byte, a longArray, opts Options) bool {
+	second := nextLine(src, a.start)
+	sample := append(src[:nextLine(src, second):nextLine(src, second)], src[a.penult:]...)
+	var warnings warningList
+	opts.Warnings = &warnings
+	res, err := formatFile(filename, sample, opts)
+	if err != nil || len(warnings) > 0 {
+		return false
+	}
+
+	var values [4][]byte
+	for i, off := range []int{a.start, second, a.penult, a.last} {
+		values[i], _, _ = arrayElement(src[off:nextLine(src, off)])
+	}
+	lines := bytes.SplitAfter(res, []byte("\n"))
+	at := -1 // index of the line of the first element in res
+	var indent, suffix []byte
+	for i := 0; i+len(values) <= len(lines); i++ {
+		in, suf, ok := elementLayout(lines[i+1], values[1])
+		if !ok || !bytes.Equal(lineEOL(lines[i]), lineEOL(lines[i+1])) {
+			continue
+		}
+		if !sameLayout(lines[i], values[0], in, suf) || !sameLayout(lines[i+2], values[2], in, suf) {
+			continue
+		}
+		if _, _, ok := elementLayout(lines[i+3], values[3]); !ok {
+			continue
+		}
+		if at >= 0 {
+			// The elements are found twice, so which of them
+			// are those of the array is not known.
+			return false
+		}
+		at, indent, suffix = i, in, suf
+	}
+	if at < 0 {
+		return false
+	}
+
+	eol := lineEOL(lines[at])
+	for _, l := range lines[:at] {
+		w.Write(l)
+	}
+	for off := a.start; off < a.last; off = nextLine(src, off) {
+		v, _, _ := arrayElement(src[off:nextLine(src, off)])
+		w.Write(indent)
+		w.Write(v)
+		w.Write(suffix)
+		w.Write(eol)
+	}
+	for _, l := range lines[at+3:] {
+		w.Write(l)
+	}
+	return true
+}
+
+// findLongArray returns the first long array of src. Its elements must
+// be all numbers or all strings, those but the last must all end in a
+// comma or all not, and its [ must end the line before them and its ]
+// start the line after them.
+func findLongArray(src []byte) (longArray, bool) {
+	var a longArray
+	open := false // the line before ends in [
+	n := 0        // number of elements since a.start
+	var first, prev, quoted bool
+	for off := 0; off < len(src); off = nextLine(src, off) {
+		line := src[off:nextLine(src, off)]
+		if v, comma, ok := arrayElement(line); ok && (open || n > 0) {
+			switch {
+			case n == 0:
+				a.start, first, quoted = off, comma, v[0] == '"'
+			case prev != first || (v[0] == '"') != quoted:
+				// An element differs from those before.
+				open, n = false, 0
+				continue
+			}
+			a.penult, a.last = a.last, off
+			prev = comma
+			n++
+			continue
+		}
+		text := bytes.TrimSpace(line)
+		if n >= streamMin && len(text) > 0 && text[0] == ']' && opensArray(src[:a.start]) {
+			return a, true
+		}
+		open, n = len(text) > 0 && text[len(text)-1] == '[', 0
+	}
+	return longArray{}, false
+}
+
+// opensArray reports whether head, the text before the elements of an
+// array, ends in its [ rather than in a comment, string or off region.
+func opensArray(head []byte) bool {
+	if bytes.Contains(head, []byte(formatOff)) {
+		return false
+	}
+	toks, err := tokenize(head)
+	if err != nil {
+		return false
+	}
+	for i := len(toks) - 1; i >= 0; i-- {
+		switch toks[i].kind {
+		case tokSpace, tokNewline:
+		case tokLBracket:
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// arrayElement returns the element of line if it is a lone number, as
+// in 42 or -1.5, or a string of printable ASCII without escapes, with
+// an optional comma, and whether it ends in the comma. Formatting keeps
+// white space after an element as it is written, so a line with any is
+// not one of a long array.
+func arrayElement(line []byte) (value []byte, comma, ok bool) {
+	i := skipBlanks(line, 0)
+	start := i
+	switch {
+	case i < len(line) && line[i] == '"':
+		for i++; i < len(line) && line[i] != '"'; i++ {
+			if line[i] < ' ' || line[i] > '~' || line[i] == '\\' {
+				return nil, false, false
+			}
+		}
+		if i == len(line) {
+			return nil, false, false
+		}
+		i++
+	default:
+		if i < len(line) && line[i] == '-' {
+			i++
+		}
+		i = skipDigits(line, i)
+		if i < len(line) && line[i] == '.' {
+			i = skipDigits(line, i+1)
+			if line[i-1] == '.' {
+				return nil, false, false
+			}
+		}
+		if i == start || line[i-1] == '-' {
+			return nil, false, false
+		}
+	}
+	value = line[start:i]
+	if i < len(line) && line[i] == ',' {
+		comma = true
+		i++
+	}
+	if i < len(line) && line[i] == '\r' {
+		i++
+	}
+	if i < len(line) && line[i] == '\n' {
+		i++
+	}
+	return value, comma, i == len(line)
+}
+
+// elementLayout splits line, a line of formatted output holding the
+// element v, into the indentation before v and the comma, if any, after
+// it.
+func elementLayout(line, v []byte) (indent, suffix []byte, ok bool) {
+	text := bytes.TrimSuffix(line, lineEOL(line))
+	i := skipBlanks(text, 0)
+	if !bytes.HasPrefix(text[i:], v) {
+		return nil, nil, false
+	}
+	suffix = text[i+len(v):]
+	if len(suffix) > 0 && string(suffix) != "," {
+		return nil, nil, false
+	}
+	return text[:i], suffix, true
+}
+
+// sameLayout reports whether line holds the element v laid out with
+// indent and suffix.
+func sameLayout(line, v, indent, suffix []byte) bool {
+	in, suf, ok := elementLayout(line, v)
+	return ok && bytes.Equal(in, indent) && bytes.Equal(suf, suffix)
+}
+
+// lineEOL returns the line ending of line, "\r\n", "\n" or nothing.
+func lineEOL(line []byte) []byte {
+	switch {
+	case bytes.HasSuffix(line, []byte("\r\n")):
+		return line[len(line)-2:]
+	case bytes.HasSuffix(line, []byte("\n")):
+		return line[len(line)-1:]
+	}
+	return nil
+}
+
+// nextLine returns the offset just past the line of src starting at off.
+func nextLine(src []byte, off int) int {
+	if i := bytes.IndexByte(src[off:], '\n'); i >= 0 {
+		return off + i + 1
+	}
+	return len(src)
+}
+
+func skipBlanks(b []byte, i int) int {
+	for i < len(b) && (b[i] == ' ' || b[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+func skipDigits(b []byte, i int) int {
+	for i < len(b) && '0' <= b[i] && b[i] <= '9' {
+		i++
+	}
+	return i
+}
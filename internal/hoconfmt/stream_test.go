@@ -0,0 +1,133 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// longList returns the lines of streamMin elements that elem writes.
+func longList(elem func(i int) string) string {
+	var b strings.Builder
+	for i := 0; i < streamMin; i++ {
+		b.WriteString(elem(i))
+	}
+	return b.String()
+}
+
+// TestStreamFile checks that streaming a long array gives what
+// formatFile returns, and that input that cannot be streamed is
+// formatted as a whole.
+func TestStreamFile(t *testing.T) {
+	numbers := longList(func(i int) string { return fmt.Sprintf("%d\n", i) })
+	strs := longList(func(i int) string { return fmt.Sprintf("\t\"host-%d\",\n", i) })
+	mixed := longList(func(i int) string {
+		if i%2 == 0 {
+			return fmt.Sprintf("  %d,\n", i)
+		}
+		return fmt.Sprintf("  -%d.5\n", i)
+	})
+	kinds := longList(func(i int) string {
+		if i == streamMin/2 {
+			return "\"x\"\n"
+		}
+		return fmt.Sprintf("%d\n", i)
+	})
+	crlf := longList(func(i int) string { return fmt.Sprintf("  %d,\r\n", i) })
+
+	indent := DefaultOptions()
+	indent.Indent = "\t"
+	smart := DefaultOptions()
+	smart.Commas = commasSmart
+	sorted := DefaultOptions()
+	sorted.SortKeys = true
+	toCRLF := DefaultOptions()
+	toCRLF.EOL = eolCRLF
+	columns := DefaultOptions()
+	columns.ArrayColumns = 8
+
+	for _, test := range []struct {
+		name    string
+		src     string
+		opts    Options
+		streams bool
+	}{
+		{"numbers", "ids = [\n" + numbers + "]\n", DefaultOptions(), true},
+		{"nested", "a {\nz = 1\n  b = [\n" + strs + "    ]\n  c = [1, 2]\n}\n", indent, true},
+		{"smart commas", "a = [\n" + strs + "]\nb = 1\n", smart, true},
+		{"sorted", "z = 1\nids = [ \n" + numbers + "] # ids\na = 2\n", sorted, true},
+		{"crlf", "ids = [\r\n" + crlf + "]\r\n", toCRLF, true},
+		{"columns", "ids = [\n" + numbers + "]\n", columns, false},
+		{"mixed commas", "ids = [\n" + mixed + "]\n", DefaultOptions(), false},
+		{"numbers and strings", "ids = [\n" + kinds + "]\n", DefaultOptions(), false},
+		{"comment", "# ids = [\n" + numbers + "]\n", DefaultOptions(), false},
+		{"off", "# hoconfmt:off\nids = [\n" + numbers + "]\n# hoconfmt:on\n", DefaultOptions(), false},
+		{"twice", "a = [\n" + numbers + "]\nb = [\n0\n1\n9998\n9999\n]\n", DefaultOptions(), false},
+		{"error", "ids = [\n" + numbers + "]\nb = {\n", DefaultOptions(), false},
+	} {
+		src := []byte(test.src)
+		want, werr := formatFile("stream.conf", src, test.opts)
+		var got bytes.Buffer
+		gerr := streamFile(&got, "stream.conf", src, test.opts)
+		if fmt.Sprint(gerr) != fmt.Sprint(werr) {
+			t.Errorf("%s: streamFile error %v, formatFile error %v", test.name, gerr, werr)
+			continue
+		}
+		if werr == nil && !bytes.Equal(got.Bytes(), want) {
+			t.Errorf("%s: streamFile wrote\n%.300q\nformatFile returned\n%.300q", test.name, got.Bytes(), want)
+		}
+		a, ok := findLongArray(src)
+		streams := ok && canStream(test.opts) && streamArray(ioutil.Discard, "stream.conf", src, a, test.opts)
+		if streams != test.streams {
+			t.Errorf("%s: streams = %t, want %t", test.name, streams, test.streams)
+		}
+	}
+
+	match, err := filepath.Glob("testdata/*.input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range match {
+		src, err := ioutil.ReadFile(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := DefaultOptions()
+		opts.Warnings = ioutil.Discard
+		want, werr := formatFile(in, src, opts)
+		var got bytes.Buffer
+		gerr := streamFile(&got, in, src, opts)
+		if fmt.Sprint(gerr) != fmt.Sprint(werr) || !bytes.Equal(got.Bytes(), want) {
+			t.Errorf("%s: streamFile gives %q, %v; formatFile %q, %v", in, got.Bytes(), gerr, want, werr)
+		}
+	}
+}
+
+func TestArrayElement(t *testing.T) {
+	for _, test := range []struct {
+		line, value string
+		comma, ok   bool
+	}{
+		{"  42\n", "42", false, true},
+		{"\t-1.5,\r\n", "-1.5", true, true},
+		{"1 ,\n", "", false, false},
+		{"1, \n", "", false, false},
+		{`"a b",`, `"a b"`, true, true},
+		{"1.\n", "", false, false},
+		{"-\n", "", false, false},
+		{"1 2\n", "", false, false},
+		{`"a\"b"` + "\n", "", false, false},
+		{"\"unterminated\n", "", false, false},
+		{"abc\n", "", false, false},
+		{"1 # one\n", "", false, false},
+		{"\n", "", false, false},
+	} {
+		value, comma, ok := arrayElement([]byte(test.line))
+		if ok != test.ok || ok && (string(value) != test.value || comma != test.comma) {
+			t.Errorf("arrayElement(%q) = %q, %t, %t, want %q, %t, %t", test.line, value, comma, ok, test.value, test.comma, test.ok)
+		}
+	}
+}
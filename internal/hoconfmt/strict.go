@@ -0,0 +1,183 @@
+package hoconfmt
+
+import (
+	"strings"
+)
+
+// checkStrict reports the constructs of src that hoconfmt accepts but
+// the HOCON specification does not, for -strict:
+//
+//	include file.conf    an include target without quotes
+//	a                    a key whose separator is on a later line
+//	= 1
+//	b =                  a separator whose value is on a later line
+//	2
+//	/* note */           a block comment
+//	c [1]                an array after its key without a separator
+//
+// By default the first two are fixed while formatting, see
+// quoteIncludes and joinSeparators, block comments are kept as they
+// are with a warning, and arrays are kept after their keys.
+func checkStrict(src []byte) error {
+	errs, err := blockComments(nil, src)
+	if err != nil {
+		return err
+	}
+	toks, err := tokenize(src)
+	if err != nil {
+		return err
+	}
+
+	var open []tokenKind // enclosing { and [
+	atField := true      // at the start of a field in an object
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		inObject := len(open) == 0 || open[len(open)-1] == tokLBrace
+		if atField && inObject && t.kind == tokUnquoted && t.lit == "include" && fieldSep(toks, i) == 0 {
+			if unquotedInclude(toks, i) > 0 {
+				errs.Add(position(src, toks[i+2].off), codeStrict, "include target must be a quoted string")
+			}
+		} else if atField && inObject && (t.kind == tokUnquoted || t.kind == tokString) {
+			atField = false
+			j := i
+			for j < len(toks) && (toks[j].kind == tokUnquoted || toks[j].kind == tokString || toks[j].kind == tokSpace) {
+				j++
+			}
+			if k := skipNewlines(toks, j); k > j && k < len(toks) && toks[k].kind == tokSep {
+				errs.Add(position(src, toks[k].off), codeStrict, "separator must be on the line of its key")
+				j = k
+			}
+			if j < len(toks) && toks[j].kind == tokLBracket {
+				errs.Add(position(src, toks[j].off), codeStrict, "separator must come before an array")
+			}
+			if j < len(toks) && toks[j].kind == tokSep {
+				if k := skipNewlines(toks, j+1); k > j+1 && k < len(toks) && startsValue(toks[k]) {
+					errs.Add(position(src, toks[k].off), codeStrict, "value must be on the line of its separator")
+				}
+				j++
+			}
+			i = j - 1
+			continue
+		}
+
+		switch t.kind {
+		case tokLBrace, tokLBracket:
+			open = append(open, t.kind)
+			atField = t.kind == tokLBrace
+		case tokRBrace, tokRBracket:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			atField = true
+		case tokNewline, tokComma:
+			atField = true
+		case tokSpace, tokComment:
+		default:
+			atField = false
+		}
+	}
+	errs.Sort()
+	return errs.Err()
+}
+
+// checkSyntax reports the syntax errors of src, such as a key without
+// a value or a value without a key, which the passes of formatting,
+// working line by line, would keep as they are. Block comments, which
+// are kept as text, are left out of the check.
+func checkSyntax(lx *lexer, src []byte) error {
+	src, err := blankBlockComments(lx, src)
+	if err != nil {
+		return err
+	}
+	_, err = lx.parse(src)
+	return err
+}
+
+// blockComments returns the position of every /* of src outside strings
+// and comments. HOCON has only line comments, and * may not appear in
+// unquoted text, but some tools accept C-style block comments; hoconfmt
+// reads them as text and leaves them as written.
+func blockComments(lx *lexer, src []byte) (errorList, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	var list errorList
+	for _, t := range toks {
+		if t.kind != tokUnquoted {
+			continue
+		}
+		for i := strings.Index(t.lit, "/*"); i >= 0; {
+			list.Add(position(src, t.off+i), codeStrict, "block comment: HOCON only has # and // comments")
+			j := strings.Index(t.lit[i+2:], "/*")
+			if j < 0 {
+				break
+			}
+			i += 2 + j
+		}
+	}
+	return list, nil
+}
+
+// blankBlockComments returns src with the block comments that
+// blockComments finds replaced by spaces, keeping their newlines, so
+// that parseDocument, which reads HOCON without them, can check the
+// syntax of the rest. As in format, a comment ends at the first */ in
+// unquoted text after its /*.
+func blankBlockComments(lx *lexer, src []byte) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]byte, 0, len(src))
+	open, start := false, 0
+	for _, t := range toks {
+		if t.kind != tokUnquoted {
+			if open {
+				res = append(res, spaceOut(t.lit)...)
+			} else {
+				res = append(res, t.lit...)
+			}
+			continue
+		}
+		for s := t.lit; s != ""; {
+			if !open {
+				i := strings.Index(s, "/*")
+				if i < 0 {
+					res = append(res, s...)
+					break
+				}
+				res = append(res, s[:i]...)
+				res = append(res, "  "...)
+				start = t.off + len(t.lit) - len(s) + i
+				s, open = s[i+2:], true
+				continue
+			}
+			i := strings.Index(s, "*/")
+			if i < 0 {
+				res = append(res, spaceOut(s)...)
+				break
+			}
+			res = append(res, spaceOut(s[:i+2])...)
+			s, open = s[i+2:], false
+		}
+	}
+	if open {
+		// Without its */ the comment would take the rest of the file.
+		var errs errorList
+		errs.Add(position(src, start), codeSyntax, "unterminated block comment")
+		return nil, errs
+	}
+	return res, nil
+}
+
+// spaceOut returns s with every byte but newlines replaced by a space.
+func spaceOut(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c != '\n' {
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
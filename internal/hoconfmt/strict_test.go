@@ -0,0 +1,111 @@
+package hoconfmt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckStrict(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		errs []string // messages, in order
+	}{
+		{"include file.conf\n", []string{"1:9: include target must be a quoted string"}},
+		{"include required(file.conf)\n", []string{"1:9: include target must be a quoted string"}},
+		{"a\n= 1\n", []string{"2:1: separator must be on the line of its key"}},
+		{"a {\n  b =\n    2\n}\n", []string{"3:5: value must be on the line of its separator"}},
+		{"a = [{ b\n: 1 }]\n", []string{"2:1: separator must be on the line of its key"}},
+		{"/* A block comment\n   over two lines. */\na = 1 /* one */\n", []string{"1:1: block comment: HOCON only has # and // comments", "3:7: block comment: HOCON only has # and // comments"}},
+		{"a [1]\nb {\n  c [2]\n}\n", []string{"1:3: separator must come before an array", "3:5: separator must come before an array"}},
+		// Valid HOCON passes.
+		{"a = \"/* not a comment */\"\n", nil},
+		{"include \"file.conf\"\ninclude required(\"x.conf\")\n", nil},
+		{"include = 1\na = 1\nb {\n  c = [\n    1\n  ]\n}\n", nil},
+		{"a = # note\n  1\n", nil},
+		{"a = 1\n", nil},
+	} {
+		err := checkStrict([]byte(test.src))
+		var msgs []string
+		if err != nil {
+			for _, e := range err.(errorList) {
+				msgs = append(msgs, e.Error())
+			}
+		}
+		if strings.Join(msgs, "\n") != strings.Join(test.errs, "\n") {
+			t.Errorf("checkStrict(%q) reports %q, want %q", test.src, msgs, test.errs)
+		}
+	}
+}
+
+func TestStrictFormat(t *testing.T) {
+	const src = "include defaults.conf\na\n= 1\n"
+	if _, err := formatFile("x.conf", []byte(src), DefaultOptions()); err != nil {
+		t.Fatalf("lenient formatting failed: %v", err)
+	}
+	opts := DefaultOptions()
+	opts.Strict = true
+	if _, err := formatFile("x.conf", []byte(src), opts); err == nil || !strings.HasPrefix(err.Error(), "x.conf:1:9: include target") {
+		t.Errorf("strict formatting: err = %v, want an error about the include", err)
+	}
+}
+
+func TestInvalidSyntax(t *testing.T) {
+	strict := DefaultOptions()
+	strict.Strict = true
+	for _, test := range []struct {
+		src     string
+		lenient string // the error without -strict, if any
+		strict  string
+	}{
+		{"a = 1\nb\n", "x.conf:2:1: unexpected b, expected a key", "x.conf:2:1: unexpected b, expected a key"},
+		{"a [1]\n", "", "x.conf:1:3: separator must come before an array"},
+		{"a = 1 = 2\n", "x.conf:1:7: unexpected =, expected a key", "x.conf:1:7: unexpected =, expected a key"},
+		{"= 1\n", "x.conf:1:1: unexpected =, expected a key", "x.conf:1:1: unexpected =, expected a key"},
+		{"a { b }\n", "x.conf:1:5: unexpected b, expected a key", "x.conf:1:5: unexpected b, expected a key"},
+		{"a {\n    b =\n}\n", "x.conf:2:5: b has no value", "x.conf:2:5: b has no value"},
+	} {
+		for _, mode := range []struct {
+			opts Options
+			want string
+		}{{DefaultOptions(), test.lenient}, {strict, test.strict}} {
+			_, err := formatFile("x.conf", []byte(test.src), mode.opts)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != mode.want {
+				t.Errorf("formatFile(%q) with Strict=%v: err = %q, want %q", test.src, mode.opts.Strict, got, mode.want)
+			}
+			r := checkFormat("x.conf", []byte(test.src), mode.opts, false)
+			if r.failed() != (mode.want != "") {
+				t.Errorf("checkFormat(%q) with Strict=%v: failed = %v, want %v", test.src, mode.opts.Strict, r.failed(), mode.want != "")
+			}
+		}
+	}
+}
+
+func TestBlankBlockComments(t *testing.T) {
+	const src = "/* a\n b */ a = 1 /* c */\nb = \"/* d */\"\n"
+	const want = "    \n      a = 1        \nb = \"/* d */\"\n"
+	got, err := blankBlockComments(nil, []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("blankBlockComments(%q) = %q, want %q", src, got, want)
+	}
+	if _, err := blankBlockComments(nil, []byte("a = 1\n/* b = 2\n")); err == nil || err.Error() != "2:1: unterminated block comment" {
+		t.Errorf("blankBlockComments of an open comment: err = %v, want 2:1: unterminated block comment", err)
+	}
+}
+
+func TestBlockCommentsKept(t *testing.T) {
+	const src = "/* Settings,\n   written by hand. */\na = 1 /* one */\nb = \"/* text */\"\n"
+	got, err := formatFile("x.conf", []byte(src), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != src {
+		t.Errorf("got %q, want it unchanged", got)
+	}
+}
@@ -0,0 +1,103 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// envName matches the paths that read as the names of environment
+// variables, such as HOME or JAVA_OPTS. HOCON looks a substitution that
+// the configuration does not define up in the environment.
+var envName = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// An undefinedSubst is a substitution of a path that the file does not
+// define.
+type undefinedSubst struct {
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Subst    string `json:"subst"`
+	Optional bool   `json:"optional"`    // ${?path}, which may be left unset
+	Env      bool   `json:"environment"` // the path names an environment variable
+}
+
+// A substReport is the result of -check-substs for one file.
+type substReport struct {
+	File   string           `json:"file"`
+	Substs []undefinedSubst `json:"substs"`
+}
+
+// undefinedSubsts returns the substitutions of src, read from filename,
+// whose path neither it nor the files it includes define, in order. A
+// path is defined if a field sets it or a field below it, so ${http}
+// is defined by http.port = 80. Includes are followed as -blame follows
+// them. Only the file itself is read for substitutions, and a file that
+// is included by another may refer to paths its includer defines.
+func undefinedSubsts(filename string, src []byte) ([]undefinedSubst, error) {
+	defs, err := collectDefinitions(filename, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	defined := map[string]bool{}
+	for _, d := range defs {
+		for n := 1; n <= len(d.path); n++ {
+			defined[renderPath(d.path[:n])] = true
+		}
+	}
+
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, withFilename(err, filename)
+	}
+	list := []undefinedSubst{}
+	for _, t := range toks {
+		if t.kind != tokSubst {
+			continue
+		}
+		s := trimSubst(t.lit)
+		optional := strings.HasPrefix(s, "${?")
+		path := strings.TrimPrefix(s[2:len(s)-1], "?")
+		if defined[renderPath(pathElems(path))] {
+			continue
+		}
+		pos := position(src, t.off)
+		list = append(list, undefinedSubst{pos.Line, pos.Column, s, optional, envName.MatchString(path)})
+	}
+	return list, nil
+}
+
+// broken reports whether the substitution u fails to resolve when
+// nothing else defines its path: it is required, and not one the
+// environment is expected to provide.
+func (u undefinedSubst) broken() bool {
+	return !u.Optional && !u.Env
+}
+
+// printSubsts writes the report of -check-substs.
+func printSubsts(out io.Writer, r substReport) error {
+	if *jsonReport {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", data)
+		return err
+	}
+	for _, u := range r.Substs {
+		var msg string
+		switch {
+		case u.broken():
+			msg = "undefined substitution " + u.Subst
+		case u.Env:
+			msg = u.Subst + " is not defined in the file and is read from the environment"
+		default:
+			msg = u.Subst + " is not defined in the file and is left unset"
+		}
+		if _, err := fmt.Fprintf(out, "%s:%d:%d: %s\n", r.File, u.Line, u.Column, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUndefinedSubsts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "base.conf"), []byte("db.host = localhost\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	const src = `include "base"
+http { port = 80 }
+a = ${http.port}
+b = ${http}
+c = ${db.host}
+d = ${ missing.path }
+e = ${?missing.optional}
+f = ${HOME}/app
+g = ${?JAVA_OPTS}
+h = ${"http".port}
+`
+	got, err := undefinedSubsts(filepath.Join(dir, "app.conf"), []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []undefinedSubst{
+		{6, 5, "${missing.path}", false, false},
+		{7, 5, "${?missing.optional}", true, false},
+		{8, 5, "${HOME}", false, true},
+		{9, 5, "${?JAVA_OPTS}", true, true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("substitution %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	var out bytes.Buffer
+	if err := printSubsts(&out, substReport{"app.conf", got}); err != nil {
+		t.Fatal(err)
+	}
+	const text = "app.conf:6:5: undefined substitution ${missing.path}\n" +
+		"app.conf:7:5: ${?missing.optional} is not defined in the file and is left unset\n" +
+		"app.conf:8:5: ${HOME} is not defined in the file and is read from the environment\n" +
+		"app.conf:9:5: ${?JAVA_OPTS} is not defined in the file and is read from the environment\n"
+	if out.String() != text {
+		t.Errorf("report:\n%s\nwant:\n%s", out.String(), text)
+	}
+}
+
+func TestCheckSubstsExitCode(t *testing.T) {
+	*checkSubst = true
+	defer func(code int) { *checkSubst = false; exitCode = code }(exitCode)
+	for _, test := range []struct {
+		src  string
+		code int
+	}{
+		{"a = 1\nb = ${a}\nc = ${?X.y}\nd = ${PATH}\n", 0},
+		{"a = 1\nb = ${x}\n", 1},
+	} {
+		exitCode = 0
+		if err := processFile("check.conf", bytes.NewBufferString(test.src), ioutil.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if exitCode != test.code {
+			t.Errorf("-check-substs on %q: exit code %d, want %d", test.src, exitCode, test.code)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package hoconfmt
+
+import "strings"
+
+// trimSubsts removes the white space just inside the braces of the
+// substitutions in the values of src, and between the ? of an optional
+// substitution and its path, so that
+//
+//	a = ${ ? db.host }
+//
+// becomes
+//
+//	a = ${?db.host}
+//
+// White space inside the path is part of its elements and is kept.
+// Substitutions in keys are quoted by quoteSubstKeys before this runs,
+// so they are left alone.
+func trimSubsts(src []byte) ([]byte, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []byte
+	for _, t := range toks {
+		if t.kind == tokSubst {
+			res = append(res, trimSubst(t.lit)...)
+		} else {
+			res = append(res, t.lit...)
+		}
+	}
+	return res, nil
+}
+
+// trimSubst returns the substitution s, such as "${ ?a.b }", without
+// the white space around its path.
+func trimSubst(s string) string {
+	path := strings.TrimSpace(s[2 : len(s)-1])
+	if !strings.HasPrefix(path, "?") {
+		return "${" + path + "}"
+	}
+	return "${?" + strings.TrimSpace(path[1:]) + "}"
+}
+
+// snapshotEnv replaces the optional substitutions of environment
+// variables in src that lookup resolves with the value they have now,
+// and notes the substitution in a comment after the value, so that
+//
+//	host = ${?DB_HOST}
+//
+// becomes
+//
+//	host = "db.internal" # was ${?DB_HOST}
+//
+// Only a substitution of a single unquoted name that is the whole value
+// of a field ending its line is replaced; substitutions that are part
+// of a concatenation or an array, that lookup does not resolve, or that
+// already have a comment on their line are left as they are.
+func snapshotEnv(src []byte, lookup func(string) (string, bool)) ([]byte, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[int]string{} // replacement, by index of the substitution
+	notes := map[int]string{}  // comment, by index of the end of the line
+	walkFields(toks, func(f field) {
+		if f.object || f.end != f.value+1 || toks[f.value].kind != tokSubst {
+			return
+		}
+		subst := trimSubst(toks[f.value].lit)
+		if !strings.HasPrefix(subst, "${?") {
+			return
+		}
+		name := subst[3 : len(subst)-1]
+		if name == "" || strings.ContainsAny(name, forbiddenUnquoted) {
+			return
+		}
+		j := f.end
+		for j < len(toks) && (toks[j].kind == tokSpace || toks[j].kind == tokComma) {
+			j++
+		}
+		if j < len(toks) && toks[j].kind != tokNewline {
+			return
+		}
+		v, ok := lookup(name)
+		if !ok {
+			return
+		}
+		values[f.value] = quoteString(v)
+		notes[j] = " # was " + subst
+	})
+
+	var res []byte
+	for i, t := range toks {
+		if n, ok := notes[i]; ok {
+			res = append(res, n...)
+		}
+		if v, ok := values[i]; ok {
+			res = append(res, v...)
+		} else {
+			res = append(res, t.lit...)
+		}
+	}
+	if n, ok := notes[len(toks)]; ok {
+		res = append(res, n...)
+	}
+	return res, nil
+}
@@ -0,0 +1,75 @@
+package hoconfmt
+
+import (
+	"os"
+	"testing"
+)
+
+func TestTrimSubst(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"${a}", "${a}"},
+		{"${ a.b }", "${a.b}"},
+		{"${?a}", "${?a}"},
+		{"${ ? a }", "${?a}"},
+		{"${\ta b\t}", "${a b}"},
+		{`${ "x y" }`, `${"x y"}`},
+	} {
+		if got := trimSubst(test.in); got != test.want {
+			t.Errorf("trimSubst(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestSnapshotEnv(t *testing.T) {
+	env := map[string]string{"HOST": "db.internal", "QUOTE": `say "hi"`, "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"a = ${?HOST}\n", "a = \"db.internal\" # was ${?HOST}\n"},
+		{"a = ${ ? HOST }", "a = \"db.internal\" # was ${?HOST}"},
+		{"a = ${?QUOTE}\n", "a = \"say \\\"hi\\\"\" # was ${?QUOTE}\n"},
+		{"a = ${?EMPTY}\n", "a = \"\" # was ${?EMPTY}\n"},
+		{"a = ${?UNSET}\n", "a = ${?UNSET}\n"},
+		{"a = ${HOST}\n", "a = ${HOST}\n"},       // required substitution
+		{"a = ${?a.HOST}\n", "a = ${?a.HOST}\n"}, // not an environment variable
+		{"a = ${?HOST}:5432\n", "a = ${?HOST}:5432\n"},
+		{"a = ${?HOST} # primary\n", "a = ${?HOST} # primary\n"},
+		{"a = [${?HOST}]\n", "a = [${?HOST}]\n"},
+		{"a { b = ${?HOST} }\n", "a { b = ${?HOST} }\n"},
+		{"a {\n    b = ${?HOST},\n}\n", "a {\n    b = \"db.internal\", # was ${?HOST}\n}\n"},
+	} {
+		got, err := snapshotEnv([]byte(test.in), lookup)
+		if err != nil {
+			t.Errorf("snapshotEnv(%q): %v", test.in, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("snapshotEnv(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestSnapshotEnvFormat(t *testing.T) {
+	const set, unset = "HOCONFMT_TEST_SET", "HOCONFMT_TEST_UNSET"
+	os.Setenv(set, "8080")
+	os.Unsetenv(unset)
+	defer os.Unsetenv(set)
+
+	opts := DefaultOptions()
+	opts.SnapshotEnv = true
+	src := "port = ${?" + set + "}\nhost = ${?" + unset + "}\n"
+	res, err := formatFile("test.conf", []byte(src), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "port = \"8080\" # was ${?" + set + "}\nhost = ${?" + unset + "}\n"
+	if string(res) != want {
+		t.Errorf("got %q, want %q", res, want)
+	}
+}
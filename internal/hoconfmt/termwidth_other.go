@@ -0,0 +1,9 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package hoconfmt
+
+import "os"
+
+// terminalWidth returns 0: the terminal size is only known on Unix
+// systems.
+func terminalWidth(f *os.File) int { return 0 }
@@ -0,0 +1,110 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// defaultTodoMarkers is the default value of -todo-markers.
+const defaultTodoMarkers = "TODO,FIXME,XXX"
+
+// A todo is a comment holding one of the -todo-markers.
+type todo struct {
+	Line   int    `json:"line"`
+	Column int    `json:"column"` // of the marker
+	Marker string `json:"marker"`
+	Text   string `json:"text"` // the rest of the comment
+}
+
+// todoReport is the result of -format-comments-as-todo for one file.
+type todoReport struct {
+	File  string `json:"file"`
+	Todos []todo `json:"todos"`
+}
+
+// todoMarkers splits the value of -todo-markers into its markers.
+func todoMarkers(list string) []string {
+	var markers []string
+	for _, m := range strings.Split(list, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			markers = append(markers, m)
+		}
+	}
+	return markers
+}
+
+// findTodos returns the comments of src that hold one of markers as a
+// word of their own, such as "# TODO: rotate keys" or
+// "a = 1 // FIXME(ops) too low", in order. Only the first marker of a
+// comment counts.
+func findTodos(src []byte, markers []string) ([]todo, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	todos := []todo{}
+	for _, t := range toks {
+		if t.kind != tokComment {
+			continue
+		}
+		if at, m := findMarker(t.lit, markers); at >= 0 {
+			pos := position(src, t.off+at)
+			text := strings.TrimLeft(t.lit[at+len(m):], ":")
+			todos = append(todos, todo{pos.Line, pos.Column, m, strings.TrimSpace(text)})
+		}
+	}
+	return todos, nil
+}
+
+// findMarker returns the offset in comment of the first of markers that
+// stands as a word of its own, and the marker, or -1.
+func findMarker(comment string, markers []string) (int, string) {
+	best, marker := -1, ""
+	for _, m := range markers {
+		for from := 0; ; {
+			i := strings.Index(comment[from:], m)
+			if i < 0 {
+				break
+			}
+			i += from
+			if !isWordByte(comment, i-1) && !isWordByte(comment, i+len(m)) {
+				if best < 0 || i < best {
+					best, marker = i, m
+				}
+				break
+			}
+			from = i + 1
+		}
+	}
+	return best, marker
+}
+
+// isWordByte reports whether s[i] is a letter, digit or underscore. It
+// is false outside of s.
+func isWordByte(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return false
+	}
+	c := s[i]
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_'
+}
+
+func printTodos(out io.Writer, r todoReport) error {
+	if *jsonReport {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", data)
+		return err
+	}
+	for _, t := range r.Todos {
+		if _, err := fmt.Fprintf(out, "%s:%d:%d: %s: %s\n", r.File, t.Line, t.Column, t.Marker, t.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
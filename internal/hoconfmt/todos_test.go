@@ -0,0 +1,63 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestFindTodos(t *testing.T) {
+	const src = `# TODO: split this file
+##########################
+# FIXME before the release
+##########################
+server {
+    port = 8080 // XXX temporary
+    # TODOS and NOFIXME are not markers
+    host = "# TODO inside a string"
+}
+`
+	got, err := findTodos([]byte(src), todoMarkers(defaultTodoMarkers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []todo{
+		{1, 3, "TODO", "split this file"},
+		{3, 3, "FIXME", "before the release"},
+		{6, 20, "XXX", "temporary"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findTodos = %+v, want %+v", got, want)
+	}
+
+	got, err = findTodos([]byte(src), todoMarkers(" NOTE, TODOS "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []todo{{7, 7, "TODOS", "and NOFIXME are not markers"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findTodos with custom markers = %+v, want %+v", got, want)
+	}
+}
+
+func TestPrintTodos(t *testing.T) {
+	r := todoReport{"a.conf", []todo{{2, 5, "TODO", "fix"}}}
+	var buf bytes.Buffer
+	if err := printTodos(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a.conf:2:5: TODO: fix\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	*jsonReport = true
+	defer func() { *jsonReport = false }()
+	buf.Reset()
+	if err := printTodos(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"file":"a.conf","todos":[{"line":2,"column":5,"marker":"TODO","text":"fix"}]}` + "\n"
+	if buf.String() != want {
+		t.Errorf("got %s, want %s", buf.String(), want)
+	}
+}
@@ -0,0 +1,140 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// toJSON converts the document src to JSON for -tojson, indenting each
+// level of nesting by indent. Its fields are merged as mergeDocument
+// merges them. Substitutions are not resolved: each becomes the string
+// of its text, as in "${db.host}", and so does a concatenation of text
+// with substitutions. Includes cannot be converted without following
+// them, and neither can concatenations of objects or arrays with
+// substitutions; both are reported as errors.
+func toJSON(src []byte, indent string) ([]byte, error) {
+	v, err := mergeDocument(src)
+	if err != nil {
+		return nil, err
+	}
+	return toJSONValue(src, v, indent)
+}
+
+// toJSONValue writes v, a value of the document src, as JSON, the way
+// toJSON writes documents.
+func toJSONValue(src []byte, v Value, indent string) ([]byte, error) {
+	var errs errorList
+	v = plainJSON(v, func(off int) {
+		errs.Add(position(src, off), codeConcatenation, "cannot convert a concatenation of objects or arrays with other values to JSON without resolving it")
+	})
+	if len(errs) > 0 {
+		errs.Sort()
+		return nil, errs
+	}
+
+	var b bytes.Buffer
+	writeJSON(&b, v, indent, 0)
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+// plainJSON returns v with its substitutions, and the concatenations of
+// text and substitutions, turned into strings. It calls fail with the
+// offset of every concatenation holding an object or array.
+func plainJSON(v Value, fail func(off int)) Value {
+	switch v := v.(type) {
+	case Subst:
+		return substText(v)
+	case concatenation:
+		var b strings.Builder
+		for _, p := range v.parts {
+			switch p := p.(type) {
+			case Subst:
+				b.WriteString(substText(p))
+			case string:
+				b.WriteString(p)
+			default:
+				fail(v.off)
+				return nil
+			}
+		}
+		return b.String()
+	case []Value:
+		elems := make([]Value, len(v))
+		for i, e := range v {
+			elems[i] = plainJSON(e, fail)
+		}
+		return elems
+	case Object:
+		obj := make(Object, len(v))
+		for i, f := range v {
+			obj[i] = Field{f.Key, plainJSON(f.Value, fail)}
+		}
+		return obj
+	}
+	return v
+}
+
+// substText returns s as written in a document, such as ${?HOME}.
+func substText(s Subst) string {
+	if s.Optional {
+		return "${?" + s.Path + "}"
+	}
+	return "${" + s.Path + "}"
+}
+
+// writeJSON writes v to b as JSON, with one element or field per line
+// and empty objects and arrays written as {} and [].
+func writeJSON(b *bytes.Buffer, v Value, indent string, depth int) {
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		if v {
+			b.WriteString("true")
+		} else {
+			b.WriteString("false")
+		}
+	case json.Number:
+		b.WriteString(string(v))
+	case string:
+		b.WriteString(quoteString(v))
+	case []Value:
+		if len(v) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+			b.WriteString(strings.Repeat(indent, depth+1))
+			writeJSON(b, e, indent, depth+1)
+		}
+		b.WriteByte('\n')
+		b.WriteString(strings.Repeat(indent, depth))
+		b.WriteByte(']')
+	case Object:
+		if len(v) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteByte('{')
+		for i, f := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+			b.WriteString(strings.Repeat(indent, depth+1))
+			b.WriteString(quoteString(f.Key))
+			b.WriteString(": ")
+			writeJSON(b, f.Value, indent, depth+1)
+		}
+		b.WriteByte('\n')
+		b.WriteString(strings.Repeat(indent, depth))
+		b.WriteByte('}')
+	}
+}
@@ -0,0 +1,63 @@
+package hoconfmt
+
+import "testing"
+
+func TestToJSON(t *testing.T) {
+	const src = `# comment
+db { host = localhost, port = 5432 }
+db.port = 5433
+url = "http://"${db.host}/x
+list = [1, {a = true}] [null]
+list += four
+"a.b" = """x "y" z"""
+empty {}
+`
+	const want = `{
+  "db": {
+    "host": "localhost",
+    "port": 5433
+  },
+  "url": "http://${db.host}/x",
+  "list": [
+    1,
+    {
+      "a": true
+    },
+    null,
+    "four"
+  ],
+  "a.b": "x \"y\" z",
+  "empty": {}
+}
+`
+	res, err := toJSON([]byte(src), "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != want {
+		t.Errorf("got\n%s\nwant\n%s", res, want)
+	}
+
+	res, err = toJSON([]byte("[1, [], \"a\" b]\n"), "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[\n  1,\n  [],\n  \"a b\"\n]\n"; string(res) != want {
+		t.Errorf("root array: got %q, want %q", res, want)
+	}
+}
+
+func TestToJSONErrors(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{`include "base.conf"`, `1:1: cannot merge an include statement without following it`},
+		{"a = ${b} { c = 1 }", `1:5: cannot convert a concatenation of objects or arrays with other values to JSON without resolving it`},
+		{"a = 1\na += 2", `2:1: cannot append to a, which is not an array`},
+		{"a = {", `1:5: { is not closed`},
+	}
+	for _, tt := range tests {
+		_, err := toJSON([]byte(tt.src), "  ")
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("toJSON(%q) = %v, want %s", tt.src, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// toProperties converts the document src to a Java .properties file for
+// -properties, with one key=value line for every value. Its fields are
+// merged as mergeDocument merges them, and the substitutions of the
+// paths it sets resolved; the others are written as their text, as in
+// ${HOME}. Objects flatten into dotted keys, and arrays into keys
+// numbered from 0, as in servers.0.host, or, if sep is not empty and
+// the array holds no objects or arrays, into one value joining its
+// elements with sep. Values are written as their text: null as an
+// empty value, strings without quotes. Empty objects and arrays have
+// no key to write and are left out. Comments are dropped, and include
+// statements are reported as errors.
+func toProperties(src []byte, sep string) ([]byte, error) {
+	v, err := mergeDocument(src)
+	if err != nil {
+		return nil, err
+	}
+	v = (&resolver{root: v, active: map[string]bool{}}).value(v, []string{})
+	var errs errorList
+	v = plainJSON(v, func(off int) {
+		errs.Add(position(src, off), codeConcatenation, "cannot convert a concatenation of objects or arrays with other values to .properties without resolving it")
+	})
+	if len(errs) > 0 {
+		errs.Sort()
+		return nil, errs
+	}
+
+	var b bytes.Buffer
+	writeProperties(&b, v, "", sep)
+	return b.Bytes(), nil
+}
+
+// writeProperties writes the lines of v, the value at the dotted key
+// prefix, to b.
+func writeProperties(b *bytes.Buffer, v Value, prefix, sep string) {
+	switch v := v.(type) {
+	case Object:
+		for _, f := range v {
+			writeProperties(b, f.Value, propertyKey(prefix, f.Key), sep)
+		}
+		return
+	case []Value:
+		if sep == "" || !scalarElems(v) {
+			for i, e := range v {
+				writeProperties(b, e, propertyKey(prefix, fmt.Sprint(i)), sep)
+			}
+			return
+		}
+		if len(v) == 0 {
+			return
+		}
+		texts := make([]string, len(v))
+		for i, e := range v {
+			texts[i] = propertyText(e)
+		}
+		fmt.Fprintf(b, "%s=%s\n", escapeProperty(prefix, true), escapeProperty(strings.Join(texts, sep), false))
+		return
+	}
+	fmt.Fprintf(b, "%s=%s\n", escapeProperty(prefix, true), escapeProperty(propertyText(v), false))
+}
+
+// propertyKey returns the dotted key of key in the object at prefix.
+func propertyKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// scalarElems reports whether elems holds no objects or arrays.
+func scalarElems(elems []Value) bool {
+	for _, e := range elems {
+		switch e.(type) {
+		case Object, []Value:
+			return false
+		}
+	}
+	return true
+}
+
+// propertyText returns the text of the value v, which is neither an
+// object nor an array.
+func propertyText(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case json.Number:
+		return string(v)
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+// escapeProperty escapes s as a key, if key is set, or a value of a
+// .properties file, the way Java's Properties.store does: white space
+// that ends a key or would be dropped before a value, the separators
+// and comment markers, control characters and characters outside
+// ASCII, which are written as \u escapes.
+func escapeProperty(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\f':
+			b.WriteString(`\f`)
+		case ' ':
+			if key || i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(' ')
+		case '=', ':', '#', '!':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			if r >= 0x20 && r < 0x7f {
+				b.WriteRune(r)
+				break
+			}
+			if r1, r2 := utf16.EncodeRune(r); r1 != unicode.ReplacementChar {
+				fmt.Fprintf(&b, `\u%04X\u%04X`, r1, r2)
+			} else {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,111 @@
+package hoconfmt
+
+import (
+	"go/printer"
+	"io/ioutil"
+	"testing"
+)
+
+func TestToProperties(t *testing.T) {
+	const src = `# comment
+db { host = localhost, port = 5432 }
+db.port = 5433
+url = "http://"${db.host}/x
+home = ${HOME}/app
+servers = [{ host = a }, { host = b }]
+tags = [x, y] [z]
+"key with: spaces=" = " lead\ttab"
+greeting = "héllo 😀"
+off = null
+empty {}
+`
+	const want = `db.host=localhost
+db.port=5433
+url=http\://localhost/x
+home=${HOME}/app
+servers.0.host=a
+servers.1.host=b
+tags.0=x
+tags.1=y
+tags.2=z
+key\ with\:\ spaces\==\ lead\ttab
+greeting=h\u00E9llo \uD83D\uDE00
+off=
+`
+	res, err := toProperties([]byte(src), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != want {
+		t.Errorf("got\n%s\nwant\n%s", res, want)
+	}
+
+	res, err = toProperties([]byte("tags = [x, y, 1]\nservers = [{ host = a }]\n"), ",")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "tags=x,y,1\nservers.0.host=a\n"; string(res) != want {
+		t.Errorf("with a separator: got %q, want %q", res, want)
+	}
+}
+
+func TestToPropertiesErrors(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{`include "base.conf"`, `1:1: cannot merge an include statement without following it`},
+		{"a = ${b} { c = 1 }", `1:5: cannot convert a concatenation of objects or arrays with other values to .properties without resolving it`},
+		{"a = {", `1:5: { is not closed`},
+	}
+	for _, tt := range tests {
+		_, err := toProperties([]byte(tt.src), "")
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("toProperties(%q) = %v, want %s", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestToPropertiesRoundTrip(t *testing.T) {
+	cfg := printer.Config{Mode: printerMode, Tabwidth: tabWidth}
+
+	// HOCON read back from the export has the same value, with arrays
+	// as objects keyed by index and every value but numbers and
+	// booleans a string.
+	const src = `app { name = "my app", port = 8080, debug = false }
+app.path = "C:\\temp\n"
+list = [1, { a = "x=y" }]
+`
+	const want = `app { name = "my app", port = 8080, debug = false, path = "C:\\temp\n" }
+list { 0 = 1, 1 { a = "x=y" } }
+`
+	props, err := toProperties([]byte(src), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := propertiesToHOCON(props, cfg)
+	if err != nil {
+		t.Fatalf("-from-properties of\n%s: %v", props, err)
+	}
+	if ok, err := Equal([]byte(want), back); !ok {
+		t.Errorf("-from-properties of\n%s: %v", props, err)
+	}
+
+	// A .properties file without comments comes back as it was.
+	orig, err := ioutil.ReadFile("testdata/application.properties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hocon, err := propertiesToHOCON(orig, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	props, err = toProperties(hocon, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := propertiesToHOCON(props, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := Equal(hocon, again); !ok {
+		t.Errorf("exported\n%s\nreads back differently: %v", props, err)
+	}
+}
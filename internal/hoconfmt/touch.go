@@ -0,0 +1,36 @@
+package hoconfmt
+
+import "strings"
+
+// touch makes only the changes to src that can never alter its layout:
+// CRLF line endings become LF, trailing white space is removed, and the
+// file ends in exactly one newline. Multi-line strings are copied as
+// they are, including their line endings and trailing spaces.
+func touch(src []byte) ([]byte, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop the blank lines at the end of the file.
+	end := len(toks)
+	for end > 0 && (toks[end-1].kind == tokSpace || toks[end-1].kind == tokNewline) {
+		end--
+	}
+
+	var res []byte
+	for i, t := range toks[:end] {
+		switch {
+		case t.kind == tokSpace && (i+1 == end || toks[i+1].kind == tokNewline):
+			continue
+		case t.kind == tokComment:
+			res = append(res, strings.TrimRight(t.lit, " \t\r\f\v")...)
+			continue
+		}
+		res = append(res, t.lit...)
+	}
+	if end > 0 {
+		res = append(res, '\n')
+	}
+	return res, nil
+}
@@ -0,0 +1,43 @@
+package hoconfmt
+
+import "testing"
+
+func TestTouch(t *testing.T) {
+	const src = "# settings \r\n" +
+		"a  =   1   \r\n" +
+		"b {\t\r\n" +
+		"\tc: [1,2] \r\n" +
+		"}\r\n" +
+		"d = \"\"\"keep  \r\n" +
+		"these  \"\"\"  \r\n" +
+		"e = \"x  \"\r\n" +
+		"\r\n" +
+		"  \r\n"
+	const want = "# settings\n" +
+		"a  =   1\n" +
+		"b {\n" +
+		"\tc: [1,2]\n" +
+		"}\n" +
+		"d = \"\"\"keep  \r\n" +
+		"these  \"\"\"\n" +
+		"e = \"x  \"\n"
+	got, err := touch([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+	if again, _ := touch(got); string(again) != string(got) {
+		t.Errorf("touch is not idempotent: got\n%q", again)
+	}
+
+	for _, src := range []string{"", "\n", " \r\n\r\n"} {
+		if got, _ := touch([]byte(src)); len(got) != 0 {
+			t.Errorf("touch(%q) = %q, want empty", src, got)
+		}
+	}
+	if got, _ := touch([]byte("a = 1")); string(got) != "a = 1\n" {
+		t.Errorf("missing final newline not added: got %q", got)
+	}
+}
@@ -0,0 +1,74 @@
+package hoconfmt
+
+import "fmt"
+
+// A ValueTransformer rewrites the values of fields, for programs that
+// normalize values of their own, such as host names in lower case.
+type ValueTransformer interface {
+	// TransformValue returns the text to write for the value of the
+	// field at path, a key path such as db.host, written as text, such
+	// as "Example.COM" with its quotes. Returning text leaves the value
+	// as it is.
+	TransformValue(path, text string) string
+}
+
+// transformValues rewrites the values of the fields of src with t.
+// Values holding objects or arrays are not passed to t, but the fields
+// of an object value are, in turn; those of the objects in arrays,
+// which have no key path, are left as they are. The result must still
+// parse.
+func transformValues(src []byte, t ValueTransformer) ([]byte, error) {
+	doc, err := parseDocument(src)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := doc.root.(*objectNode)
+	if !ok {
+		return src, nil
+	}
+	var res []byte
+	last := 0
+	var walk func(o *objectNode, prefix []string)
+	walk = func(o *objectNode, prefix []string) {
+		for _, it := range o.items {
+			f, ok := it.node.(*fieldNode)
+			if !ok {
+				continue
+			}
+			path := append(prefix[:len(prefix):len(prefix)], f.path...)
+			if obj := f.value.object(); obj != nil {
+				walk(obj, path)
+				continue
+			}
+			if f.value.holdsNode() {
+				continue
+			}
+			off, end := f.value.offset(), f.value.end()
+			text := string(src[off:end])
+			if v := t.TransformValue(renderPath(path), text); v != text {
+				res = append(res, src[last:off]...)
+				res = append(res, v...)
+				last = end
+			}
+		}
+	}
+	walk(root, nil)
+	if last == 0 {
+		return src, nil
+	}
+	res = append(res, src[last:]...)
+	if _, err := parseDocument(res); err != nil {
+		return nil, fmt.Errorf("the ValueTransformer made the document invalid: %v", err)
+	}
+	return res, nil
+}
+
+// holdsNode reports whether a part of v is an object or an array.
+func (v *valueNode) holdsNode() bool {
+	for _, p := range v.parts {
+		if p.node != nil {
+			return true
+		}
+	}
+	return false
+}
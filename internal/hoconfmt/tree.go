@@ -0,0 +1,263 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chankh/hoconfmt/ast"
+)
+
+// Format formats src, the contents of filename, as hoconfmt formats a
+// file with the flags opts stands for. The filename is used in error
+// messages and, with opts.EditorConfig, to find the .editorconfig; it
+// may be empty.
+func Format(filename string, src []byte, opts Options) ([]byte, error) {
+	if err := opts.check(); err != nil {
+		return nil, err
+	}
+	return formatFile(filename, src, opts)
+}
+
+// Parse parses src into the syntax tree of package ast, as formatting
+// parses it.
+func Parse(src []byte) (*ast.File, error) {
+	src = splitLoneCRs(src)
+	doc, err := parseDocument(src)
+	if err != nil {
+		return nil, err
+	}
+	c := &treeConverter{src: src}
+	return &ast.File{
+		Before: c.items(doc.before),
+		Blank:  doc.blank,
+		Root:   c.node(doc.root),
+		After:  c.items(doc.after),
+	}, nil
+}
+
+// A treeConverter turns the nodes of parseDocument into those of
+// package ast.
+type treeConverter struct {
+	src []byte
+}
+
+func (c *treeConverter) items(items []item) []ast.Item {
+	var res []ast.Item
+	for _, it := range items {
+		a := ast.Item{Node: c.node(it.node), Blank: it.blank}
+		if it.comment != nil {
+			a.Comment = &ast.Comment{Text: it.comment.text}
+		}
+		res = append(res, a)
+	}
+	return res
+}
+
+func (c *treeConverter) node(n node) ast.Node {
+	switch n := n.(type) {
+	case *objectNode:
+		return &ast.Object{
+			Braces:    n.braces,
+			Multiline: bytes.IndexByte(c.src[n.off:n.end], '\n') >= 0,
+			Items:     c.items(n.items),
+		}
+	case *arrayNode:
+		return &ast.Array{Multiline: n.multiline, Items: c.items(n.items)}
+	case *fieldNode:
+		return &ast.Field{Key: n.keyText(), Sep: n.sep, Value: c.value(n.value)}
+	case *includeNode:
+		var b strings.Builder
+		for _, t := range n.target {
+			b.WriteString(t.lit)
+		}
+		return &ast.Include{Target: strings.TrimSpace(b.String())}
+	case *valueNode:
+		return c.value(n)
+	case *commentNode:
+		return &ast.Comment{Text: n.text}
+	}
+	return nil
+}
+
+func (c *treeConverter) value(v *valueNode) *ast.Value {
+	res := &ast.Value{}
+	for i, part := range v.parts {
+		p := ast.Part{}
+		if i > 0 {
+			p.Space = part.space
+		}
+		if part.node != nil {
+			p.Node = c.node(part.node)
+		} else {
+			p.Text = part.tok.lit
+		}
+		res.Parts = append(res.Parts, p)
+	}
+	return res
+}
+
+// FormatNode writes n to w as HOCON text formatted with opts. A *File
+// is written as a document; an *Object, *Array, *Field, *Include or
+// *Comment as a document holding only it; and a *Value as it would be
+// formatted as the value of a field, without the key or a final
+// newline.
+func FormatNode(w io.Writer, n ast.Node, opts Options) error {
+	var b strings.Builder
+	if err := writeNode(&b, n); err != nil {
+		return err
+	}
+	text := b.String()
+	_, value := n.(*ast.Value)
+	if value {
+		text = "x = " + text
+	}
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	res, err := Format("", []byte(text), opts)
+	if err != nil {
+		return err
+	}
+	if value {
+		// Drop the key and whatever separator the options give it.
+		res = bytes.TrimLeft(res[1:], " ")
+		if len(res) > 0 && (res[0] == '=' || res[0] == ':') {
+			res = bytes.TrimLeft(res[1:], " ")
+		}
+		res = bytes.TrimSuffix(res, []byte("\n"))
+	}
+	_, err = w.Write(res)
+	return err
+}
+
+// writeNode writes n to b as HOCON text: an item of an object or array
+// on each line, unless it is written on one line, with the keys, values
+// and comments of the tree as they are. The text is left to formatting
+// to indent.
+func writeNode(b *strings.Builder, n ast.Node) error {
+	switch n := n.(type) {
+	case *ast.File:
+		if err := writeItems(b, n.Before); err != nil {
+			return err
+		}
+		if n.Blank {
+			b.WriteByte('\n')
+		}
+		if o, ok := n.Root.(*ast.Object); ok && !o.Braces {
+			if err := writeItems(b, o.Items); err != nil {
+				return err
+			}
+		} else {
+			if err := writeNode(b, n.Root); err != nil {
+				return err
+			}
+			b.WriteByte('\n')
+		}
+		return writeItems(b, n.After)
+	case *ast.Object:
+		return writeBlock(b, "{", "}", n.Items, n.Multiline)
+	case *ast.Array:
+		return writeBlock(b, "[", "]", n.Items, n.Multiline)
+	case *ast.Field:
+		if n.Value == nil {
+			return fmt.Errorf("field %s has no value", n.Key)
+		}
+		switch n.Sep {
+		case "":
+			b.WriteString(n.Key + " ")
+		case ":":
+			b.WriteString(n.Key + ": ")
+		default:
+			b.WriteString(n.Key + " " + n.Sep + " ")
+		}
+		return writeNode(b, n.Value)
+	case *ast.Include:
+		b.WriteString("include " + n.Target)
+	case *ast.Value:
+		if len(n.Parts) == 0 {
+			return fmt.Errorf("value has no parts")
+		}
+		for i, p := range n.Parts {
+			if strings.Trim(p.Space, " \t") != "" {
+				return fmt.Errorf("value part has space %q, which is not spaces and tabs", p.Space)
+			}
+			if i > 0 {
+				b.WriteString(p.Space)
+			}
+			switch p.Node.(type) {
+			case nil:
+				b.WriteString(p.Text)
+			case *ast.Object, *ast.Array:
+				if err := writeNode(b, p.Node); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("value part is a %T, not an object or array", p.Node)
+			}
+		}
+	case *ast.Comment:
+		b.WriteString(n.Text)
+	default:
+		return fmt.Errorf("cannot format a %T", n)
+	}
+	return nil
+}
+
+// writeItems writes items one per line, each line ending in a newline.
+func writeItems(b *strings.Builder, items []ast.Item) error {
+	for _, it := range items {
+		if it.Blank {
+			b.WriteByte('\n')
+		}
+		if err := writeNode(b, it.Node); err != nil {
+			return err
+		}
+		if it.Comment != nil {
+			b.WriteString(" " + it.Comment.Text)
+		}
+		b.WriteByte('\n')
+	}
+	return nil
+}
+
+// writeBlock writes items between open and close: on one line,
+// separated by commas, as in { a = 1, b = 2 } and [1, 2], if multiline
+// is false and they fit on one line, and one per line otherwise. Items
+// that are or hold comments, or that follow a blank line, do not fit.
+func writeBlock(b *strings.Builder, open, close string, items []ast.Item, multiline bool) error {
+	if len(items) == 0 {
+		b.WriteString(open + close)
+		return nil
+	}
+	if !multiline {
+		var line strings.Builder
+		for i, it := range items {
+			if _, ok := it.Node.(*ast.Comment); ok || it.Comment != nil || it.Blank {
+				multiline = true
+				break
+			}
+			if i > 0 {
+				line.WriteString(", ")
+			}
+			if err := writeNode(&line, it.Node); err != nil {
+				return err
+			}
+		}
+		if !multiline && !strings.Contains(line.String(), "\n") {
+			if open == "{" {
+				b.WriteString("{ " + line.String() + " }")
+			} else {
+				b.WriteString(open + line.String() + close)
+			}
+			return nil
+		}
+	}
+	b.WriteString(open + "\n")
+	if err := writeItems(b, items); err != nil {
+		return err
+	}
+	b.WriteString(close)
+	return nil
+}
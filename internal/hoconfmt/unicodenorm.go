@@ -0,0 +1,72 @@
+package hoconfmt
+
+import "golang.org/x/text/unicode/norm"
+
+// normalizeUnicode rewrites the keys of src, its unquoted values and the
+// paths of its substitutions to Unicode normalization form C, for
+// -unicode-normalize, so that a key typed with a composed é and one
+// typed with e and a combining accent are the same key, as they look.
+// Quoted and multi-line strings that are not keys are only normalized if
+// strs is set (-unicode-normalize-strings), since that changes the bytes
+// of values that may be compared with text from elsewhere. Comments and
+// the file names of include statements are left alone.
+func normalizeUnicode(src []byte, strs bool) ([]byte, error) {
+	doc, err := parseDocument(src)
+	if err != nil {
+		return nil, err
+	}
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	keys, includes := map[int]bool{}, map[int]bool{}
+	var visit func(n node)
+	visit = func(n node) {
+		switch n := n.(type) {
+		case *objectNode:
+			for _, it := range n.items {
+				visit(it.node)
+			}
+		case *arrayNode:
+			for _, it := range n.items {
+				visit(it.node)
+			}
+		case *fieldNode:
+			for _, t := range n.key {
+				keys[t.off] = true
+			}
+			if n.value != nil {
+				visit(n.value)
+			}
+		case *valueNode:
+			for _, p := range n.parts {
+				if p.node != nil {
+					visit(p.node)
+				}
+			}
+		case *includeNode:
+			for _, t := range n.target {
+				includes[t.off] = true
+			}
+		}
+	}
+	visit(doc.root)
+
+	var edits []textEdit
+	for _, t := range toks {
+		normalize := false
+		switch t.kind {
+		case tokUnquoted, tokSubst:
+			normalize = !includes[t.off]
+		case tokString:
+			normalize = keys[t.off] || strs && !includes[t.off]
+		case tokMultiline:
+			normalize = strs
+		}
+		if !normalize || norm.NFC.IsNormalString(t.lit) {
+			continue
+		}
+		edits = append(edits, textEdit{t.off, len(t.lit), norm.NFC.String(t.lit)})
+	}
+	return spliceEdits(src, edits), nil
+}
@@ -0,0 +1,65 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeUnicode(t *testing.T) {
+	const (
+		composed   = "caf\u00e9"  // é as one code point
+		decomposed = "cafe\u0301" // e and a combining acute accent
+	)
+	tests := []struct {
+		src, want, wantStrs string
+	}{
+		{decomposed + " = 1\n", composed + " = 1\n", ""},
+		{"\"" + decomposed + "\".x = 1\n", "\"" + composed + "\".x = 1\n", ""},
+		{"a = " + decomposed + "\n", "a = " + composed + "\n", ""},
+		{"a = ${" + decomposed + "}\n", "a = ${" + composed + "}\n", ""},
+		{"a = [{ " + decomposed + " = 1 }]\n", "a = [{ " + composed + " = 1 }]\n", ""},
+		// Quoted values change only with strs.
+		{"a = \"" + decomposed + "\"\n", "", "a = \"" + composed + "\"\n"},
+		{"a = \"\"\"" + decomposed + "\"\"\"\n", "", "a = \"\"\"" + composed + "\"\"\"\n"},
+		// Comments and included file names are left alone.
+		{"# " + decomposed + "\na = 1\n", "", ""},
+		{"include \"" + decomposed + ".conf\"\n", "", ""},
+	}
+	for _, tt := range tests {
+		if tt.want == "" {
+			tt.want = tt.src
+		}
+		if tt.wantStrs == "" {
+			tt.wantStrs = tt.want
+		}
+		for _, strs := range []bool{false, true} {
+			want := tt.want
+			if strs {
+				want = tt.wantStrs
+			}
+			res, err := normalizeUnicode([]byte(tt.src), strs)
+			if err != nil {
+				t.Errorf("normalizing %q: %v", tt.src, err)
+				continue
+			}
+			if string(res) != want {
+				t.Errorf("normalizing %q with strings %v = %q, want %q", tt.src, strs, res, want)
+			}
+		}
+	}
+
+	// Keys that look the same are found to be the same key.
+	var warnings bytes.Buffer
+	opts := DefaultOptions()
+	opts.UnicodeNFC = true
+	opts.WarnDuplicates = true
+	opts.Warnings = &warnings
+	res, err := formatFile("a.conf", []byte(composed+" = 1\n"+decomposed+" = 2\n"), opts)
+	if want := composed + " = 1\n" + composed + " = 2\n"; err != nil || string(res) != want {
+		t.Errorf("-unicode-normalize: got %q, %v, want %q", res, err, want)
+	}
+	if !strings.Contains(warnings.String(), "a.conf:2:1: warning: ") {
+		t.Errorf("-unicode-normalize with -warn-duplicates warned %q, want a warning about line 2", warnings.String())
+	}
+}
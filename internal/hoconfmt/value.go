@@ -0,0 +1,196 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A Value is a HOCON value built in Go, to be rendered by FormatValue.
+// It is one of
+//
+//	nil                              null
+//	bool                             true or false
+//	string                           a quoted string
+//	int, int64, float64, json.Number a number
+//	Subst                            a substitution
+//	[]Value                          an array
+//	Object                           an object, with its fields in order
+//	map[string]Value                 an object, with its keys sorted
+type Value interface{}
+
+// Subst is a substitution of the value at Path, such as ${db.host}, or
+// ${?db.host} if Optional.
+type Subst struct {
+	Path     string
+	Optional bool
+}
+
+// Object is an object whose fields keep their order.
+type Object []Field
+
+// A Field is one key and value of an Object. The key is a single path
+// element, quoted in the output where it would not read back as the
+// same key, such as when it contains a '.'.
+type Field struct {
+	Key   string
+	Value Value
+}
+
+// FormatValue renders v as HOCON text the way hoconfmt formats a
+// document: keys are unquoted where that reads back as the same key,
+// strings are quoted, non-empty objects are laid out one field per line
+// with an indentation of one level per object, opts.ObjectEq decides
+// whether keys with object values get a separator, and empty objects
+// and arrays are compact. Arrays are written on one line unless they
+// hold a non-empty object.
+func FormatValue(v Value, opts Options) ([]byte, error) {
+	if err := opts.check(); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	if err := writeValue(&b, v, opts, 0); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+func writeValue(b *strings.Builder, v Value, opts Options, depth int) error {
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		fmt.Fprint(b, v)
+	case string:
+		b.WriteString(quoteString(v))
+	case int, int64, float64, json.Number:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("cannot format number %v: %v", v, err)
+		}
+		b.Write(data)
+	case Subst:
+		if v.Path == "" {
+			return errors.New("cannot format a substitution without a path")
+		}
+		b.WriteString("${")
+		if v.Optional {
+			b.WriteByte('?')
+		}
+		b.WriteString(v.Path)
+		b.WriteByte('}')
+	case []Value:
+		return writeArray(b, v, opts, depth)
+	case map[string]Value:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		obj := make(Object, len(keys))
+		for i, k := range keys {
+			obj[i] = Field{k, v[k]}
+		}
+		return writeObject(b, obj, opts, depth)
+	case Object:
+		return writeObject(b, v, opts, depth)
+	case concatenation:
+		// The parts of a merged value that stay apart, for -get.
+		for i, p := range v.parts {
+			if i > 0 && (isComposite(p) || isComposite(v.parts[i-1])) {
+				b.WriteByte(' ')
+			}
+			if err := writeValue(b, p, opts, depth); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot format a value of type %T", v)
+	}
+	return nil
+}
+
+func writeArray(b *strings.Builder, elems []Value, opts Options, depth int) error {
+	multiline := false
+	for _, e := range elems {
+		if isNonEmptyObject(e) {
+			multiline = true
+		}
+	}
+	b.WriteByte('[')
+	for i, e := range elems {
+		switch {
+		case multiline:
+			b.WriteByte('\n')
+			b.WriteString(strings.Repeat(valueIndent(opts), depth+1))
+		case i > 0:
+			b.WriteString(", ")
+		}
+		if err := writeValue(b, e, opts, depth+1); err != nil {
+			return err
+		}
+	}
+	if multiline {
+		b.WriteByte('\n')
+		b.WriteString(strings.Repeat(valueIndent(opts), depth))
+	}
+	b.WriteByte(']')
+	return nil
+}
+
+func writeObject(b *strings.Builder, fields Object, opts Options, depth int) error {
+	if len(fields) == 0 {
+		b.WriteString("{}")
+		return nil
+	}
+	b.WriteString("{\n")
+	for _, f := range fields {
+		b.WriteString(strings.Repeat(valueIndent(opts), depth+1))
+		if f.Key == "include" {
+			b.WriteString(quoteString(f.Key)) // not an include statement
+		} else {
+			b.WriteString(hoconKey(f.Key))
+		}
+		if isObject(f.Value) && opts.ObjectEq != objectEqRequire {
+			b.WriteByte(' ')
+		} else {
+			b.WriteString(" = ")
+		}
+		if err := writeValue(b, f.Value, opts, depth+1); err != nil {
+			return err
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString(strings.Repeat(valueIndent(opts), depth))
+	b.WriteByte('}')
+	return nil
+}
+
+// valueIndent returns one level of indentation in FormatValue output:
+// opts.Indent, or what printerMode and tabWidth select for documents.
+func valueIndent(opts Options) string {
+	if opts.Indent != "" {
+		return opts.Indent
+	}
+	return strings.Repeat(" ", tabWidth)
+}
+
+func isObject(v Value) bool {
+	switch v.(type) {
+	case Object, map[string]Value:
+		return true
+	}
+	return false
+}
+
+func isNonEmptyObject(v Value) bool {
+	switch v := v.(type) {
+	case Object:
+		return len(v) > 0
+	case map[string]Value:
+		return len(v) > 0
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package hoconfmt
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFormatValue(t *testing.T) {
+	for _, test := range []struct {
+		v    Value
+		want string
+	}{
+		{nil, "null"},
+		{true, "true"},
+		{"a \"b\"\n", `"a \"b\"\n"`},
+		{42, "42"},
+		{int64(-7), "-7"},
+		{1.5, "1.5"},
+		{json.Number("1e3"), "1e3"},
+		{Subst{Path: "db.host"}, "${db.host}"},
+		{Subst{Path: "PORT", Optional: true}, "${?PORT}"},
+		{[]Value{}, "[]"},
+		{[]Value{1, "x", Subst{Path: "y"}}, `[1, "x", ${y}]`},
+		{Object{}, "{}"},
+		{Object{{"b", 1}, {"a.b", "x"}, {"include", true}}, "{\n    b = 1\n    \"a.b\" = \"x\"\n    \"include\" = true\n}"},
+		{map[string]Value{"z": nil, "a": Object{{"c", 2}}}, "{\n    a {\n        c = 2\n    }\n    z = null\n}"},
+		{[]Value{Object{{"a", 1}}, []Value{}}, "[\n    {\n        a = 1\n    }\n    []\n]"},
+	} {
+		got, err := FormatValue(test.v, DefaultOptions())
+		if err != nil {
+			t.Errorf("FormatValue(%#v): %v", test.v, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("FormatValue(%#v) = %q, want %q", test.v, got, test.want)
+		}
+	}
+}
+
+func TestFormatValueObjectEq(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ObjectEq = objectEqRequire
+	got, err := FormatValue(Object{{"a", Object{}}}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n    a = {}\n}"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatValueIndent(t *testing.T) {
+	v := Object{{"a", Object{{"b", []Value{Object{{"c", 1}}}}}}}
+	for _, test := range []struct {
+		indent, want string
+	}{
+		{"  ", "{\n  a {\n    b = [\n      {\n        c = 1\n      }\n    ]\n  }\n}"},
+		{"\t", "{\n\ta {\n\t\tb = [\n\t\t\t{\n\t\t\t\tc = 1\n\t\t\t}\n\t\t]\n\t}\n}"},
+	} {
+		opts := DefaultOptions()
+		opts.Indent = test.indent
+		got, err := FormatValue(v, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("indent %q: got %q, want %q", test.indent, got, test.want)
+		}
+	}
+}
+
+func TestFormatValueErrors(t *testing.T) {
+	for _, v := range []Value{struct{}{}, Subst{}, []Value{uint8(1)}} {
+		if _, err := FormatValue(v, DefaultOptions()); err == nil {
+			t.Errorf("FormatValue(%#v): no error", v)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// checkValue reports the first field of src whose value res changes,
+// drops or adds, for -verify. Both documents are merged as
+// mergeDocument merges them, with their include statements standing
+// for themselves, so fields that only move, or change in layout,
+// quoting or comments, count as the same, and so do durations and
+// sizes that -fix-quotes spaces differently. Substitutions compare by
+// their paths, without resolving them. If src does not parse or merge
+// there is nothing to compare res with, and nothing is reported. If
+// the comparison itself fails, that is reported, so that a result that
+// could not be verified is not written.
+func checkValue(src, res []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var errs errorList
+			errs.Add(position(src, 0), codeVerify, fmt.Sprintf("cannot verify the result: %v", r))
+			err = errs
+		}
+	}()
+	want, err := (&merger{src: src, includes: true, overrides: true}).document()
+	if err != nil {
+		return nil
+	}
+	var errs errorList
+	got, err := (&merger{src: res, includes: true, overrides: true}).document()
+	if err != nil {
+		errs.Add(position(src, 0), codeVerify, "formatting would make the document invalid: "+err.Error())
+		return errs
+	}
+	d := valueDiff(want, got, nil)
+	if d == nil {
+		return nil
+	}
+	var msg string
+	switch {
+	case d.removed:
+		msg = pathName(d.path) + " would be removed"
+	case d.added:
+		msg = pathName(d.path) + " would be added"
+	default:
+		msg = fmt.Sprintf("%s would change from %s to %s", pathName(d.path), valueName(d.before), valueName(d.after))
+	}
+	errs.Add(position(src, fieldOffset(src, d.path)), codeVerify, "formatting would change the value: "+msg)
+	return errs
+}
+
+// verifyInput returns src, read from filename, as -verify compares the
+// result of formatting it with: with the changes that formatting makes
+// to what it says on purpose. The spaces that tightenPaths removes are
+// meant to go, include targets without quotes get them, and with
+// snapshot the optional substitutions of set environment variables are
+// replaced by their values.
+func verifyInput(filename string, src []byte, snapshot bool) ([]byte, error) {
+	want, err := tightenPaths(nil, func(warning) {}, filename, src)
+	if err != nil {
+		return nil, err
+	}
+	if want, err = quoteIncludes(nil, want); err != nil {
+		return nil, withFilename(err, filename)
+	}
+	if snapshot {
+		if want, err = snapshotEnv(want, os.LookupEnv); err != nil {
+			return nil, withFilename(err, filename)
+		}
+	}
+	return want, nil
+}
+
+// A valueChange is a difference between two values, found by valueDiff:
+// the value at path is before in the first and after in the second, or it is
+// only in one of them.
+type valueChange struct {
+	path           []string
+	before, after  Value
+	removed, added bool
+}
+
+// valueDiff returns the first difference between before and after, the
+// values at path, or nil if they are the same.
+func valueDiff(before, after Value, path []string) *valueChange {
+	changed := &valueChange{path: path, before: before, after: after}
+	switch o := before.(type) {
+	case Object:
+		n, ok := after.(Object)
+		if !ok {
+			return changed
+		}
+		have := map[string]Value{}
+		for _, f := range n {
+			have[f.Key] = f.Value
+		}
+		seen := map[string]bool{}
+		for _, f := range o {
+			seen[f.Key] = true
+			p := append(path[:len(path):len(path)], f.Key)
+			v, ok := have[f.Key]
+			if !ok {
+				return &valueChange{path: p, before: f.Value, removed: true}
+			}
+			if d := valueDiff(f.Value, v, p); d != nil {
+				return d
+			}
+		}
+		for _, f := range n {
+			if !seen[f.Key] {
+				return &valueChange{path: append(path[:len(path):len(path)], f.Key), after: f.Value, added: true}
+			}
+		}
+		return nil
+	case []Value:
+		n, ok := after.([]Value)
+		if !ok {
+			return changed
+		}
+		for i := 0; i < len(o) && i < len(n); i++ {
+			if d := valueDiff(o[i], n[i], append(path[:len(path):len(path)], fmt.Sprint(i))); d != nil {
+				return d
+			}
+		}
+		if len(o) != len(n) {
+			return changed
+		}
+		return nil
+	case concatenation:
+		n, ok := after.(concatenation)
+		if !ok || len(o.parts) != len(n.parts) {
+			return changed
+		}
+		for i := range o.parts {
+			if valueDiff(o.parts[i], n.parts[i], path) != nil {
+				return changed
+			}
+		}
+		return nil
+	case override:
+		n, ok := after.(override)
+		if !ok || valueDiff(o.value, n.value, path) != nil || valueDiff(o.prev, n.prev, path) != nil {
+			return changed
+		}
+		return nil
+	case Subst:
+		n, ok := after.(Subst)
+		if !ok || n.Optional != o.Optional || renderPath(pathElems(n.Path)) != renderPath(pathElems(o.Path)) {
+			return changed
+		}
+		return nil
+	case string:
+		if n, ok := after.(string); ok && sameUnitValue(o, n) {
+			return nil
+		}
+	}
+	if before != after {
+		return changed
+	}
+	return nil
+}
+
+// sameUnitValue reports whether a and b are the same duration, period
+// or size, written with and without a space before the unit, as in 10s
+// and 10 s.
+func sameUnitValue(a, b string) bool {
+	ma, mb := unitValue.FindStringSubmatch(a), unitValue.FindStringSubmatch(b)
+	return ma != nil && mb != nil && valueUnits[ma[2]] != "" && ma[1] == mb[1] && ma[2] == mb[2]
+}
+
+// pathName returns the path p as valueDiff describes it: the key path,
+// with array elements numbered from 0, or the text of an include
+// statement.
+func pathName(p []string) string {
+	if len(p) == 0 {
+		return "the document"
+	}
+	if last := p[len(p)-1]; strings.HasPrefix(last, includeKey) {
+		name := last[len(includeKey):]
+		if len(p) > 1 {
+			name += " in " + renderPath(p[:len(p)-1])
+		}
+		return name
+	}
+	return renderPath(p)
+}
+
+// valueName returns a description of v, naming its type, for
+// valueDiff.
+func valueName(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case json.Number:
+		return "the number " + string(v)
+	case string:
+		return "the string " + quoteString(v)
+	case Subst:
+		return "the substitution " + substText(v)
+	case concatenation:
+		var b bytes.Buffer
+		writeCanonical(&b, v, 0)
+		return "the concatenation " + b.String()
+	case override:
+		return valueName(v.value) + " over " + valueName(v.prev)
+	case Object:
+		return "an object"
+	case []Value:
+		if len(v) == 1 {
+			return "an array of 1 element"
+		}
+		return fmt.Sprintf("an array of %d elements", len(v))
+	}
+	return fmt.Sprint(v)
+}
+
+// fieldOffset returns the offset of the key of the last field of src
+// that sets path or the longest part of it, or 0 if none does.
+func fieldOffset(src []byte, path []string) int {
+	doc, err := parseDocument(src)
+	if err != nil {
+		return 0
+	}
+	off, longest := 0, 0
+	var object func(o *objectNode, prefix []string)
+	object = func(o *objectNode, prefix []string) {
+		for _, it := range o.items {
+			f, ok := it.node.(*fieldNode)
+			if !ok {
+				continue
+			}
+			p := append(prefix[:len(prefix):len(prefix)], f.path...)
+			n := 0
+			for n < len(p) && n < len(path) && p[n] == path[n] {
+				n++
+			}
+			if n == 0 || n < longest {
+				continue
+			}
+			off, longest = f.offset(), n
+			if n == len(p) {
+				for _, part := range f.value.parts {
+					if o, ok := part.node.(*objectNode); ok {
+						object(o, p)
+					}
+				}
+			}
+		}
+	}
+	if o, ok := doc.root.(*objectNode); ok {
+		object(o, nil)
+	}
+	return off
+}
+
+// checkIdempotent reports the first line of res, the result of
+// formatting a file with opts, that formatting res again would change,
+// for -verify with a ValueTransformer or UnitClassifier: one that
+// rewrites what it wrote itself, or recognizes a unit only some of the
+// time, would change the file on every run. The passes that apply once,
+// converting properties, rewriting keys and explaining types, are left
+// out of the second run.
+func checkIdempotent(filename string, res []byte, opts Options) error {
+	opts.Verify, opts.FromProperties, opts.Explain = false, false, false
+	opts.Touch, opts.ModifiedBlocksOnly = false, false
+	opts.Rewrite = ""
+	opts.Warnings = io.Discard
+	var errs errorList
+	again, _, err := formatLines(&lexer{}, filename, res, opts)
+	if err != nil {
+		errs.Add(position(res, 0), codeVerify, "formatting the result again fails: "+err.Error())
+		return errs
+	}
+	if bytes.Equal(again, res) {
+		return nil
+	}
+	off := 0
+	for off < len(res) && off < len(again) && res[off] == again[off] {
+		off++
+	}
+	off = bytes.LastIndexByte(res[:off], '\n') + 1
+	errs.Add(position(res, off), codeVerify, "formatting is not idempotent: formatting the result again would change this line; the ValueTransformer or UnitClassifier does not give the same result twice")
+	return errs
+}
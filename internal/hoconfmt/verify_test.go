@@ -0,0 +1,93 @@
+package hoconfmt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckValue(t *testing.T) {
+	tests := []struct{ src, res, want string }{
+		{"a.b = 1\nc: [1,2]", "a {\n    b = 1\n}\nc = [\n    1\n    2\n]\n", ""},
+		{"a = \"x\"\nb = 10s", "a = x\nb = 10 s", ""},
+		{"include \"b.conf\"\na = ${ x }", "include \"b.conf\"\na = ${x}", ""},
+		{"a = 1 {", "a = 2", ""}, // nothing to compare with
+		{"a = 1\nb = 2", "a = 1\nb = \"2\"", "2:1: formatting would change the value: b would change from the number 2 to the string \"2\""},
+		{"a { b = [1, 2] }", "a { b = [1] }", "1:5: formatting would change the value: a.b would change from an array of 2 elements to an array of 1 element"},
+		{"a { b = [{ c = 1 }] }", "a { b = [{ c = 2 }] }", "1:5: formatting would change the value: a.b.0.c would change from the number 1 to the number 2"},
+		{"a = 1\nb = 2", "a = 1", "2:1: formatting would change the value: b would be removed"},
+		{"a = ${x} /y", "a = ${x}/y", "1:1: formatting would change the value: a would change from the concatenation ${x}\" /y\" to the concatenation ${x}\"/y\""},
+		{"a { include \"b.conf\" }", "a {}", "1:1: formatting would change the value: include \"b.conf\" in a would be removed"},
+		{"a = 1", "a = {", "1:1: formatting would make the document invalid: 1:5: { is not closed"},
+		{"g = x\ng = ${?G}", "g = \"x\"\ng = ${?G}", ""},
+		{"g = x\ng = ${?G}", "g = ${?G}", "2:1: formatting would change the value: g would change from the substitution ${?G} over the string \"x\" to the substitution ${?G}"},
+	}
+	for _, tt := range tests {
+		err := checkValue([]byte(tt.src), []byte(tt.res))
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("checkValue(%q, %q) = %q, want %q", tt.src, tt.res, got, tt.want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const src = "a.b: 1\nc = [1, 2]\nd = ${HOME} /logs # where\ne = 30seconds\n"
+	opts := DefaultOptions()
+	opts.Verify, opts.FixQuotes, opts.KeyPaths = true, true, keyPathsNested
+	if _, err := formatFile("a.conf", []byte(src), opts); err != nil {
+		t.Errorf("formatFile with Verify: %v", err)
+	}
+}
+
+// growing appends a 0 to every number, so that each run changes it
+// again.
+type growing struct{}
+
+func (growing) TransformValue(path, text string) string {
+	if text != "" && text[0] >= '0' && text[0] <= '9' {
+		return text + "0"
+	}
+	return text
+}
+
+// lower writes host names in lower case, the same every time.
+type lower struct{}
+
+func (lower) TransformValue(path, text string) string {
+	if strings.HasSuffix(path, ".host") {
+		return strings.ToLower(text)
+	}
+	return text
+}
+
+func TestVerifyIdempotent(t *testing.T) {
+	const src = "db {\n    host = Example.COM\n    port = 5432\n}\n"
+	opts := DefaultOptions()
+	opts.Verify, opts.ValueTransformer = true, lower{}
+	res, err := formatFile("a.conf", []byte(src), opts)
+	if err != nil {
+		t.Fatalf("formatFile with lower: %v", err)
+	}
+	if want := "db {\n    host = example.com\n    port = 5432\n}\n"; string(res) != want {
+		t.Errorf("formatFile with lower = %q, want %q", res, want)
+	}
+
+	opts.ValueTransformer = growing{}
+	_, err = formatFile("a.conf", []byte(src), opts)
+	list, ok := err.(errorList)
+	if !ok || len(list) != 1 || list[0].Code != codeVerify {
+		t.Fatalf("formatFile with growing = %v, want a %s error", err, codeVerify)
+	}
+	if want := "a.conf:3:1: formatting is not idempotent"; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("formatFile with growing = %q, want prefix %q", err, want)
+	}
+
+	opts.Verify = false
+	res, err = formatFile("a.conf", []byte(src), opts)
+	if err != nil || !strings.Contains(string(res), "port = 54320") {
+		t.Errorf("formatFile with growing without Verify = %q, %v", res, err)
+	}
+}
@@ -0,0 +1,58 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+)
+
+// A warning is a problem in the input that formatting works around or
+// leaves for the user, such as a comma that -fix-commas removes.
+type warning struct {
+	Pos  token.Position // Filename is set; invalid if there is no position
+	Msg  string
+	Code string // as in diagnostic
+}
+
+func (w warning) String() string {
+	if !w.Pos.IsValid() {
+		return "warning: " + w.Msg
+	}
+	return fmt.Sprintf("%s: warning: %s", w.Pos, w.Msg)
+}
+
+// A warningSink takes warnings as they are found. An Options.Warnings
+// that is one receives them as values rather than as text, so that
+// -check and -lsp get their positions as they are.
+type warningSink interface {
+	addWarning(w warning)
+}
+
+// warnFunc returns the function formatFile reports warnings with: to w
+// if it is a warningSink, and otherwise as lines of text written to w,
+// or to standard error if w is nil.
+func warnFunc(w io.Writer) func(warning) {
+	if w == nil {
+		w = os.Stderr
+	}
+	if s, ok := w.(warningSink); ok {
+		return s.addWarning
+	}
+	return func(x warning) { fmt.Fprintln(w, x) }
+}
+
+// A warningList collects the warnings of formatting a file.
+type warningList []warning
+
+func (l *warningList) addWarning(w warning) { *l = append(*l, w) }
+
+// Write adds the lines of p as warnings without a position, for text
+// written to Options.Warnings other than through warnFunc.
+func (l *warningList) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(bytes.TrimSuffix(p, []byte("\n")), []byte("\n")) {
+		*l = append(*l, warning{Msg: string(line), Code: codeOther})
+	}
+	return len(p), nil
+}
@@ -0,0 +1,37 @@
+package hoconfmt
+
+import (
+	"bytes"
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+func TestWarnFunc(t *testing.T) {
+	pos := token.Position{Filename: "a.conf", Line: 2, Column: 5}
+
+	var buf bytes.Buffer
+	warn := warnFunc(&buf)
+	warn(warning{pos, "removing ,", codeComma})
+	warn(warning{Msg: "no position"})
+	if want := "a.conf:2:5: warning: removing ,\nwarning: no position\n"; buf.String() != want {
+		t.Errorf("text warnings = %q, want %q", buf.String(), want)
+	}
+
+	var list warningList
+	warn = warnFunc(&list)
+	warn(warning{pos, "a: b: c", codeOther})
+	if want := (warningList{{pos, "a: b: c", codeOther}}); !reflect.DeepEqual(list, want) {
+		t.Errorf("collected warnings = %v, want %v", list, want)
+	}
+}
+
+func TestCheckFormatWarnings(t *testing.T) {
+	opts := DefaultOptions()
+	opts.FixCommas = true
+	r := checkFormat("a.conf", []byte("a = [1,,2]\n"), opts, false)
+	want := []diagnostic{{1, 8, "warning", "removing doubled comma", "comma"}}
+	if !reflect.DeepEqual(r.Diagnostics, want) {
+		t.Errorf("diagnostics = %v, want %v", r.Diagnostics, want)
+	}
+}
@@ -0,0 +1,147 @@
+package hoconfmt
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// spaceLen returns the length in bytes of the white space character at
+// the start of src, or 0 if there is none. Besides ASCII space, tab and
+// carriage return, HOCON counts the Unicode space separators (such as
+// the no-break space U+00A0), U+2028, U+2029 and the byte order mark as
+// white space. Newlines are not included.
+func spaceLen(src []byte) int {
+	if len(src) == 0 || src[0] == '\n' {
+		return 0
+	}
+	if src[0] < utf8.RuneSelf {
+		if isSpace(src[0]) {
+			return 1
+		}
+		return 0
+	}
+	r, n := utf8.DecodeRune(src)
+	if unicode.Is(unicode.Zs, r) || r == '\u2028' || r == '\u2029' || r == '\uFEFF' {
+		return n
+	}
+	return 0
+}
+
+// contentSpaces reports for each token of toks whether it is white
+// space that is part of a value or key rather than structural white
+// space. In HOCON the white space between the parts of an unquoted
+// concatenation such as "a b" is kept in the value; white space used
+// for indentation or around separators, brackets and comments is not.
+func contentSpaces(toks []tok) []bool {
+	content := make([]bool, len(toks))
+	first := -1 // index of the first token of the current field
+	for i, t := range toks {
+		switch t.kind {
+		case tokNewline, tokComma, tokLBrace, tokLBracket, tokRBrace, tokRBracket:
+			first = -1
+			continue
+		case tokSpace, tokComment:
+		default:
+			if first < 0 {
+				first = i
+			}
+		}
+		if t.kind != tokSpace || i == 0 || i+1 == len(toks) || !isText(toks[i-1]) || !isText(toks[i+1]) {
+			continue
+		}
+		if i-1 == first && toks[first].lit == "include" {
+			// include "file" is a statement, not a concatenation
+			continue
+		}
+		content[i] = true
+	}
+	return content
+}
+
+// contentTabs returns the offsets of the tabs and non-ASCII white space
+// characters in src that are part of a value or key. Formatting must
+// never change them, and they are easily mistaken for structure.
+func contentTabs(lx *lexer, src []byte) ([]int, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var offs []int
+	for i, content := range contentSpaces(toks) {
+		if !content {
+			continue
+		}
+		for j, c := range toks[i].lit {
+			if c == '\t' || c >= utf8.RuneSelf {
+				offs = append(offs, toks[i].off+j)
+			}
+		}
+	}
+	return offs, nil
+}
+
+// isText reports whether t can be part of a concatenated value or key.
+func isText(t tok) bool {
+	switch t.kind {
+	case tokUnquoted, tokString, tokMultiline, tokSubst:
+		return true
+	}
+	return false
+}
+
+// checkContentTabs warns to warn about the tabs and unusual white space
+// inside values of src, and makes sure formatting into res kept every
+// one of them.
+func checkContentTabs(lx *lexer, warn func(warning), filename string, src, res []byte) error {
+	before, err := contentTabs(lx, src)
+	if err != nil {
+		return withFilename(err, filename)
+	}
+	for _, off := range before {
+		pos := position(src, off)
+		pos.Filename = filename
+		r, _ := utf8.DecodeRune(src[off:])
+		warn(warning{pos, fmt.Sprintf("white space %U inside a value is part of its content", r), codeWhitespace})
+	}
+
+	after, err := contentTabs(lx, res)
+	if err != nil {
+		return withFilename(err, filename)
+	}
+	if len(after) != len(before) {
+		return fmt.Errorf("%s: formatting would change the white space inside values", filename)
+	}
+	return nil
+}
+
+// normalizeSpaces replaces the non-ASCII white space characters that
+// are only structure, such as a no-break space between a key and its
+// separator, with plain spaces. A byte order mark is dropped instead.
+// White space inside values is kept as it is.
+func normalizeSpaces(lx *lexer, src []byte) ([]byte, error) {
+	toks, err := lx.tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, 0, len(src))
+	content := contentSpaces(toks)
+	for i, t := range toks {
+		if t.kind != tokSpace || content[i] {
+			res = append(res, t.lit...)
+			continue
+		}
+		for _, c := range t.lit {
+			switch {
+			case c == '\uFEFF':
+			case c >= utf8.RuneSelf:
+				res = append(res, ' ')
+			default:
+				res = append(res, byte(c))
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,53 @@
+package hoconfmt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContentTabs(t *testing.T) {
+	const src = "\ta\t=\tfoo\tbar\t# comment\n" +
+		"b = [x\ty,\tz]\n" +
+		"include\t\"c.conf\"\n" +
+		"d\te = ${x}\t\"s\"\n"
+	got, err := contentTabs(nil, []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// foo<tab>bar, x<tab>y, d<tab>e and ${x}<tab>"s"
+	want := []int{8, 29, 54, 63}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("contentTabs = %v, want %v", got, want)
+	}
+}
+
+func TestNormalizeSpaces(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"key\u00a0= 1\n", "key = 1\n"},
+		{"key =\u3000\"v\" # c\n", "key = \"v\" # c\n"},
+		{"\uFEFFa = 1\n", "a = 1\n"},
+		// inside a concatenation the no-break space is part of the value
+		{"a = foo\u00a0bar\n", "a = foo\u00a0bar\n"},
+	} {
+		got, err := normalizeSpaces(nil, []byte(test.in))
+		if err != nil {
+			t.Errorf("normalizeSpaces(%q): %s", test.in, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("normalizeSpaces(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestTokenizeNBSP(t *testing.T) {
+	toks, err := tokenize([]byte("key\u00a0= 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(toks) != 5 || toks[0].lit != "key" || toks[1].kind != tokSpace || toks[2].kind != tokSep {
+		t.Errorf("no-break space was not read as white space: %+v", toks)
+	}
+}
@@ -0,0 +1,59 @@
+package hoconfmt
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// widthAuto is the -width value that asks for the width of the
+// terminal.
+const widthAuto = "auto"
+
+// A widthValue is the value of the -width flag: a number of columns, or
+// widthAuto.
+type widthValue struct {
+	n    int
+	auto bool
+}
+
+// widthFlag defines a flag like -width with the given default number of
+// columns.
+func widthFlag(name string, value int, usage string) *widthValue {
+	w := &widthValue{n: value}
+	commandLine.Var(w, name, usage)
+	return w
+}
+
+func (w *widthValue) String() string {
+	if w.auto {
+		return widthAuto
+	}
+	return strconv.Itoa(w.n)
+}
+
+func (w *widthValue) Set(s string) error {
+	if s == widthAuto {
+		w.auto = true
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("must be a number or %s", widthAuto)
+	}
+	w.n, w.auto = n, false
+	return nil
+}
+
+// width returns the number of columns w stands for. With widthAuto it
+// is the width of the terminal out is connected to, or defaultWidth if
+// out is not a terminal.
+func (w *widthValue) width(out *os.File) int {
+	if !w.auto {
+		return w.n
+	}
+	if n := terminalWidth(out); n > 0 {
+		return n
+	}
+	return defaultWidth
+}
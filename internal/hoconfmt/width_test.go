@@ -0,0 +1,34 @@
+package hoconfmt
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestWidthValue(t *testing.T) {
+	var w widthValue
+	if err := w.Set("100"); err != nil || w.width(nil) != 100 {
+		t.Errorf("-width=100 gives %d (%v)", w.width(nil), err)
+	}
+	if err := w.Set("wide"); err == nil {
+		t.Error("no error for -width=wide")
+	}
+	if err := w.Set(widthAuto); err != nil || w.String() != widthAuto {
+		t.Errorf("-width=auto gives %s (%v)", w.String(), err)
+	}
+}
+
+func TestWidthAutoNotTerminal(t *testing.T) {
+	f, err := ioutil.TempFile("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := widthValue{auto: true}
+	if got := w.width(f); got != defaultWidth {
+		t.Errorf("-width=auto on a file gives %d, want %d", got, defaultWidth)
+	}
+}
@@ -0,0 +1,74 @@
+package hoconfmt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic replaces the contents of filename with data so that
+// the file holds either its old or its new contents even if hoconfmt
+// dies while writing it: data goes to a temporary file in the same
+// directory, which is synced and then renamed over filename. The new
+// file keeps the permissions and, where the system allows it, the
+// owner of the old one; a file that does not exist yet is created
+// with mode 0644. A symbolic link is followed, and the file it points
+// to is replaced. Devices and pipes, such as /dev/stdout, are written
+// directly.
+func writeFileAtomic(filename string, data []byte) error {
+	return writeFileAtomicPerm(filename, data, 0)
+}
+
+// writeFileAtomicPerm is writeFileAtomic giving the new file the
+// permissions perm, unless perm is 0.
+func writeFileAtomicPerm(filename string, data []byte, perm os.FileMode) error {
+	if target, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = target
+	}
+	var old os.FileInfo
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(filename); err == nil {
+		if !fi.Mode().IsRegular() {
+			return ioutil.WriteFile(filename, data, 0644)
+		}
+		old, mode = fi, fi.Mode().Perm()
+	}
+	if perm != 0 {
+		mode = perm
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".hoconfmt-")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	err = writeSynced(f, data, mode, old)
+	if err == nil {
+		err = os.Rename(tmp, filename)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// writeSynced writes data to f, gives it mode and the owner of old, if
+// any, syncs it to disk and closes it.
+func writeSynced(f *os.File, data []byte, mode os.FileMode, old os.FileInfo) error {
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+	if old != nil {
+		copyOwner(f, old)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,62 @@
+package hoconfmt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hoconfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "app.conf")
+	if err := ioutil.WriteFile(name, []byte("a  =  1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.conf")
+	if err := os.Symlink("app.conf", link); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(link, []byte("a = 1\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ioutil.ReadFile(name); string(got) != "a = 1\n" {
+		t.Errorf("contents = %q, want %q", got, "a = 1\n")
+	}
+	fi, err := os.Lstat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && fi.Mode() != 0600 {
+		t.Errorf("mode = %v, want %v", fi.Mode(), os.FileMode(0600))
+	}
+	if fi, err := os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("%s is no longer a symbolic link", link)
+	}
+
+	created := filepath.Join(dir, "new.conf")
+	if err := writeFileAtomic(created, []byte("b = 2\n")); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(created); err != nil || runtime.GOOS != "windows" && fi.Mode() != 0644 {
+		t.Errorf("new file: %v, %v", fi, err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Name())
+		}
+		t.Errorf("files left in the directory: %q, want app.conf, link.conf and new.conf", names)
+	}
+}
@@ -0,0 +1,14 @@
+// Command hoconfmt formats HOCON configuration files. Programs that
+// format HOCON without running the command use the packages format,
+// parser and ast.
+package main
+
+import "github.com/chankh/hoconfmt/internal/hoconfmt"
+
+// version is the version printed by -version. Release builds set it
+// with -ldflags "-X main.version=v1.2.3".
+var version = "devel"
+
+func main() {
+	hoconfmt.Main(version)
+}
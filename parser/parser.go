@@ -0,0 +1,15 @@
+// Package parser parses HOCON documents into the syntax trees of
+// package ast.
+package parser
+
+import (
+	"github.com/chankh/hoconfmt/ast"
+	"github.com/chankh/hoconfmt/internal/hoconfmt"
+)
+
+// ParseFile parses src, the contents of a HOCON file. It accepts what
+// hoconfmt formats, and reports syntax errors with their line and
+// column.
+func ParseFile(src []byte) (*ast.File, error) {
+	return hoconfmt.Parse(src)
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/chankh/hoconfmt/ast"
+)
+
+func TestParseFile(t *testing.T) {
+	const src = `# top
+a.b = 1, c: [x, { d = 2 }] # note
+
+e += ${f} " g"
+include required("h.conf")
+`
+	f, err := ParseFile([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, ok := f.Root.(*ast.Object)
+	if !ok || root.Braces {
+		t.Fatalf("root is %#v, want an object without braces", f.Root)
+	}
+	items := root.Items
+	if len(items) != 5 {
+		t.Fatalf("got %d items, want 5", len(items))
+	}
+	if c, ok := items[0].Node.(*ast.Comment); !ok || c.Text != "# top" {
+		t.Errorf("item 0 is %#v, want the comment # top", items[0].Node)
+	}
+	if ab, ok := items[1].Node.(*ast.Field); !ok || ab.Key != "a.b" || ab.Sep != "=" || ab.Value.Parts[0].Text != "1" {
+		t.Errorf("item 1 is %#v, want the field a.b", items[1].Node)
+	}
+	c, ok := items[2].Node.(*ast.Field)
+	if !ok || c.Key != "c" || c.Sep != ":" || items[2].Comment == nil || items[2].Comment.Text != "# note" {
+		t.Errorf("item 2 is %#v, want the field c with a comment", items[2].Node)
+	} else if arr, ok := c.Value.Parts[0].Node.(*ast.Array); !ok || len(arr.Items) != 2 || arr.Multiline {
+		t.Errorf("c holds %#v, want a one-line array of two elements", c.Value.Parts[0].Node)
+	} else if obj, ok := arr.Items[1].Node.(*ast.Value).Parts[0].Node.(*ast.Object); !ok || !obj.Braces || obj.Multiline || len(obj.Items) != 1 {
+		t.Errorf("second element of c is %#v, want a one-line object", arr.Items[1].Node)
+	}
+	e, ok := items[3].Node.(*ast.Field)
+	if !ok || e.Sep != "+=" || !items[3].Blank || len(e.Value.Parts) != 2 || e.Value.Parts[1].Space != " " || e.Value.Parts[1].Text != `" g"` {
+		t.Errorf("item 3 is %#v, want the concatenation e after a blank line", items[3].Node)
+	}
+	if inc, ok := items[4].Node.(*ast.Include); !ok || inc.Target != `required("h.conf")` {
+		t.Errorf("item 4 is %#v, want the include", items[4].Node)
+	}
+}
+
+func TestParseFileErrors(t *testing.T) {
+	for _, test := range []struct{ src, want string }{
+		{"a = 1\nb\n", "2:1: unexpected b, expected a key"},
+		{"a = {\n", "1:5: { is not closed"},
+		{"a = \"b\n", "1:5: unterminated string"},
+	} {
+		_, err := ParseFile([]byte(test.src))
+		if err == nil || err.Error() != test.want {
+			t.Errorf("ParseFile(%q) error = %v, want %s", test.src, err, test.want)
+		}
+	}
+}